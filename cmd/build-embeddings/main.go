@@ -14,6 +14,8 @@ import (
 	"strings"
 	"time"
 
+	"meritdraft-backend/criteria"
+
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -260,7 +262,7 @@ TASK: Extract only the WINNING ARGUMENTS from this AAO Appeal Decision.
 CONTEXT: This is a "Sustained" (Approved) decision.
 
 INSTRUCTIONS:
-1. Identify which of the 10 O-1 criteria are discussed (e.g., "Judging", "Original Contributions").
+1. Identify which O-1A or O-1B criteria are discussed (e.g., "Judging", "Original Contributions").
 2. For each criterion, extract the paragraph where the AAO explains WHY the evidence was sufficient.
 3. IGNORE the Director's denial arguments.
 4. EXTRACT METRICS if present (e.g., citation counts, salary amounts, years of experience).
@@ -296,7 +298,7 @@ IMPORTANT: In the metadata.metrics object, all numeric values MUST be integers (
 - "years_experience": extract as integer from text like "10 years" → 10
 Only include metrics that are explicitly mentioned in the text. If a metric is not present, omit it from the metrics object.
 
-CRITERION_TAG must be one of: awards, membership, media_coverage, judging, original_contributions, authorship, exhibitions, critical_role, high_salary, commercial_success (or null if not applicable).
+CRITERION_TAG must be one of: `+criterionTagList()+` (or null if not applicable).
 
 Chunking Rules:
 - Extract complete winning arguments (500-1000 words)
@@ -328,7 +330,7 @@ For each chunk, extract:
 2. regulatory_citation: Array of CFR citations (e.g., ["8 CFR § 204.5(h)(3)(vi)"])
 3. case_citation: null
 4. appeal_citation: null
-5. criterion_tag: One of: awards, membership, media_coverage, judging, original_contributions, authorship, exhibitions, critical_role, high_salary, commercial_success (or null)
+5. criterion_tag: One of: `+criterionTagList()+` (or null)
 6. legal_standard: Name of legal test if applicable (e.g., "Kazarian Two-Step", "Final Merits Determination")
 7. legal_test: Full name of legal test if applicable
 8. metadata: JSON object with type-specific fields
@@ -373,7 +375,7 @@ For each chunk, extract:
 2. regulatory_citation: Array of CFR citations if applicable
 3. case_citation: Full case citation
 4. appeal_citation: null
-5. criterion_tag: One of: awards, membership, media_coverage, judging, original_contributions, authorship, exhibitions, critical_role, high_salary, commercial_success (or null)
+5. criterion_tag: One of: `+criterionTagList()+` (or null)
 6. legal_standard: Name of legal test (e.g., "Kazarian Two-Step", "Final Merits Determination")
 7. legal_test: Full name of legal test
 8. metadata: JSON object with type-specific fields
@@ -418,7 +420,7 @@ For each chunk, extract:
 2. regulatory_citation: Array of CFR citations if applicable
 3. case_citation: Full case citation if applicable
 4. appeal_citation: Full appeal citation if applicable
-5. criterion_tag: One of: awards, membership, media_coverage, judging, original_contributions, authorship, exhibitions, critical_role, high_salary, commercial_success (or null)
+5. criterion_tag: One of: `+criterionTagList()+` (or null)
 6. legal_standard: Name of legal test if applicable
 7. legal_test: Full name of legal test if applicable
 8. metadata: JSON object with type-specific fields
@@ -509,6 +511,11 @@ func callGeminiAPI(apiKey, prompt string) (string, error) {
 	return responseText.String(), nil
 }
 
+// criterionTagList lists the registered criteria tags for the chunking prompts
+func criterionTagList() string {
+	return strings.Join(criteria.Tags(), ", ")
+}
+
 // normalizeCriterionTag normalizes and validates a criterion tag against the allowed values
 func normalizeCriterionTag(tag string) string {
 	if tag == "" {
@@ -521,25 +528,8 @@ func normalizeCriterionTag(tag string) string {
 	normalized = strings.ReplaceAll(normalized, "-", "_")
 	normalized = strings.TrimSpace(normalized)
 
-	// Valid O-1 criteria tags (must match database constraint exactly)
-	validTags := map[string]bool{
-		"awards":                 true,
-		"membership":             true,
-		"media_coverage":         true,
-		"judging":                true,
-		"original_contributions": true,
-		"authorship":             true,
-		"exhibitions":            true,
-		"critical_role":          true,
-		"high_salary":            true,
-		"commercial_success":     true,
-		// NIW tags for future-proofing
-		"niw_substantial_merit":   true,
-		"niw_national_importance": true,
-		"niw_well_positioned":     true,
-	}
-
-	if validTags[normalized] {
+	// Registered criteria tags (the database constraint is built from the same registry)
+	if criteria.IsTag(normalized) {
 		return normalized
 	}
 
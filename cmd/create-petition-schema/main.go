@@ -56,6 +56,44 @@ CREATE TABLE IF NOT EXISTS users (
 	}
 	log.Println("✓ Created users table")
 
+	// Create firms table
+	firmsSQL := `
+CREATE TABLE IF NOT EXISTS firms (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    letterhead TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);`
+
+	_, err = pool.Exec(ctx, firmsSQL)
+	if err != nil {
+		log.Fatalf("Failed to create firms table: %v", err)
+	}
+	log.Println("✓ Created firms table")
+
+	// Letterhead lines printed on exported petitions, for databases created before exports
+	_, err = pool.Exec(ctx, `ALTER TABLE firms ADD COLUMN IF NOT EXISTS letterhead TEXT[] NOT NULL DEFAULT '{}'`)
+	if err != nil {
+		log.Fatalf("Failed to add firms.letterhead column: %v", err)
+	}
+
+	// Create firm_members table
+	firmMembersSQL := `
+CREATE TABLE IF NOT EXISTS firm_members (
+    firm_id UUID NOT NULL REFERENCES firms(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'attorney', 'paralegal', 'read_only')),
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (firm_id, user_id)
+);`
+
+	_, err = pool.Exec(ctx, firmMembersSQL)
+	if err != nil {
+		log.Fatalf("Failed to create firm_members table: %v", err)
+	}
+	log.Println("✓ Created firm_members table")
+
 	// Create files table (needed before petitions due to FK)
 	filesSQL := `
 CREATE TABLE IF NOT EXISTS files (
@@ -80,6 +118,7 @@ CREATE TABLE IF NOT EXISTS files (
 CREATE TABLE IF NOT EXISTS petitions (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    firm_id UUID REFERENCES firms(id) ON DELETE SET NULL,
     status VARCHAR(50) NOT NULL DEFAULT 'draft',
     
     -- Step 1: Intake
@@ -103,10 +142,13 @@ CREATE TABLE IF NOT EXISTS petitions (
     -- Step 5/6: Generation
     generated_content TEXT,
     refine_instructions TEXT,
+    drafting_model VARCHAR(50),
     
     created_at TIMESTAMP DEFAULT NOW(),
     updated_at TIMESTAMP DEFAULT NOW(),
-    completed_at TIMESTAMP
+    completed_at TIMESTAMP,
+    archived_at TIMESTAMP,
+    purge_after TIMESTAMP
 );`
 
 	_, err = pool.Exec(ctx, petitionsSQL)
@@ -115,6 +157,30 @@ CREATE TABLE IF NOT EXISTS petitions (
 	}
 	log.Println("✓ Created petitions table")
 
+	// Add firm sharing to petitions tables created before firms existed
+	_, err = pool.Exec(ctx, `ALTER TABLE petitions ADD COLUMN IF NOT EXISTS firm_id UUID REFERENCES firms(id) ON DELETE SET NULL`)
+	if err != nil {
+		log.Fatalf("Failed to add petitions.firm_id column: %v", err)
+	}
+	log.Println("✓ Ensured petitions.firm_id column")
+
+	// Add archive/purge tracking to existing petitions tables
+	_, err = pool.Exec(ctx, `
+ALTER TABLE petitions
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS purge_after TIMESTAMP`)
+	if err != nil {
+		log.Fatalf("Failed to add petitions archive columns: %v", err)
+	}
+	log.Println("✓ Ensured petitions.archived_at and petitions.purge_after columns")
+
+	// Add per-petition drafting model selection to existing petitions tables
+	_, err = pool.Exec(ctx, `ALTER TABLE petitions ADD COLUMN IF NOT EXISTS drafting_model VARCHAR(50)`)
+	if err != nil {
+		log.Fatalf("Failed to add petitions.drafting_model column: %v", err)
+	}
+	log.Println("✓ Ensured petitions.drafting_model column")
+
 	// Add FK constraint for files.petition_id after petitions table exists
 	// Check if constraint already exists first
 	var constraintExists bool
@@ -162,6 +228,11 @@ CREATE TABLE IF NOT EXISTS generation_jobs (
     current_step VARCHAR(255),
     steps JSONB,
     error_message TEXT,
+    partial_text TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    lease_owner TEXT,
+    lease_expires_at TIMESTAMP,
+    heartbeat_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT NOW(),
     updated_at TIMESTAMP DEFAULT NOW(),
     completed_at TIMESTAMP
@@ -173,6 +244,160 @@ CREATE TABLE IF NOT EXISTS generation_jobs (
 	}
 	log.Println("✓ Created generation_jobs table")
 
+	// Add queue lease tracking to existing generation_jobs tables
+	_, err = pool.Exec(ctx, `
+ALTER TABLE generation_jobs
+    ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS lease_owner TEXT,
+    ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP`)
+	if err != nil {
+		log.Fatalf("Failed to add generation_jobs lease columns: %v", err)
+	}
+	log.Println("✓ Ensured generation_jobs lease columns")
+
+	// Add streamed section text to existing generation_jobs tables
+	_, err = pool.Exec(ctx, `ALTER TABLE generation_jobs ADD COLUMN IF NOT EXISTS partial_text TEXT`)
+	if err != nil {
+		log.Fatalf("Failed to add generation_jobs.partial_text column: %v", err)
+	}
+	log.Println("✓ Ensured generation_jobs.partial_text column")
+
+	// Create generation_job_sections table (sections saved as each step completes)
+	generationJobSectionsSQL := `
+CREATE TABLE IF NOT EXISTS generation_job_sections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_id UUID NOT NULL REFERENCES generation_jobs(id) ON DELETE CASCADE,
+    step_name VARCHAR(255) NOT NULL,
+    position INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    citations TEXT[] NOT NULL DEFAULT '{}',
+    chunk_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (job_id, step_name)
+);`
+
+	_, err = pool.Exec(ctx, generationJobSectionsSQL)
+	if err != nil {
+		log.Fatalf("Failed to create generation_job_sections table: %v", err)
+	}
+	log.Println("✓ Created generation_job_sections table")
+
+	// Create petition_sections table (the current draft of each petition, in document order)
+	petitionSectionsSQL := `
+CREATE TABLE IF NOT EXISTS petition_sections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    petition_id UUID NOT NULL REFERENCES petitions(id) ON DELETE CASCADE,
+    job_id UUID REFERENCES generation_jobs(id) ON DELETE SET NULL,
+    position INTEGER NOT NULL,
+    level INTEGER NOT NULL,
+    step_name VARCHAR(255),
+    criterion VARCHAR(100),
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    citations TEXT[] NOT NULL DEFAULT '{}',
+    chunk_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (petition_id, position)
+);`
+
+	_, err = pool.Exec(ctx, petitionSectionsSQL)
+	if err != nil {
+		log.Fatalf("Failed to create petition_sections table: %v", err)
+	}
+	log.Println("✓ Created petition_sections table")
+
+	// Create document_extractions table (text and evidence parsed from uploads)
+	documentExtractionsSQL := `
+CREATE TABLE IF NOT EXISTS document_extractions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    file_id UUID NOT NULL UNIQUE REFERENCES files(id) ON DELETE CASCADE,
+    petition_id UUID REFERENCES petitions(id) ON DELETE SET NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    text TEXT NOT NULL DEFAULT '',
+    candidates JSONB NOT NULL DEFAULT '[]'::jsonb,
+    prefilled TEXT[] NOT NULL DEFAULT '{}',
+    error_message TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    lease_expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP
+);`
+
+	_, err = pool.Exec(ctx, documentExtractionsSQL)
+	if err != nil {
+		log.Fatalf("Failed to create document_extractions table: %v", err)
+	}
+	log.Println("✓ Created document_extractions table")
+
+	// Create exhibits table (uploaded files tagged as evidence of criterion facts)
+	exhibitsSQL := `
+CREATE TABLE IF NOT EXISTS exhibits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    petition_id UUID NOT NULL REFERENCES petitions(id) ON DELETE CASCADE,
+    file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    criterion VARCHAR(100) NOT NULL,
+    fact_key VARCHAR(100),
+    fact_index INTEGER,
+    description TEXT NOT NULL DEFAULT '',
+    label VARCHAR(10) NOT NULL DEFAULT '',
+    reference_count INTEGER NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (petition_id, file_id)
+);`
+
+	_, err = pool.Exec(ctx, exhibitsSQL)
+	if err != nil {
+		log.Fatalf("Failed to create exhibits table: %v", err)
+	}
+	log.Println("✓ Created exhibits table")
+
+	// Create packets table (filing packets bundled in the background)
+	packetsSQL := `
+CREATE TABLE IF NOT EXISTS packets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    petition_id UUID NOT NULL REFERENCES petitions(id) ON DELETE CASCADE,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    bates_prefix VARCHAR(20) NOT NULL DEFAULT '',
+    file_id UUID REFERENCES files(id) ON DELETE SET NULL,
+    page_count INTEGER NOT NULL DEFAULT 0,
+    exhibits JSONB NOT NULL DEFAULT '[]'::jsonb,
+    error_message TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    lease_expires_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP
+);`
+
+	_, err = pool.Exec(ctx, packetsSQL)
+	if err != nil {
+		log.Fatalf("Failed to create packets table: %v", err)
+	}
+	log.Println("✓ Created packets table")
+
+	// Create blob_deletions table (storage blobs of purged files, deleted
+	// after commit and retried until storage confirms)
+	blobDeletionsSQL := `
+CREATE TABLE IF NOT EXISTS blob_deletions (
+    storage_path TEXT PRIMARY KEY,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);`
+
+	_, err = pool.Exec(ctx, blobDeletionsSQL)
+	if err != nil {
+		log.Fatalf("Failed to create blob_deletions table: %v", err)
+	}
+	log.Println("✓ Created blob_deletions table")
+
 	// Create indexes
 	indexes := []struct {
 		name string
@@ -190,6 +415,22 @@ CREATE TABLE IF NOT EXISTS generation_jobs (
 			name: "idx_petitions_created_at",
 			sql:  "CREATE INDEX IF NOT EXISTS idx_petitions_created_at ON petitions(created_at DESC);",
 		},
+		{
+			name: "idx_petitions_updated_at",
+			sql:  "CREATE INDEX IF NOT EXISTS idx_petitions_updated_at ON petitions(updated_at DESC);",
+		},
+		{
+			name: "idx_petitions_purge_after",
+			sql:  "CREATE INDEX IF NOT EXISTS idx_petitions_purge_after ON petitions(purge_after) WHERE purge_after IS NOT NULL;",
+		},
+		{
+			name: "idx_petitions_firm_id",
+			sql:  "CREATE INDEX IF NOT EXISTS idx_petitions_firm_id ON petitions(firm_id) WHERE firm_id IS NOT NULL;",
+		},
+		{
+			name: "idx_firm_members_user_id",
+			sql:  "CREATE INDEX IF NOT EXISTS idx_firm_members_user_id ON firm_members(user_id);",
+		},
 		{
 			name: "idx_files_user_id",
 			sql:  "CREATE INDEX IF NOT EXISTS idx_files_user_id ON files(user_id);",
@@ -206,6 +447,18 @@ CREATE TABLE IF NOT EXISTS generation_jobs (
 			name: "idx_generation_jobs_status",
 			sql:  "CREATE INDEX IF NOT EXISTS idx_generation_jobs_status ON generation_jobs(status);",
 		},
+		{
+			name: "idx_generation_jobs_queue",
+			sql:  "CREATE INDEX IF NOT EXISTS idx_generation_jobs_queue ON generation_jobs(status, created_at) WHERE status IN ('pending', 'in_progress');",
+		},
+		{
+			name: "idx_document_extractions_queue",
+			sql:  "CREATE INDEX IF NOT EXISTS idx_document_extractions_queue ON document_extractions(status, created_at) WHERE status IN ('pending', 'in_progress');",
+		},
+		{
+			name: "idx_packets_queue",
+			sql:  "CREATE INDEX IF NOT EXISTS idx_packets_queue ON packets(status, created_at) WHERE status IN ('pending', 'in_progress');",
+		},
 	}
 
 	for _, idx := range indexes {
@@ -218,7 +471,7 @@ CREATE TABLE IF NOT EXISTS generation_jobs (
 	}
 
 	fmt.Println("\n✅ Core entity schema created successfully!")
-	fmt.Println("   Tables: users, files, petitions, user_preferences, generation_jobs")
-	fmt.Println("   Indexes: 7 indexes created")
+	fmt.Println("   Tables: users, firms, firm_members, files, petitions, user_preferences, generation_jobs, generation_job_sections, petition_sections, document_extractions, exhibits, packets")
+	fmt.Println("   Indexes: 14 indexes created")
 }
 
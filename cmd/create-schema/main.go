@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+
+	"meritdraft-backend/criteria"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -63,7 +66,7 @@ CREATE TABLE legal_chunks (
     criterion_tag VARCHAR(100),
     
     -- Visa type (O-1 for MVP, expandable for future visa types)
-    visa_type VARCHAR(20) NOT NULL DEFAULT 'O-1' CHECK (visa_type IN ('O-1', 'NIW', 'EB-1A')),
+    visa_type VARCHAR(20) NOT NULL DEFAULT 'O-1' CHECK (visa_type IN (%s)),
     
     -- Legal standards and tests (unified across types)
     legal_standard VARCHAR(255),
@@ -94,18 +97,13 @@ CREATE TABLE legal_chunks (
     -- === CONSTRAINTS ===
     CONSTRAINT chunk_order_unique UNIQUE (source_document, chunk_index),
     
-    -- Ensure only valid O-1 criteria tags (with future-proofing for NIW tags)
+    -- Ensure only criteria tags registered in the criteria package
     CONSTRAINT check_o1_criteria CHECK (
         criterion_tag IS NULL OR 
-        criterion_tag IN (
-            'awards', 'membership', 'media_coverage', 'judging', 
-            'original_contributions', 'authorship', 'exhibitions', 
-            'critical_role', 'high_salary', 'commercial_success',
-            -- Keep these for future-proofing but ignore them in queries for now
-            'niw_substantial_merit', 'niw_national_importance', 'niw_well_positioned'
-        )
+        criterion_tag IN (%s)
     )
 );`
+	schemaSQL = fmt.Sprintf(schemaSQL, sqlList(criteria.Corpora()), sqlList(criteria.Tags()))
 
 	_, err = pool.Exec(ctx, schemaSQL)
 	if err != nil {
@@ -198,3 +196,12 @@ WHERE source_type = 'appeal_decision';`,
 	fmt.Println("   Table: legal_chunks")
 	fmt.Println("   Indexes: 15 indexes created")
 }
+
+// sqlList formats values as a quoted SQL list for an IN (...) constraint
+func sqlList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -2,19 +2,25 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"meritdraft-backend/handlers"
+	"meritdraft-backend/llm"
 	"meritdraft-backend/repository"
 	"meritdraft-backend/service"
 	"meritdraft-backend/storage"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/generative-ai-go/genai"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
-	"google.golang.org/api/option"
 )
 
 func main() {
@@ -45,33 +51,143 @@ func main() {
 	jobRepo := repository.NewGenerationJobRepository(db)
 	fileRepo := repository.NewFileRepository(db)
 	legalChunkRepo := repository.NewLegalChunkRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	firmRepo := repository.NewFirmRepository(db)
+	jobEventRepo := repository.NewJobEventRepository(db)
+	extractionRepo := repository.NewDocumentExtractionRepository(db)
+	exhibitRepo := repository.NewExhibitRepository(db)
+	packetRepo := repository.NewPacketRepository(db)
+	sectionRepo := repository.NewPetitionSectionRepository(db)
 
-	// Initialize Gemini client
-	geminiClient, err := initGemini()
+	// Initialize LLM provider (LLM_PROVIDER=gemini by default, or fake for offline use)
+	llmConfig := llm.ConfigFromEnv()
+	generator, embedder, err := llm.New(llmConfig)
 	if err != nil {
-		log.Fatal("Failed to initialize Gemini:", err)
+		log.Fatal("Failed to initialize LLM provider:", err)
 	}
 
 	// Initialize services
+	authService := service.NewAuthService(
+		service.AuthWithUserRepository(userRepo),
+		service.AuthWithSecret(loadSessionSecret()),
+		service.AuthWithTokenTTL(loadDuration("SESSION_TTL", 24*time.Hour)),
+	)
+
 	petitionService := service.NewPetitionService(
 		service.WithPetitionRepository(petitionRepo),
 		service.WithGenerationJobRepository(jobRepo),
+		service.WithFirmRepository(firmRepo),
+		service.WithFileRepository(fileRepo),
+		service.WithExhibitRepository(exhibitRepo),
+		service.WithPetitionSectionRepository(sectionRepo),
+		service.WithStorage(fileStorage),
+		service.WithPurgeRetention(loadDuration("PURGE_RETENTION", 30*24*time.Hour)),
 	)
 
+	// Stop background work and drain HTTP requests on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Background work is waited for before the database is closed
+	var workerWG sync.WaitGroup
+
+	// Hard-delete archived petitions whose retention window has elapsed
+	workerWG.Add(1)
+	go func() {
+		defer workerWG.Done()
+		petitionService.RunPurger(ctx, loadDuration("PURGE_INTERVAL", time.Hour))
+	}()
+
+	firmService := service.NewFirmService(
+		service.FirmWithFirmRepository(firmRepo),
+		service.FirmWithUserRepository(userRepo),
+	)
+
+	// Relay job progress from NOTIFY to SSE subscribers on this instance
+	jobEventService := service.NewJobEventService(
+		service.JobEventWithRepository(jobEventRepo),
+	)
+	go jobEventService.Run(ctx)
+
 	draftService := service.NewDraftService(
 		service.DraftWithPetitionRepository(petitionRepo),
 		service.DraftWithGenerationJobRepository(jobRepo),
 		service.DraftWithLegalChunkRepository(legalChunkRepo),
+		service.DraftWithDocumentExtractionRepository(extractionRepo),
+		service.DraftWithExhibitRepository(exhibitRepo),
 		service.DraftWithDatabase(db),
-		service.DraftWithGeminiClient(geminiClient),
+		service.DraftWithTextGenerator(generator),
+		service.DraftWithTextGenerators(llm.NewGenerators(llmConfig)),
+		service.DraftWithEmbedder(embedder),
+		service.DraftWithJobEventService(jobEventService),
+	)
+
+	exhibitService := service.NewExhibitService(
+		service.ExhibitWithRepository(exhibitRepo),
+		service.ExhibitWithFileRepository(fileRepo),
 	)
 
+	packetService := service.NewPacketService(
+		service.PacketWithRepository(packetRepo),
+		service.PacketWithPetitionRepository(petitionRepo),
+		service.PacketWithExhibitRepository(exhibitRepo),
+		service.PacketWithPetitionSectionRepository(sectionRepo),
+		service.PacketWithFileRepository(fileRepo),
+		service.PacketWithPetitionService(petitionService),
+		service.PacketWithStorage(fileStorage),
+		service.PacketWithMaxAttempts(loadInt("WORKER_MAX_ATTEMPTS", 3)),
+	)
+
+	documentService := service.NewDocumentService(
+		service.DocumentWithExtractionRepository(extractionRepo),
+		service.DocumentWithFileRepository(fileRepo),
+		service.DocumentWithPetitionRepository(petitionRepo),
+		service.DocumentWithStorage(fileStorage),
+		service.DocumentWithMaxAttempts(loadInt("WORKER_MAX_ATTEMPTS", 3)),
+	)
+
+	// Process queued generation jobs, document extractions and packet
+	// builds in-process unless WORKER_CONCURRENCY=0, in which case they are
+	// left to the standalone cmd/worker binary
+	if concurrency := loadInt("WORKER_CONCURRENCY", 2); concurrency > 0 {
+		workerWG.Add(1)
+		go func() {
+			defer workerWG.Done()
+			documentService.RunExtractor(ctx, loadDuration("WORKER_POLL_INTERVAL", 2*time.Second))
+		}()
+		workerWG.Add(1)
+		go func() {
+			defer workerWG.Done()
+			packetService.RunBuilder(ctx, loadDuration("WORKER_POLL_INTERVAL", 2*time.Second))
+		}()
+
+		worker := service.NewWorker(
+			service.WorkerWithDraftService(draftService),
+			service.WorkerWithGenerationJobRepository(jobRepo),
+			service.WorkerWithConcurrency(concurrency),
+			service.WorkerWithPollInterval(loadDuration("WORKER_POLL_INTERVAL", 2*time.Second)),
+			service.WorkerWithLeaseDuration(loadDuration("WORKER_LEASE_DURATION", 2*time.Minute)),
+			service.WorkerWithMaxAttempts(loadInt("WORKER_MAX_ATTEMPTS", 3)),
+		)
+		workerWG.Add(1)
+		go func() {
+			defer workerWG.Done()
+			if err := worker.Run(ctx); err != nil {
+				log.Printf("Worker stopped: %v", err)
+			}
+		}()
+	}
+
 	// Initialize handlers
-	petitionHandler := handlers.NewPetitionHandler(petitionService, draftService)
-	fileHandler := handlers.NewFileHandler(fileRepo, petitionRepo, fileStorage)
+	authHandler := handlers.NewAuthHandler(authService)
+	firmHandler := handlers.NewFirmHandler(firmService)
+	petitionHandler := handlers.NewPetitionHandler(petitionService, draftService, jobEventService, exhibitService, packetService)
+	fileHandler := handlers.NewFileHandler(fileRepo, petitionRepo, fileStorage, documentService)
+	criteriaHandler := handlers.NewCriteriaHandler()
 
-	// Setup Gin router
-	r := gin.Default()
+	// Setup Gin router; the request log redacts ?access_token=
+	r := gin.New()
+	r.Use(handlers.RequestLogger(), gin.Recovery())
 
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
@@ -80,21 +196,61 @@ func main() {
 		})
 	})
 
-	// API routes
-	api := r.Group("/api")
+	// Public auth routes
+	r.POST("/api/auth/login", authHandler.Login)
+
+	// Job event stream; also accepts ?access_token= since EventSource cannot set headers
+	r.GET("/api/jobs/:id/events", handlers.TokenFromQuery(), handlers.RequireAuth(authService), petitionHandler.StreamJobEvents)
+
+	// API routes (require a valid session token)
+	api := r.Group("/api", handlers.RequireAuth(authService))
 	{
+		// Auth endpoints
+		api.GET("/auth/me", authHandler.Me)
+
 		// Petition endpoints
 		api.POST("/petitions", petitionHandler.CreatePetition)
+		api.GET("/petitions", petitionHandler.ListPetitions)
 		api.GET("/petitions/:id", petitionHandler.GetPetition)
 		api.PUT("/petitions/:id", petitionHandler.UpdatePetition)
+		api.DELETE("/petitions/:id", petitionHandler.DeletePetition)
+		api.POST("/petitions/:id/restore", petitionHandler.RestorePetition)
+		api.GET("/petitions/:id/sections", petitionHandler.ListSections)
+		api.PUT("/petitions/:id/sections/:section_id", petitionHandler.UpdateSection)
+		api.GET("/petitions/:id/readiness", petitionHandler.GetReadiness)
+		api.POST("/petitions/:id/strategy", petitionHandler.SuggestStrategy)
+		api.POST("/petitions/:id/scholar", petitionHandler.ImportScholarProfile)
+		api.GET("/petitions/:id/export", petitionHandler.ExportPetition)
+		api.POST("/petitions/:id/export", petitionHandler.SaveExport)
+		api.POST("/petitions/:id/exhibits", petitionHandler.CreateExhibit)
+		api.GET("/petitions/:id/exhibits", petitionHandler.ListExhibits)
+		api.DELETE("/petitions/:id/exhibits/:exhibit_id", petitionHandler.DeleteExhibit)
+		api.POST("/petitions/:id/packet", petitionHandler.CreatePacket)
+		api.GET("/petitions/:id/packet/:packet_id", petitionHandler.GetPacket)
 		api.POST("/petitions/:id/generate", petitionHandler.GenerateDraft)
 
+		// Firm endpoints
+		api.POST("/firms", firmHandler.CreateFirm)
+		api.GET("/firms", firmHandler.ListFirms)
+		api.GET("/firms/:id", firmHandler.GetFirm)
+		api.PUT("/firms/:id", firmHandler.UpdateFirm)
+		api.POST("/firms/:id/members", firmHandler.AddMember)
+		api.PUT("/firms/:id/members/:user_id", firmHandler.UpdateMember)
+		api.DELETE("/firms/:id/members/:user_id", firmHandler.RemoveMember)
+
 		// Job endpoints
 		api.GET("/jobs/:id", petitionHandler.GetJobStatus)
+		api.POST("/jobs/:id/cancel", petitionHandler.CancelJob)
+		api.POST("/jobs/:id/retry", petitionHandler.RetryJob)
 
 		// File endpoints
 		api.POST("/files/upload", fileHandler.UploadFile)
 		api.GET("/files/:id", fileHandler.GetFile)
+		api.GET("/files/:id/extraction", fileHandler.GetExtraction)
+		api.POST("/files/:id/extraction", fileHandler.ExtractFile)
+
+		// Criteria endpoints
+		api.GET("/criteria/:visa_type/schema", criteriaHandler.GetSchema)
 	}
 
 	// Start server
@@ -103,10 +259,29 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: HTTP server shutdown: %v", err)
 	}
+
+	// In-flight jobs are released back to the queue by the worker
+	workerWG.Wait()
 }
 
 func initPostgres() (*pgxpool.Pool, error) {
@@ -138,18 +313,47 @@ func initPostgres() (*pgxpool.Pool, error) {
 	return pool, nil
 }
 
-func initGemini() (*genai.Client, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		log.Println("Warning: GEMINI_API_KEY not set")
+// loadSessionSecret reads the HMAC key for session tokens from SESSION_SECRET
+func loadSessionSecret() []byte {
+	secret := os.Getenv("SESSION_SECRET")
+	if secret != "" {
+		return []byte(secret)
 	}
 
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	log.Println("Warning: SESSION_SECRET not set, generating an ephemeral secret (sessions will not survive restarts)")
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Fatal("Failed to generate session secret:", err)
+	}
+	return buf
+}
+
+// loadDuration reads a duration such as "12h" from an environment variable
+func loadDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
 	if err != nil {
-		return nil, err
+		log.Printf("Warning: Invalid %s %q, using %s", name, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// loadInt reads an integer from an environment variable
+func loadInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
 	}
 
-	log.Println("Gemini client initialized")
-	return client, nil
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: Invalid %s %q, using %d", name, value, fallback)
+		return fallback
+	}
+	return n
 }
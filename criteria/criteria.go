@@ -0,0 +1,229 @@
+// Package criteria is the registry of what each visa type is argued on:
+// its regulatory criteria (or Dhanasar prongs), their citations, fallback
+// regulation text, the facts collected for each, and prompt hints. The
+// registry is loaded from the embedded criteria.json, so adding a visa type
+// or a criterion is a data change.
+package criteria
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+//go:embed criteria.json
+var registryJSON []byte
+
+// Fact types
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+)
+
+// FactField describes one fact collected for a criterion
+type FactField struct {
+	Key      string      `json:"key"`      // Key within the criterion's CriteriaDetail
+	Label    string      `json:"label"`    // Label used when presenting the fact to the drafting model
+	Type     string      `json:"type"`     // One of the fact types; defaults to string
+	Items    []FactField `json:"items"`    // Fields of each entry of an array; entries are strings when empty
+	Minimum  *float64    `json:"minimum"`  // Lower bound of an integer or number
+	Required bool        `json:"required"` // Must be present and, for arrays, non-empty
+	Advice   string      `json:"advice"`   // Readiness warning shown when the fact is missing; marks it as strengthening the case
+}
+
+// Criterion describes one criterion of a visa type
+type Criterion struct {
+	ID          string      `json:"id"`
+	Title       string      `json:"title"`     // Section heading in the assembled petition
+	StepName    string      `json:"step_name"` // Generation step name
+	Citation    string      `json:"citation"`
+	Regulation  string      `json:"regulation"` // Fallback regulation text when retrieval finds none
+	Tags        []string    `json:"tags"`       // legal_chunks criterion tags; defaults to [ID], empty searches untagged chunks
+	Facts       []FactField `json:"facts"`
+	PromptHints []string    `json:"prompt_hints"`
+	Synthesis   bool        `json:"synthesis"`  // Built on the sections before it, so always redrafted on retry
+	Standalone  bool        `json:"standalone"` // Satisfies the evidentiary requirements on its own, without min_criteria
+}
+
+// FinalMerits holds the framing of the Final Merits Determination prompt
+type FinalMerits struct {
+	Standard    string `json:"standard"`     // How the Kazarian two-part test applies
+	Acclaim     string `json:"acclaim"`      // What the totality of the evidence must establish
+	Citations   string `json:"citations"`    // Citations the section must include
+	LinkExample string `json:"link_example"` // Example of linking the criteria together
+}
+
+// VisaType describes how petitions of one visa type are drafted
+type VisaType struct {
+	VisaType           string       `json:"visa_type"` // models.VisaType value
+	Corpus             string       `json:"corpus"`    // legal_chunks.visa_type holding its authority
+	Classification     string       `json:"classification"`
+	Heading            string       `json:"heading"` // Title line of the assembled petition
+	FallbackRegulation string       `json:"fallback_regulation"`
+	DenialExample      string       `json:"denial_example"` // Common denial reason the criterion sections preempt
+	MinCriteria        int          `json:"min_criteria"`   // Criteria the regulations require; 0 when there is no minimum
+	FinalMerits        *FinalMerits `json:"final_merits"`   // Nil when the visa type has no final merits step
+	Criteria           []Criterion  `json:"criteria"`
+}
+
+var registry = mustLoad(registryJSON)
+
+// mustLoad parses and validates the registry. The registry is embedded, so
+// an invalid one is a build defect.
+func mustLoad(data []byte) []VisaType {
+	var file struct {
+		VisaTypes []VisaType `json:"visa_types"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		panic(fmt.Sprintf("criteria: invalid registry: %v", err))
+	}
+	if len(file.VisaTypes) == 0 {
+		panic("criteria: registry has no visa types")
+	}
+
+	seen := make(map[string]bool)
+	for i := range file.VisaTypes {
+		visa := &file.VisaTypes[i]
+		if visa.VisaType == "" || visa.Corpus == "" || seen[visa.VisaType] {
+			panic(fmt.Sprintf("criteria: invalid or duplicate visa type %q", visa.VisaType))
+		}
+		seen[visa.VisaType] = true
+
+		ids := make(map[string]bool)
+		for j := range visa.Criteria {
+			criterion := &visa.Criteria[j]
+			if criterion.ID == "" || ids[criterion.ID] {
+				panic(fmt.Sprintf("criteria: invalid or duplicate criterion %q in %s", criterion.ID, visa.VisaType))
+			}
+			ids[criterion.ID] = true
+
+			// An explicit empty list means the criterion searches untagged chunks
+			if criterion.Tags == nil {
+				criterion.Tags = []string{criterion.ID}
+			}
+
+			if err := normalizeFacts(criterion.Facts); err != nil {
+				panic(fmt.Sprintf("criteria: %s in %s: %v", criterion.ID, visa.VisaType, err))
+			}
+		}
+	}
+
+	return file.VisaTypes
+}
+
+// normalizeFacts defaults fact types and checks that each is known
+func normalizeFacts(fields []FactField) error {
+	for i := range fields {
+		field := &fields[i]
+		if field.Type == "" {
+			field.Type = TypeString
+		}
+		switch field.Type {
+		case TypeString, TypeInteger, TypeNumber, TypeBoolean:
+		case TypeArray:
+			if err := normalizeFacts(field.Items); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("fact %q has unknown type %q", field.Key, field.Type)
+		}
+	}
+	return nil
+}
+
+// All returns every registered visa type
+func All() []VisaType {
+	return registry
+}
+
+// Lookup returns the registered visa type
+func Lookup(visaType string) (*VisaType, bool) {
+	for i := range registry {
+		if registry[i].VisaType == visaType {
+			return &registry[i], true
+		}
+	}
+	return nil, false
+}
+
+// Criterion returns the criterion with the given ID
+func (v *VisaType) Criterion(id string) (*Criterion, bool) {
+	for i := range v.Criteria {
+		if v.Criteria[i].ID == id {
+			return &v.Criteria[i], true
+		}
+	}
+	return nil, false
+}
+
+// MissingFacts returns the labels of required facts absent from details
+func (c *Criterion) MissingFacts(details map[string]interface{}) []string {
+	var missing []string
+	for _, field := range c.Facts {
+		if !field.Required {
+			continue
+		}
+		if isEmpty(details[field.Key]) {
+			missing = append(missing, field.Label)
+		}
+	}
+	return missing
+}
+
+// Tags returns every legal_chunks criterion tag across all visa types, sorted
+func Tags() []string {
+	set := make(map[string]bool)
+	for _, visa := range registry {
+		for _, criterion := range visa.Criteria {
+			for _, tag := range criterion.Tags {
+				set[tag] = true
+			}
+		}
+	}
+	return sortedKeys(set)
+}
+
+// Corpora returns every legal_chunks visa type, sorted
+func Corpora() []string {
+	set := make(map[string]bool)
+	for _, visa := range registry {
+		set[visa.Corpus] = true
+	}
+	return sortedKeys(set)
+}
+
+// IsTag reports whether tag is a registered legal_chunks criterion tag
+func IsTag(tag string) bool {
+	for _, t := range Tags() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSynthesisStep reports whether the named generation step drafts a
+// synthesis criterion of any visa type
+func IsSynthesisStep(stepName string) bool {
+	for _, visa := range registry {
+		for _, criterion := range visa.Criteria {
+			if criterion.Synthesis && criterion.StepName == stepName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,59 @@
+package criteria
+
+import "fmt"
+
+// Gap is a fact a criterion's details do not provide
+type Gap struct {
+	Field    string // Path such as criteria_details.judging.papers_reviewed
+	Label    string
+	Required bool   // Drafting cannot start without it
+	Advice   string // Why providing it strengthens the case; empty for required facts without advice
+}
+
+// Gaps returns the required facts and the facts with advice that are
+// absent from details, including those of each array entry
+func (c *Criterion) Gaps(details map[string]interface{}) []Gap {
+	return objectGaps("criteria_details."+c.ID, c.Facts, details)
+}
+
+// objectGaps returns the gaps of an object holding fields
+func objectGaps(path string, fields []FactField, object map[string]interface{}) []Gap {
+	var gaps []Gap
+	for _, field := range fields {
+		fieldPath := path + "." + field.Key
+		value := object[field.Key]
+
+		if isEmpty(value) {
+			if field.Required || field.Advice != "" {
+				gaps = append(gaps, Gap{Field: fieldPath, Label: field.Label, Required: field.Required, Advice: field.Advice})
+			}
+			continue
+		}
+
+		entries, ok := value.([]interface{})
+		if !ok || len(field.Items) == 0 {
+			continue
+		}
+		for i, entry := range entries {
+			if entryObject, ok := entry.(map[string]interface{}); ok {
+				gaps = append(gaps, objectGaps(fmt.Sprintf("%s[%d]", fieldPath, i), field.Items, entryObject)...)
+			}
+		}
+	}
+	return gaps
+}
+
+// Coverage returns the fraction of the criterion's top-level facts present
+// in details, or 0 when it collects none
+func (c *Criterion) Coverage(details map[string]interface{}) float64 {
+	if len(c.Facts) == 0 {
+		return 0
+	}
+	filled := 0
+	for _, field := range c.Facts {
+		if !isEmpty(details[field.Key]) {
+			filled++
+		}
+	}
+	return float64(filled) / float64(len(c.Facts))
+}
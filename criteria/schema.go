@@ -0,0 +1,207 @@
+package criteria
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// jsonSchemaDraft identifies the JSON Schema dialect of generated schemas
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// FieldError describes one field of criteria_details that failed validation
+type FieldError struct {
+	Field   string `json:"field"` // Path such as criteria_details.awards.awards[0].name
+	Message string `json:"message"`
+}
+
+// JSONSchema returns the JSON Schema of a petition's criteria_details for
+// this visa type, with one property per criterion
+func (v *VisaType) JSONSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(v.Criteria))
+	for _, criterion := range v.Criteria {
+		schema := objectSchema(criterion.Facts)
+		schema["title"] = criterion.Title
+		properties[criterion.ID] = schema
+	}
+
+	return map[string]interface{}{
+		"$schema":              jsonSchemaDraft,
+		"title":                v.VisaType + " criteria details",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// objectSchema returns the schema of an object holding fields
+func objectSchema(fields []FactField) map[string]interface{} {
+	properties := make(map[string]interface{}, len(fields))
+	required := make([]string, 0)
+	for _, field := range fields {
+		properties[field.Key] = fieldSchema(field)
+		if field.Required {
+			required = append(required, field.Key)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fieldSchema returns the schema of one fact
+func fieldSchema(field FactField) map[string]interface{} {
+	var schema map[string]interface{}
+	switch field.Type {
+	case TypeArray:
+		items := map[string]interface{}{"type": TypeString}
+		if len(field.Items) > 0 {
+			items = objectSchema(field.Items)
+		}
+		schema = map[string]interface{}{
+			"type":  TypeArray,
+			"items": items,
+		}
+		if field.Required {
+			schema["minItems"] = 1
+		}
+	default:
+		schema = map[string]interface{}{"type": field.Type}
+		if field.Minimum != nil {
+			schema["minimum"] = *field.Minimum
+		}
+		if field.Type == TypeString && field.Required {
+			schema["minLength"] = 1
+		}
+	}
+	schema["title"] = field.Label
+	return schema
+}
+
+// Validate checks criteria_details as decoded from a JSON request body
+// against this visa type's schema and returns every failing field
+func (v *VisaType) Validate(details map[string]interface{}) []FieldError {
+	var errs []FieldError
+
+	for _, id := range sortedMapKeys(details) {
+		path := "criteria_details." + id
+		criterion, ok := v.Criterion(id)
+		if !ok {
+			errs = append(errs, FieldError{Field: path, Message: "is not a criterion of " + v.VisaType})
+			continue
+		}
+		errs = append(errs, validateObject(path, criterion.Facts, details[id])...)
+	}
+
+	return errs
+}
+
+// validateObject validates value as an object holding fields
+func validateObject(path string, fields []FactField, value interface{}) []FieldError {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return []FieldError{{Field: path, Message: "must be an object"}}
+	}
+
+	var errs []FieldError
+	known := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		known[field.Key] = true
+		fieldPath := path + "." + field.Key
+
+		fieldValue, present := object[field.Key]
+		if !present || fieldValue == nil {
+			if field.Required {
+				errs = append(errs, FieldError{Field: fieldPath, Message: "is required"})
+			}
+			continue
+		}
+		errs = append(errs, validateField(fieldPath, field, fieldValue)...)
+	}
+
+	for _, key := range sortedMapKeys(object) {
+		if !known[key] {
+			errs = append(errs, FieldError{Field: path + "." + key, Message: "is not a recognized field"})
+		}
+	}
+
+	return errs
+}
+
+// validateField validates one present, non-null fact
+func validateField(path string, field FactField, value interface{}) []FieldError {
+	switch field.Type {
+	case TypeString:
+		s, ok := value.(string)
+		if !ok {
+			return []FieldError{{Field: path, Message: "must be a string"}}
+		}
+		if field.Required && s == "" {
+			return []FieldError{{Field: path, Message: "is required"}}
+		}
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return []FieldError{{Field: path, Message: "must be a boolean"}}
+		}
+	case TypeInteger, TypeNumber:
+		n, ok := value.(float64)
+		if !ok {
+			return []FieldError{{Field: path, Message: "must be a number"}}
+		}
+		if field.Type == TypeInteger && n != math.Trunc(n) {
+			return []FieldError{{Field: path, Message: "must be a whole number"}}
+		}
+		if field.Minimum != nil && n < *field.Minimum {
+			return []FieldError{{Field: path, Message: fmt.Sprintf("must be at least %g", *field.Minimum)}}
+		}
+	case TypeArray:
+		entries, ok := value.([]interface{})
+		if !ok {
+			return []FieldError{{Field: path, Message: "must be an array"}}
+		}
+		if field.Required && len(entries) == 0 {
+			return []FieldError{{Field: path, Message: "must have at least one entry"}}
+		}
+
+		var errs []FieldError
+		for i, entry := range entries {
+			entryPath := fmt.Sprintf("%s[%d]", path, i)
+			if len(field.Items) > 0 {
+				errs = append(errs, validateObject(entryPath, field.Items, entry)...)
+			} else if _, ok := entry.(string); !ok {
+				errs = append(errs, FieldError{Field: entryPath, Message: "must be a string"})
+			}
+		}
+		return errs
+	}
+	return nil
+}
+
+// isEmpty reports whether a fact value is missing, null, "" or an empty array
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
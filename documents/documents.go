@@ -0,0 +1,98 @@
+// Package documents turns uploaded files into evidence: it extracts plain
+// text from PDF, DOCX and TXT uploads and finds candidate facts (publications,
+// awards, memberships, review service, roles, salary) in CVs and offer
+// letters. Every candidate keeps the span of text it was read from so an
+// attorney can check it against the source before relying on it. PDF pages
+// can also be imported whole, for bundling into filing packets.
+package documents
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// MIME types with a text extractor
+const (
+	MimeTypePDF  = "application/pdf"
+	MimeTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	MimeTypeText = "text/plain"
+)
+
+var (
+	// ErrUnsupportedFormat is returned for files with no text extractor
+	ErrUnsupportedFormat = errors.New("unsupported document format")
+	// ErrNoText is returned when a document holds no extractable text, e.g. a scanned PDF
+	ErrNoText = errors.New("document contains no extractable text")
+)
+
+// Supported reports whether text can be extracted from files of this type
+func Supported(mimeType, filename string) bool {
+	return format(mimeType, filename) != ""
+}
+
+// ExtractText returns the plain text of a PDF, DOCX or text document, with
+// paragraphs separated by newlines
+func ExtractText(mimeType, filename string, data []byte) (string, error) {
+	var text string
+	var err error
+
+	switch format(mimeType, filename) {
+	case MimeTypePDF:
+		text, err = extractPDF(data)
+	case MimeTypeDOCX:
+		text, err = extractDOCX(data)
+	case MimeTypeText:
+		if !utf8.Valid(data) {
+			data = []byte(strings.ToValidUTF8(string(data), "\uFFFD"))
+		}
+		text = string(data)
+	default:
+		return "", ErrUnsupportedFormat
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text = normalizeText(text)
+	if strings.TrimSpace(text) == "" {
+		return "", ErrNoText
+	}
+	return text, nil
+}
+
+// format resolves the extractor for a file from its MIME type, falling back
+// to the file extension for generic uploads
+func format(mimeType, filename string) string {
+	switch {
+	case mimeType == MimeTypePDF, mimeType == MimeTypeDOCX:
+		return mimeType
+	case strings.HasPrefix(mimeType, "text/"):
+		return MimeTypeText
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pdf":
+		return MimeTypePDF
+	case ".docx":
+		return MimeTypeDOCX
+	case ".txt":
+		return MimeTypeText
+	}
+	return ""
+}
+
+// normalizeText unifies line endings and trims trailing spaces from lines,
+// so source span offsets are stable across formats
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,95 @@
+package documents
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// wordNamespace is the WordprocessingML main namespace
+const wordNamespace = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+// maxDOCXDocumentSize bounds the uncompressed size of word/document.xml, so
+// a zip bomb is rejected instead of exhausting memory
+const maxDOCXDocumentSize = 64 << 20
+
+// extractDOCX returns the text of word/document.xml, one line per paragraph
+// (table cells hold paragraphs too)
+func extractDOCX(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX: %w", err)
+	}
+
+	var document *zip.File
+	for _, f := range archive.File {
+		if f.Name == "word/document.xml" {
+			document = f
+			break
+		}
+	}
+	if document == nil {
+		return "", fmt.Errorf("invalid DOCX: word/document.xml not found")
+	}
+
+	rc, err := document.Open()
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX: %w", err)
+	}
+	defer rc.Close()
+
+	xmlData, err := io.ReadAll(io.LimitReader(rc, maxDOCXDocumentSize+1))
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX: %w", err)
+	}
+	if len(xmlData) > maxDOCXDocumentSize {
+		return "", fmt.Errorf("invalid DOCX: word/document.xml exceeds %d MB", maxDOCXDocumentSize>>20)
+	}
+
+	var text strings.Builder
+	decoder := xml.NewDecoder(bytes.NewReader(xmlData))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid DOCX: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Space != wordNamespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteByte('\t')
+			case "br", "cr":
+				text.WriteByte('\n')
+			}
+		case xml.EndElement:
+			if t.Name.Space != wordNamespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+
+	return text.String(), nil
+}
@@ -0,0 +1,574 @@
+package documents
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"meritdraft-backend/models"
+)
+
+// Candidates are found with layout heuristics that suit CVs: section
+// headings such as "Publications" or "Awards and Honors" set the kind of
+// the entries below them, and each bullet or line of a section is one
+// entry. Salary figures are found anywhere in the text, which also covers
+// offer letters.
+
+// criterionForKind maps each kind of evidence to the O-1A and EB-1A
+// criterion it supports
+var criterionForKind = map[models.EvidenceKind]string{
+	models.EvidencePublication: "authorship",
+	models.EvidenceAward:       "awards",
+	models.EvidenceMembership:  "membership",
+	models.EvidenceReview:      "judging",
+	models.EvidenceRole:        "critical_role",
+	models.EvidenceSalary:      "high_salary",
+}
+
+// listFact is the criteria_details list each kind of entry belongs in;
+// review and salary candidates fill the criterion's facts directly
+var listFact = map[models.EvidenceKind]string{
+	models.EvidencePublication: "publications",
+	models.EvidenceAward:       "awards",
+	models.EvidenceMembership:  "associations",
+	models.EvidenceRole:        "roles",
+}
+
+// headingWords are the words CV section headings are made of. A short line
+// made only of these words is a heading.
+var headingWords = map[string]bool{
+	"selected": true, "recent": true, "peer": true, "reviewed": true, "refereed": true, "and": true, "other": true,
+	"publications": true, "publication": true, "papers": true, "journal": true, "journals": true, "articles": true,
+	"conference": true, "conferences": true, "proceedings": true, "books": true, "book": true, "chapters": true,
+	"awards": true, "award": true, "honors": true, "honours": true, "prizes": true, "distinctions": true,
+	"fellowships": true, "scholarships": true, "achievements": true, "recognition": true,
+	"memberships": true, "membership": true, "affiliations": true, "professional": true, "societies": true,
+	"review": true, "reviewing": true, "reviewer": true, "service": true, "services": true, "editorial": true,
+	"activities": true, "committees": true, "committee": true, "program": true,
+	"experience": true, "employment": true, "work": true, "history": true, "positions": true, "appointments": true,
+	"academic": true, "research": true, "industry": true, "career": true, "held": true,
+	"education": true, "skills": true, "technical": true, "languages": true, "references": true, "interests": true,
+	"patents": true, "presentations": true, "talks": true, "invited": true, "teaching": true, "grants": true,
+	"funding": true, "projects": true, "certifications": true, "summary": true, "objective": true, "profile": true,
+	"contact": true, "information": true, "volunteer": true, "media": true, "press": true, "coverage": true,
+	"personal": true, "details": true, "training": true, "courses": true, "qualifications": true, "leadership": true,
+}
+
+var (
+	yearPattern        = regexp.MustCompile(`\b(19[5-9]\d|20\d\d)\b`)
+	monthPattern       = `(?:jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.?\s+`
+	dateRangePattern   = regexp.MustCompile(`(?i)((?:` + monthPattern + `)?(?:19|20)\d\d)\s*(?:-|–|—|to)\s*((?:` + monthPattern + `)?(?:19|20)\d\d|present|current|now)\b`)
+	quotedPattern      = regexp.MustCompile(`[“"]([^”"]{8,})[”"]`)
+	volumePattern      = regexp.MustCompile(`(?i),?\s*(?:\(?\d|pp?\.\s|vol\.\s|no\.\s).*$`)
+	reviewRolePattern  = regexp.MustCompile(`(?i)\b(associate editor|guest editor|editor|area chair|senior program committee member|program committee member|program committee|session chair|senior reviewer|reviewer|referee|judge)s?\b`)
+	reviewCountPattern = regexp.MustCompile(`(?i)(\d+)\+?\s+(?:papers|manuscripts|submissions|articles|proposals|grant applications)(?:\s+reviewed)?`)
+	bulletPattern      = regexp.MustCompile(`^(?:[•▪◦●■\-*–·]|\[\d+\]|\d+[.)])\s+`)
+	roleWords          = regexp.MustCompile(`(?i)\b(engineer|scientist|professor|director|manager|lead|head|researcher|fellow|founder|officer|president|consultant|analyst|developer|architect|chief|principal|partner|associate|assistant|postdoc|postdoctoral|intern|chair|editor|cto|ceo|vp)\b`)
+
+	salaryBefore = regexp.MustCompile(`(?i)\b(?:salary|compensation|remuneration|base pay)\b[^.\n$€£\d]{0,40}?(USD|EUR|GBP|\$|€|£)\s?(\d{1,3}(?:,\d{3})+|\d+)(?:\.\d+)?\s?([kK])?(?:\s*(?:per|/|a|an)\s*(year|annum|yr|month|hour|hr))?`)
+	salaryAfter  = regexp.MustCompile(`(?i)(USD|EUR|GBP|\$|€|£)\s?(\d{1,3}(?:,\d{3})+|\d+)(?:\.\d+)?\s?([kK])?\s*(?:(?:per|/|a|an)\s*(year|annum|yr|month|hour|hr)\s+)?(?:(?:annual|yearly|base|gross)\s+)*(?:salary|compensation)`)
+)
+
+// line is a trimmed line of text with its byte offsets in the document
+type line struct {
+	text       string
+	start, end int
+}
+
+// entry is one item of a CV section, possibly spanning several lines
+type entry struct {
+	text       string
+	start, end int
+}
+
+// FindEvidence returns the evidence candidates found in a document's text
+func FindEvidence(text string) models.EvidenceCandidates {
+	candidates := make(models.EvidenceCandidates, 0)
+
+	lines := splitLines(text)
+	var kind models.EvidenceKind
+	var section []line
+	flush := func() {
+		if kind != "" {
+			for _, e := range sectionEntries(section) {
+				if c, ok := parseEntry(kind, e, text); ok {
+					candidates = append(candidates, c)
+				}
+			}
+		}
+		section = nil
+	}
+
+	for _, l := range lines {
+		if headingKind, ok := heading(l.text); ok {
+			flush()
+			kind = headingKind
+			continue
+		}
+		section = append(section, l)
+	}
+	flush()
+
+	return append(candidates, findSalaries(text, lines)...)
+}
+
+// CriteriaDetails builds criteria_details entries from candidates: list
+// entries are collected under their criterion, review service is merged
+// into one judging entry and the first salary is used
+func CriteriaDetails(candidates models.EvidenceCandidates) map[string]interface{} {
+	details := make(map[string]interface{})
+
+	var venues, roles []string
+	papers, counted := 0.0, false
+	for _, c := range candidates {
+		switch c.Kind {
+		case models.EvidenceReview:
+			venues = appendUnique(venues, c.Facts["venue"])
+			roles = appendUnique(roles, c.Facts["role"])
+			if n, ok := c.Facts["papers_reviewed"].(float64); ok {
+				papers += n
+				counted = true
+			}
+		case models.EvidenceSalary:
+			if _, ok := details[c.Criterion]; !ok {
+				details[c.Criterion] = copyFacts(c.Facts)
+			}
+		default:
+			key := listFact[c.Kind]
+			detail, _ := details[c.Criterion].(map[string]interface{})
+			if detail == nil {
+				detail = map[string]interface{}{key: []interface{}{}}
+				details[c.Criterion] = detail
+			}
+			detail[key] = append(detail[key].([]interface{}), copyFacts(c.Facts))
+		}
+	}
+
+	if len(venues) > 0 {
+		judging := map[string]interface{}{
+			"venue": strings.Join(venues, "; "),
+			"role":  strings.Join(roles, "; "),
+		}
+		if counted {
+			judging["papers_reviewed"] = papers
+		}
+		details[criterionForKind[models.EvidenceReview]] = judging
+	}
+
+	return details
+}
+
+func appendUnique(list []string, value interface{}) []string {
+	s, _ := value.(string)
+	if s == "" {
+		return list
+	}
+	for _, existing := range list {
+		if strings.EqualFold(existing, s) {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func copyFacts(facts map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(facts))
+	for key, value := range facts {
+		out[key] = value
+	}
+	return out
+}
+
+// splitLines splits text into trimmed lines, keeping blank lines as
+// entry separators
+func splitLines(text string) []line {
+	var lines []line
+	offset := 0
+	for _, raw := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(raw)
+		start := offset + strings.Index(raw, trimmed)
+		if trimmed == "" {
+			start = offset
+		}
+		lines = append(lines, line{text: trimmed, start: start, end: start + len(trimmed)})
+		offset += len(raw)
+	}
+	return lines
+}
+
+// heading reports whether a line is a section heading, and the kind of
+// evidence listed under it ("" for sections that are not read)
+func heading(text string) (models.EvidenceKind, bool) {
+	if text == "" || len(text) > 50 || strings.ContainsAny(text, "0123456789.,;") {
+		return "", false
+	}
+
+	normalized := strings.ToLower(strings.TrimRight(text, ": "))
+	words := strings.FieldsFunc(normalized, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-'
+	})
+	if len(words) == 0 || len(words) > 6 {
+		return "", false
+	}
+	for _, word := range words {
+		if !headingWords[word] && word != "&" {
+			return "", false
+		}
+	}
+
+	has := func(parts ...string) bool {
+		for _, part := range parts {
+			if strings.Contains(normalized, part) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("review", "editorial", "committee", "service"):
+		return models.EvidenceReview, true
+	case has("publication", "papers", "articles", "proceedings", "journal", "book"):
+		return models.EvidencePublication, true
+	case has("award", "honor", "honour", "prize", "distinction", "fellowship", "scholarship", "recognition"):
+		return models.EvidenceAward, true
+	case has("membership", "affiliation", "societies"):
+		return models.EvidenceMembership, true
+	case has("experience", "employment", "positions", "appointments", "career", "work history"):
+		return models.EvidenceRole, true
+	}
+	return "", true
+}
+
+// sectionEntries groups the lines of a section into entries. Bullets and
+// numbers start entries, blank lines end them, and in sections without
+// bullets every line is an entry. In a bulleted section, a paragraph
+// without a bullet after a blank line is prose that follows the list, and
+// ends the section.
+func sectionEntries(lines []line) []entry {
+	bulleted := false
+	for _, l := range lines {
+		if bulletPattern.MatchString(l.text) {
+			bulleted = true
+			break
+		}
+	}
+
+	var entries []entry
+	var current *entry
+	blank := false
+	for _, l := range lines {
+		if l.text == "" {
+			current = nil
+			blank = true
+			continue
+		}
+
+		text := l.text
+		marker := bulletPattern.FindString(text)
+		if bulleted && blank && marker == "" && len(entries) > 0 {
+			break
+		}
+		blank = false
+		text = strings.TrimSpace(text[len(marker):])
+		continuation := current != nil && marker == "" &&
+			(bulleted || startsLowercase(text) || strings.HasSuffix(current.text, ","))
+		if continuation {
+			current.text += " " + text
+			current.end = l.end
+			continue
+		}
+
+		entries = append(entries, entry{text: text, start: l.start + len(marker), end: l.end})
+		current = &entries[len(entries)-1]
+	}
+	return entries
+}
+
+func startsLowercase(s string) bool {
+	for _, r := range s {
+		return unicode.IsLower(r)
+	}
+	return false
+}
+
+// parseEntry reads the facts of one section entry
+func parseEntry(kind models.EvidenceKind, e entry, text string) (models.EvidenceCandidate, bool) {
+	if len(e.text) > 500 {
+		return models.EvidenceCandidate{}, false
+	}
+
+	var facts map[string]interface{}
+	switch kind {
+	case models.EvidencePublication:
+		facts = parsePublication(e.text)
+	case models.EvidenceAward:
+		facts = parseDated(e.text, "name")
+	case models.EvidenceMembership:
+		facts = parseDated(e.text, "name")
+	case models.EvidenceReview:
+		facts = parseReview(e.text)
+	case models.EvidenceRole:
+		facts = parseRole(e.text)
+	}
+	if facts == nil {
+		return models.EvidenceCandidate{}, false
+	}
+
+	return models.EvidenceCandidate{
+		Kind:      kind,
+		Criterion: criterionForKind[kind],
+		Facts:     facts,
+		Source:    models.SourceSpan{Start: e.start, End: e.end, Text: text[e.start:e.end]},
+	}, true
+}
+
+// parsePublication reads a citation such as
+// "A. Author, B. Author. Title of the paper. Venue, 12(3), 2021."
+func parsePublication(s string) map[string]interface{} {
+	var title, journal string
+	if m := quotedPattern.FindStringSubmatchIndex(s); m != nil {
+		title = s[m[2]:m[3]]
+		journal = s[m[1]:]
+	} else {
+		parts := splitSentences(s)
+		switch {
+		case len(parts) >= 3:
+			title, journal = parts[1], parts[2]
+		case len(parts) == 2:
+			title, journal = parts[0], parts[1]
+		default:
+			title = s
+		}
+	}
+
+	title = trimPunctuation(title)
+	if len(title) < 3 {
+		return nil
+	}
+	facts := map[string]interface{}{"title": title}
+
+	journal = strings.TrimLeft(journal, " ,.:;")
+	journal = strings.TrimPrefix(strings.TrimPrefix(journal, "In "), "in ")
+	journal = trimPunctuation(volumePattern.ReplaceAllString(journal, ""))
+	if journal != "" {
+		facts["journal"] = journal
+	}
+	if years := yearPattern.FindAllString(s, -1); len(years) > 0 {
+		year, _ := strconv.Atoi(years[len(years)-1])
+		facts["year"] = float64(year)
+	}
+	return facts
+}
+
+// splitSentences splits a citation at periods that end a word of three or
+// more characters, so author initials stay together
+func splitSentences(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i+1 < len(s); i++ {
+		if s[i] != '.' || s[i+1] != ' ' {
+			continue
+		}
+		wordStart := strings.LastIndexAny(s[:i], " ,") + 1
+		if i-wordStart < 3 {
+			continue
+		}
+		if part := strings.TrimSpace(s[start:i]); part != "" {
+			parts = append(parts, part)
+		}
+		start = i + 1
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// parseDated reads an entry naming something with an optional date, such
+// as "Best Paper Award, ICML 2021" or "IEEE Senior Member (2018-present)"
+func parseDated(s, nameKey string) map[string]interface{} {
+	var date string
+	if m := dateRangePattern.FindString(s); m != "" {
+		date = m
+	} else if years := yearPattern.FindAllString(s, -1); len(years) > 0 {
+		date = years[0]
+	}
+
+	name := trimPunctuation(removeDates(s))
+	if len(name) < 3 {
+		return nil
+	}
+	facts := map[string]interface{}{nameKey: name}
+	if date != "" {
+		facts["date"] = date
+	}
+	return facts
+}
+
+// parseReview reads an entry such as "Reviewer, NeurIPS (2019-2023), 40+ papers";
+// entries naming no reviewing role are not review service
+func parseReview(s string) map[string]interface{} {
+	m := reviewRolePattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil
+	}
+
+	facts := map[string]interface{}{"role": titleCase(m[1])}
+	if m := reviewCountPattern.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		facts["papers_reviewed"] = float64(n)
+	}
+
+	venue := reviewCountPattern.ReplaceAllString(s, "")
+	venue = reviewRolePattern.ReplaceAllString(venue, "")
+	venue = removeDates(venue)
+	venue = strings.TrimSpace(venue)
+	for _, prefix := range []string{"member of", "for the", "for", "of", "at", ":", ","} {
+		venue = strings.TrimSpace(strings.TrimPrefix(strings.TrimLeft(venue, " ,:;-–—"), prefix))
+	}
+	venue = trimPunctuation(venue)
+	if len(venue) < 2 {
+		return nil
+	}
+	facts["venue"] = venue
+	return facts
+}
+
+// parseRole reads a dated position such as
+// "Senior Research Scientist, Acme Labs, Jan 2019 - Present"; entries
+// without a date range are descriptions, not positions
+func parseRole(s string) map[string]interface{} {
+	m := dateRangePattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil
+	}
+
+	rest := trimPunctuation(removeDates(s))
+	var title, organization string
+	if i := strings.Index(rest, " at "); i > 0 {
+		title, organization = rest[:i], rest[i+4:]
+	} else {
+		parts := strings.FieldsFunc(rest, func(r rune) bool { return r == ',' || r == '|' || r == '–' || r == '—' })
+		for i := range parts {
+			parts[i] = trimPunctuation(parts[i])
+		}
+		if len(parts) < 2 {
+			return nil
+		}
+		title, organization = parts[0], parts[1]
+		if !roleWords.MatchString(title) && roleWords.MatchString(organization) {
+			title, organization = organization, title
+		}
+	}
+
+	title, organization = trimPunctuation(title), trimPunctuation(organization)
+	if organization == "" {
+		return nil
+	}
+	facts := map[string]interface{}{
+		"organization": organization,
+		"start_date":   m[1],
+		"end_date":     titleCase(m[2]),
+	}
+	if title != "" {
+		facts["title"] = title
+	}
+	return facts
+}
+
+// findSalaries finds salary figures anywhere in the text, reporting the
+// line each was found on as its source
+func findSalaries(text string, lines []line) models.EvidenceCandidates {
+	candidates := make(models.EvidenceCandidates, 0)
+	seen := make(map[int]bool)
+
+	for _, pattern := range []*regexp.Regexp{salaryBefore, salaryAfter} {
+		for _, m := range pattern.FindAllStringSubmatchIndex(text, -1) {
+			amount, err := strconv.ParseFloat(strings.ReplaceAll(text[m[4]:m[5]], ",", ""), 64)
+			if err != nil || amount <= 0 {
+				continue
+			}
+			if m[6] >= 0 {
+				amount *= 1000
+			}
+
+			l := lineAt(lines, m[0])
+			if seen[l.start] {
+				continue
+			}
+			seen[l.start] = true
+
+			facts := map[string]interface{}{
+				"salary":   amount,
+				"currency": currencyCode(text[m[2]:m[3]]),
+			}
+			period := ""
+			if m[8] >= 0 {
+				period = strings.ToLower(text[m[8]:m[9]])
+			}
+			switch {
+			case period == "hour" || period == "hr":
+				facts["period"] = "hourly"
+			case period == "month":
+				facts["period"] = "monthly"
+			case period != "" || strings.Contains(strings.ToLower(text[m[0]:m[1]]), "annual"):
+				facts["period"] = "annual"
+			}
+
+			candidates = append(candidates, models.EvidenceCandidate{
+				Kind:      models.EvidenceSalary,
+				Criterion: criterionForKind[models.EvidenceSalary],
+				Facts:     facts,
+				Source:    models.SourceSpan{Start: l.start, End: l.end, Text: text[l.start:l.end]},
+			})
+		}
+	}
+	return candidates
+}
+
+// lineAt returns the line holding the byte offset
+func lineAt(lines []line, offset int) line {
+	for _, l := range lines {
+		if offset >= l.start && offset <= l.end {
+			return l
+		}
+	}
+	return line{start: offset, end: offset}
+}
+
+func currencyCode(symbol string) string {
+	switch strings.ToUpper(symbol) {
+	case "$":
+		return "USD"
+	case "€":
+		return "EUR"
+	case "£":
+		return "GBP"
+	}
+	return strings.ToUpper(symbol)
+}
+
+// removeDates drops date ranges, years and the parentheses left empty by them
+func removeDates(s string) string {
+	s = dateRangePattern.ReplaceAllString(s, "")
+	s = yearPattern.ReplaceAllString(s, "")
+	s = strings.NewReplacer("()", "", "( )", "", "[]", "").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// trimPunctuation trims separators and unbalanced punctuation from both ends
+func trimPunctuation(s string) string {
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(s), " ,.;:-–—|()[]\"“”"))
+}
+
+func titleCase(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, word := range words {
+		if word == "and" || word == "of" {
+			continue
+		}
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,947 @@
+package documents
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// The PDF extractor reads the text-showing operators of each page's content
+// streams in page order. It handles FlateDecode, ASCIIHexDecode and
+// ASCII85Decode streams, compressed object streams and ToUnicode CMaps.
+// Encrypted PDFs and scanned images yield no text.
+
+type (
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string
+	pdfArray   []interface{}
+	pdfDict    map[pdfName]interface{}
+	pdfRef     struct{ num, gen int }
+)
+
+// pdfStream is a stream object with its undecoded data
+type pdfStream struct {
+	dict pdfDict
+	data []byte
+}
+
+const (
+	// maxPDFNesting bounds how deeply arrays and dictionaries may nest, so
+	// a malicious file cannot exhaust the stack
+	maxPDFNesting = 256
+
+	// maxPDFStreamSize bounds the decompressed size of one stream
+	maxPDFStreamSize = 64 << 20
+)
+
+var (
+	errPDFSyntax    = errors.New("invalid PDF syntax")
+	errPDFEncrypted = errors.New("encrypted PDFs are not supported")
+
+	pdfObjectHeader     = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfEncryptedTrailer = regexp.MustCompile(`(?s)trailer\s*<<.{0,1000}?/Encrypt\b`)
+)
+
+// extractPDF returns the text of every page of a PDF
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", fmt.Errorf("invalid PDF: missing header")
+	}
+
+	doc := &pdfDocument{objects: make(map[int]interface{})}
+	doc.loadObjects(data)
+	if doc.encrypted(data) {
+		return "", errPDFEncrypted
+	}
+
+	var text strings.Builder
+	for _, page := range doc.pages() {
+		doc.extractPage(page, &text)
+		text.WriteString("\n\n")
+	}
+	return text.String(), nil
+}
+
+// pdfDocument holds the objects of a PDF by object number
+type pdfDocument struct {
+	objects map[int]interface{}
+	fonts   map[pdfRef]*pdfFont
+}
+
+// loadObjects parses every indirect object in the file, then the objects
+// packed into object streams. Later definitions of an object number win,
+// as they do in incrementally updated files.
+func (d *pdfDocument) loadObjects(data []byte) {
+	for _, match := range pdfObjectHeader.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		lexer := &pdfLexer{data: data, pos: match[1]}
+		object, err := lexer.parseObject()
+		if err != nil {
+			continue
+		}
+
+		if dict, ok := object.(pdfDict); ok && lexer.nextKeywordIs("stream") {
+			object = &pdfStream{dict: dict, data: lexer.streamData(dict)}
+		}
+		d.objects[num] = object
+	}
+
+	for _, object := range d.objects {
+		stream, ok := object.(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		d.loadObjectStream(stream)
+	}
+}
+
+// loadObjectStream adds the objects of a compressed object stream
+func (d *pdfDocument) loadObjectStream(stream *pdfStream) {
+	data, err := d.decodeStream(stream)
+	if err != nil {
+		return
+	}
+	count, _ := d.resolve(stream.dict["N"]).(float64)
+	first, _ := d.resolve(stream.dict["First"]).(float64)
+	if int(first) > len(data) {
+		return
+	}
+
+	header := &pdfLexer{data: data[:int(first)]}
+	for i := 0; i < int(count); i++ {
+		num, err1 := header.next()
+		offset, err2 := header.next()
+		if err1 != nil || err2 != nil {
+			return
+		}
+		n, ok1 := num.(float64)
+		o, ok2 := offset.(float64)
+		if !ok1 || !ok2 || int(first+o) >= len(data) {
+			return
+		}
+		if _, defined := d.objects[int(n)]; defined {
+			continue
+		}
+		lexer := &pdfLexer{data: data, pos: int(first + o)}
+		if object, err := lexer.parseObject(); err == nil {
+			d.objects[int(n)] = object
+		}
+	}
+}
+
+// encrypted reports whether the trailer, or a cross-reference stream
+// standing in for it, names an encryption dictionary
+func (d *pdfDocument) encrypted(data []byte) bool {
+	if pdfEncryptedTrailer.Match(data) {
+		return true
+	}
+	for _, object := range d.objects {
+		if stream, ok := object.(*pdfStream); ok && stream.dict["Type"] == pdfName("XRef") {
+			if _, ok := stream.dict["Encrypt"]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve follows indirect references
+func (d *pdfDocument) resolve(value interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := value.(pdfRef)
+		if !ok {
+			return value
+		}
+		value = d.objects[ref.num]
+	}
+	return nil
+}
+
+// dict resolves value to a dictionary, taking a stream's dictionary
+func (d *pdfDocument) dict(value interface{}) pdfDict {
+	switch v := d.resolve(value).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// Page attributes a page inherits from its ancestors in the page tree
+var pdfInheritable = []pdfName{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// pages returns the page dictionaries in document order, with inheritable
+// attributes copied down from their ancestors
+func (d *pdfDocument) pages() []pdfDict {
+	var pages []pdfDict
+	for _, object := range d.objects {
+		catalog, ok := object.(pdfDict)
+		if !ok || catalog["Type"] != pdfName("Catalog") {
+			continue
+		}
+		d.walkPages(catalog["Pages"], nil, &pages, 0)
+		if len(pages) > 0 {
+			return pages
+		}
+	}
+
+	// No usable page tree: fall back to page objects in object number order
+	nums := make([]int, 0)
+	for num, object := range d.objects {
+		if dict, ok := object.(pdfDict); ok && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		pages = append(pages, d.objects[num].(pdfDict))
+	}
+	return pages
+}
+
+// walkPages appends the leaves of a page tree node
+func (d *pdfDocument) walkPages(node interface{}, inherited pdfDict, pages *[]pdfDict, depth int) {
+	dict := d.dict(node)
+	if dict == nil || depth > 64 {
+		return
+	}
+	attributes := make(pdfDict, len(pdfInheritable))
+	for _, key := range pdfInheritable {
+		if value, ok := dict[key]; ok {
+			attributes[key] = value
+		} else if value, ok := inherited[key]; ok {
+			attributes[key] = value
+		}
+	}
+
+	if dict["Type"] == pdfName("Page") {
+		page := make(pdfDict, len(dict)+len(attributes))
+		for key, value := range dict {
+			page[key] = value
+		}
+		for key, value := range attributes {
+			page[key] = value
+		}
+		*pages = append(*pages, page)
+		return
+	}
+
+	kids, _ := d.resolve(dict["Kids"]).(pdfArray)
+	for _, kid := range kids {
+		d.walkPages(kid, attributes, pages, depth+1)
+	}
+}
+
+// decodeStream applies a stream's filters
+func (d *pdfDocument) decodeStream(stream *pdfStream) ([]byte, error) {
+	var filters []interface{}
+	switch f := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []interface{}{f}
+	case pdfArray:
+		filters = f
+	}
+
+	data := stream.data
+	for _, filter := range filters {
+		switch d.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data = inflate(data)
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data = decodeHexString(data)
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			trimmed := bytes.TrimSuffix(bytes.TrimSpace(data), []byte("~>"))
+			decoded := make([]byte, len(trimmed)*4+4) // "z" expands to four bytes
+			n, _, err := ascii85.Decode(decoded, trimmed, true)
+			if err != nil {
+				return nil, err
+			}
+			data = decoded[:n]
+		default:
+			return nil, fmt.Errorf("unsupported PDF filter %v", filter)
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib (or raw deflate) data, keeping whatever was
+// recovered from a truncated stream and at most maxPDFStreamSize bytes
+func inflate(data []byte) []byte {
+	var out []byte
+	if r, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		out, _ = io.ReadAll(io.LimitReader(r, maxPDFStreamSize))
+	}
+	if len(out) == 0 {
+		out, _ = io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data)), maxPDFStreamSize))
+	}
+	return out
+}
+
+// extractPage writes the text of one page
+func (d *pdfDocument) extractPage(page pdfDict, text *strings.Builder) {
+	fonts := make(map[pdfName]*pdfFont)
+	if resources := d.dict(page["Resources"]); resources != nil {
+		for name, ref := range d.dict(resources["Font"]) {
+			fonts[name] = d.font(ref)
+		}
+	}
+
+	var contents []interface{}
+	switch c := d.resolve(page["Contents"]).(type) {
+	case pdfArray:
+		contents = c
+	case *pdfStream:
+		contents = []interface{}{c}
+	}
+
+	// The page's content streams form one stream when concatenated
+	var data []byte
+	for _, content := range contents {
+		stream, ok := d.resolve(content).(*pdfStream)
+		if !ok {
+			continue
+		}
+		decoded, err := d.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+		data = append(data, decoded...)
+		data = append(data, '\n')
+	}
+
+	(&pdfTextWriter{out: text, fonts: fonts}).run(data)
+}
+
+// pdfFont maps a font's character codes to text
+type pdfFont struct {
+	toUnicode map[uint32]string // From the ToUnicode CMap; nil for simple fonts without one
+	codeBytes int               // Bytes per character code
+	composite bool              // Type0 font, unreadable without a ToUnicode CMap
+}
+
+// font loads the font a resource refers to, caching fonts shared by pages
+func (d *pdfDocument) font(value interface{}) *pdfFont {
+	ref, isRef := value.(pdfRef)
+	if isRef {
+		if font, ok := d.fonts[ref]; ok {
+			return font
+		}
+	}
+
+	font := &pdfFont{codeBytes: 1}
+	if dict := d.dict(value); dict != nil {
+		font.composite = dict["Subtype"] == pdfName("Type0")
+		if font.composite {
+			font.codeBytes = 2
+		}
+		if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+			if data, err := d.decodeStream(stream); err == nil {
+				font.toUnicode, font.codeBytes = parseCMap(data, font.codeBytes)
+			}
+		}
+	}
+
+	if isRef {
+		if d.fonts == nil {
+			d.fonts = make(map[pdfRef]*pdfFont)
+		}
+		d.fonts[ref] = font
+	}
+	return font
+}
+
+// decode converts a shown string to text
+func (f *pdfFont) decode(s pdfString) string {
+	if f == nil {
+		return decodeWinAnsi(s)
+	}
+	if f.toUnicode == nil {
+		if f.composite {
+			return ""
+		}
+		return decodeWinAnsi(s)
+	}
+
+	var out strings.Builder
+	for i := 0; i+f.codeBytes <= len(s); i += f.codeBytes {
+		var code uint32
+		for _, b := range s[i : i+f.codeBytes] {
+			code = code<<8 | uint32(b)
+		}
+		if text, ok := f.toUnicode[code]; ok {
+			out.WriteString(text)
+		} else if !f.composite && code >= 32 && code < 127 {
+			out.WriteByte(byte(code))
+		}
+	}
+	return out.String()
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap and
+// returns them with the code width they use
+func parseCMap(data []byte, codeBytes int) (map[uint32]string, int) {
+	mapping := make(map[uint32]string)
+	lexer := &pdfLexer{data: data}
+
+	var operands []interface{}
+	for {
+		token, err := lexer.parseObject()
+		if err != nil {
+			break
+		}
+		keyword, ok := token.(pdfKeyword)
+		if !ok {
+			operands = append(operands, token)
+			continue
+		}
+
+		switch keyword {
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					codeBytes = len(src)
+					mapping[codeValue(src)] = decodeUTF16(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 || codeValue(hi) < codeValue(lo) || codeValue(hi)-codeValue(lo) > 0xFFFF {
+					continue
+				}
+				codeBytes = len(lo)
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					// Consecutive codes map to consecutive final characters
+					runes := utf16.Decode(utf16Units(dst))
+					for code := codeValue(lo); code <= codeValue(hi); code++ {
+						if len(runes) > 0 {
+							mapped := append([]rune{}, runes...)
+							mapped[len(mapped)-1] += rune(code - codeValue(lo))
+							mapping[code] = string(mapped)
+						}
+					}
+				case pdfArray:
+					for j, entry := range dst {
+						if s, ok := entry.(pdfString); ok {
+							mapping[codeValue(lo)+uint32(j)] = decodeUTF16(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+
+	if codeBytes < 1 || codeBytes > 4 {
+		codeBytes = 1
+	}
+	return mapping, codeBytes
+}
+
+func codeValue(s pdfString) uint32 {
+	var code uint32
+	for _, b := range s {
+		code = code<<8 | uint32(b)
+	}
+	return code
+}
+
+func utf16Units(s pdfString) []uint16 {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return units
+}
+
+func decodeUTF16(s pdfString) string {
+	return string(utf16.Decode(utf16Units(s)))
+}
+
+// winAnsiHigh maps the printable Windows-1252 codes that differ from Latin-1
+var winAnsiHigh = map[byte]rune{
+	0x80: '€', 0x85: '…', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”',
+	0x95: '•', 0x96: '–', 0x97: '—', 0x99: '™',
+}
+
+// decodeWinAnsi decodes a simple font's string as WinAnsiEncoding, the
+// encoding most PDF producers use for standard fonts
+func decodeWinAnsi(s pdfString) string {
+	var out strings.Builder
+	for _, b := range s {
+		switch {
+		case b >= 32 && b < 127:
+			out.WriteByte(b)
+		case b >= 0x80:
+			if r, ok := winAnsiHigh[b]; ok {
+				out.WriteRune(r)
+			} else if b >= 0xA0 {
+				out.WriteRune(rune(b))
+			}
+		case b == '\t':
+			out.WriteByte(' ')
+		}
+	}
+	return out.String()
+}
+
+// pdfTextWriter interprets a content stream's text operators
+type pdfTextWriter struct {
+	out      *strings.Builder
+	fonts    map[pdfName]*pdfFont
+	font     *pdfFont
+	fontSize float64
+	scale    float64 // Vertical scale of the text matrix
+	lineY    float64 // Baseline of the current line in user space
+	started  bool    // Some text has been written on this page
+}
+
+func (w *pdfTextWriter) run(data []byte) {
+	w.scale = 1
+	lexer := &pdfLexer{data: data}
+
+	var operands []interface{}
+	for {
+		token, err := lexer.parseObject()
+		if err != nil {
+			return
+		}
+		keyword, ok := token.(pdfKeyword)
+		if !ok {
+			operands = append(operands, token)
+			continue
+		}
+
+		switch keyword {
+		case "Tf":
+			if len(operands) >= 2 {
+				name, _ := operands[0].(pdfName)
+				w.font = w.fonts[name]
+				w.fontSize, _ = operands[1].(float64)
+			}
+		case "Tj":
+			w.show(operands, 0)
+		case "'":
+			w.newline()
+			w.show(operands, 0)
+		case "\"":
+			w.newline()
+			w.show(operands, 2)
+		case "TJ":
+			if len(operands) > 0 {
+				array, _ := operands[0].(pdfArray)
+				for _, item := range array {
+					switch v := item.(type) {
+					case pdfString:
+						w.write(w.font.decode(v))
+					case float64:
+						// Large negative adjustments are word gaps
+						if v < -250 {
+							w.space()
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx, _ := operands[0].(float64)
+				ty, _ := operands[1].(float64)
+				w.move(ty*w.scale, tx > 0)
+				w.lineY += ty * w.scale
+			}
+		case "T*":
+			w.newline()
+		case "Tm":
+			if len(operands) >= 6 {
+				d, _ := operands[3].(float64)
+				y, _ := operands[5].(float64)
+				if d != 0 {
+					w.scale = math.Abs(d)
+				}
+				w.move(y-w.lineY, true)
+				w.lineY = y
+			}
+		case "BT":
+			w.lineY = 0
+		case "ID":
+			lexer.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// show writes the string operand at index i
+func (w *pdfTextWriter) show(operands []interface{}, i int) {
+	if i < len(operands) {
+		if s, ok := operands[i].(pdfString); ok {
+			w.write(w.font.decode(s))
+		}
+	}
+}
+
+// move starts a new line, or a paragraph after a large vertical gap, when
+// the baseline moves by dy; horizontal moves on the same line become spaces
+func (w *pdfTextWriter) move(dy float64, horizontal bool) {
+	lineHeight := w.fontSize * w.scale
+	if lineHeight <= 0 {
+		lineHeight = 12
+	}
+
+	switch {
+	case math.Abs(dy) > 1.8*lineHeight:
+		w.newline()
+		if w.started && !strings.HasSuffix(w.out.String(), "\n\n") {
+			w.out.WriteByte('\n')
+		}
+	case math.Abs(dy) > 0.1*lineHeight:
+		w.newline()
+	case horizontal:
+		w.space()
+	}
+}
+
+func (w *pdfTextWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	w.out.WriteString(s)
+	w.started = true
+}
+
+func (w *pdfTextWriter) newline() {
+	if w.started && !strings.HasSuffix(w.out.String(), "\n") {
+		w.out.WriteByte('\n')
+	}
+}
+
+func (w *pdfTextWriter) space() {
+	s := w.out.String()
+	if w.started && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		w.out.WriteByte(' ')
+	}
+}
+
+// pdfLexer reads PDF tokens and objects
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	depth int // Arrays and dictionaries being parsed
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// skipSpace skips whitespace and comments
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// next returns the next token: a number, name, string, or keyword
+// (including the delimiters <<, >>, [ and ])
+func (l *pdfLexer) next() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfName(decodeNameEscapes(l.data[start:l.pos])), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), nil
+		}
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			return nil, errPDFSyntax
+		}
+		s := decodeHexString(l.data[l.pos+1 : l.pos+end])
+		l.pos += end + 1
+		return pdfString(s), nil
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), nil
+		}
+		l.pos++
+		return nil, errPDFSyntax
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	case c == ')':
+		l.pos++
+		return nil, errPDFSyntax
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil {
+		return n, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+// parseObject reads one object; keywords other than true, false and null
+// are returned as pdfKeyword
+func (l *pdfLexer) parseObject() (interface{}, error) {
+	token, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case float64:
+		// An indirect reference is "num gen R"
+		saved := l.pos
+		if gen, err := l.next(); err == nil {
+			if g, ok := gen.(float64); ok {
+				if r, err := l.next(); err == nil && r == pdfKeyword("R") {
+					return pdfRef{num: int(t), gen: int(g)}, nil
+				}
+			}
+		}
+		l.pos = saved
+		return t, nil
+	case pdfKeyword:
+		if t == "<<" || t == "[" {
+			if l.depth >= maxPDFNesting {
+				return nil, errPDFSyntax
+			}
+			l.depth++
+			defer func() { l.depth-- }()
+		}
+
+		switch t {
+		case "<<":
+			dict := make(pdfDict)
+			for {
+				key, err := l.parseObject()
+				if err != nil {
+					return nil, err
+				}
+				if key == pdfKeyword(">>") {
+					return dict, nil
+				}
+				name, ok := key.(pdfName)
+				if !ok {
+					return nil, errPDFSyntax
+				}
+				value, err := l.parseObject()
+				if err != nil {
+					return nil, err
+				}
+				dict[name] = value
+			}
+		case "[":
+			array := make(pdfArray, 0)
+			for {
+				item, err := l.parseObject()
+				if err != nil {
+					return nil, err
+				}
+				if item == pdfKeyword("]") {
+					return array, nil
+				}
+				array = append(array, item)
+			}
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+	return token, nil
+}
+
+// nextKeywordIs reports whether the next token is keyword, consuming it if so
+func (l *pdfLexer) nextKeywordIs(keyword string) bool {
+	saved := l.pos
+	if token, err := l.next(); err == nil && token == pdfKeyword(keyword) {
+		return true
+	}
+	l.pos = saved
+	return false
+}
+
+// streamData returns the raw data of the stream whose "stream" keyword was
+// just read, using /Length when it is direct and plausible
+func (l *pdfLexer) streamData(dict pdfDict) []byte {
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+
+	if length, ok := dict["Length"].(float64); ok {
+		end := start + int(length)
+		if end <= len(l.data) && bytes.HasPrefix(bytes.TrimLeft(l.data[end:], "\r\n \t"), []byte("endstream")) {
+			l.pos = end
+			return l.data[start:end]
+		}
+	}
+
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		l.pos = len(l.data)
+		return l.data[start:]
+	}
+	l.pos = start + end
+	return bytes.TrimRight(l.data[start:start+end], "\r\n")
+}
+
+// literalString reads a parenthesized string, handling nesting and escapes
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// skipInlineImage skips the binary data of an inline image after its ID operator
+func (l *pdfLexer) skipInlineImage() {
+	for i := l.pos; i+2 < len(l.data); i++ {
+		if isPDFSpace(l.data[i]) && l.data[i+1] == 'E' && l.data[i+2] == 'I' &&
+			(i+3 == len(l.data) || isPDFSpace(l.data[i+3])) {
+			l.pos = i + 3
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
+
+// decodeHexString decodes hex digits, ignoring whitespace and padding an odd final digit
+func decodeHexString(data []byte) []byte {
+	digits := make([]byte, 0, len(data))
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	n, _ := hex.Decode(out, digits)
+	return out[:n]
+}
+
+// decodeNameEscapes expands #xx escapes in a name
+func decodeNameEscapes(name []byte) string {
+	if bytes.IndexByte(name, '#') < 0 {
+		return string(name)
+	}
+	var out []byte
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) {
+			if b, err := strconv.ParseUint(string(name[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(b))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, name[i])
+	}
+	return string(out)
+}
@@ -0,0 +1,276 @@
+package documents
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ImportedPDF is the pages of a PDF converted to form XObjects, numbered
+// for writing into another PDF
+type ImportedPDF struct {
+	Objects [][]byte // Bodies of objects First, First+1, ...
+	First   int
+	Pages   []ImportedPage
+}
+
+// ImportedPage is one page of an imported PDF
+type ImportedPage struct {
+	Form   int        // Object number of the form XObject that draws the page
+	Box    [4]float64 // Visible area in form space: lower-left x, y, upper-right x, y
+	Rotate int        // Clockwise rotation when displayed: 0, 90, 180 or 270
+}
+
+// Width returns the displayed width of the page, after rotation
+func (p ImportedPage) Width() float64 {
+	if p.Rotate == 90 || p.Rotate == 270 {
+		return p.Box[3] - p.Box[1]
+	}
+	return p.Box[2] - p.Box[0]
+}
+
+// Height returns the displayed height of the page, after rotation
+func (p ImportedPage) Height() float64 {
+	if p.Rotate == 90 || p.Rotate == 270 {
+		return p.Box[2] - p.Box[0]
+	}
+	return p.Box[3] - p.Box[1]
+}
+
+// ErrNoPages is returned when a PDF has no pages to import
+var ErrNoPages = errors.New("PDF has no pages")
+
+// ImportPDF converts every page of a PDF to a form XObject holding the
+// page's content and the objects its resources use, renumbered from first.
+// Annotations, links and form fields are not carried over.
+func ImportPDF(data []byte, first int) (*ImportedPDF, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return nil, fmt.Errorf("invalid PDF: missing header")
+	}
+
+	doc := &pdfDocument{objects: make(map[int]interface{})}
+	doc.loadObjects(data)
+	if doc.encrypted(data) {
+		return nil, errPDFEncrypted
+	}
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return nil, ErrNoPages
+	}
+
+	im := &pdfImporter{doc: doc, first: first, numbers: make(map[int]int)}
+	imported := &ImportedPDF{First: first}
+	for i, page := range pages {
+		p, err := im.page(page)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i+1, err)
+		}
+		imported.Pages = append(imported.Pages, p)
+	}
+	imported.Objects = im.objects
+	return imported, nil
+}
+
+// pdfImporter copies objects of a parsed PDF into a new numbering
+type pdfImporter struct {
+	doc     *pdfDocument
+	first   int
+	numbers map[int]int // Source object number to imported number
+	objects [][]byte
+}
+
+// page writes a page's form XObject
+func (im *pdfImporter) page(page pdfDict) (ImportedPage, error) {
+	box, ok := im.box(page["CropBox"])
+	if media, mediaOK := im.box(page["MediaBox"]); !ok {
+		box, ok = media, mediaOK
+	} else if mediaOK {
+		// The crop box is clipped to the media box
+		box = [4]float64{
+			math.Max(box[0], media[0]), math.Max(box[1], media[1]),
+			math.Min(box[2], media[2]), math.Min(box[3], media[3]),
+		}
+	}
+	if !ok || box[2] <= box[0] || box[3] <= box[1] {
+		box = [4]float64{0, 0, 612, 792}
+	}
+
+	rotate, _ := im.doc.resolve(page["Rotate"]).(float64)
+	degrees := (int(rotate)%360 + 360) % 360
+	if degrees%90 != 0 {
+		degrees = 0
+	}
+
+	var content bytes.Buffer
+	var streams []interface{}
+	switch contents := im.doc.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		streams = []interface{}{contents}
+	case pdfArray:
+		streams = contents
+	}
+	for _, s := range streams {
+		stream, ok := im.doc.resolve(s).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := im.doc.decodeStream(stream)
+		if err != nil {
+			return ImportedPage{}, err
+		}
+		content.Write(data)
+		content.WriteByte('\n')
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(content.Bytes()); err != nil {
+		return ImportedPage{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return ImportedPage{}, err
+	}
+
+	var dict bytes.Buffer
+	fmt.Fprintf(&dict, "<< /Type /XObject /Subtype /Form /BBox [%s %s %s %s] /Resources ",
+		formatNumber(box[0]), formatNumber(box[1]), formatNumber(box[2]), formatNumber(box[3]))
+	if resources, ok := page["Resources"]; ok && resources != nil {
+		im.write(&dict, resources)
+	} else {
+		dict.WriteString("<< >>")
+	}
+	if group, ok := page["Group"]; ok {
+		dict.WriteString(" /Group ")
+		im.write(&dict, group)
+	}
+	fmt.Fprintf(&dict, " /Filter /FlateDecode /Length %d >>\nstream\n", compressed.Len())
+	dict.Write(compressed.Bytes())
+	dict.WriteString("\nendstream")
+
+	im.objects = append(im.objects, dict.Bytes())
+	return ImportedPage{Form: im.first + len(im.objects) - 1, Box: box, Rotate: degrees}, nil
+}
+
+// box reads a rectangle, normalizing its corners
+func (im *pdfImporter) box(value interface{}) ([4]float64, bool) {
+	array, _ := im.doc.resolve(value).(pdfArray)
+	if len(array) != 4 {
+		return [4]float64{}, false
+	}
+	var v [4]float64
+	for i, item := range array {
+		n, ok := im.doc.resolve(item).(float64)
+		if !ok {
+			return [4]float64{}, false
+		}
+		v[i] = n
+	}
+	return [4]float64{math.Min(v[0], v[2]), math.Min(v[1], v[3]), math.Max(v[0], v[2]), math.Max(v[1], v[3])}, true
+}
+
+// ref returns the imported number of a source object, copying it on first use
+func (im *pdfImporter) ref(num int) int {
+	if n, ok := im.numbers[num]; ok {
+		return n
+	}
+	index := len(im.objects)
+	im.numbers[num] = im.first + index
+	im.objects = append(im.objects, nil)
+
+	var body bytes.Buffer
+	if stream, ok := im.doc.objects[num].(*pdfStream); ok {
+		dict := make(pdfDict, len(stream.dict))
+		for key, value := range stream.dict {
+			dict[key] = value
+		}
+		dict["Length"] = float64(len(stream.data))
+		im.write(&body, dict)
+		body.WriteString("\nstream\n")
+		body.Write(stream.data)
+		body.WriteString("\nendstream")
+	} else {
+		im.write(&body, im.doc.objects[num])
+	}
+	im.objects[index] = body.Bytes()
+	return im.first + index
+}
+
+// write serializes a value, copying the objects it references. Parent
+// links are dropped so that a page's resources never pull in the page tree.
+func (im *pdfImporter) write(b *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case float64:
+		b.WriteString(formatNumber(v))
+	case pdfName:
+		writeName(b, v)
+	case pdfString:
+		b.WriteByte('<')
+		b.WriteString(hex.EncodeToString(v))
+		b.WriteByte('>')
+	case pdfKeyword:
+		b.WriteString(string(v))
+	case pdfRef:
+		fmt.Fprintf(b, "%d 0 R", im.ref(v.num))
+	case pdfArray:
+		b.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			im.write(b, item)
+		}
+		b.WriteByte(']')
+	case pdfDict:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if key != "Parent" {
+				keys = append(keys, string(key))
+			}
+		}
+		sort.Strings(keys)
+		b.WriteString("<<")
+		for _, key := range keys {
+			b.WriteByte(' ')
+			writeName(b, pdfName(key))
+			b.WriteByte(' ')
+			im.write(b, v[pdfName(key)])
+		}
+		b.WriteString(" >>")
+	case *pdfStream:
+		// Direct streams only occur inside object streams, where they are invalid
+		b.WriteString("null")
+	default:
+		b.WriteString("null")
+	}
+}
+
+// writeName writes a name, escaping delimiters and characters outside printable ASCII
+func writeName(b *bytes.Buffer, name pdfName) {
+	b.WriteByte('/')
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= ' ' || c > '~' || c == '#' || isPDFDelimiter(c) {
+			fmt.Fprintf(b, "#%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+}
+
+// formatNumber writes integers without a fraction and other numbers with
+// enough precision to round-trip
+func formatNumber(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
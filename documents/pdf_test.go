@@ -0,0 +1,94 @@
+package documents
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// minimalPDF returns a one-page PDF showing text with a Flate-compressed
+// content stream
+func minimalPDF(text string) []byte {
+	var content bytes.Buffer
+	w := zlib.NewWriter(&content)
+	fmt.Fprintf(w, "BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	w.Close()
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	b.WriteString("2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n")
+	b.WriteString("3 0 obj << /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >> endobj\n")
+	fmt.Fprintf(&b, "4 0 obj << /Length %d /Filter /FlateDecode >> stream\n", content.Len())
+	b.Write(content.Bytes())
+	b.WriteString("\nendstream endobj\n")
+	b.WriteString("5 0 obj << /Type /Font /Subtype /Type1 /BaseFont /Times-Roman >> endobj\n")
+	b.WriteString("trailer << /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+// pdfSeeds are the seed inputs of the PDF fuzz tests
+func pdfSeeds() [][]byte {
+	return [][]byte{
+		minimalPDF("Hello, world"),
+		[]byte("%PDF-1.4\n1 0 obj " + strings.Repeat("[", 2*maxPDFNesting) + " endobj"),
+		[]byte("%PDF-1.4\n1 0 obj " + strings.Repeat("<< /A ", 2*maxPDFNesting) + " endobj"),
+		[]byte("%PDF-1.7\n1 0 obj << /Type /ObjStm /N 1 /First 4 /Length 6 >> stream\n1 0 [[\nendstream endobj"),
+		[]byte("%PDF-1.4\n1 0 obj (unterminated"),
+	}
+}
+
+func TestExtractPDF(t *testing.T) {
+	text, err := extractPDF(minimalPDF("Hello, world"))
+	if err != nil {
+		t.Fatalf("extractPDF: %v", err)
+	}
+	if !strings.Contains(text, "Hello, world") {
+		t.Errorf("extractPDF = %q, want the page text", text)
+	}
+}
+
+func TestParseObjectNestingLimit(t *testing.T) {
+	for _, open := range []string{"[", "<< /A "} {
+		l := &pdfLexer{data: []byte(strings.Repeat(open, maxPDFNesting+1))}
+		if _, err := l.parseObject(); err != errPDFSyntax {
+			t.Errorf("parseObject of %d nested %q: err = %v, want errPDFSyntax", maxPDFNesting+1, open, err)
+		}
+	}
+
+	l := &pdfLexer{data: []byte(strings.Repeat("[", maxPDFNesting) + strings.Repeat("]", maxPDFNesting))}
+	if _, err := l.parseObject(); err != nil {
+		t.Errorf("parseObject of %d nested arrays: %v", maxPDFNesting, err)
+	}
+}
+
+func TestInflateLimit(t *testing.T) {
+	var bomb bytes.Buffer
+	w := zlib.NewWriter(&bomb)
+	w.Write(make([]byte, maxPDFStreamSize+1024))
+	w.Close()
+
+	if n := len(inflate(bomb.Bytes())); n != maxPDFStreamSize {
+		t.Errorf("inflate returned %d bytes, want %d", n, maxPDFStreamSize)
+	}
+}
+
+func FuzzExtractPDF(f *testing.F) {
+	for _, seed := range pdfSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		extractPDF(data)
+	})
+}
+
+func FuzzImportPDF(f *testing.F) {
+	for _, seed := range pdfSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ImportPDF(data, 10)
+	})
+}
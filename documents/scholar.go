@@ -0,0 +1,552 @@
+package documents
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"meritdraft-backend/models"
+
+	"golang.org/x/net/html"
+)
+
+// Scholar profile formats
+const (
+	ScholarHTML   = "html"
+	ScholarBibTeX = "bibtex"
+	ScholarCSV    = "csv"
+)
+
+var (
+	// ErrUnsupportedScholarFormat is returned for uploads that are not a saved
+	// profile page, BibTeX or CSV export
+	ErrUnsupportedScholarFormat = errors.New("unsupported scholar profile format")
+	// ErrNoPublications is returned when a profile lists no publications
+	ErrNoPublications = errors.New("no publications found in the scholar profile")
+)
+
+// ScholarProfile is the publication record read from a Google Scholar
+// profile. Saved profile pages only list the publications expanded before
+// saving, so their totals come from the page's metrics table when present.
+type ScholarProfile struct {
+	Format         string               `json:"format"`
+	Name           string               `json:"name,omitempty"`
+	URL            string               `json:"url,omitempty"` // Profile URL found in a saved page
+	Publications   []models.Publication `json:"publications"`  // Most cited first
+	TotalCitations int                  `json:"total_citations"`
+	HIndex         int                  `json:"h_index"`
+}
+
+// ParseScholarProfile parses a saved Google Scholar profile page, or a
+// BibTeX or CSV export of one
+func ParseScholarProfile(mimeType, filename string, data []byte) (*ScholarProfile, error) {
+	if !utf8.Valid(data) {
+		data = []byte(strings.ToValidUTF8(string(data), "\uFFFD"))
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	var profile *ScholarProfile
+	var err error
+	switch format := scholarFormat(mimeType, filename, data); format {
+	case ScholarHTML:
+		profile, err = parseScholarHTML(data)
+	case ScholarBibTeX:
+		profile = &ScholarProfile{Format: ScholarBibTeX, Publications: parseBibTeX(string(data))}
+	case ScholarCSV:
+		profile, err = parseScholarCSV(data)
+	default:
+		return nil, ErrUnsupportedScholarFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(profile.Publications) == 0 {
+		return nil, ErrNoPublications
+	}
+
+	// Most cited first; ties keep the profile's order
+	sort.SliceStable(profile.Publications, func(i, j int) bool {
+		return citations(profile.Publications[i]) > citations(profile.Publications[j])
+	})
+
+	// Computed metrics are a lower bound when the page lists only some publications
+	counts := make([]int, len(profile.Publications))
+	total := 0
+	for i, publication := range profile.Publications {
+		counts[i] = citations(publication)
+		total += counts[i]
+	}
+	profile.TotalCitations = max(profile.TotalCitations, total)
+	profile.HIndex = max(profile.HIndex, HIndex(counts))
+
+	return profile, nil
+}
+
+// HIndex returns the largest h such that h of the counts are at least h
+func HIndex(counts []int) int {
+	sorted := append([]int(nil), counts...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	h := 0
+	for i, count := range sorted {
+		if count < i+1 {
+			break
+		}
+		h = i + 1
+	}
+	return h
+}
+
+func citations(p models.Publication) int {
+	if p.Citations == nil {
+		return 0
+	}
+	return *p.Citations
+}
+
+// scholarFormat resolves the format from the MIME type, the extension, then
+// the content
+func scholarFormat(mimeType, filename string, data []byte) string {
+	switch strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0])) {
+	case "text/html", "application/xhtml+xml":
+		return ScholarHTML
+	case "application/x-bibtex", "text/x-bibtex":
+		return ScholarBibTeX
+	case "text/csv", "application/csv":
+		return ScholarCSV
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".html", ".htm":
+		return ScholarHTML
+	case ".bib", ".bibtex":
+		return ScholarBibTeX
+	case ".csv":
+		return ScholarCSV
+	}
+
+	head := strings.ToLower(string(bytes.TrimSpace(data[:min(len(data), 512)])))
+	switch {
+	case strings.HasPrefix(head, "@"):
+		return ScholarBibTeX
+	case strings.HasPrefix(head, "<"):
+		return ScholarHTML
+	case strings.Contains(strings.SplitN(head, "\n", 2)[0], "title"):
+		return ScholarCSV
+	}
+	return ""
+}
+
+var (
+	savedFromPattern = regexp.MustCompile(`saved from url=\(\d+\)(\S+)`)
+	digitsPattern    = regexp.MustCompile(`\d+`)
+)
+
+// parseScholarHTML reads the publication table (tr.gsc_a_tr), the metrics
+// table (#gsc_rsb_st) and the profile name and URL of a saved profile page
+func parseScholarHTML(data []byte) (*ScholarProfile, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &ScholarProfile{Format: ScholarHTML}
+	walk(root, func(n *html.Node) bool {
+		switch {
+		case n.Type != html.ElementNode:
+		case n.Data == "tr" && hasClass(n, "gsc_a_tr"):
+			if publication, ok := scholarRow(n); ok {
+				profile.Publications = append(profile.Publications, publication)
+			}
+			return false
+		case n.Data == "table" && attr(n, "id") == "gsc_rsb_st":
+			scholarMetrics(n, profile)
+			return false
+		case attr(n, "id") == "gsc_prf_in":
+			profile.Name = nodeText(n)
+			return false
+		case n.Data == "link" && attr(n, "rel") == "canonical", n.Data == "meta" && attr(n, "property") == "og:url":
+			if url := attr(n, "href") + attr(n, "content"); isScholarURL(url) {
+				profile.URL = url
+			}
+		}
+		return true
+	})
+
+	if profile.URL == "" {
+		if m := savedFromPattern.FindSubmatch(data); m != nil && isScholarURL(string(m[1])) {
+			profile.URL = string(m[1])
+		}
+	}
+	return profile, nil
+}
+
+// scholarRow reads one publication row: the title link, the authors and
+// venue lines, the citation count and the year
+func scholarRow(row *html.Node) (models.Publication, bool) {
+	var publication models.Publication
+	var gray []string
+	walk(row, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		switch {
+		case n.Data == "a" && hasClass(n, "gsc_a_at"):
+			publication.Title = nodeText(n)
+		case n.Data == "div" && hasClass(n, "gs_gray"):
+			gray = append(gray, nodeText(n))
+		case hasClass(n, "gsc_a_ac"):
+			if count, err := strconv.Atoi(digitsPattern.FindString(nodeText(n))); err == nil {
+				publication.Citations = &count
+			}
+		case hasClass(n, "gsc_a_h"), n.Data == "td" && hasClass(n, "gsc_a_y"):
+			if year, ok := parseYear(nodeText(n)); ok {
+				publication.Year = &year
+			}
+		default:
+			return true
+		}
+		return false
+	})
+
+	if len(gray) > 1 {
+		publication.Journal = cleanVenue(gray[1])
+	}
+	if publication.Citations == nil {
+		zero := 0
+		publication.Citations = &zero
+	}
+	return publication, publication.Title != ""
+}
+
+// scholarMetrics reads the "All" column of the citations and h-index rows
+func scholarMetrics(table *html.Node, profile *ScholarProfile) {
+	walk(table, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.Data != "tr" {
+			return true
+		}
+		var cells []string
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+				cells = append(cells, nodeText(c))
+			}
+		}
+		if len(cells) < 2 {
+			return false
+		}
+		value, err := strconv.Atoi(digitsPattern.FindString(cells[1]))
+		if err != nil {
+			return false
+		}
+		switch strings.ToLower(cells[0]) {
+		case "citations":
+			profile.TotalCitations = value
+		case "h-index":
+			profile.HIndex = value
+		}
+		return false
+	})
+}
+
+func isScholarURL(url string) bool {
+	return strings.Contains(url, "scholar.google.") && strings.Contains(url, "user=")
+}
+
+// walk visits n and its descendants depth first; visit returns false to
+// skip a node's children
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeText returns the collapsed text of a node, leaving out the
+// ", 2019" year repeated in span.gs_oph for narrow screens
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && hasClass(c, "gs_oph") {
+			return false
+		}
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		return true
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+var venueSuffixPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`,\s*(?:19|20)\d\d$`),                                        // Year
+	regexp.MustCompile(`,\s*(?:pp?\.\s*)?[A-Za-z]?\d+(?:\s*[-–]\s*[A-Za-z]?\d+)?$`), // Pages or article number
+	regexp.MustCompile(`\s+\d+\s*(?:\([^)]*\))?$`),                                  // Volume and issue
+	regexp.MustCompile(`\s*\([^)]*\)$`),                                             // Issue without volume
+}
+
+// cleanVenue reduces a Scholar venue line such as
+// "Nature 521 (7553), 436-444, 2015" to the venue name
+func cleanVenue(venue string) string {
+	for {
+		before := venue
+		for _, pattern := range venueSuffixPatterns {
+			venue = strings.TrimSpace(pattern.ReplaceAllString(venue, ""))
+		}
+		if venue == before {
+			return trimPunctuation(venue)
+		}
+	}
+}
+
+func parseYear(s string) (int, bool) {
+	year, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || year < 1000 || year > 9999 {
+		return 0, false
+	}
+	return year, true
+}
+
+// parseBibTeX reads the entries of a BibTeX file. @string abbreviations
+// are expanded; @preamble and @comment blocks are skipped.
+func parseBibTeX(data string) []models.Publication {
+	publications := []models.Publication{}
+	macros := make(map[string]string)
+	for {
+		at := strings.IndexByte(data, '@')
+		if at < 0 {
+			return publications
+		}
+		data = data[at+1:]
+
+		open := strings.IndexAny(data, "{(")
+		if open < 0 {
+			return publications
+		}
+		entryType := strings.ToLower(strings.TrimSpace(data[:open]))
+		body, rest := balanced(data[open:])
+		data = rest
+		switch entryType {
+		case "string":
+			for name, value := range bibFields(","+body, macros) {
+				macros[name] = value
+			}
+			continue
+		case "preamble", "comment":
+			continue
+		}
+
+		fields := bibFields(body, macros)
+		publication := models.Publication{Title: fields["title"]}
+		if publication.Title == "" {
+			continue
+		}
+		for _, key := range []string{"journal", "booktitle", "publisher", "school", "institution", "howpublished"} {
+			if fields[key] != "" {
+				publication.Journal = fields[key]
+				break
+			}
+		}
+		if year, ok := parseYear(fields["year"]); ok {
+			publication.Year = &year
+		}
+		for _, key := range []string{"citations", "cited-by", "citedby", "times-cited", "note"} {
+			if count, ok := citationCount(key, fields[key]); ok {
+				publication.Citations = &count
+				break
+			}
+		}
+		publications = append(publications, publication)
+	}
+}
+
+var citedByPattern = regexp.MustCompile(`(?i)cited by\s+(\d+)`)
+
+// citationCount reads a count field, or "Cited by N" from a note
+func citationCount(key, value string) (int, bool) {
+	if key == "note" {
+		m := citedByPattern.FindStringSubmatch(value)
+		if m == nil {
+			return 0, false
+		}
+		value = m[1]
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(value))
+	return count, err == nil && count >= 0
+}
+
+// balanced splits s, which starts with an opening brace or parenthesis, at
+// its matching close. Returns the enclosed text and the rest.
+func balanced(s string) (string, string) {
+	open, close := s[0], byte('}')
+	if open == '(' {
+		close = ')'
+	}
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return s[1:i], s[i+1:]
+			}
+		}
+	}
+	return s[1:], ""
+}
+
+// bibFields parses "key, name = {value}, name = "value", name = 2019" into
+// cleaned values keyed by lowercase name, expanding @string abbreviations
+func bibFields(body string, macros map[string]string) map[string]string {
+	fields := make(map[string]string)
+	comma := strings.IndexByte(body, ',')
+	if comma < 0 {
+		return fields
+	}
+	s := body[comma+1:]
+
+	for {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return fields
+		}
+		name := strings.ToLower(strings.Trim(strings.TrimSpace(s[:eq]), ","))
+		s = strings.TrimLeft(s[eq+1:], " \t\r\n")
+
+		var parts []string
+		for {
+			var part string
+			switch {
+			case s == "":
+			case s[0] == '{':
+				part, s = balanced(s)
+			case s[0] == '"':
+				end := strings.IndexByte(s[1:], '"')
+				if end < 0 {
+					part, s = s[1:], ""
+				} else {
+					part, s = s[1:end+1], s[end+2:]
+				}
+			default:
+				end := strings.IndexAny(s, ",#")
+				if end < 0 {
+					end = len(s)
+				}
+				part, s = strings.TrimSpace(s[:end]), s[end:]
+				if macro, ok := macros[strings.ToLower(part)]; ok {
+					part = macro
+				}
+			}
+			parts = append(parts, part)
+
+			s = strings.TrimLeft(s, " \t\r\n")
+			if !strings.HasPrefix(s, "#") {
+				break
+			}
+			s = strings.TrimLeft(s[1:], " \t\r\n")
+		}
+		fields[name] = cleanTeX(strings.Join(parts, ""))
+
+		comma := strings.IndexByte(s, ',')
+		if comma < 0 {
+			return fields
+		}
+		s = s[comma+1:]
+	}
+}
+
+var texCommandPattern = regexp.MustCompile(`\\[a-zA-Z]+\s*|\\(.)`)
+
+// cleanTeX drops braces and TeX commands, keeping escaped characters
+func cleanTeX(s string) string {
+	s = texCommandPattern.ReplaceAllString(s, "$1")
+	s = strings.NewReplacer("{", "", "}", "", "~", " ").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// CSV columns by lowercase header, covering Scholar's export and common
+// citation managers
+var (
+	csvTitleColumns    = []string{"title"}
+	csvVenueColumns    = []string{"publication", "source", "journal", "venue", "booktitle", "publisher"}
+	csvYearColumns     = []string{"year"}
+	csvCitationColumns = []string{"cites", "citations", "cited by", "times cited"}
+)
+
+// parseScholarCSV reads a CSV export with a header row
+func parseScholarCSV(data []byte) (*ScholarProfile, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrNoPublications
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	column := func(record []string, names []string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) && strings.TrimSpace(record[i]) != "" {
+				return strings.TrimSpace(record[i])
+			}
+		}
+		return ""
+	}
+
+	profile := &ScholarProfile{Format: ScholarCSV, Publications: []models.Publication{}}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		publication := models.Publication{
+			Title:   column(record, csvTitleColumns),
+			Journal: column(record, csvVenueColumns),
+		}
+		if publication.Title == "" {
+			continue
+		}
+		if year, ok := parseYear(column(record, csvYearColumns)); ok {
+			publication.Year = &year
+		}
+		if count, err := strconv.Atoi(column(record, csvCitationColumns)); err == nil && count >= 0 {
+			publication.Citations = &count
+		}
+		profile.Publications = append(profile.Publications, publication)
+	}
+	return profile, nil
+}
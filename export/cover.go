@@ -0,0 +1,121 @@
+package export
+
+import (
+	"fmt"
+	"time"
+)
+
+// CoverLetter is the letter on the firm's letterhead in front of a filing
+// packet, listing its enclosures with their Bates ranges
+type CoverLetter struct {
+	Letterhead Letterhead
+	Date       time.Time
+	Recipient  []string // Address lines, e.g. the USCIS service center
+	Subject    string   // Printed after "Re:"
+	Body       []string // Paragraphs before the list of enclosures
+	Signature  string   // Name under the closing; none when empty
+}
+
+// enclosure is a row of the cover letter's list of enclosures
+type enclosure struct {
+	label       string
+	description string
+	bates       BatesRange
+}
+
+// coverLayout lays out a cover letter listing enclosures. Bates numbers
+// have a fixed width, so the page count does not depend on the ranges.
+func coverLayout(cover *CoverLetter, enclosures []enclosure) *pdfLayout {
+	l := &pdfLayout{doc: &Document{Letterhead: cover.Letterhead}, exhibitPages: make(map[string][]int)}
+	l.newPage()
+	l.letterhead()
+
+	text := func(s string, font pdfFont, align alignment) {
+		for _, ln := range l.wrap(textWords(s, font, bodySize), font, bodySize, bodyLeading, pageMargin, contentWidth, 0, align) {
+			l.place(ln)
+		}
+	}
+
+	if !cover.Date.IsZero() {
+		text(cover.Date.Format("January 2, 2006"), fontRegular, alignLeft)
+		l.y -= bodyLeading
+	}
+	for _, address := range cover.Recipient {
+		text(address, fontRegular, alignLeft)
+	}
+	if len(cover.Recipient) > 0 {
+		l.y -= bodyLeading
+	}
+	if cover.Subject != "" {
+		text("Re: "+cover.Subject, fontBold, alignLeft)
+		l.y -= bodyLeading
+	}
+
+	text("Dear Sir or Madam:", fontRegular, alignLeft)
+	l.y -= paragraphGap
+	for _, paragraph := range cover.Body {
+		lines := l.wrap(textWords(paragraph, fontRegular, bodySize), fontRegular, bodySize, bodyLeading, pageMargin, contentWidth, paragraphIndent, alignJustify)
+		for i, ln := range lines {
+			if i == len(lines)-1 {
+				ln.align = alignLeft
+			}
+			l.place(ln)
+		}
+		l.y -= paragraphGap
+	}
+	l.y -= paragraphGap
+
+	const labelWidth, batesWidth, gutter, rowLeading = 72.0, 144.0, 12.0, 14.0
+	descriptionX := pageMargin + labelWidth
+	descriptionWidth := contentWidth - labelWidth - batesWidth - gutter
+
+	header := func() {
+		baseline := l.y - bodySize
+		l.drawLine(line{words: textWords("Enclosure", fontBold, bodySize), font: fontBold, size: bodySize, x: pageMargin, width: labelWidth}, baseline)
+		l.drawLine(line{words: textWords("Description", fontBold, bodySize), font: fontBold, size: bodySize, x: descriptionX, width: descriptionWidth}, baseline)
+		l.drawLine(line{words: textWords("Bates", fontBold, bodySize), font: fontBold, size: bodySize, x: pageWidth - pageMargin - batesWidth, width: batesWidth, align: alignRight}, baseline)
+		l.y -= bodySize + 6
+		fmt.Fprintf(&l.page.content, "0.5 w %s %s m %s %s l S\n", num(pageMargin), num(l.y), num(pageWidth-pageMargin), num(l.y))
+		l.y -= 8
+	}
+	l.ensure(3 * bodyLeading)
+	header()
+
+	for _, e := range enclosures {
+		description := l.wrap(textWords(e.description, fontRegular, bodySize), fontRegular, bodySize, rowLeading, descriptionX, descriptionWidth, 0, alignLeft)
+		bates := l.wrap(textWords(batesText(e.bates), fontRegular, bodySize), fontRegular, bodySize, rowLeading, pageWidth-pageMargin-batesWidth, batesWidth, 0, alignRight)
+		rows := max(len(description), len(bates), 1)
+		if l.y-float64(rows)*rowLeading < pageMargin {
+			l.newPage()
+			header()
+		}
+
+		top := l.y
+		baseline := top - bodySize
+		l.drawLine(line{words: textWords(e.label, fontBold, bodySize), font: fontBold, size: bodySize, x: pageMargin, width: labelWidth}, baseline)
+		for i, ln := range description {
+			l.drawLine(ln, baseline-float64(i)*rowLeading)
+		}
+		for i, ln := range bates {
+			l.drawLine(ln, baseline-float64(i)*rowLeading)
+		}
+		l.y = top - float64(rows)*rowLeading - 6
+	}
+
+	l.y -= bodyLeading
+	l.ensure(4 * bodyLeading)
+	text("Respectfully submitted,", fontRegular, alignLeft)
+	if cover.Signature != "" {
+		l.y -= 2 * bodyLeading
+		text(cover.Signature, fontRegular, alignLeft)
+	}
+	return l
+}
+
+// batesText formats a Bates range, e.g. "DOE000002 – DOE000010"
+func batesText(r BatesRange) string {
+	if r.First == r.Last {
+		return r.First
+	}
+	return r.First + " – " + r.Last
+}
@@ -0,0 +1,242 @@
+// Package export renders assembled petitions as filing documents. The
+// sections of a draft are laid out as numbered sections and paragraphs,
+// with parenthetical citations moved into footnotes, and then written out
+// as DOCX or PDF. Exhibit placeholders are lettered against the tagged
+// exhibits by ResolveExhibits.
+package export
+
+import (
+	"regexp"
+	"strings"
+
+	"meritdraft-backend/models"
+)
+
+// Letterhead is the firm identification printed at the top of the first page
+type Letterhead struct {
+	Name  string
+	Lines []string // Address, phone and email
+}
+
+// Document is an assembled petition laid out for export
+type Document struct {
+	Title      string // Heading line of the petition, e.g. "PETITION FOR O-1A VISA"
+	Letterhead Letterhead
+	Sections   []Section
+	Footnotes  []string // Run.Footnote n refers to Footnotes[n-1]
+	Exhibits   []Exhibit
+}
+
+// Exhibit is a tagged exhibit listed after the letter
+type Exhibit struct {
+	Label       string // "A", "B", ...
+	Description string
+}
+
+// Section is a heading and the paragraphs under it. Sections are listed in
+// document order; a level-2 section belongs to the level-1 section before it.
+type Section struct {
+	Level      int    // 1 for the Roman-numeral parts, 2 for criteria and prongs, 0 for text before the first heading
+	Number     string // "I.", "II." at level 1; "A.", "B." at level 2
+	Heading    string
+	Paragraphs []Paragraph
+}
+
+// Paragraph is a sequence of runs
+type Paragraph []Run
+
+// Run is text optionally followed by a footnote reference
+type Run struct {
+	Text     string
+	Footnote int // 1-based index into Document.Footnotes; 0 for none
+}
+
+// Text returns the paragraph's text without footnote references
+func (p Paragraph) Text() string {
+	var b strings.Builder
+	for _, run := range p {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+var (
+	// Parts written by assembleDocument, e.g. "III. REGULATORY CRITERIA"
+	partHeadingPattern = regexp.MustCompile(`^[IVXLC]+\.\s+(.+)$`)
+
+	// A parenthetical, allowing one level of nested parentheses as in "§ 214.2(o)(3)"
+	parentheticalPattern = regexp.MustCompile(`\s*\((?:[^()]|\([^()]*\))*\)`)
+
+	// Evidence placeholders the drafting prompts ask for, e.g. "[Exhibit __]"
+	exhibitPattern = regexp.MustCompile(`\[Exhibits?\s*([^\[\]]{0,20})\]`)
+
+	// End of a sentence, e.g. ". " or ".\" "
+	sentenceBreakPattern = regexp.MustCompile(`[.?!;]["”’)]?\s+`)
+
+	// Markers of a statutory or regulatory citation inside a parenthetical.
+	// Case citations are left inline: their court and year parentheticals,
+	// as in "26 I&N Dec. 884 (AAO 2016)", belong to the cite.
+	citationPattern = regexp.MustCompile(`C\.F\.R\.|U\.S\.C\.|\bINA\b|§|Fed\. Reg\.`)
+)
+
+// FromSections lays out a draft stored as sections. The level-0 title is
+// the document title, level-1 sections are renumbered I., II., ... and
+// level-2 sections lettered A., B., ... within their part. Each line of a
+// section's content is a paragraph. Citations of the section's retrieved
+// context that its text does not already give are footnoted after its
+// first paragraph.
+//
+// Exhibit placeholders are kept as they are; Document.Exhibits is left for
+// the caller to fill in from the lettered exhibits.
+func FromSections(sections []*models.PetitionSection) *Document {
+	doc := &Document{}
+	parts, subparts := 0, 0
+
+	for _, section := range sections {
+		title := strings.TrimSpace(section.Title)
+		var current Section
+		switch section.Level {
+		case 0:
+			if doc.Title == "" {
+				doc.Title = title
+				title = ""
+			}
+		case 1:
+			parts++
+			subparts = 0
+			heading := title
+			if m := partHeadingPattern.FindStringSubmatch(title); m != nil {
+				heading = m[1]
+			}
+			current = Section{Level: 1, Number: roman(parts) + ".", Heading: heading}
+		default:
+			subparts++
+			current = Section{Level: 2, Number: letter(subparts) + ".", Heading: strings.TrimSuffix(title, ":")}
+		}
+
+		uncited := uncitedCitations(section)
+		for _, line := range sectionLines(section.Content, title) {
+			current.Paragraphs = append(current.Paragraphs, doc.footnote(line))
+			if len(uncited) > 0 {
+				doc.Footnotes = append(doc.Footnotes, strings.Join(uncited, "; ")+".")
+				last := &current.Paragraphs[len(current.Paragraphs)-1]
+				*last = append(*last, Run{Footnote: len(doc.Footnotes)})
+				uncited = nil
+			}
+		}
+
+		if current.Level > 0 || len(current.Paragraphs) > 0 {
+			doc.Sections = append(doc.Sections, current)
+		}
+	}
+
+	return doc
+}
+
+// sectionLines returns the non-blank lines of a section's content, without
+// an opening line in which the model repeated the title
+func sectionLines(content, title string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) > 0 && title != "" && len(lines[0]) >= len(title) && strings.EqualFold(lines[0][:len(title)], title) {
+		rest := strings.TrimLeft(lines[0][len(title):], " :.-–—")
+		if rest == "" {
+			lines = lines[1:]
+		} else {
+			lines[0] = rest
+		}
+	}
+	return lines
+}
+
+// uncitedCitations returns the section's citations that its content does
+// not already give, without duplicates
+func uncitedCitations(section *models.PetitionSection) []string {
+	seen := make(map[string]bool)
+	var uncited []string
+	for _, citation := range section.Citations {
+		citation = strings.TrimRight(strings.TrimSpace(citation), ".")
+		if citation == "" || seen[citation] || strings.Contains(section.Content, citation) {
+			continue
+		}
+		seen[citation] = true
+		uncited = append(uncited, citation)
+	}
+	return uncited
+}
+
+// footnote splits a line into runs, moving parenthetical citations into
+// footnotes placed after any punctuation that followed them
+func (d *Document) footnote(line string) Paragraph {
+	var paragraph Paragraph
+	last := 0
+	for _, loc := range parentheticalPattern.FindAllStringIndex(line, -1) {
+		inner := strings.TrimSpace(line[loc[0]:loc[1]])
+		inner = strings.TrimSpace(inner[1 : len(inner)-1])
+		if !citationPattern.MatchString(inner) || loc[0] < last {
+			continue
+		}
+
+		text := line[last:loc[0]]
+		end := loc[1]
+		if end < len(line) && strings.ContainsRune(".,;:", rune(line[end])) {
+			text += line[end : end+1]
+			end++
+		}
+
+		if !strings.HasSuffix(inner, ".") {
+			inner += "."
+		}
+		if n := len(d.Footnotes); n > 0 && d.Footnotes[n-1] == inner {
+			inner = "Id."
+		}
+		d.Footnotes = append(d.Footnotes, inner)
+		paragraph = append(paragraph, Run{Text: text, Footnote: len(d.Footnotes)})
+		last = end
+	}
+
+	if rest := line[last:]; rest != "" || len(paragraph) == 0 {
+		paragraph = append(paragraph, Run{Text: rest})
+	}
+	return paragraph
+}
+
+// headingKey normalizes a heading for matching against subheadings
+func headingKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimSpace(s), ":")), " "))
+}
+
+// isUpper reports whether s has letters and none of them are lowercase
+func isUpper(s string) bool {
+	return strings.ToUpper(s) == s && strings.ToLower(s) != s
+}
+
+// roman formats n as an uppercase Roman numeral
+func roman(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var b strings.Builder
+	for i, value := range values {
+		for n >= value {
+			b.WriteString(symbols[i])
+			n -= value
+		}
+	}
+	return b.String()
+}
+
+// letter formats n as A, B, ..., Z, AA, AB, ...
+func letter(n int) string {
+	s := ""
+	for n > 0 {
+		n--
+		s = string(rune('A'+n%26)) + s
+		n /= 26
+	}
+	return s
+}
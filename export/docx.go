@@ -0,0 +1,299 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// MimeTypeDOCX is the MIME type of exported DOCX documents
+const MimeTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+const (
+	wordNamespaces = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+)
+
+// WriteDOCX writes doc as a Word document: the letterhead in the first-page
+// header, the title and a table of contents, numbered Heading 1 and Heading
+// 2 sections, citations as footnotes, the exhibit list and "Page N of M" in
+// the footer. Word refreshes the table of contents' page numbers when the
+// file is opened.
+func WriteDOCX(w io.Writer, doc *Document) error {
+	archive := zip.NewWriter(w)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxPackageRels},
+		{"docProps/core.xml", docxCoreProperties(doc)},
+		{"word/_rels/document.xml.rels", docxDocumentRels},
+		{"word/document.xml", docxDocument(doc)},
+		{"word/styles.xml", docxStyles},
+		{"word/settings.xml", docxSettings},
+		{"word/footnotes.xml", docxFootnotes(doc)},
+		{"word/header1.xml", docxLetterhead(doc.Letterhead)},
+		{"word/header2.xml", docxRunningHeader(doc.Title)},
+		{"word/footer1.xml", docxFooter},
+	}
+	for _, part := range parts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// escape escapes text for XML character data and attributes
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// textRun writes a run of text, preserving its leading and trailing spaces
+func textRun(b *strings.Builder, text, properties string) {
+	if text == "" {
+		return
+	}
+	b.WriteString(`<w:r>`)
+	if properties != "" {
+		b.WriteString(`<w:rPr>` + properties + `</w:rPr>`)
+	}
+	b.WriteString(`<w:t xml:space="preserve">` + escape(text) + `</w:t></w:r>`)
+}
+
+// styledParagraph writes a paragraph of one run in the given style
+func styledParagraph(b *strings.Builder, style, text string) {
+	b.WriteString(`<w:p><w:pPr><w:pStyle w:val="` + style + `"/></w:pPr>`)
+	textRun(b, text, "")
+	b.WriteString(`</w:p>`)
+}
+
+func sectionHeading(section Section) string {
+	return strings.TrimSpace(section.Number + " " + section.Heading)
+}
+
+func docxDocument(doc *Document) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<w:document ` + wordNamespaces + `><w:body>`)
+
+	if doc.Title != "" {
+		styledParagraph(&b, "Title", doc.Title)
+	}
+
+	// Table of contents, pre-filled with the headings so it reads correctly
+	// before Word updates the field with page numbers
+	styledParagraph(&b, "TOCHeading", "TABLE OF CONTENTS")
+	b.WriteString(`<w:p><w:pPr><w:pStyle w:val="TOC1"/></w:pPr>` +
+		`<w:r><w:fldChar w:fldCharType="begin" w:dirty="true"/></w:r>` +
+		`<w:r><w:instrText xml:space="preserve"> TOC \o "1-2" \h \z \u </w:instrText></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="separate"/></w:r></w:p>`)
+	for _, section := range doc.Sections {
+		if section.Level == 1 || section.Level == 2 {
+			styledParagraph(&b, fmt.Sprintf("TOC%d", section.Level), sectionHeading(section))
+		}
+	}
+	b.WriteString(`<w:p><w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`)
+	b.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+
+	for _, section := range doc.Sections {
+		if section.Level == 1 || section.Level == 2 {
+			styledParagraph(&b, fmt.Sprintf("Heading%d", section.Level), sectionHeading(section))
+		}
+		for _, paragraph := range section.Paragraphs {
+			b.WriteString(`<w:p>`)
+			for _, run := range paragraph {
+				textRun(&b, run.Text, "")
+				if run.Footnote > 0 {
+					fmt.Fprintf(&b, `<w:r><w:rPr><w:rStyle w:val="FootnoteReference"/></w:rPr><w:footnoteReference w:id="%d"/></w:r>`, run.Footnote)
+				}
+			}
+			b.WriteString(`</w:p>`)
+		}
+	}
+	docxExhibitList(&b, doc.Exhibits)
+
+	// US Letter with one-inch margins; the letterhead only on the first page
+	b.WriteString(`<w:sectPr>` +
+		`<w:headerReference w:type="default" r:id="rIdHeaderRunning"/>` +
+		`<w:headerReference w:type="first" r:id="rIdHeaderLetterhead"/>` +
+		`<w:footerReference w:type="default" r:id="rIdFooter"/>` +
+		`<w:footerReference w:type="first" r:id="rIdFooter"/>` +
+		`<w:pgSz w:w="12240" w:h="15840"/>` +
+		`<w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="720" w:footer="720" w:gutter="0"/>` +
+		`<w:titlePg/>` +
+		`</w:sectPr>`)
+	b.WriteString(`</w:body></w:document>`)
+	return b.String()
+}
+
+// docxExhibitList writes the exhibits as a table on a page of their own.
+// Unlike the PDF it cannot list the pages that reference each exhibit,
+// since Word lays out the pages.
+func docxExhibitList(b *strings.Builder, exhibits []Exhibit) {
+	if len(exhibits) == 0 {
+		return
+	}
+
+	b.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+	styledParagraph(b, "ExhibitListHeading", "EXHIBIT LIST")
+
+	// Label and description columns across the 6.5" text width, with the
+	// header row repeated on every page
+	b.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="9360" w:type="dxa"/><w:tblLayout w:type="fixed"/>` +
+		`<w:tblBorders><w:insideH w:val="single" w:sz="4" w:space="0" w:color="auto"/></w:tblBorders>` +
+		`<w:tblCellMar><w:top w:w="60" w:type="dxa"/><w:bottom w:w="60" w:type="dxa"/></w:tblCellMar></w:tblPr>` +
+		`<w:tblGrid><w:gridCol w:w="1200"/><w:gridCol w:w="8160"/></w:tblGrid>`)
+	widths := []int{1200, 8160}
+	row := func(label, description, properties string) {
+		b.WriteString(`<w:tr>`)
+		if properties != "" {
+			b.WriteString(`<w:trPr>` + properties + `</w:trPr>`)
+		}
+		for i, text := range []string{label, description} {
+			fmt.Fprintf(b, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr><w:p><w:pPr><w:pStyle w:val="TableText"/></w:pPr>`, widths[i])
+			if i == 0 || properties != "" {
+				textRun(b, text, `<w:b/>`)
+			} else {
+				textRun(b, text, "")
+			}
+			b.WriteString(`</w:p></w:tc>`)
+		}
+		b.WriteString(`</w:tr>`)
+	}
+	row("Exhibit", "Description", `<w:tblHeader/><w:cantSplit/>`)
+	for _, exhibit := range exhibits {
+		row(exhibit.Label, exhibit.Description, "")
+	}
+	b.WriteString(`</w:tbl>`)
+}
+
+func docxFootnotes(doc *Document) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<w:footnotes ` + wordNamespaces + `>` +
+		`<w:footnote w:type="separator" w:id="-1"><w:p><w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:r><w:separator/></w:r></w:p></w:footnote>` +
+		`<w:footnote w:type="continuationSeparator" w:id="0"><w:p><w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:r><w:continuationSeparator/></w:r></w:p></w:footnote>`)
+	for i, footnote := range doc.Footnotes {
+		fmt.Fprintf(&b, `<w:footnote w:id="%d"><w:p><w:pPr><w:pStyle w:val="FootnoteText"/></w:pPr>`+
+			`<w:r><w:rPr><w:rStyle w:val="FootnoteReference"/></w:rPr><w:footnoteRef/></w:r>`, i+1)
+		textRun(&b, " "+footnote, "")
+		b.WriteString(`</w:p></w:footnote>`)
+	}
+	b.WriteString(`</w:footnotes>`)
+	return b.String()
+}
+
+func docxLetterhead(letterhead Letterhead) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<w:hdr ` + wordNamespaces + `>`)
+	if letterhead.Name == "" && len(letterhead.Lines) == 0 {
+		b.WriteString(`<w:p/>`)
+	} else {
+		styledParagraph(&b, "LetterheadName", letterhead.Name)
+		for _, line := range letterhead.Lines {
+			styledParagraph(&b, "LetterheadLine", line)
+		}
+	}
+	b.WriteString(`</w:hdr>`)
+	return b.String()
+}
+
+func docxRunningHeader(title string) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<w:hdr ` + wordNamespaces + `>`)
+	styledParagraph(&b, "Header", title)
+	b.WriteString(`</w:hdr>`)
+	return b.String()
+}
+
+func docxCoreProperties(doc *Document) string {
+	now := time.Now().UTC().Format(time.RFC3339)
+	return xmlHeader + `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" ` +
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + escape(doc.Title) + `</dc:title>` +
+		`<dc:creator>` + escape(doc.Letterhead.Name) + `</dc:creator>` +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + now + `</dcterms:created>` +
+		`<dcterms:modified xsi:type="dcterms:W3CDTF">` + now + `</dcterms:modified>` +
+		`</cp:coreProperties>`
+}
+
+const docxContentTypes = xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`<Override PartName="/word/settings.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml"/>` +
+	`<Override PartName="/word/footnotes.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml"/>` +
+	`<Override PartName="/word/header1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/>` +
+	`<Override PartName="/word/header2.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/>` +
+	`<Override PartName="/word/footer1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footer+xml"/>` +
+	`</Types>`
+
+const docxPackageRels = xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+	`</Relationships>`
+
+const docxDocumentRels = xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rIdStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`<Relationship Id="rIdSettings" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings" Target="settings.xml"/>` +
+	`<Relationship Id="rIdFootnotes" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footnotes" Target="footnotes.xml"/>` +
+	`<Relationship Id="rIdHeaderLetterhead" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="header1.xml"/>` +
+	`<Relationship Id="rIdHeaderRunning" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="header2.xml"/>` +
+	`<Relationship Id="rIdFooter" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footer" Target="footer1.xml"/>` +
+	`</Relationships>`
+
+// docxSettings asks Word to update fields on open so the table of contents
+// gets its page numbers
+const docxSettings = xmlHeader + `<w:settings ` + wordNamespaces + `>` +
+	`<w:updateFields w:val="true"/>` +
+	`<w:defaultTabStop w:val="720"/>` +
+	`<w:footnotePr><w:footnote w:id="-1"/><w:footnote w:id="0"/></w:footnotePr>` +
+	`<w:compat><w:compatSetting w:name="compatibilityMode" w:uri="http://schemas.microsoft.com/office/word" w:val="15"/></w:compat>` +
+	`</w:settings>`
+
+const docxFooter = xmlHeader + `<w:ftr ` + wordNamespaces + `>` +
+	`<w:p><w:pPr><w:pStyle w:val="Footer"/></w:pPr>` +
+	`<w:r><w:t xml:space="preserve">Page </w:t></w:r>` +
+	`<w:fldSimple w:instr=" PAGE "><w:r><w:t>1</w:t></w:r></w:fldSimple>` +
+	`<w:r><w:t xml:space="preserve"> of </w:t></w:r>` +
+	`<w:fldSimple w:instr=" NUMPAGES "><w:r><w:t>1</w:t></w:r></w:fldSimple>` +
+	`</w:p></w:ftr>`
+
+// docxStyles sets Times New Roman 12pt justified body text with bold
+// headings that feed the table of contents through their outline levels
+const docxStyles = xmlHeader + `<w:styles ` + wordNamespaces + `>` +
+	`<w:docDefaults>` +
+	`<w:rPrDefault><w:rPr><w:rFonts w:ascii="Times New Roman" w:hAnsi="Times New Roman" w:eastAsia="Times New Roman" w:cs="Times New Roman"/><w:sz w:val="24"/><w:szCs w:val="24"/><w:lang w:val="en-US"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="240" w:line="276" w:lineRule="auto"/></w:pPr></w:pPrDefault>` +
+	`</w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/><w:pPr><w:jc w:val="both"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:jc w:val="center"/><w:spacing w:before="240" w:after="480"/></w:pPr><w:rPr><w:b/><w:sz w:val="28"/><w:szCs w:val="28"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:jc w:val="left"/><w:spacing w:before="360" w:after="240"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:caps/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:jc w:val="left"/><w:spacing w:before="240" w:after="240"/><w:ind w:left="360"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOCHeading"><w:name w:val="TOC Heading"/><w:basedOn w:val="Normal"/><w:next w:val="TOC1"/><w:pPr><w:jc w:val="center"/><w:spacing w:after="240"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOC1"><w:name w:val="toc 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:tabs><w:tab w:val="right" w:leader="dot" w:pos="9350"/></w:tabs><w:jc w:val="left"/><w:spacing w:after="100"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOC2"><w:name w:val="toc 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:tabs><w:tab w:val="right" w:leader="dot" w:pos="9350"/></w:tabs><w:jc w:val="left"/><w:spacing w:after="100"/><w:ind w:left="360"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="ExhibitListHeading"><w:name w:val="Exhibit List Heading"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:jc w:val="center"/><w:spacing w:after="240"/></w:pPr><w:rPr><w:b/><w:sz w:val="28"/><w:szCs w:val="28"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TableText"><w:name w:val="Table Text"/><w:basedOn w:val="Normal"/><w:pPr><w:jc w:val="left"/><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="FootnoteText"><w:name w:val="footnote text"/><w:basedOn w:val="Normal"/><w:pPr><w:jc w:val="left"/><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:rPr><w:sz w:val="20"/><w:szCs w:val="20"/></w:rPr></w:style>` +
+	`<w:style w:type="character" w:styleId="FootnoteReference"><w:name w:val="footnote reference"/><w:rPr><w:vertAlign w:val="superscript"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Header"><w:name w:val="header"/><w:basedOn w:val="Normal"/><w:pPr><w:jc w:val="right"/><w:spacing w:after="0"/></w:pPr><w:rPr><w:sz w:val="18"/><w:szCs w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Footer"><w:name w:val="footer"/><w:basedOn w:val="Normal"/><w:pPr><w:jc w:val="center"/><w:spacing w:after="0"/></w:pPr><w:rPr><w:sz w:val="20"/><w:szCs w:val="20"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="LetterheadName"><w:name w:val="Letterhead Name"/><w:basedOn w:val="Normal"/><w:pPr><w:jc w:val="center"/><w:spacing w:after="0"/></w:pPr><w:rPr><w:b/><w:caps/><w:sz w:val="28"/><w:szCs w:val="28"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="LetterheadLine"><w:name w:val="Letterhead Line"/><w:basedOn w:val="Normal"/><w:pPr><w:jc w:val="center"/><w:spacing w:after="0"/></w:pPr><w:rPr><w:sz w:val="18"/><w:szCs w:val="18"/></w:rPr></w:style>` +
+	`</w:styles>`
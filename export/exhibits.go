@@ -0,0 +1,219 @@
+package export
+
+import (
+	"strings"
+	"unicode"
+
+	"meritdraft-backend/models"
+)
+
+// ResolveExhibits letters the exhibits cited in an assembled petition and
+// rewrites its placeholders to match. criterionTitles maps the section
+// headings of the petition to criterion IDs.
+//
+// A placeholder cites the exhibit whose Ref it holds, else the exhibit
+// whose current label it holds, else the exhibit of the enclosing
+// criterion whose description best matches the sentence, or the only
+// exhibit of that criterion. Cited exhibits are lettered A, B, C... in
+// order of first reference, and the others after them in their given
+// order. Placeholders that cite no exhibit are left as "[Exhibit __]".
+// Label and References of every exhibit are updated.
+func ResolveExhibits(content string, criterionTitles map[string]string, exhibits []*models.Exhibit) string {
+	return resolveExhibits([]string{""}, []string{content}, criterionTitles, exhibits)[0]
+}
+
+// ResolveSectionExhibits is ResolveExhibits for a draft stored as sections.
+// Each section's content is resolved on its own under its title, with
+// exhibits lettered across sections in document order, and the sections
+// whose content changed are returned.
+func ResolveSectionExhibits(sections []*models.PetitionSection, criterionTitles map[string]string, exhibits []*models.Exhibit) []*models.PetitionSection {
+	titles := make([]string, len(sections))
+	contents := make([]string, len(sections))
+	for i, section := range sections {
+		titles[i] = section.Title
+		contents[i] = section.Content
+	}
+
+	var changed []*models.PetitionSection
+	for i, content := range resolveExhibits(titles, contents, criterionTitles, exhibits) {
+		if content != sections[i].Content {
+			sections[i].Content = content
+			changed = append(changed, sections[i])
+		}
+	}
+	return changed
+}
+
+// resolveExhibits letters the exhibits cited in texts, read in order, and
+// returns the texts with their placeholders rewritten. Each text is read
+// under its heading, which is not itself rewritten; a heading that names
+// no criterion or part keeps the criterion of the text before it.
+func resolveExhibits(headings, texts []string, criterionTitles map[string]string, exhibits []*models.Exhibit) []string {
+	sections := make(map[string]string, len(criterionTitles))
+	for title, criterion := range criterionTitles {
+		sections[headingKey(title)] = criterion
+	}
+	byRef := make(map[string]*models.Exhibit, len(exhibits))
+	byLabel := make(map[string]*models.Exhibit, len(exhibits))
+	for _, exhibit := range exhibits {
+		byRef[exhibit.Ref()] = exhibit
+		if exhibit.Label != "" {
+			byLabel[exhibit.Label] = exhibit
+		}
+		exhibit.References = 0
+	}
+
+	// enter returns the criterion in effect after a heading line, and
+	// whether the line is a heading
+	enter := func(line, criterion string) (string, bool) {
+		trimmed := strings.TrimSpace(line)
+		if id, ok := sections[headingKey(trimmed)]; ok {
+			return id, true
+		}
+		if m := partHeadingPattern.FindStringSubmatch(trimmed); m != nil && isUpper(m[1]) {
+			return "", true
+		}
+		return criterion, false
+	}
+
+	// First pass: find the exhibit each placeholder cites
+	var cited []*models.Exhibit
+	var order []*models.Exhibit
+	criterion := ""
+	for i, text := range texts {
+		criterion, _ = enter(headings[i], criterion)
+		for _, line := range strings.Split(text, "\n") {
+			var heading bool
+			if criterion, heading = enter(line, criterion); heading {
+				continue
+			}
+
+			for _, loc := range exhibitPattern.FindAllStringSubmatchIndex(line, -1) {
+				key := strings.TrimSpace(line[loc[2]:loc[3]])
+				exhibit := byRef[strings.ToLower(key)]
+				if exhibit == nil {
+					exhibit = byLabel[key]
+				}
+				if exhibit == nil {
+					exhibit = matchExhibit(line[:loc[0]], criterion, exhibits)
+				}
+				if exhibit != nil {
+					if exhibit.References == 0 {
+						order = append(order, exhibit)
+					}
+					exhibit.References++
+				}
+				cited = append(cited, exhibit)
+			}
+		}
+	}
+
+	// Letter the cited exhibits, then the rest
+	for _, exhibit := range exhibits {
+		if exhibit.References == 0 {
+			order = append(order, exhibit)
+		}
+	}
+	for i, exhibit := range order {
+		exhibit.Label = letter(i + 1)
+	}
+
+	// Second pass: rewrite the placeholders. Headings hold none, since
+	// the first pass skipped them.
+	resolved := make([]string, len(texts))
+	n := 0
+	for i, text := range texts {
+		lines := strings.Split(text, "\n")
+		criterion, _ = enter(headings[i], criterion)
+		for j, line := range lines {
+			var heading bool
+			if criterion, heading = enter(line, criterion); heading {
+				continue
+			}
+			lines[j] = exhibitPattern.ReplaceAllStringFunc(line, func(string) string {
+				exhibit := cited[n]
+				n++
+				if exhibit == nil {
+					return "[Exhibit __]"
+				}
+				return "[Exhibit " + exhibit.Label + "]"
+			})
+		}
+		resolved[i] = strings.Join(lines, "\n")
+	}
+	return resolved
+}
+
+// CountUnresolvedExhibits counts the placeholders of content that cite no exhibit
+func CountUnresolvedExhibits(content string) int {
+	count := 0
+	for _, m := range exhibitPattern.FindAllStringSubmatch(content, -1) {
+		if key := strings.Trim(strings.TrimSpace(m[1]), "_"); key == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// matchExhibit returns the exhibit of criterion whose description shares
+// the most words with the sentence ending at the placeholder. Outside a
+// criterion section every exhibit is a candidate.
+func matchExhibit(before, criterion string, exhibits []*models.Exhibit) *models.Exhibit {
+	sentence := before
+	if breaks := sentenceBreakPattern.FindAllStringIndex(strings.TrimSpace(before), -1); len(breaks) > 0 {
+		sentence = strings.TrimSpace(before)[breaks[len(breaks)-1][1]:]
+	}
+	words := make(map[string]bool)
+	for _, word := range exhibitWords(sentence) {
+		words[word] = true
+	}
+
+	var candidates []*models.Exhibit
+	for _, exhibit := range exhibits {
+		if criterion == "" || exhibit.Criterion == criterion {
+			candidates = append(candidates, exhibit)
+		}
+	}
+
+	var best *models.Exhibit
+	bestScore := 0
+	for _, exhibit := range candidates {
+		score := 0
+		for _, word := range exhibitWords(exhibit.Description) {
+			if words[word] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = exhibit, score
+		}
+	}
+	if best == nil && criterion != "" && len(candidates) == 1 {
+		best = candidates[0]
+	}
+	return best
+}
+
+// exhibitWords returns the distinct significant words of s, lowercased
+func exhibitWords(s string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) < 4 || seen[word] || exhibitStopWords[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// Words too common in support letters to tell exhibits apart
+var exhibitStopWords = map[string]bool{
+	"with": true, "from": true, "that": true, "this": true, "their": true, "which": true,
+	"were": true, "have": true, "been": true, "into": true,
+	"beneficiary": true, "petitioner": true, "evidence": true, "documented": true,
+	"attached": true, "hereto": true, "exhibit": true, "exhibits": true, "field": true,
+}
@@ -0,0 +1,153 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"meritdraft-backend/models"
+)
+
+// testExhibit returns an exhibit of criterion whose Ref is ref
+func testExhibit(ref, criterion, description, label string) *models.Exhibit {
+	return &models.Exhibit{
+		ID:          uuid.MustParse(ref + "-0000-0000-0000-000000000000"),
+		Criterion:   criterion,
+		Description: description,
+		Label:       label,
+	}
+}
+
+var testCriterionTitles = map[string]string{
+	"Awards":     "awards",
+	"Membership": "membership",
+}
+
+func TestResolveExhibits(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		exhibits   []*models.Exhibit
+		want       string
+		wantLabels []string
+		wantRefs   []int
+	}{
+		{
+			name:    "ref placeholders lettered in order of first reference",
+			content: "Awards\nShe won [Exhibit bbbbbbbb] and [Exhibit aaaaaaaa].\nAgain [Exhibit bbbbbbbb].",
+			exhibits: []*models.Exhibit{
+				testExhibit("aaaaaaaa", "awards", "Turing Award certificate", ""),
+				testExhibit("bbbbbbbb", "awards", "Gödel Prize letter", ""),
+			},
+			want:       "Awards\nShe won [Exhibit A] and [Exhibit B].\nAgain [Exhibit A].",
+			wantLabels: []string{"B", "A"},
+			wantRefs:   []int{1, 2},
+		},
+		{
+			name:    "current label reused after an edit",
+			content: "Awards\nAs shown in [Exhibit B], and [Exhibit A].",
+			exhibits: []*models.Exhibit{
+				testExhibit("aaaaaaaa", "awards", "Turing Award certificate", "A"),
+				testExhibit("bbbbbbbb", "awards", "Gödel Prize letter", "B"),
+			},
+			want:       "Awards\nAs shown in [Exhibit A], and [Exhibit B].",
+			wantLabels: []string{"B", "A"},
+			wantRefs:   []int{1, 1},
+		},
+		{
+			name:    "blank placeholder matched by description within its criterion",
+			content: "Membership\nShe is a fellow of the society [Exhibit __].\nAwards\nShe received the Turing Award [Exhibit __].",
+			exhibits: []*models.Exhibit{
+				testExhibit("aaaaaaaa", "awards", "Turing Award certificate", ""),
+				testExhibit("bbbbbbbb", "membership", "Society fellowship letter", ""),
+			},
+			want:       "Membership\nShe is a fellow of the society [Exhibit A].\nAwards\nShe received the Turing Award [Exhibit B].",
+			wantLabels: []string{"B", "A"},
+			wantRefs:   []int{1, 1},
+		},
+		{
+			name:    "blank placeholder with no match left blank",
+			content: "Awards\nShe was recognized [Exhibit __]. Also [Exhibit zzzzzzzz].",
+			exhibits: []*models.Exhibit{
+				testExhibit("aaaaaaaa", "awards", "Turing Award certificate", ""),
+				testExhibit("bbbbbbbb", "awards", "Gödel Prize letter", ""),
+			},
+			want:       "Awards\nShe was recognized [Exhibit __]. Also [Exhibit __].",
+			wantLabels: []string{"A", "B"},
+			wantRefs:   []int{0, 0},
+		},
+		{
+			name:    "only exhibit of a criterion cited by a blank placeholder",
+			content: "Awards\nShe was honored [Exhibit __].\nII. CONCLUSION\nSee [Exhibit __].",
+			exhibits: []*models.Exhibit{
+				testExhibit("aaaaaaaa", "membership", "Society fellowship letter", ""),
+				testExhibit("bbbbbbbb", "awards", "Turing Award certificate", ""),
+			},
+			want:       "Awards\nShe was honored [Exhibit A].\nII. CONCLUSION\nSee [Exhibit __].",
+			wantLabels: []string{"B", "A"},
+			wantRefs:   []int{0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ResolveExhibits(tt.content, testCriterionTitles, tt.exhibits)
+			if got != tt.want {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+			for i, exhibit := range tt.exhibits {
+				if exhibit.Label != tt.wantLabels[i] || exhibit.References != tt.wantRefs[i] {
+					t.Errorf("exhibit %d: label %q with %d references, want %q with %d",
+						i, exhibit.Label, exhibit.References, tt.wantLabels[i], tt.wantRefs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestResolveSectionExhibits(t *testing.T) {
+	exhibits := []*models.Exhibit{
+		testExhibit("aaaaaaaa", "awards", "Turing Award certificate", ""),
+		testExhibit("bbbbbbbb", "membership", "Society fellowship letter", ""),
+	}
+	sections := []*models.PetitionSection{
+		{Title: "Membership", Content: "She is a fellow [Exhibit bbbbbbbb]."},
+		{Title: "Awards\nInjected", Content: "She won the Turing Award [Exhibit aaaaaaaa].\nSecond line."},
+		{Title: "Conclusion", Content: "No citations."},
+	}
+
+	changed := ResolveSectionExhibits(sections, testCriterionTitles, exhibits)
+	want := []string{
+		"She is a fellow [Exhibit A].",
+		"She won the Turing Award [Exhibit B].\nSecond line.",
+		"No citations.",
+	}
+	for i, section := range sections {
+		if section.Content != want[i] {
+			t.Errorf("section %d content = %q, want %q", i, section.Content, want[i])
+		}
+	}
+	if len(changed) != 2 || changed[0] != sections[0] || changed[1] != sections[1] {
+		t.Errorf("changed %d sections, want the first two", len(changed))
+	}
+	if exhibits[0].Label != "B" || exhibits[1].Label != "A" {
+		t.Errorf("labels = %q, %q, want B, A", exhibits[0].Label, exhibits[1].Label)
+	}
+}
+
+func TestCountUnresolvedExhibits(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"", 0},
+		{"See [Exhibit A] and [Exhibits B].", 0},
+		{"See [Exhibit __] and [Exhibit ] and [Exhibit].", 3},
+		{"See [Exhibit aaaaaaaa] and [Exhibit ___].", 1},
+	}
+	for _, tt := range tests {
+		if got := CountUnresolvedExhibits(tt.content); got != tt.want {
+			t.Errorf("CountUnresolvedExhibits(%q) = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf16"
+)
+
+// ErrUnsupportedCharacter is returned for text that none of the PDF fonts
+// can print, rather than printing it as "?"
+var ErrUnsupportedCharacter = errors.New("text has a character the PDF fonts cannot print")
+
+// The standard Type 1 fonts every PDF reader provides, so nothing is
+// embedded for WinAnsi text, and the embedded fonts for other characters
+type pdfFont int
+
+const (
+	fontRegular pdfFont = iota
+	fontBold
+	fontUnicode
+	fontUnicodeBold
+)
+
+// pdfFontNames are the resource names and base fonts of the PDF fonts
+var pdfFontNames = [...]struct{ resource, base string }{
+	fontRegular:     {"F1", "Times-Roman"},
+	fontBold:        {"F2", "Times-Bold"},
+	fontUnicode:     {"F3", "DejaVuSerif"},
+	fontUnicodeBold: {"F4", "DejaVuSerif-Bold"},
+}
+
+// embedded reports whether the font is embedded and encodes its text as
+// two-byte glyph IDs
+func (f pdfFont) embedded() bool {
+	return f == fontUnicode || f == fontUnicodeBold
+}
+
+// unicode returns the embedded font used for the characters of f outside
+// WinAnsiEncoding
+func (f pdfFont) unicode() pdfFont {
+	if f == fontBold || f == fontUnicodeBold {
+		return fontUnicodeBold
+	}
+	return fontUnicode
+}
+
+// Advance widths of the printable ASCII characters (32-126) in thousandths
+// of the font size, from the Adobe font metrics
+var asciiWidths = [...][95]int{
+	fontRegular: {
+		250, 333, 408, 500, 500, 833, 778, 180, 333, 333, 500, 564, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 278, 278, 564, 564, 564, 444,
+		921, 722, 667, 667, 722, 611, 556, 722, 722, 333, 389, 722, 611, 889, 722, 722,
+		556, 722, 667, 556, 611, 722, 722, 944, 722, 722, 611, 333, 278, 333, 469, 500,
+		333, 444, 500, 444, 500, 444, 333, 500, 500, 278, 278, 500, 278, 778, 500, 500,
+		500, 500, 333, 389, 278, 500, 500, 722, 500, 500, 444, 480, 200, 480, 541,
+	},
+	fontBold: {
+		250, 333, 555, 500, 500, 1000, 833, 278, 333, 333, 500, 570, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 570, 570, 570, 500,
+		930, 722, 667, 722, 722, 667, 611, 778, 778, 389, 500, 778, 667, 944, 722, 778,
+		611, 778, 722, 556, 667, 722, 722, 1000, 722, 722, 667, 333, 278, 333, 581, 500,
+		333, 500, 556, 444, 556, 444, 333, 500, 556, 278, 333, 556, 278, 833, 556, 500,
+		556, 556, 444, 389, 333, 556, 500, 722, 500, 500, 444, 394, 220, 394, 520,
+	},
+}
+
+// Widths of the WinAnsi characters above 127 that are not accented letters
+var extendedWidths = map[byte]int{
+	0x80: 500, 0x82: 333, 0x83: 500, 0x84: 444, 0x85: 1000, 0x86: 500, 0x87: 500, 0x88: 333,
+	0x89: 1000, 0x8B: 333, 0x8C: 889, 0x91: 333, 0x92: 333, 0x93: 444, 0x94: 444, 0x95: 350,
+	0x96: 500, 0x97: 1000, 0x98: 333, 0x99: 980, 0x9B: 333, 0x9C: 722,
+	0xA0: 250, 0xA1: 333, 0xA2: 500, 0xA3: 500, 0xA4: 500, 0xA5: 500, 0xA6: 200, 0xA7: 500,
+	0xA8: 333, 0xA9: 760, 0xAA: 276, 0xAB: 500, 0xAC: 564, 0xAD: 333, 0xAE: 760, 0xAF: 333,
+	0xB0: 400, 0xB1: 564, 0xB2: 300, 0xB3: 300, 0xB4: 333, 0xB5: 500, 0xB6: 453, 0xB7: 250,
+	0xB8: 333, 0xB9: 300, 0xBA: 310, 0xBB: 500, 0xBC: 750, 0xBD: 750, 0xBE: 750, 0xBF: 444,
+	0xC6: 889, 0xD7: 564, 0xDE: 556, 0xDF: 500, 0xE6: 667, 0xF7: 564, 0xFE: 500,
+}
+
+// WinAnsi codes of the characters between 128 and 159
+var winAnsiExtended = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// Unaccented letters of the Latin-1 letters and of the WinAnsi letters
+// between 128 and 159, used for their widths
+const latin1Base = "AAAAAA CEEEEIIIIDNOOOOO OUUUUY  aaaaaa ceeeeiiiidnooooo ouuuuy y"
+
+var extendedBase = map[byte]byte{0x8A: 'S', 0x8E: 'Z', 0x9A: 's', 0x9E: 'z', 0x9F: 'Y'}
+
+// winAnsi encodes s for the standard fonts, replacing control characters
+// with spaces and other characters outside WinAnsiEncoding with "?"
+func winAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case unicode.IsControl(r):
+			out = append(out, ' ')
+		case r >= 32 && r < 127, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		default:
+			if b, ok := winAnsiExtended[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// charWidth returns the width of a WinAnsi character in thousandths of the font size
+func charWidth(font pdfFont, b byte) int {
+	if b >= 0xC0 && latin1Base[b-0xC0] != ' ' {
+		b = latin1Base[b-0xC0]
+	} else if base, ok := extendedBase[b]; ok {
+		b = base
+	}
+	if b >= 32 && b < 127 {
+		return asciiWidths[font][b-32]
+	}
+	if w, ok := extendedWidths[b]; ok {
+		return w
+	}
+	return 500
+}
+
+// winAnsiEncodable reports whether r prints in the standard fonts
+func winAnsiEncodable(r rune) bool {
+	_, extended := winAnsiExtended[r]
+	return unicode.IsControl(r) || (r >= 32 && r < 127) || (r >= 0xA0 && r <= 0xFF) || extended
+}
+
+// encode encodes text for font, switching to the matching embedded font for
+// the characters outside WinAnsiEncoding. Characters neither can print
+// become "?"; checkText reports them beforehand.
+func encode(text string, font pdfFont, size float64) []fragment {
+	var fragments []fragment
+	for _, r := range text {
+		f, code := font, []byte(nil)
+		if !winAnsiEncodable(r) {
+			if gid, ok := trueTypeFonts()[font.unicode()].glyph(r); ok {
+				f, code = font.unicode(), []byte{byte(gid >> 8), byte(gid)}
+			}
+		}
+		if code == nil {
+			code = winAnsi(string(r))
+		}
+
+		if n := len(fragments); n > 0 && fragments[n-1].font == f {
+			fragments[n-1].text = append(fragments[n-1].text, code...)
+		} else {
+			fragments = append(fragments, fragment{text: code, font: f, size: size})
+		}
+	}
+	return fragments
+}
+
+// checkText returns ErrUnsupportedCharacter for the first character of
+// texts that neither the standard nor the embedded fonts can print
+func checkText(texts ...string) error {
+	fonts := trueTypeFonts()
+	for _, text := range texts {
+		for _, r := range text {
+			if winAnsiEncodable(r) {
+				continue
+			}
+			_, regular := fonts[fontUnicode].glyph(r)
+			_, bold := fonts[fontUnicodeBold].glyph(r)
+			if !regular || !bold {
+				return fmt.Errorf("%w: %q (U+%04X)", ErrUnsupportedCharacter, r, r)
+			}
+		}
+	}
+	return nil
+}
+
+// textWidth returns the width in points of text encoded for font at size
+func textWidth(font pdfFont, text []byte, size float64) float64 {
+	total := 0
+	if font.embedded() {
+		ttf := trueTypeFonts()[font]
+		for i := 0; i+1 < len(text); i += 2 {
+			total += ttf.width(uint16(text[i])<<8 | uint16(text[i+1]))
+		}
+		return float64(total) * size / 1000
+	}
+	for _, b := range text {
+		total += charWidth(font, b)
+	}
+	return float64(total) * size / 1000
+}
+
+// textString formats text as a PDF text string for the document
+// information: WinAnsi where it suffices, else UTF-16
+func textString(text string) string {
+	for _, r := range text {
+		if !winAnsiEncodable(r) {
+			s := "<FEFF"
+			for _, unit := range utf16.Encode([]rune(text)) {
+				s += fmt.Sprintf("%04X", unit)
+			}
+			return s + ">"
+		}
+	}
+	return pdfString(winAnsi(text))
+}
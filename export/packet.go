@@ -0,0 +1,448 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Registers GIF exhibits with image.Decode
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"meritdraft-backend/documents"
+)
+
+// Packet layout in points
+const (
+	stampBand      = 24.0 // Strip at the bottom of every page kept clear for the stamps
+	stampInset     = 36.0
+	tabSize        = 36.0
+	maxImagePixels = 40_000_000
+)
+
+var errImageTooLarge = errors.New("image is too large to embed")
+
+// Packet is a filing packet: a cover letter, the petition letter and each
+// exhibit behind a tab page
+type Packet struct {
+	Title       string // Document information title
+	Author      string
+	Cover       *CoverLetter // Listing the enclosures in front of the letter; none when nil
+	Letter      []byte       // The letter as a PDF, e.g. written by WritePDF
+	Exhibits    []PacketExhibit
+	BatesPrefix string // Printed before the six-digit page number, e.g. "DOE" for DOE000001
+}
+
+// PacketExhibit is an exhibit file bundled behind its tab page
+type PacketExhibit struct {
+	Label       string
+	Description string
+	Filename    string
+	MimeType    string
+	Data        []byte
+	Omitted     string // Why the file is left out, e.g. too large; a slip sheet stands in for it
+}
+
+// PacketIndex records where the parts of a written packet fall
+type PacketIndex struct {
+	Pages    int
+	Cover    BatesRange
+	Letter   BatesRange
+	Exhibits []PacketEntry
+}
+
+// BatesRange is the first and last Bates number of a part of the packet
+type BatesRange struct {
+	First string
+	Last  string
+}
+
+// PacketEntry is the position of an exhibit in the packet
+type PacketEntry struct {
+	Label string
+	Bates BatesRange // From the tab page through the exhibit's last page
+	Pages int        // Pages of the exhibit itself, excluding the tab page
+	Note  string     // Why a slip sheet stands in for the file, e.g. an encrypted PDF
+}
+
+// WritePacket writes a packet as one PDF: the cover letter listing the
+// enclosures with their Bates ranges, the letter's pages, then a tab page
+// and the pages of each exhibit. PDF exhibits are embedded page by page,
+// images get a page each and DOCX and text files are typeset; a slip sheet
+// naming the file stands in for anything else. Every page is scaled onto
+// US Letter above a strip stamped with its Bates number, and exhibit pages
+// with their exhibit label.
+func WritePacket(w io.Writer, packet *Packet) (*PacketIndex, error) {
+	texts := []string{packet.Title, packet.Author, packet.BatesPrefix}
+	if cover := packet.Cover; cover != nil {
+		texts = append(texts, cover.Letterhead.Name, cover.Subject, cover.Signature)
+		texts = append(append(append(texts, cover.Letterhead.Lines...), cover.Recipient...), cover.Body...)
+	}
+	for _, exhibit := range packet.Exhibits {
+		texts = append(texts, exhibit.Label, exhibit.Description, exhibit.Filename)
+	}
+	if err := checkText(texts...); err != nil {
+		return nil, err
+	}
+
+	p := &packetWriter{
+		layout:  &pdfLayout{doc: &Document{}, exhibitPages: make(map[string][]int)},
+		prefix:  packet.BatesPrefix,
+		objects: make([][]byte, 5), // Catalog, page tree, fonts and information are filled in last
+	}
+
+	letter, err := documents.ImportPDF(packet.Letter, len(p.objects)+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read letter: %w", err)
+	}
+	letterPages := p.importPages(letter, "")
+
+	index := &PacketIndex{}
+	firsts := make([]int, len(packet.Exhibits)) // Page of each tab, counted without the cover letter
+	for i, exhibit := range packet.Exhibits {
+		firsts[i] = len(p.layout.pages) + 1
+		p.tab(exhibit)
+		pages, note := p.exhibit(exhibit)
+		index.Exhibits = append(index.Exhibits, PacketEntry{Label: exhibit.Label, Pages: pages, Note: note})
+	}
+
+	// The cover letter lists the Bates ranges of the parts behind it, so it
+	// is laid out last and moved to the front
+	number := func(cover int) {
+		index.Cover = p.bates(1, cover)
+		index.Letter = p.bates(cover+1, letterPages)
+		for i := range index.Exhibits {
+			index.Exhibits[i].Bates = p.bates(cover+firsts[i], index.Exhibits[i].Pages+1)
+		}
+	}
+	number(0)
+	if packet.Cover != nil {
+		if err := p.cover(packet, index, number); err != nil {
+			return nil, fmt.Errorf("failed to write cover letter: %w", err)
+		}
+	}
+	index.Pages = len(p.layout.pages)
+
+	return index, p.write(w, packet)
+}
+
+// packetWriter lays out the pages of a packet
+type packetWriter struct {
+	layout  *pdfLayout
+	xobject []int    // Form or image drawn on each page, 0 for none
+	labels  []string // Exhibit label stamped on each page
+	objects [][]byte // objects[i] is object i+1
+	prefix  string
+}
+
+// newPage starts a page of the exhibit labelled label, "" for the letter
+func (p *packetWriter) newPage(label string) *pdfPage {
+	p.layout.newPage()
+	p.xobject = append(p.xobject, 0)
+	p.labels = append(p.labels, label)
+	return p.layout.page
+}
+
+func (p *packetWriter) add(body []byte) int {
+	p.objects = append(p.objects, body)
+	return len(p.objects)
+}
+
+// bates returns the Bates range of count pages starting at page first
+func (p *packetWriter) bates(first, count int) BatesRange {
+	if count == 0 {
+		return BatesRange{}
+	}
+	return BatesRange{First: p.stamp(first), Last: p.stamp(first + count - 1)}
+}
+
+func (p *packetWriter) stamp(page int) string {
+	return fmt.Sprintf("%s%06d", p.prefix, page)
+}
+
+// importPages adds the pages of an imported PDF, whose objects must be
+// numbered from the next free object, and returns how many were added
+func (p *packetWriter) importPages(imported *documents.ImportedPDF, label string) int {
+	p.objects = append(p.objects, imported.Objects...)
+
+	for _, page := range imported.Pages {
+		content := &p.newPage(label).content
+		p.xobject[len(p.xobject)-1] = page.Form
+
+		// Fit the page above the stamp strip, centered
+		width, height := page.Width(), page.Height()
+		scale := min(pageWidth/width, (pageHeight-stampBand)/height)
+		x := (pageWidth - width*scale) / 2
+		y := stampBand + (pageHeight-stampBand-height*scale)/2
+		fmt.Fprintf(content, "q %s 0 0 %s %s %s cm\n", num4(scale), num4(scale), num(x), num(y))
+
+		// Then turn it as /Rotate asks and move its box to the origin
+		boxWidth, boxHeight := page.Box[2]-page.Box[0], page.Box[3]-page.Box[1]
+		switch page.Rotate {
+		case 90:
+			fmt.Fprintf(content, "0 -1 1 0 0 %s cm\n", num4(boxWidth))
+		case 180:
+			fmt.Fprintf(content, "-1 0 0 -1 %s %s cm\n", num4(boxWidth), num4(boxHeight))
+		case 270:
+			fmt.Fprintf(content, "0 1 -1 0 %s 0 cm\n", num4(boxHeight))
+		}
+		fmt.Fprintf(content, "1 0 0 1 %s %s cm /X1 Do Q\n", num4(-page.Box[0]), num4(-page.Box[1]))
+	}
+	return len(imported.Pages)
+}
+
+// cover lays out the cover letter, numbering the parts of index behind it
+// with number, and puts its pages in front of the letter
+func (p *packetWriter) cover(packet *Packet, index *PacketIndex, number func(cover int)) error {
+	enclosures := func() []enclosure {
+		list := []enclosure{{label: "Letter", description: "Petition letter", bates: index.Letter}}
+		for i, entry := range index.Exhibits {
+			list = append(list, enclosure{label: "Exhibit " + entry.Label, description: packet.Exhibits[i].Description, bates: entry.Bates})
+		}
+		return list
+	}
+
+	// Laid out once to count its pages, then again with the final numbers
+	number(len(coverLayout(packet.Cover, enclosures()).pages))
+	l := coverLayout(packet.Cover, enclosures())
+	var buf bytes.Buffer
+	l.finishPage()
+	if err := l.write(&buf); err != nil {
+		return err
+	}
+	imported, err := documents.ImportPDF(buf.Bytes(), len(p.objects)+1)
+	if err != nil {
+		return err
+	}
+
+	rest := len(p.layout.pages)
+	p.importPages(imported, "")
+	p.layout.pages = slices.Concat(p.layout.pages[rest:], p.layout.pages[:rest])
+	p.xobject = slices.Concat(p.xobject[rest:], p.xobject[:rest])
+	p.labels = slices.Concat(p.labels[rest:], p.labels[:rest])
+	return nil
+}
+
+// tab adds the separator page in front of an exhibit
+func (p *packetWriter) tab(exhibit PacketExhibit) {
+	p.newPage(exhibit.Label)
+	l := p.layout
+	l.y = pageHeight/2 + 2*tabSize
+	p.center(l.wrap(textWords("EXHIBIT "+exhibit.Label, fontBold, tabSize), fontBold, tabSize, tabSize*1.3, pageMargin, contentWidth, 0, alignCenter), 2)
+	l.y -= bodyLeading
+	p.center(l.wrap(textWords(exhibit.Description, fontRegular, bodySize), fontRegular, bodySize, bodyLeading, pageMargin, contentWidth, 0, alignCenter), 10)
+}
+
+// center places at most maxLines lines, so that text on a tab page or slip
+// sheet never runs onto another page
+func (p *packetWriter) center(lines []line, maxLines int) {
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+	for _, ln := range lines {
+		p.layout.place(ln)
+	}
+}
+
+// exhibit adds the pages of an exhibit file and returns how many were
+// added, with the reason when a slip sheet stands in for the file
+func (p *packetWriter) exhibit(exhibit PacketExhibit) (int, string) {
+	if exhibit.Omitted != "" {
+		p.slipSheet(exhibit, exhibit.Omitted)
+		return 1, exhibit.Omitted
+	}
+
+	var err error
+	data := exhibit.Data
+	switch {
+	case bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")):
+		var imported *documents.ImportedPDF
+		if imported, err = documents.ImportPDF(data, len(p.objects)+1); err == nil {
+			return p.importPages(imported, exhibit.Label), ""
+		}
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")), bytes.HasPrefix(data, []byte("\x89PNG")), bytes.HasPrefix(data, []byte("GIF8")):
+		if err = p.image(exhibit); err == nil {
+			return 1, ""
+		}
+	case documents.Supported(exhibit.MimeType, exhibit.Filename):
+		var pages int
+		if pages, err = p.text(exhibit); err == nil {
+			return pages, ""
+		}
+	default:
+		err = errors.New("files of this type cannot be bundled")
+	}
+
+	p.slipSheet(exhibit, err.Error())
+	return 1, err.Error()
+}
+
+// image adds a page showing an image exhibit fitted within the margins
+func (p *packetWriter) image(exhibit PacketExhibit) error {
+	body, width, height, err := imageObject(exhibit.Data)
+	if err != nil {
+		return err
+	}
+
+	content := &p.newPage(exhibit.Label).content
+	p.xobject[len(p.xobject)-1] = p.add(body)
+
+	areaWidth, areaHeight := pageWidth-2*stampInset, pageHeight-2*stampInset
+	scale := min(areaWidth/float64(width), areaHeight/float64(height))
+	w, h := float64(width)*scale, float64(height)*scale
+	fmt.Fprintf(content, "q %s 0 0 %s %s %s cm /X1 Do Q\n",
+		num(w), num(h), num(stampInset+(areaWidth-w)/2), num(stampInset+(areaHeight-h)/2))
+	return nil
+}
+
+// text typesets the text of a DOCX or text exhibit and adds its pages
+func (p *packetWriter) text(exhibit PacketExhibit) (int, error) {
+	text, err := documents.ExtractText(exhibit.MimeType, exhibit.Filename, exhibit.Data)
+	if err != nil {
+		return 0, err
+	}
+
+	section := Section{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		if strings.TrimSpace(paragraph) != "" {
+			section.Paragraphs = append(section.Paragraphs, Paragraph{{Text: paragraph}})
+		}
+	}
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, &Document{Sections: []Section{section}}); err != nil {
+		return 0, err
+	}
+
+	imported, err := documents.ImportPDF(buf.Bytes(), len(p.objects)+1)
+	if err != nil {
+		return 0, err
+	}
+	return p.importPages(imported, exhibit.Label), nil
+}
+
+// slipSheet adds a page naming an exhibit file that could not be bundled
+func (p *packetWriter) slipSheet(exhibit PacketExhibit, reason string) {
+	p.newPage(exhibit.Label)
+	l := p.layout
+	l.y = pageHeight/2 + 3*bodyLeading
+	p.center(l.wrap(textWords(exhibit.Filename, fontBold, bodySize), fontBold, bodySize, bodyLeading, pageMargin, contentWidth, 0, alignCenter), 3)
+	l.y -= bodyLeading
+	message := fmt.Sprintf("This exhibit could not be included in the bundled PDF (%s). The original file must be added to the filing separately.", reason)
+	p.center(l.wrap(textWords(message, fontRegular, bodySize), fontRegular, bodySize, bodyLeading, pageMargin, contentWidth, 0, alignCenter), 10)
+}
+
+// write stamps the pages and writes the packet
+func (p *packetWriter) write(w io.Writer, packet *Packet) error {
+	const pagesObject = 2
+	for i, page := range p.layout.pages {
+		p.layout.page = page
+		p.layout.drawLine(line{words: textWords(p.stamp(i+1), fontBold, headerSize), font: fontBold, size: headerSize, x: stampInset, width: pageWidth - 2*stampInset, align: alignRight}, stampBand/2-headerSize/3)
+		if label := p.labels[i]; label != "" {
+			p.layout.drawLine(line{words: textWords("Exhibit "+label, fontBold, headerSize), font: fontBold, size: headerSize, x: stampInset, width: pageWidth - 2*stampInset}, stampBand/2-headerSize/3)
+		}
+	}
+	fonts, err := p.layout.fonts(p.add)
+	if err != nil {
+		return err
+	}
+
+	kids := make([]string, len(p.layout.pages))
+	for i, page := range p.layout.pages {
+		stream, err := flateStream("", page.content.Bytes())
+		if err != nil {
+			return err
+		}
+		resources := fonts
+		if p.xobject[i] != 0 {
+			resources += fmt.Sprintf(" /XObject << /X1 %d 0 R >>", p.xobject[i])
+		}
+		contents := p.add(stream)
+		kids[i] = fmt.Sprintf("%d 0 R", p.add([]byte(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << %s >> /Contents %d 0 R >>",
+			pagesObject, num(pageWidth), num(pageHeight), resources, contents))))
+	}
+
+	p.objects[0] = []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+	p.objects[1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	standardFonts(p.objects)
+	p.objects[4] = []byte(fmt.Sprintf("<< /Title %s /Author %s /Creator (MeritDraft) /CreationDate (D:%s) >>",
+		textString(packet.Title), textString(packet.Author), time.Now().UTC().Format("20060102150405Z")))
+
+	return writeObjects(w, p.objects)
+}
+
+// imageObject converts an image file to an image XObject and returns it
+// with the image's size in pixels. Baseline RGB and grayscale JPEGs are
+// embedded as they are; other images are flattened onto white.
+func imageObject(data []byte) ([]byte, int, int, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxImagePixels {
+		return nil, 0, 0, errImageTooLarge
+	}
+
+	if format == "jpeg" && (config.ColorModel == color.YCbCrModel || config.ColorModel == color.GrayModel) {
+		space := "DeviceRGB"
+		if config.ColorModel == color.GrayModel {
+			space = "DeviceGray"
+		}
+		body := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n",
+			config.Width, config.Height, space, len(data))
+		return append(append([]byte(body), data...), "\nendstream"...), config.Width, config.Height, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	bounds := img.Bounds()
+	pixels := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// Colors are alpha-premultiplied, so adding the missing
+			// coverage composites onto white
+			r, g, b, a := img.At(x, y).RGBA()
+			pixels = append(pixels, byte((r+0xffff-a)>>8), byte((g+0xffff-a)>>8), byte((b+0xffff-a)>>8))
+		}
+	}
+	body, err := flateStream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 ", bounds.Dx(), bounds.Dy()), pixels)
+	return body, bounds.Dx(), bounds.Dy(), err
+}
+
+// flateStream compresses data into a stream object with the given
+// dictionary entries
+func flateStream(entries string, data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<< %s/Filter /FlateDecode /Length %d >>\nstream\n", entries, compressed.Len())
+	b.Write(compressed.Bytes())
+	b.WriteString("\nendstream")
+	return b.Bytes(), nil
+}
+
+// num4 formats a scale or offset with four decimals, for transformations
+// where two would visibly misplace a page
+func num4(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 4, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-" || s == "-0" {
+		return "0"
+	}
+	return s
+}
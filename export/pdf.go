@@ -0,0 +1,609 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MimeTypePDF is the MIME type of exported PDF documents
+const MimeTypePDF = "application/pdf"
+
+// Page geometry and type sizes in points: US Letter with one-inch margins
+const (
+	pageWidth    = 612.0
+	pageHeight   = 792.0
+	pageMargin   = 72.0
+	contentWidth = pageWidth - 2*pageMargin
+
+	bodySize        = 12.0
+	bodyLeading     = 18.0 // One and a half spacing
+	paragraphIndent = 36.0
+	paragraphGap    = 6.0
+	subheadIndent   = 18.0
+	titleSize       = 14.0
+	footnoteSize    = 10.0
+	footnoteLeading = 12.0
+	footnoteGap     = 12.0 // Between the body and the footnote separator
+	markSize        = 7.0  // Footnote reference marks
+	markRise        = 4.0
+	headerSize      = 9.0
+)
+
+// Exhibit references kept on one line by a no-break space
+var exhibitReferencePattern = regexp.MustCompile(`\[Exhibit\x{00a0}([^\]]+)\]`)
+
+type alignment int
+
+const (
+	alignLeft alignment = iota
+	alignCenter
+	alignRight
+	alignJustify
+)
+
+// fragment is text in one font, size and baseline rise
+type fragment struct {
+	text []byte // WinAnsi encoded
+	font pdfFont
+	size float64
+	rise float64
+}
+
+// word is a run of fragments that is never broken across lines
+type word struct {
+	fragments []fragment
+	footnotes []int    // Footnotes referenced in the word
+	exhibits  []string // Labels of the exhibits referenced in the word
+}
+
+func (w word) width() float64 {
+	total := 0.0
+	for _, f := range w.fragments {
+		total += textWidth(f.font, f.text, f.size)
+	}
+	return total
+}
+
+// line is a laid-out line of words separated by spaces of font and size
+type line struct {
+	words   []word
+	font    pdfFont
+	size    float64
+	leading float64
+	x       float64 // Left edge
+	width   float64 // Width available from x
+	align   alignment
+}
+
+// pdfPage is the content stream of a page and the footnotes placed on it
+type pdfPage struct {
+	content        bytes.Buffer
+	footnotes      []int
+	footnoteHeight float64 // Reserved at the bottom, including footnoteGap
+}
+
+// pdfLayout flows a document onto pages
+type pdfLayout struct {
+	doc          *Document
+	pages        []*pdfPage
+	page         *pdfPage
+	y            float64  // Top of the next line
+	notes        [][]line // Laid-out footnotes, notes[n-1] for footnote n
+	exhibitPages map[string][]int
+	glyphs       map[pdfFont]map[uint16]bool // Glyphs drawn in each embedded font
+}
+
+// WritePDF writes doc as a PDF: the letterhead and title on the first
+// page, numbered headings, justified paragraphs with citations as
+// footnotes at the bottom of the page, the title as a running header,
+// "Page N of M" footers and an exhibit list built from the exhibit
+// references. Text is set in the standard Times fonts, and characters
+// outside WinAnsiEncoding in an embedded subset of DejaVu Serif. Returns
+// ErrUnsupportedCharacter for text neither can print.
+func WritePDF(w io.Writer, doc *Document) error {
+	if err := checkText(documentText(doc)...); err != nil {
+		return err
+	}
+
+	l := &pdfLayout{doc: doc, exhibitPages: make(map[string][]int)}
+	for i, footnote := range doc.Footnotes {
+		mark := word{fragments: []fragment{{text: []byte(strconv.Itoa(i + 1)), font: fontRegular, size: markSize, rise: markRise}}}
+		words := append([]word{mark}, textWords(footnote, fontRegular, footnoteSize)...)
+		l.notes = append(l.notes, l.wrap(words, fontRegular, footnoteSize, footnoteLeading, pageMargin, contentWidth, 0, alignLeft))
+	}
+
+	l.newPage()
+	l.letterhead()
+	if doc.Title != "" {
+		for _, ln := range l.wrap(textWords(doc.Title, fontBold, titleSize), fontBold, titleSize, titleSize*1.3, pageMargin, contentWidth, 0, alignCenter) {
+			l.place(ln)
+		}
+		l.y -= bodyLeading
+	}
+
+	for _, section := range doc.Sections {
+		l.section(section)
+	}
+	l.exhibitList()
+	l.finishPage()
+
+	return l.write(w)
+}
+
+// documentText returns the texts of doc that are printed
+func documentText(doc *Document) []string {
+	texts := append([]string{doc.Title, doc.Letterhead.Name}, doc.Letterhead.Lines...)
+	texts = append(texts, doc.Footnotes...)
+	for _, section := range doc.Sections {
+		texts = append(texts, section.Number, section.Heading)
+		for _, paragraph := range section.Paragraphs {
+			texts = append(texts, paragraph.Text())
+		}
+	}
+	for _, exhibit := range doc.Exhibits {
+		texts = append(texts, exhibit.Label, exhibit.Description)
+	}
+	return texts
+}
+
+// letterhead draws the firm's name and contact lines centered above a rule
+func (l *pdfLayout) letterhead() {
+	letterhead := l.doc.Letterhead
+	if letterhead.Name == "" && len(letterhead.Lines) == 0 {
+		return
+	}
+
+	l.y = pageHeight - pageMargin/2
+	if letterhead.Name != "" {
+		for _, ln := range l.wrap(textWords(strings.ToUpper(letterhead.Name), fontBold, titleSize), fontBold, titleSize, titleSize*1.3, pageMargin, contentWidth, 0, alignCenter) {
+			l.place(ln)
+		}
+	}
+	for _, text := range letterhead.Lines {
+		for _, ln := range l.wrap(textWords(text, fontRegular, headerSize), fontRegular, headerSize, headerSize*1.3, pageMargin, contentWidth, 0, alignCenter) {
+			l.place(ln)
+		}
+	}
+
+	l.y -= 6
+	fmt.Fprintf(&l.page.content, "0.75 w %s %s m %s %s l S\n", num(pageMargin), num(l.y), num(pageWidth-pageMargin), num(l.y))
+	l.y -= bodyLeading
+}
+
+// section lays out a heading and its paragraphs, keeping the heading with
+// the first lines that follow it
+func (l *pdfLayout) section(section Section) {
+	if section.Level > 0 {
+		x, width := pageMargin, contentWidth
+		if section.Level == 2 {
+			x, width = pageMargin+subheadIndent, contentWidth-subheadIndent
+		}
+		heading := l.wrap(textWords(sectionHeading(section), fontBold, bodySize), fontBold, bodySize, bodyLeading, x, width, 0, alignLeft)
+
+		l.y -= paragraphGap
+		l.ensure(float64(len(heading)+2) * bodyLeading)
+		for _, ln := range heading {
+			l.place(ln)
+		}
+		l.y -= paragraphGap
+	}
+
+	for _, paragraph := range section.Paragraphs {
+		var words []word
+		for _, run := range paragraph {
+			words = appendRun(words, run)
+		}
+		lines := l.wrap(words, fontRegular, bodySize, bodyLeading, pageMargin, contentWidth, paragraphIndent, alignJustify)
+		for i, ln := range lines {
+			if i == len(lines)-1 {
+				ln.align = alignLeft
+			}
+			l.place(ln)
+		}
+		l.y -= paragraphGap
+	}
+}
+
+// exhibitList appends the exhibits with the pages that reference them
+func (l *pdfLayout) exhibitList() {
+	if len(l.doc.Exhibits) == 0 {
+		return
+	}
+
+	const labelWidth, pagesWidth, gutter = 60.0, 72.0, 12.0
+	descriptionX := pageMargin + labelWidth
+	descriptionWidth := contentWidth - labelWidth - pagesWidth - gutter
+
+	l.finishPage()
+	l.newPage()
+	for _, ln := range l.wrap(textWords("EXHIBIT LIST", fontBold, titleSize), fontBold, titleSize, titleSize*1.3, pageMargin, contentWidth, 0, alignCenter) {
+		l.place(ln)
+	}
+	l.y -= bodyLeading
+
+	header := func() {
+		baseline := l.y - bodySize
+		l.drawLine(line{words: textWords("Exhibit", fontBold, bodySize), font: fontBold, size: bodySize, x: pageMargin, width: labelWidth}, baseline)
+		l.drawLine(line{words: textWords("Description", fontBold, bodySize), font: fontBold, size: bodySize, x: descriptionX, width: descriptionWidth}, baseline)
+		l.drawLine(line{words: textWords("Page", fontBold, bodySize), font: fontBold, size: bodySize, x: pageWidth - pageMargin - pagesWidth, width: pagesWidth, align: alignRight}, baseline)
+		l.y -= bodySize + 6
+		fmt.Fprintf(&l.page.content, "0.5 w %s %s m %s %s l S\n", num(pageMargin), num(l.y), num(pageWidth-pageMargin), num(l.y))
+		l.y -= 8
+	}
+	header()
+
+	const rowLeading = 14.0
+	for _, exhibit := range l.doc.Exhibits {
+		description := l.wrap(textWords(exhibit.Description, fontRegular, bodySize), fontRegular, bodySize, rowLeading, descriptionX, descriptionWidth, 0, alignLeft)
+		pages := make([]string, 0, len(l.exhibitPages[exhibit.Label]))
+		for _, page := range l.exhibitPages[exhibit.Label] {
+			pages = append(pages, strconv.Itoa(page))
+		}
+		pageLines := l.wrap(textWords(strings.Join(pages, ", "), fontRegular, bodySize), fontRegular, bodySize, rowLeading, pageWidth-pageMargin-pagesWidth, pagesWidth, 0, alignRight)
+
+		rows := max(len(description), len(pageLines), 1)
+		if l.y-float64(rows)*rowLeading < pageMargin {
+			l.finishPage()
+			l.newPage()
+			header()
+		}
+
+		top := l.y
+		baseline := top - bodySize
+		l.drawLine(line{words: textWords(exhibit.Label, fontBold, bodySize), font: fontBold, size: bodySize, x: pageMargin, width: labelWidth}, baseline)
+		for i, ln := range description {
+			l.drawLine(ln, baseline-float64(i)*rowLeading)
+		}
+		for i, ln := range pageLines {
+			l.drawLine(ln, baseline-float64(i)*rowLeading)
+		}
+		l.y = top - float64(rows)*rowLeading - 6
+	}
+}
+
+// newPage starts a page below the top margin
+func (l *pdfLayout) newPage() {
+	l.page = &pdfPage{}
+	l.pages = append(l.pages, l.page)
+	l.y = pageHeight - pageMargin
+}
+
+// ensure starts a new page unless height fits above the footnotes
+func (l *pdfLayout) ensure(height float64) {
+	if l.y-height < pageMargin+l.page.footnoteHeight && l.y < pageHeight-pageMargin {
+		l.finishPage()
+		l.newPage()
+	}
+}
+
+// place draws a line at the cursor, with its footnotes at the bottom of
+// the same page, starting a new page when they do not fit
+func (l *pdfLayout) place(ln line) {
+	var footnotes []int
+	for _, w := range ln.words {
+		footnotes = append(footnotes, w.footnotes...)
+	}
+
+	reserve := func() float64 {
+		height := 0.0
+		if len(footnotes) > 0 && len(l.page.footnotes) == 0 {
+			height += footnoteGap
+		}
+		for _, n := range footnotes {
+			height += float64(len(l.notes[n-1])) * footnoteLeading
+		}
+		return height
+	}
+	if l.y-ln.leading < pageMargin+l.page.footnoteHeight+reserve() && l.y < pageHeight-pageMargin {
+		l.finishPage()
+		l.newPage()
+	}
+	l.page.footnoteHeight += reserve()
+	l.page.footnotes = append(l.page.footnotes, footnotes...)
+
+	l.drawLine(ln, l.y-ln.size-(ln.leading-ln.size)/2)
+	l.y -= ln.leading
+
+	page := len(l.pages)
+	for _, w := range ln.words {
+		for _, label := range w.exhibits {
+			if pages := l.exhibitPages[label]; len(pages) == 0 || pages[len(pages)-1] != page {
+				l.exhibitPages[label] = append(pages, page)
+			}
+		}
+	}
+}
+
+// finishPage draws the footnotes of the current page below a short rule
+func (l *pdfLayout) finishPage() {
+	page := l.page
+	if len(page.footnotes) == 0 {
+		return
+	}
+
+	top := pageMargin + page.footnoteHeight
+	fmt.Fprintf(&page.content, "0.5 w %s %s m %s %s l S\n", num(pageMargin), num(top-footnoteGap/2), num(pageMargin+144), num(top-footnoteGap/2))
+	y := top - footnoteGap
+	for _, n := range page.footnotes {
+		for _, ln := range l.notes[n-1] {
+			l.drawLine(ln, y-ln.size-(ln.leading-ln.size)/2)
+			y -= ln.leading
+		}
+	}
+}
+
+// drawLine writes a line's text operators with its left edge at ln.x and
+// its baseline at baseline
+func (l *pdfLayout) drawLine(ln line, baseline float64) {
+	if len(ln.words) == 0 {
+		return
+	}
+
+	space := textWidth(ln.font, []byte{' '}, ln.size)
+	natural := space * float64(len(ln.words)-1)
+	for _, w := range ln.words {
+		natural += w.width()
+	}
+
+	x, wordSpacing := ln.x, 0.0
+	switch ln.align {
+	case alignCenter:
+		x += (ln.width - natural) / 2
+	case alignRight:
+		x += ln.width - natural
+	case alignJustify:
+		if len(ln.words) > 1 && natural < ln.width {
+			wordSpacing = (ln.width - natural) / float64(len(ln.words)-1)
+		}
+	}
+
+	b := &l.page.content
+	fmt.Fprintf(b, "BT %s %s Td %s Tw\n", num(x), num(baseline), num(wordSpacing))
+	// Consecutive fragments in the same font, size and rise share one string
+	font, size, rise := pdfFont(-1), 0.0, 0.0
+	var pending []byte
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		if font.embedded() {
+			// Glyph IDs may contain line-end bytes, which a literal string
+			// would not keep
+			fmt.Fprintf(b, "<%X> Tj\n", pending)
+		} else {
+			b.WriteString(pdfString(pending) + " Tj\n")
+		}
+		pending = pending[:0]
+	}
+	show := func(f fragment) {
+		if f.font != font || f.size != size {
+			flush()
+			fmt.Fprintf(b, "/%s %s Tf ", pdfFontNames[f.font].resource, num(f.size))
+			font, size = f.font, f.size
+		}
+		if f.rise != rise {
+			flush()
+			fmt.Fprintf(b, "%s Ts ", num(f.rise))
+			rise = f.rise
+		}
+		pending = append(pending, f.text...)
+		if f.font.embedded() {
+			if l.glyphs == nil {
+				l.glyphs = make(map[pdfFont]map[uint16]bool)
+			}
+			if l.glyphs[f.font] == nil {
+				l.glyphs[f.font] = make(map[uint16]bool)
+			}
+			for i := 0; i+1 < len(f.text); i += 2 {
+				l.glyphs[f.font][uint16(f.text[i])<<8|uint16(f.text[i+1])] = true
+			}
+		}
+	}
+	for i, w := range ln.words {
+		if i > 0 {
+			show(fragment{text: []byte{' '}, font: ln.font, size: ln.size})
+		}
+		for _, f := range w.fragments {
+			show(f)
+		}
+	}
+	flush()
+	if rise != 0 {
+		b.WriteString("0 Ts ")
+	}
+	b.WriteString("ET\n")
+}
+
+// wrap breaks words into lines of width, the first indented by indent
+func (l *pdfLayout) wrap(words []word, font pdfFont, size, leading, x, width, indent float64, align alignment) []line {
+	space := textWidth(font, []byte{' '}, size)
+	var lines []line
+	current := line{font: font, size: size, leading: leading, x: x + indent, width: width - indent, align: align}
+	used := 0.0
+	for _, w := range words {
+		ww := w.width()
+		if len(current.words) > 0 && used+space+ww > current.width {
+			lines = append(lines, current)
+			current = line{font: font, size: size, leading: leading, x: x, width: width, align: align}
+			used = 0
+		}
+		if len(current.words) > 0 {
+			used += space
+		}
+		current.words = append(current.words, w)
+		used += ww
+	}
+	if len(current.words) > 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// appendRun adds a run's words to words, continuing the last word when the
+// run does not start with a space, and attaches its footnote mark to the
+// last word
+func appendRun(words []word, run Run) []word {
+	text := strings.ReplaceAll(run.Text, "[Exhibit ", "[Exhibit\u00a0")
+	continues := len(words) > 0 && text != "" && !strings.HasPrefix(text, " ")
+	for i, w := range textWords(text, fontRegular, bodySize) {
+		if i == 0 && continues {
+			last := &words[len(words)-1]
+			last.fragments = append(last.fragments, w.fragments...)
+			last.exhibits = append(last.exhibits, w.exhibits...)
+			continue
+		}
+		words = append(words, w)
+	}
+
+	if run.Footnote > 0 {
+		mark := fragment{text: []byte(strconv.Itoa(run.Footnote)), font: fontRegular, size: markSize, rise: markRise}
+		if len(words) == 0 {
+			words = append(words, word{})
+		}
+		last := &words[len(words)-1]
+		last.fragments = append(last.fragments, mark)
+		last.footnotes = append(last.footnotes, run.Footnote)
+	}
+	return words
+}
+
+// textWords splits text into words at spaces, but not at no-break spaces
+func textWords(text string, font pdfFont, size float64) []word {
+	var words []word
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' }) {
+		w := word{fragments: encode(field, font, size)}
+		for _, m := range exhibitReferencePattern.FindAllStringSubmatch(field, -1) {
+			w.exhibits = append(w.exhibits, m[1])
+		}
+		words = append(words, w)
+	}
+	return words
+}
+
+// write serializes the laid-out pages with the running header and footer
+func (l *pdfLayout) write(w io.Writer) error {
+	for i, page := range l.pages {
+		l.page = page
+		if i > 0 && l.doc.Title != "" {
+			l.drawLine(line{words: textWords(l.doc.Title, fontRegular, headerSize), font: fontRegular, size: headerSize, x: pageMargin, width: contentWidth, align: alignRight}, pageHeight-pageMargin/2-headerSize)
+			fmt.Fprintf(&page.content, "0.5 w %s %s m %s %s l S\n", num(pageMargin), num(pageHeight-pageMargin/2-headerSize-4), num(pageWidth-pageMargin), num(pageHeight-pageMargin/2-headerSize-4))
+		}
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(l.pages))
+		l.drawLine(line{words: textWords(footer, fontRegular, headerSize), font: fontRegular, size: headerSize, x: pageMargin, width: contentWidth, align: alignCenter}, pageMargin/2)
+	}
+
+	// Objects 1-5: catalog, page tree, standard fonts and document
+	// information, filled in last; then the embedded fonts and a content
+	// stream and page object per page
+	const pagesObject = 2
+	objects := make([][]byte, 5)
+	add := func(body []byte) int {
+		objects = append(objects, body)
+		return len(objects)
+	}
+	fonts, err := l.fonts(add)
+	if err != nil {
+		return err
+	}
+
+	kids := make([]string, len(l.pages))
+	for i, page := range l.pages {
+		stream, err := flateStream("", page.content.Bytes())
+		if err != nil {
+			return err
+		}
+		contents := add(stream)
+		kids[i] = fmt.Sprintf("%d 0 R", add([]byte(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << %s >> /Contents %d 0 R >>",
+			pagesObject, num(pageWidth), num(pageHeight), fonts, contents))))
+	}
+
+	objects[0] = []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+	objects[1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	standardFonts(objects)
+	objects[4] = []byte(fmt.Sprintf("<< /Title %s /Author %s /Creator (MeritDraft) /CreationDate (D:%s) >>",
+		textString(l.doc.Title), textString(l.doc.Letterhead.Name), time.Now().UTC().Format("20060102150405Z")))
+
+	return writeObjects(w, objects)
+}
+
+// standardFonts fills in objects 3 and 4 with the standard fonts
+func standardFonts(objects [][]byte) {
+	for i, font := range []pdfFont{fontRegular, fontBold} {
+		objects[2+i] = []byte(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", pdfFontNames[font].base))
+	}
+}
+
+// fonts adds subsets of the embedded fonts with the glyphs drawn on the
+// pages and returns the font resources of the pages
+func (l *pdfLayout) fonts(add func([]byte) int) (string, error) {
+	resources := "/Font << /F1 3 0 R /F2 4 0 R"
+	for _, font := range []pdfFont{fontUnicode, fontUnicodeBold} {
+		if len(l.glyphs[font]) == 0 {
+			continue
+		}
+		n, err := trueTypeFonts()[font].embed(pdfFontNames[font].base, l.glyphs[font], add)
+		if err != nil {
+			return "", err
+		}
+		resources += fmt.Sprintf(" /%s %d 0 R", pdfFontNames[font].resource, n)
+	}
+	return resources + " >>", nil
+}
+
+// writeObjects writes a PDF file of numbered objects, objects[i] being
+// object i+1, with object 1 as the catalog and object 5 as the document
+// information
+func writeObjects(w io.Writer, objects [][]byte) error {
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(body)
+		out.WriteString("\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// pdfString formats WinAnsi text as a PDF literal string
+func pdfString(text []byte) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range text {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// num formats a coordinate with at most two decimals
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-" {
+		return "0"
+	}
+	return s
+}
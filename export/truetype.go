@@ -0,0 +1,455 @@
+package export
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// DejaVu Serif prints the characters outside WinAnsiEncoding, such as
+// Polish, Turkish, Vietnamese, Greek and Cyrillic names. Only the glyphs a
+// document uses are embedded. See fonts/LICENSE.
+var (
+	//go:embed fonts/DejaVuSerif.ttf
+	dejaVuSerif []byte
+	//go:embed fonts/DejaVuSerif-Bold.ttf
+	dejaVuSerifBold []byte
+)
+
+// trueTypeFonts parses the embedded fonts once. They are part of the
+// binary, so a font that fails to parse panics.
+var trueTypeFonts = sync.OnceValue(func() map[pdfFont]*trueTypeFont {
+	fonts := make(map[pdfFont]*trueTypeFont)
+	for font, data := range map[pdfFont][]byte{fontUnicode: dejaVuSerif, fontUnicodeBold: dejaVuSerifBold} {
+		parsed, err := parseTrueType(data)
+		if err != nil {
+			panic(fmt.Sprintf("export: invalid embedded font %s: %v", pdfFontNames[font].base, err))
+		}
+		fonts[font] = parsed
+	}
+	return fonts
+})
+
+// trueTypeFont is a parsed TrueType font
+type trueTypeFont struct {
+	tables     map[string][]byte
+	unitsPerEm int
+	numGlyphs  int
+	glyphs     map[rune]uint16 // From the Unicode cmap
+	runes      map[uint16]rune // First character of each mapped glyph
+	advances   []int           // Advance widths by glyph ID, in font units
+	loca       []int           // Glyph offsets into glyf, numGlyphs+1 entries
+	bbox       [4]int
+	ascent     int
+	descent    int
+	capHeight  int
+}
+
+var errInvalidFont = errors.New("invalid TrueType font")
+
+// parseTrueType reads the tables of a TrueType font needed to measure,
+// subset and embed it
+func parseTrueType(data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, errInvalidFont
+	}
+	f := &trueTypeFont{tables: make(map[string][]byte)}
+	numTables := int(u16(data, 4))
+	for i := 0; i < numTables; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, errInvalidFont
+		}
+		offset, length := int(u32(data, record+8)), int(u32(data, record+12))
+		if offset+length > len(data) {
+			return nil, errInvalidFont
+		}
+		f.tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if f.tables[tag] == nil {
+			return nil, fmt.Errorf("%w: no %s table", errInvalidFont, tag)
+		}
+	}
+
+	head, hhea := f.tables["head"], f.tables["hhea"]
+	if len(head) < 54 || len(hhea) < 36 || len(f.tables["maxp"]) < 6 {
+		return nil, errInvalidFont
+	}
+	f.unitsPerEm = int(u16(head, 18))
+	f.bbox = [4]int{int(int16(u16(head, 36))), int(int16(u16(head, 38))), int(int16(u16(head, 40))), int(int16(u16(head, 42)))}
+	f.ascent, f.descent = int(int16(u16(hhea, 4))), int(int16(u16(hhea, 6)))
+	f.capHeight = f.ascent
+	if os2 := f.tables["OS/2"]; len(os2) >= 90 && u16(os2, 0) >= 2 {
+		f.capHeight = int(int16(u16(os2, 88)))
+	}
+	f.numGlyphs = int(u16(f.tables["maxp"], 4))
+	if f.unitsPerEm == 0 || f.numGlyphs == 0 {
+		return nil, errInvalidFont
+	}
+
+	// Glyphs past the last long metric repeat its advance
+	hmtx, metrics := f.tables["hmtx"], int(u16(hhea, 34))
+	if metrics == 0 || len(hmtx) < 4*metrics {
+		return nil, errInvalidFont
+	}
+	f.advances = make([]int, f.numGlyphs)
+	for gid := range f.advances {
+		f.advances[gid] = int(u16(hmtx, 4*min(gid, metrics-1)))
+	}
+
+	loca, long := f.tables["loca"], u16(head, 50) == 1
+	f.loca = make([]int, f.numGlyphs+1)
+	for i := range f.loca {
+		switch {
+		case long && len(loca) >= 4*(i+1):
+			f.loca[i] = int(u32(loca, 4*i))
+		case !long && len(loca) >= 2*(i+1):
+			f.loca[i] = 2 * int(u16(loca, 2*i))
+		default:
+			return nil, errInvalidFont
+		}
+		if f.loca[i] > len(f.tables["glyf"]) || (i > 0 && f.loca[i] < f.loca[i-1]) {
+			return nil, errInvalidFont
+		}
+	}
+
+	if err := f.parseCmap(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseCmap reads the Windows Unicode character map, preferring the full
+// repertoire (format 12) to the Basic Multilingual Plane (format 4)
+func (f *trueTypeFont) parseCmap() error {
+	cmap := f.tables["cmap"]
+	if len(cmap) < 4 {
+		return errInvalidFont
+	}
+	var subtable []byte
+	for i := 0; i < int(u16(cmap, 2)); i++ {
+		record := 4 + 8*i
+		if record+8 > len(cmap) {
+			return errInvalidFont
+		}
+		platform, encoding, offset := u16(cmap, record), u16(cmap, record+2), int(u32(cmap, record+4))
+		if platform != 3 || (encoding != 1 && encoding != 10) || offset+4 > len(cmap) {
+			continue
+		}
+		if subtable == nil || u16(cmap, offset) == 12 {
+			subtable = cmap[offset:]
+		}
+	}
+	if subtable == nil {
+		return fmt.Errorf("%w: no Unicode cmap", errInvalidFont)
+	}
+
+	f.glyphs = make(map[rune]uint16)
+	f.runes = make(map[uint16]rune)
+	add := func(r rune, gid int) {
+		if gid > 0 && gid < f.numGlyphs {
+			f.glyphs[r] = uint16(gid)
+			if _, ok := f.runes[uint16(gid)]; !ok {
+				f.runes[uint16(gid)] = r
+			}
+		}
+	}
+
+	switch u16(subtable, 0) {
+	case 4:
+		if len(subtable) < 14 {
+			return errInvalidFont
+		}
+		segments := int(u16(subtable, 6)) / 2
+		ends, starts, deltas, ranges := 14, 16+2*segments, 16+4*segments, 16+6*segments
+		if ranges+2*segments > len(subtable) {
+			return errInvalidFont
+		}
+		for i := 0; i < segments; i++ {
+			start, end := int(u16(subtable, starts+2*i)), int(u16(subtable, ends+2*i))
+			delta, rangeOffset := int(u16(subtable, deltas+2*i)), int(u16(subtable, ranges+2*i))
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				if rangeOffset == 0 {
+					add(rune(c), (c+delta)&0xFFFF)
+					continue
+				}
+				at := ranges + 2*i + rangeOffset + 2*(c-start)
+				if at+2 > len(subtable) {
+					return errInvalidFont
+				}
+				if gid := int(u16(subtable, at)); gid != 0 {
+					add(rune(c), (gid+delta)&0xFFFF)
+				}
+			}
+		}
+	case 12:
+		if len(subtable) < 16 {
+			return errInvalidFont
+		}
+		groups := int(u32(subtable, 12))
+		if 16+12*groups > len(subtable) {
+			return errInvalidFont
+		}
+		for i := 0; i < groups; i++ {
+			start, end, gid := rune(u32(subtable, 16+12*i)), rune(u32(subtable, 20+12*i)), int(u32(subtable, 24+12*i))
+			for c := start; c <= end && c <= 0x10FFFF; c++ {
+				add(c, gid+int(c-start))
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unsupported cmap format %d", errInvalidFont, u16(subtable, 0))
+	}
+	return nil
+}
+
+// glyph returns the glyph ID of r, or false if the font cannot print it
+func (f *trueTypeFont) glyph(r rune) (uint16, bool) {
+	gid, ok := f.glyphs[r]
+	return gid, ok
+}
+
+// width returns the advance of a glyph in thousandths of the font size
+func (f *trueTypeFont) width(gid uint16) int {
+	if int(gid) >= len(f.advances) {
+		return 0
+	}
+	return (f.advances[gid]*1000 + f.unitsPerEm/2) / f.unitsPerEm
+}
+
+// scale converts font units to thousandths of the font size
+func (f *trueTypeFont) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// subset returns a font file with the outlines of only the given glyphs,
+// the components of composite glyphs and the .notdef glyph. Glyph IDs are
+// kept, so the subset maps character codes to glyphs as the full font does.
+func (f *trueTypeFont) subset(used []uint16) []byte {
+	keep := map[uint16]bool{0: true}
+	pending := append([]uint16{0}, used...)
+	for len(pending) > 0 {
+		gid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		keep[gid] = true
+		for _, component := range f.components(gid) {
+			if !keep[component] {
+				pending = append(pending, component)
+			}
+		}
+	}
+
+	glyf := f.tables["glyf"]
+	var newGlyf []byte
+	newLoca := make([]byte, 4*(f.numGlyphs+1))
+	for gid := 0; gid < f.numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(newLoca[4*gid:], uint32(len(newGlyf)))
+		if keep[uint16(gid)] {
+			newGlyf = append(newGlyf, glyf[f.loca[gid]:f.loca[gid+1]]...)
+			for len(newGlyf)%4 != 0 {
+				newGlyf = append(newGlyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[4*f.numGlyphs:], uint32(len(newGlyf)))
+
+	// Long glyph offsets, and the whole-font checksum filled in below
+	head := slices.Clone(f.tables["head"])
+	binary.BigEndian.PutUint16(head[50:], 1)
+	binary.BigEndian.PutUint32(head[8:], 0)
+
+	tables := map[string][]byte{
+		"head": head, "hhea": f.tables["hhea"], "maxp": f.tables["maxp"], "hmtx": f.tables["hmtx"],
+		"loca": newLoca, "glyf": newGlyf,
+	}
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if table := f.tables[tag]; table != nil {
+			tables[tag] = table
+		}
+	}
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	// Offset table and table directory, then the tables padded to four bytes
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= len(tags) {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+	out := make([]byte, 12+16*len(tags))
+	binary.BigEndian.PutUint32(out[0:], 0x00010000)
+	binary.BigEndian.PutUint16(out[4:], uint16(len(tags)))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(16*len(tags)-searchRange))
+	headOffset := 0
+	for i, tag := range tags {
+		table := tables[tag]
+		record := out[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], checksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+		if tag == "head" {
+			headOffset = len(out)
+		}
+		out = append(out, table...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-checksum(out))
+	return out
+}
+
+// components returns the glyphs a composite glyph is built from
+func (f *trueTypeFont) components(gid uint16) []uint16 {
+	if int(gid) >= f.numGlyphs {
+		return nil
+	}
+	data := f.tables["glyf"][f.loca[gid]:f.loca[gid+1]]
+	if len(data) < 10 || int16(u16(data, 0)) >= 0 {
+		return nil
+	}
+
+	const (
+		argsAreWords   = 0x0001
+		haveScale      = 0x0008
+		moreComponents = 0x0020
+		haveXYScale    = 0x0040
+		haveTwoByTwo   = 0x0080
+	)
+	var components []uint16
+	for at := 10; at+4 <= len(data); {
+		flags, component := u16(data, at), u16(data, at+2)
+		components = append(components, component)
+		at += 4
+		if flags&argsAreWords != 0 {
+			at += 4
+		} else {
+			at += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			at += 2
+		case flags&haveXYScale != 0:
+			at += 4
+		case flags&haveTwoByTwo != 0:
+			at += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// embed adds the objects of a Type 0 font embedding the subset of the
+// given glyphs and returns the number of the font object
+func (f *trueTypeFont) embed(name string, glyphs map[uint16]bool, add func([]byte) int) (int, error) {
+	used := make([]uint16, 0, len(glyphs))
+	for gid := range glyphs {
+		used = append(used, gid)
+	}
+	slices.Sort(used)
+
+	// Subsets are named with a tag derived from their glyphs
+	h := fnv.New32a()
+	for _, gid := range used {
+		h.Write([]byte{byte(gid >> 8), byte(gid)})
+	}
+	tag := make([]byte, 6)
+	for i, sum := 0, h.Sum32(); i < len(tag); i, sum = i+1, sum/26 {
+		tag[i] = 'A' + byte(sum%26)
+	}
+	name = string(tag) + "+" + name
+
+	file := f.subset(used)
+	fontFile, err := flateStream(fmt.Sprintf("/Length1 %d ", len(file)), file)
+	if err != nil {
+		return 0, err
+	}
+	toUnicode, err := flateStream("", f.toUnicode(used))
+	if err != nil {
+		return 0, err
+	}
+
+	descriptor := add([]byte(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 6 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight), add(fontFile))))
+
+	var widths strings.Builder
+	for _, gid := range used {
+		fmt.Fprintf(&widths, "%d [%d] ", gid, f.width(gid))
+	}
+	cidFont := add([]byte(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /W [%s] /CIDToGIDMap /Identity >>",
+		name, descriptor, strings.TrimSpace(widths.String()))))
+
+	return add([]byte(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, cidFont, add(toUnicode)))), nil
+}
+
+// toUnicode writes the CMap that lets readers copy and search the text
+// set in the given glyphs
+func (f *trueTypeFont) toUnicode(used []uint16) []byte {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	var mapped []uint16
+	for _, gid := range used {
+		if _, ok := f.runes[gid]; ok {
+			mapped = append(mapped, gid)
+		}
+	}
+	for len(mapped) > 0 {
+		block := mapped[:min(len(mapped), 100)]
+		mapped = mapped[len(block):]
+		fmt.Fprintf(&b, "%d beginbfchar\n", len(block))
+		for _, gid := range block {
+			fmt.Fprintf(&b, "<%04X> <", gid)
+			for _, unit := range utf16.Encode([]rune{f.runes[gid]}) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	return []byte(b.String())
+}
+
+// checksum sums a table as big-endian 32-bit words, zero padded
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+func u16(b []byte, at int) uint16 {
+	if at+2 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b[at:])
+}
+
+func u32(b []byte, at int) uint32 {
+	if at+4 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[at:])
+}
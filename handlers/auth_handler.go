@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"meritdraft-backend/models"
+	"meritdraft-backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUserKey is the gin context key holding the authenticated *models.User
+const currentUserKey = "currentUser"
+
+// AuthHandler handles HTTP requests for authentication
+type AuthHandler struct {
+	authService *service.AuthService
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+	}
+}
+
+// LoginRequest represents the request body for logging in
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Login handles POST /api/auth/login
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.authService.Login(c.Request.Context(), service.LoginRequest{
+		Email:    req.Email,
+		Password: req.Password,
+	})
+	if err != nil {
+		if err == service.ErrInvalidCredentials {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_CREDENTIALS",
+					"message": "Invalid email or password",
+				},
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "LOGIN_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"token":      result.Token,
+			"expires_at": result.ExpiresAt,
+			"user":       result.User,
+		},
+	})
+}
+
+// Me handles GET /api/auth/me
+func (h *AuthHandler) Me(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    currentUser(c),
+	})
+}
+
+// RequireAuth returns middleware that verifies the bearer session token and
+// stores the caller's user in the gin context
+func RequireAuth(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "UNAUTHORIZED",
+					"message": "Missing bearer token",
+				},
+			})
+			return
+		}
+
+		user, err := authService.Authenticate(c.Request.Context(), strings.TrimSpace(token))
+		if err != nil {
+			code := "INVALID_TOKEN"
+			if err == service.ErrTokenExpired {
+				code = "TOKEN_EXPIRED"
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    code,
+					"message": err.Error(),
+				},
+			})
+			return
+		}
+
+		c.Set(currentUserKey, user)
+		c.Next()
+	}
+}
+
+// TokenFromQuery copies an access_token query parameter into the
+// Authorization header for clients that cannot set headers, such as the
+// browser EventSource API. Only use it on routes that need it, since
+// tokens in URLs can end up in access logs; RequestLogger redacts them
+// from the server's own log.
+func TokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequestLogger logs requests in gin's default format, with any
+// access_token query parameter redacted so tokens accepted by
+// TokenFromQuery never reach the access log
+func RequestLogger() gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Formatter: func(param gin.LogFormatterParams) string {
+			return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				param.StatusCode,
+				param.Latency,
+				param.ClientIP,
+				param.Method,
+				redactToken(param.Path),
+				param.ErrorMessage,
+			)
+		},
+	})
+}
+
+// redactToken replaces the value of an access_token query parameter in a
+// request path. A query that cannot be parsed is dropped entirely.
+func redactToken(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok || !strings.Contains(rawQuery, "access_token") {
+		return path
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base
+	}
+	if _, ok := query["access_token"]; ok {
+		query.Set("access_token", "REDACTED")
+	}
+	return base + "?" + query.Encode()
+}
+
+// currentUser returns the authenticated user set by RequireAuth
+func currentUser(c *gin.Context) *models.User {
+	if v, ok := c.Get(currentUserKey); ok {
+		if user, ok := v.(*models.User); ok {
+			return user
+		}
+	}
+	return nil
+}
+
+// respondForbidden writes the standard 403 response for cross-user access
+func respondForbidden(c *gin.Context, message string) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    "FORBIDDEN",
+			"message": message,
+		},
+	})
+}
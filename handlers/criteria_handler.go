@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"meritdraft-backend/criteria"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CriteriaHandler handles HTTP requests for the visa criteria registry
+type CriteriaHandler struct{}
+
+// NewCriteriaHandler creates a new criteria handler
+func NewCriteriaHandler() *CriteriaHandler {
+	return &CriteriaHandler{}
+}
+
+// GetSchema handles GET /api/criteria/:visa_type/schema
+//
+// Returns the JSON Schema that PUT /api/petitions/:id validates
+// criteria_details against, so intake forms can be generated from it.
+func (h *CriteriaHandler) GetSchema(c *gin.Context) {
+	visa, ok := criteria.Lookup(c.Param("visa_type"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "UNKNOWN_VISA_TYPE",
+				"message": "Unknown visa type",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    visa.JSONSchema(),
+	})
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"meritdraft-backend/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateExhibitRequest represents the request body for tagging a file as an exhibit
+type CreateExhibitRequest struct {
+	FileID      string  `json:"file_id" binding:"required"`
+	Criterion   string  `json:"criterion" binding:"required"`
+	FactKey     *string `json:"fact_key"`   // Optional, e.g. "awards"
+	FactIndex   *int    `json:"fact_index"` // Optional entry of an array fact
+	Description string  `json:"description"`
+}
+
+// CreateExhibit handles POST /api/petitions/:id/exhibits
+func (h *PetitionHandler) CreateExhibit(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	var req CreateExhibitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	fileID, err := uuid.Parse(req.FileID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid file ID format",
+			},
+		})
+		return
+	}
+
+	petition, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+	if !role.CanEdit() {
+		respondForbidden(c, "Your role does not allow editing this petition")
+		return
+	}
+
+	result, err := h.exhibitService.CreateExhibit(c.Request.Context(), service.CreateExhibitRequest{
+		Petition:    petition,
+		FileID:      fileID,
+		Criterion:   req.Criterion,
+		FactKey:     req.FactKey,
+		FactIndex:   req.FactIndex,
+		Description: req.Description,
+		ActorID:     currentUser(c).ID,
+	})
+	if errors.Is(err, service.ErrInvalidExhibitFact) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_EXHIBIT",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if errors.Is(err, service.ErrExhibitFileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "FILE_NOT_FOUND",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if errors.Is(err, service.ErrExhibitExists) {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "EXHIBIT_EXISTS",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "CREATE_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    result.Exhibit,
+	})
+}
+
+// ListExhibits handles GET /api/petitions/:id/exhibits, returning the
+// exhibit list in letter order and the number of placeholders in the
+// generated content that cite no exhibit
+func (h *PetitionHandler) ListExhibits(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	petition, _, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+
+	result, err := h.exhibitService.ListExhibits(c.Request.Context(), service.ListExhibitsRequest{
+		Petition: petition,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "RETRIEVAL_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"exhibits":   result.Exhibits,
+			"unresolved": result.Unresolved,
+		},
+	})
+}
+
+// DeleteExhibit handles DELETE /api/petitions/:id/exhibits/:exhibit_id. The
+// file stays attached to the petition.
+func (h *PetitionHandler) DeleteExhibit(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	exhibitID, err := uuid.Parse(c.Param("exhibit_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid exhibit ID format",
+			},
+		})
+		return
+	}
+
+	petition, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+	if !role.CanEdit() {
+		respondForbidden(c, "Your role does not allow editing this petition")
+		return
+	}
+
+	_, err = h.exhibitService.DeleteExhibit(c.Request.Context(), service.DeleteExhibitRequest{
+		Petition:  petition,
+		ExhibitID: exhibitID,
+	})
+	if errors.Is(err, service.ErrExhibitNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "EXHIBIT_NOT_FOUND",
+				"message": "Exhibit not found",
+			},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "DELETE_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"id": exhibitID,
+		},
+	})
+}
@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
 	"meritdraft-backend/models"
 	"meritdraft-backend/repository"
+	"meritdraft-backend/service"
 	"meritdraft-backend/storage"
 
 	"github.com/gin-gonic/gin"
@@ -18,16 +20,18 @@ type FileHandler struct {
 	fileRepo        *repository.FileRepository
 	petitionRepo    *repository.PetitionRepository
 	storage         storage.Storage
+	documentService *service.DocumentService
 	maxFileSize     int64
 	allowedMimeTypes map[string]bool
 }
 
 // NewFileHandler creates a new file handler
-func NewFileHandler(fileRepo *repository.FileRepository, petitionRepo *repository.PetitionRepository, storage storage.Storage) *FileHandler {
+func NewFileHandler(fileRepo *repository.FileRepository, petitionRepo *repository.PetitionRepository, storage storage.Storage, documentService *service.DocumentService) *FileHandler {
 	return &FileHandler{
 		fileRepo:        fileRepo,
 		petitionRepo:    petitionRepo,
 		storage:         storage,
+		documentService: documentService,
 		maxFileSize:     10 * 1024 * 1024, // 10MB
 		allowedMimeTypes: map[string]bool{
 			"application/pdf":      true,
@@ -40,10 +44,10 @@ func NewFileHandler(fileRepo *repository.FileRepository, petitionRepo *repositor
 
 // UploadFile handles POST /api/files/upload
 func (h *FileHandler) UploadFile(c *gin.Context) {
-	// Get user_id from query or form (for now, we'll get it from petition)
+	// Files are always owned by the authenticated caller
+	userID := currentUser(c).ID
 	petitionIDStr := c.PostForm("petition_id")
 	var petitionID *uuid.UUID
-	var userID uuid.UUID
 
 	if petitionIDStr != "" {
 		pid, err := uuid.Parse(petitionIDStr)
@@ -59,7 +63,7 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		}
 		petitionID = &pid
 
-		// Get petition to retrieve user_id
+		// Only allow attaching files to petitions the caller may edit
 		petition, err := h.petitionRepo.GetByID(c.Request.Context(), pid)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -71,32 +75,11 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 			})
 			return
 		}
-		userID = petition.UserID
-	} else {
-		// If no petition_id, require user_id in form
-		userIDStr := c.PostForm("user_id")
-		if userIDStr == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"error": gin.H{
-					"code":    "MISSING_USER_ID",
-					"message": "Either petition_id or user_id is required",
-				},
-			})
+		role, err := h.petitionRepo.GetAccessRole(c.Request.Context(), petition, userID)
+		if err != nil || !role.CanEdit() {
+			respondForbidden(c, "You do not have access to this petition")
 			return
 		}
-		uid, err := uuid.Parse(userIDStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"error": gin.H{
-					"code":    "INVALID_USER_ID",
-					"message": "Invalid user_id format",
-				},
-			})
-			return
-		}
-		userID = uid
 	}
 
 	// Get file from form
@@ -227,15 +210,30 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		}
 	}
 
+	data := gin.H{
+		"id":        fileRecord.ID,
+		"filename":  fileRecord.Filename,
+		"mime_type": fileRecord.MimeType,
+		"size":      fileRecord.Size,
+		"created_at": fileRecord.CreatedAt,
+	}
+
+	// Parse petition documents in the background to prefill criteria details
+	if petitionID != nil {
+		result, err := h.documentService.QueueExtraction(c.Request.Context(), service.QueueExtractionRequest{
+			File: fileRecord,
+		})
+		if err == nil {
+			data["extraction_status"] = result.Extraction.Status
+		} else if err != service.ErrUnsupportedDocument {
+			// Log error but don't fail the upload
+			log.Printf("Warning: Failed to queue extraction of file %s: %v", fileRecord.ID, err)
+		}
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
-		"data": gin.H{
-			"id":        fileRecord.ID,
-			"filename":  fileRecord.Filename,
-			"mime_type": fileRecord.MimeType,
-			"size":      fileRecord.Size,
-			"created_at": fileRecord.CreatedAt,
-		},
+		"data":    data,
 	})
 }
 
@@ -266,6 +264,11 @@ func (h *FileHandler) GetFile(c *gin.Context) {
 		return
 	}
 
+	if !h.canReadFile(c, file) {
+		respondForbidden(c, "You do not have access to this file")
+		return
+	}
+
 	// Download from storage
 	reader, err := h.storage.Download(c.Request.Context(), file.StoragePath)
 	if err != nil {
@@ -286,3 +289,156 @@ func (h *FileHandler) GetFile(c *gin.Context) {
 	c.DataFromReader(http.StatusOK, file.Size, file.MimeType, reader, nil)
 }
 
+
+// canReadFile reports whether the caller uploaded the file or can access the
+// petition it is attached to
+func (h *FileHandler) canReadFile(c *gin.Context, file *models.File) bool {
+	userID := currentUser(c).ID
+	if file.UserID == userID {
+		return true
+	}
+	if file.PetitionID == nil {
+		return false
+	}
+
+	petition, err := h.petitionRepo.GetByID(c.Request.Context(), *file.PetitionID)
+	if err != nil {
+		return false
+	}
+	role, err := h.petitionRepo.GetAccessRole(c.Request.Context(), petition, userID)
+	return err == nil && role != ""
+}
+
+// canEditFile reports whether the caller can edit the petition the file is
+// attached to, or uploaded it when it is attached to none. Uploaders whose
+// access to the petition was revoked or made read-only cannot edit it.
+func (h *FileHandler) canEditFile(c *gin.Context, file *models.File) bool {
+	userID := currentUser(c).ID
+	if file.PetitionID == nil {
+		return file.UserID == userID
+	}
+
+	petition, err := h.petitionRepo.GetByID(c.Request.Context(), *file.PetitionID)
+	if err != nil {
+		return false
+	}
+	role, err := h.petitionRepo.GetAccessRole(c.Request.Context(), petition, userID)
+	return err == nil && role.CanEdit()
+}
+
+// loadFile parses the :id parameter and loads the file, writing the error
+// response and returning false when it is invalid or missing
+func (h *FileHandler) loadFile(c *gin.Context) (*models.File, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid file ID format",
+			},
+		})
+		return nil, false
+	}
+
+	file, err := h.fileRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "NOT_FOUND",
+				"message": "File not found",
+			},
+		})
+		return nil, false
+	}
+	return file, true
+}
+
+// GetExtraction handles GET /api/files/:id/extraction
+//
+// Returns the text parsed from the file and the evidence candidates found in
+// it, each with the source span it was read from, for attorney review.
+func (h *FileHandler) GetExtraction(c *gin.Context) {
+	file, ok := h.loadFile(c)
+	if !ok {
+		return
+	}
+	if !h.canReadFile(c, file) {
+		respondForbidden(c, "You do not have access to this file")
+		return
+	}
+
+	result, err := h.documentService.GetExtraction(c.Request.Context(), service.GetExtractionRequest{
+		FileID: file.ID,
+	})
+	if err == service.ErrExtractionNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "NOT_FOUND",
+				"message": "File has not been parsed",
+			},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "RETRIEVAL_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result.Extraction,
+	})
+}
+
+// ExtractFile handles POST /api/files/:id/extraction
+//
+// Queues the file to be parsed again, e.g. after the petition's visa type
+// changes. Criteria details already filled in are never overwritten.
+func (h *FileHandler) ExtractFile(c *gin.Context) {
+	file, ok := h.loadFile(c)
+	if !ok {
+		return
+	}
+	if !h.canEditFile(c, file) {
+		respondForbidden(c, "You do not have permission to edit this file")
+		return
+	}
+
+	result, err := h.documentService.QueueExtraction(c.Request.Context(), service.QueueExtractionRequest{
+		File: file,
+	})
+	if err == service.ErrUnsupportedDocument {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "UNSUPPORTED_DOCUMENT",
+				"message": "Text can only be extracted from PDF, DOCX and TXT files",
+			},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "EXTRACTION_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    result.Extraction,
+	})
+}
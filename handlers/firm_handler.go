@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"net/http"
+
+	"meritdraft-backend/models"
+	"meritdraft-backend/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// FirmHandler handles HTTP requests for law firm workspaces
+type FirmHandler struct {
+	firmService *service.FirmService
+}
+
+// NewFirmHandler creates a new firm handler
+func NewFirmHandler(firmService *service.FirmService) *FirmHandler {
+	return &FirmHandler{
+		firmService: firmService,
+	}
+}
+
+// CreateFirmRequest represents the request body for creating a firm
+type CreateFirmRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	Letterhead []string `json:"letterhead"` // Optional address, phone and email lines
+}
+
+// CreateFirm handles POST /api/firms
+func (h *FirmHandler) CreateFirm(c *gin.Context) {
+	var req CreateFirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.firmService.CreateFirm(c.Request.Context(), service.CreateFirmRequest{
+		Name:       req.Name,
+		Letterhead: req.Letterhead,
+		OwnerID:    currentUser(c).ID,
+	})
+	if err != nil {
+		h.respondFirmError(c, err, "CREATE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    result.Firm,
+	})
+}
+
+// ListFirms handles GET /api/firms
+func (h *FirmHandler) ListFirms(c *gin.Context) {
+	result, err := h.firmService.ListFirms(c.Request.Context(), service.ListFirmsRequest{
+		UserID: currentUser(c).ID,
+	})
+	if err != nil {
+		h.respondFirmError(c, err, "RETRIEVAL_FAILED")
+		return
+	}
+
+	firms := result.Firms
+	if firms == nil {
+		firms = []*models.Firm{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    firms,
+	})
+}
+
+// GetFirm handles GET /api/firms/:id
+func (h *FirmHandler) GetFirm(c *gin.Context) {
+	firmID, ok := parseFirmID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.firmService.GetFirm(c.Request.Context(), service.GetFirmRequest{
+		FirmID: firmID,
+		UserID: currentUser(c).ID,
+	})
+	if err != nil {
+		h.respondFirmError(c, err, "RETRIEVAL_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"firm":    result.Firm,
+			"members": result.Members,
+			"role":    result.Role,
+		},
+	})
+}
+
+// UpdateFirmRequest represents the request body for updating a firm. Omitted
+// fields are left unchanged.
+type UpdateFirmRequest struct {
+	Name       *string  `json:"name"`
+	Letterhead []string `json:"letterhead"`
+}
+
+// UpdateFirm handles PUT /api/firms/:id
+func (h *FirmHandler) UpdateFirm(c *gin.Context) {
+	firmID, ok := parseFirmID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateFirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.firmService.UpdateFirm(c.Request.Context(), service.UpdateFirmRequest{
+		FirmID:     firmID,
+		ActorID:    currentUser(c).ID,
+		Name:       req.Name,
+		Letterhead: req.Letterhead,
+	})
+	if err != nil {
+		h.respondFirmError(c, err, "UPDATE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result.Firm,
+	})
+}
+
+// AddMemberRequest represents the request body for adding a firm member
+type AddMemberRequest struct {
+	Email string `json:"email" binding:"required"`
+	Role  string `json:"role" binding:"required"`
+}
+
+// AddMember handles POST /api/firms/:id/members
+func (h *FirmHandler) AddMember(c *gin.Context) {
+	firmID, ok := parseFirmID(c)
+	if !ok {
+		return
+	}
+
+	var req AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.firmService.AddMember(c.Request.Context(), service.AddMemberRequest{
+		FirmID:      firmID,
+		ActorID:     currentUser(c).ID,
+		MemberEmail: req.Email,
+		Role:        models.FirmRole(req.Role),
+	})
+	if err != nil {
+		h.respondFirmError(c, err, "UPDATE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    result.Member,
+	})
+}
+
+// UpdateMemberRequest represents the request body for changing a member's role
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdateMember handles PUT /api/firms/:id/members/:user_id
+func (h *FirmHandler) UpdateMember(c *gin.Context) {
+	firmID, ok := parseFirmID(c)
+	if !ok {
+		return
+	}
+	memberID, ok := parseMemberID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.firmService.UpdateMemberRole(c.Request.Context(), service.UpdateMemberRoleRequest{
+		FirmID:   firmID,
+		ActorID:  currentUser(c).ID,
+		MemberID: memberID,
+		Role:     models.FirmRole(req.Role),
+	})
+	if err != nil {
+		h.respondFirmError(c, err, "UPDATE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result.Member,
+	})
+}
+
+// RemoveMember handles DELETE /api/firms/:id/members/:user_id
+func (h *FirmHandler) RemoveMember(c *gin.Context) {
+	firmID, ok := parseFirmID(c)
+	if !ok {
+		return
+	}
+	memberID, ok := parseMemberID(c)
+	if !ok {
+		return
+	}
+
+	err := h.firmService.RemoveMember(c.Request.Context(), service.RemoveMemberRequest{
+		FirmID:   firmID,
+		ActorID:  currentUser(c).ID,
+		MemberID: memberID,
+	})
+	if err != nil {
+		h.respondFirmError(c, err, "UPDATE_FAILED")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// parseFirmID parses the :id path parameter, writing a 400 on failure
+func parseFirmID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid firm ID format",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// parseMemberID parses the :user_id path parameter, writing a 400 on failure
+func parseMemberID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_USER_ID",
+				"message": "Invalid user ID format",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// respondFirmError maps firm service errors onto HTTP responses
+func (h *FirmHandler) respondFirmError(c *gin.Context, err error, fallbackCode string) {
+	switch err {
+	case service.ErrFirmNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "NOT_FOUND",
+				"message": "Firm not found",
+			},
+		})
+	case service.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "USER_NOT_FOUND",
+				"message": "User not found",
+			},
+		})
+	case service.ErrForbidden:
+		respondForbidden(c, "You do not have permission to manage this firm")
+	case service.ErrInvalidRole, service.ErrFirmNameRequired, service.ErrLastFirmOwner:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    fallbackCode,
+				"message": err.Error(),
+			},
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"meritdraft-backend/models"
+	"meritdraft-backend/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// jobEventsKeepAlive is how often an idle event stream is pinged and the
+// job re-read, which also catches a terminal event missed during a
+// listener reconnect
+const jobEventsKeepAlive = 15 * time.Second
+
+// StreamJobEvents handles GET /api/jobs/:id/events, streaming step
+// transitions, section text and the terminal status as Server-Sent Events
+func (h *PetitionHandler) StreamJobEvents(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid job ID format",
+			},
+		})
+		return
+	}
+
+	// Subscribe before reading the job so no transition falls in between
+	events, unsubscribe := h.jobEvents.Subscribe(id)
+	defer unsubscribe()
+
+	result, err := h.draftService.GetJobStatus(c.Request.Context(), service.GetJobStatusRequest{
+		JobID: id,
+	})
+	if err != nil {
+		if err == service.ErrJobNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "NOT_FOUND",
+					"message": "Generation job not found",
+				},
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "RETRIEVAL_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	if _, _, ok := h.loadAuthorizedPetition(c, result.Job.PetitionID); !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Start with the current state so late subscribers are in sync
+	c.SSEvent("snapshot", result.Job)
+	if jobFinished(result.Job) {
+		c.SSEvent(string(models.JobEventStatus), statusEvent(result.Job))
+		return
+	}
+
+	ticker := time.NewTicker(jobEventsKeepAlive)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-events:
+			c.SSEvent(string(event.Type), event)
+			return !event.Terminal()
+		case <-ticker.C:
+			current, err := h.draftService.GetJobStatus(c.Request.Context(), service.GetJobStatusRequest{
+				JobID: id,
+			})
+			if err == nil && jobFinished(current.Job) {
+				c.SSEvent(string(models.JobEventStatus), statusEvent(current.Job))
+				return false
+			}
+			c.SSEvent("ping", gin.H{"time": time.Now().UTC()})
+			return true
+		}
+	})
+}
+
+// jobFinished reports whether a job has reached a terminal status
+func jobFinished(job *models.GenerationJob) bool {
+	return statusEvent(job).Terminal()
+}
+
+// statusEvent builds the status event describing a job's current state
+func statusEvent(job *models.GenerationJob) models.JobEvent {
+	event := models.JobEvent{
+		JobID:  job.ID,
+		Type:   models.JobEventStatus,
+		Status: job.Status,
+		Steps:  job.Steps,
+	}
+	if job.ErrorMessage != nil {
+		event.Error = *job.ErrorMessage
+	}
+	return event
+}
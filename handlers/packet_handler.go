@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"meritdraft-backend/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreatePacketRequest represents the optional request body for building a packet
+type CreatePacketRequest struct {
+	BatesPrefix *string `json:"bates_prefix"` // Defaults to the client's surname
+}
+
+// CreatePacket handles POST /api/petitions/:id/packet, queueing a build of
+// the filing packet. Poll GET /api/petitions/:id/packet/:packet_id until it
+// completes; the bundled PDF is then a file of the petition.
+func (h *PetitionHandler) CreatePacket(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	var req CreatePacketRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	petition, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+	if !role.CanEdit() {
+		respondForbidden(c, "Your role does not allow saving files to this petition")
+		return
+	}
+
+	result, err := h.packetService.QueuePacket(c.Request.Context(), service.QueuePacketRequest{
+		Petition:    petition,
+		BatesPrefix: req.BatesPrefix,
+		ActorID:     currentUser(c).ID,
+	})
+	if errors.Is(err, service.ErrInvalidBatesPrefix) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_BATES_PREFIX",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if errors.Is(err, service.ErrUnknownVisaType) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "UNKNOWN_VISA_TYPE",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if errors.Is(err, service.ErrNothingToExport) {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "NOTHING_TO_EXPORT",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "PACKET_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    result.Packet,
+	})
+}
+
+// GetPacket handles GET /api/petitions/:id/packet/:packet_id
+func (h *PetitionHandler) GetPacket(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	packetID, err := uuid.Parse(c.Param("packet_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid packet ID format",
+			},
+		})
+		return
+	}
+
+	petition, _, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+
+	result, err := h.packetService.GetPacket(c.Request.Context(), service.GetPacketRequest{
+		Petition: petition,
+		PacketID: packetID,
+	})
+	if errors.Is(err, service.ErrPacketNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "PACKET_NOT_FOUND",
+				"message": "Packet not found",
+			},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "RETRIEVAL_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result.Packet,
+	})
+}
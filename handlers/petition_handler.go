@@ -1,12 +1,20 @@
 package handlers
 
 import (
-	"context"
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
-	"log"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/llm"
 	"meritdraft-backend/models"
+	"meritdraft-backend/repository"
 	"meritdraft-backend/service"
 
 	"github.com/gin-gonic/gin"
@@ -17,19 +25,25 @@ import (
 type PetitionHandler struct {
 	petitionService *service.PetitionService
 	draftService    *service.DraftService
+	jobEvents       *service.JobEventService
+	exhibitService  *service.ExhibitService
+	packetService   *service.PacketService
 }
 
 // NewPetitionHandler creates a new petition handler
-func NewPetitionHandler(petitionService *service.PetitionService, draftService *service.DraftService) *PetitionHandler {
+func NewPetitionHandler(petitionService *service.PetitionService, draftService *service.DraftService, jobEvents *service.JobEventService, exhibitService *service.ExhibitService, packetService *service.PacketService) *PetitionHandler {
 	return &PetitionHandler{
 		petitionService: petitionService,
 		draftService:    draftService,
+		jobEvents:       jobEvents,
+		exhibitService:  exhibitService,
+		packetService:   packetService,
 	}
 }
 
-// CreatePetitionRequest represents the request body for creating a petition
+// CreatePetitionRequest represents the request body for creating a petition.
+// The owner is always the authenticated caller.
 type CreatePetitionRequest struct {
-	UserID           string                 `json:"user_id" binding:"required"`
 	Status           string                 `json:"status"`
 	ClientName       string                 `json:"client_name"`
 	VisaType         string                 `json:"visa_type"`
@@ -37,6 +51,7 @@ type CreatePetitionRequest struct {
 	FieldOfExpertise string                 `json:"field_of_expertise"`
 	SelectedCriteria []string               `json:"selected_criteria"`
 	CriteriaDetails  map[string]interface{} `json:"criteria_details"`
+	FirmID           *string                `json:"firm_id"` // Optional, shares the petition with a firm
 }
 
 // CreatePetition handles POST /api/petitions
@@ -53,32 +68,92 @@ func (h *PetitionHandler) CreatePetition(c *gin.Context) {
 		return
 	}
 
-	userID, err := uuid.Parse(req.UserID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"error": gin.H{
-				"code":    "INVALID_USER_ID",
-				"message": "Invalid user_id format",
-			},
-		})
-		return
+	if req.VisaType != "" {
+		if _, ok := criteria.Lookup(req.VisaType); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_VISA_TYPE",
+					"message": "Unknown visa type",
+				},
+			})
+			return
+		}
+	}
+	if req.CriteriaDetails != nil {
+		// Validate against the schema served by GET /api/criteria/:visa_type/schema
+		visa, ok := criteria.Lookup(req.VisaType)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_VISA_TYPE",
+					"message": "Set the petition's visa type with its criteria_details",
+				},
+			})
+			return
+		}
+		fieldErrors := visa.Validate(req.CriteriaDetails)
+		if len(fieldErrors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_CRITERIA_DETAILS",
+					"message": "criteria_details failed validation",
+					"fields":  fieldErrors,
+				},
+			})
+			return
+		}
 	}
 
+	userID := currentUser(c).ID
+
 	var status models.PetitionStatus
 	if req.Status != "" {
 		status = models.PetitionStatus(req.Status)
+		if !status.Settable() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_STATUS",
+					"message": "status must be draft, in_progress or completed; archive a petition by deleting it",
+				},
+			})
+			return
+		}
 	} else {
 		status = models.StatusDraft
 	}
 
+	var firmID *uuid.UUID
+	if req.FirmID != nil && *req.FirmID != "" {
+		fid, err := uuid.Parse(*req.FirmID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_FIRM_ID",
+					"message": "Invalid firm_id format",
+				},
+			})
+			return
+		}
+		firmID = &fid
+	}
+
 	serviceReq := service.CreatePetitionRequest{
 		UserID: userID,
+		FirmID: firmID,
 		Status: status,
 	}
 
 	result, err := h.petitionService.CreatePetition(c.Request.Context(), serviceReq)
 	if err != nil {
+		if err == service.ErrForbidden {
+			respondForbidden(c, "You cannot create petitions for this firm")
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error": gin.H{
@@ -149,17 +224,46 @@ func (h *PetitionHandler) GetPetition(c *gin.Context) {
 		return
 	}
 
-	serviceReq := service.GetPetitionRequest{
-		ID: id,
+	petition, _, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
 	}
 
-	result, err := h.petitionService.GetPetition(c.Request.Context(), serviceReq)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    petition,
+	})
+}
+
+// ListSections handles GET /api/petitions/:id/sections, returning the
+// sections of the petition's draft in document order with their citations
+// and retrieved legal chunks. generated_content is their rendering.
+func (h *PetitionHandler) ListSections(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"error": gin.H{
-				"code":    "NOT_FOUND",
-				"message": "Petition not found",
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	if _, _, ok := h.loadAuthorizedPetition(c, id); !ok {
+		return
+	}
+
+	result, err := h.petitionService.ListSections(c.Request.Context(), service.ListSectionsRequest{
+		PetitionID: id,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "RETRIEVAL_FAILED",
+				"message": err.Error(),
 			},
 		})
 		return
@@ -167,30 +271,20 @@ func (h *PetitionHandler) GetPetition(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    result.Petition,
+		"data":    result.Sections,
 	})
 }
 
-// UpdatePetitionRequest represents the request body for updating a petition
-type UpdatePetitionRequest struct {
-	Status            string                 `json:"status"`
-	ClientName        string                 `json:"client_name"`
-	VisaType          string                 `json:"visa_type"`
-	PetitionerName    string                 `json:"petitioner_name"`
-	FieldOfExpertise  string                 `json:"field_of_expertise"`
-	CVFileID          *string                `json:"cv_file_id"`
-	JobOfferFileID    *string                `json:"job_offer_file_id"`
-	ScholarLink       *string                `json:"scholar_link"`
-	ParsedDocuments   map[string]interface{} `json:"parsed_documents"`
-	SelectedCriteria  []string               `json:"selected_criteria"`
-	CriteriaDetails   map[string]interface{} `json:"criteria_details"`
-	RefineInstructions *string               `json:"refine_instructions"`
+// UpdateSectionRequest represents the request body for editing a draft section
+type UpdateSectionRequest struct {
+	Title   *string `json:"title"`
+	Content *string `json:"content"`
 }
 
-// UpdatePetition handles PUT /api/petitions/:id
-func (h *PetitionHandler) UpdatePetition(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+// UpdateSection handles PUT /api/petitions/:id/sections/:section_id. The
+// petition's generated_content is rendered from the edited sections again.
+func (h *PetitionHandler) UpdateSection(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -202,23 +296,19 @@ func (h *PetitionHandler) UpdatePetition(c *gin.Context) {
 		return
 	}
 
-	// Get existing petition
-	getReq := service.GetPetitionRequest{ID: id}
-	result, err := h.petitionService.GetPetition(c.Request.Context(), getReq)
+	sectionID, err := uuid.Parse(c.Param("section_id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"error": gin.H{
-				"code":    "NOT_FOUND",
-				"message": "Petition not found",
+				"code":    "INVALID_ID",
+				"message": "Invalid section ID format",
 			},
 		})
 		return
 	}
 
-	petition := result.Petition
-
-	var req UpdatePetitionRequest
+	var req UpdateSectionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -230,66 +320,41 @@ func (h *PetitionHandler) UpdatePetition(c *gin.Context) {
 		return
 	}
 
-	// Update fields if provided
-	if req.Status != "" {
-		petition.Status = models.PetitionStatus(req.Status)
-	}
-	if req.ClientName != "" {
-		petition.ClientName = req.ClientName
-	}
-	if req.VisaType != "" {
-		petition.VisaType = models.VisaType(req.VisaType)
-	}
-	if req.PetitionerName != "" {
-		petition.PetitionerName = req.PetitionerName
-	}
-	if req.FieldOfExpertise != "" {
-		petition.FieldOfExpertise = req.FieldOfExpertise
-	}
-	if req.CVFileID != nil {
-		cvID, err := uuid.Parse(*req.CVFileID)
-		if err == nil {
-			petition.CVFileID = &cvID
-		}
-	}
-	if req.JobOfferFileID != nil {
-		jobID, err := uuid.Parse(*req.JobOfferFileID)
-		if err == nil {
-			petition.JobOfferFileID = &jobID
-		}
-	}
-	if req.ScholarLink != nil {
-		petition.ScholarLink = req.ScholarLink
-	}
-	if req.ParsedDocuments != nil {
-		pubCount, _ := req.ParsedDocuments["publicationsCount"].(float64)
-		citCount, _ := req.ParsedDocuments["citationsCount"].(float64)
-		petition.ParsedDocuments = &models.ParsedDocuments{
-			PublicationsCount: int(pubCount),
-			CitationsCount:    int(citCount),
-		}
-	}
-	if req.SelectedCriteria != nil {
-		petition.SelectedCriteria = req.SelectedCriteria
-	}
-	if req.CriteriaDetails != nil {
-		criteriaDetails := make(models.CriteriaDetails)
-		for k, v := range req.CriteriaDetails {
-			if detailMap, ok := v.(map[string]interface{}); ok {
-				criteriaDetails[k] = models.CriteriaDetail(detailMap)
-			}
-		}
-		petition.CriteriaDetails = criteriaDetails
+	petition, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
 	}
-	if req.RefineInstructions != nil {
-		petition.RefineInstructions = req.RefineInstructions
+	if !role.CanEdit() {
+		respondForbidden(c, "Your role does not allow editing this petition")
+		return
 	}
 
-	updateReq := service.UpdatePetitionRequest{
-		Petition: petition,
+	result, err := h.petitionService.UpdateSection(c.Request.Context(), service.UpdateSectionRequest{
+		Petition:  petition,
+		SectionID: sectionID,
+		Title:     req.Title,
+		Content:   req.Content,
+	})
+	if errors.Is(err, service.ErrSectionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "SECTION_NOT_FOUND",
+				"message": "Section not found",
+			},
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidSectionTitle) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_TITLE",
+				"message": err.Error(),
+			},
+		})
+		return
 	}
-
-	updateResult, err := h.petitionService.UpdatePetition(c.Request.Context(), updateReq)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -303,74 +368,185 @@ func (h *PetitionHandler) UpdatePetition(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    updateResult.Petition,
+		"data":    result.Section,
 	})
 }
 
-// GenerateDraft handles POST /api/petitions/:id/generate
-func (h *PetitionHandler) GenerateDraft(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+// loadAuthorizedPetition fetches a petition and resolves the caller's role on it.
+// It writes the error response and returns false when the caller may not proceed.
+func (h *PetitionHandler) loadAuthorizedPetition(c *gin.Context, id uuid.UUID) (*models.Petition, models.FirmRole, bool) {
+	result, err := h.petitionService.AuthorizePetition(c.Request.Context(), service.AuthorizePetitionRequest{
+		PetitionID: id,
+		UserID:     currentUser(c).ID,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"error": gin.H{
-				"code":    "INVALID_ID",
-				"message": "Invalid petition ID format",
-			},
-		})
+		switch err {
+		case service.ErrPetitionNotFound:
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "NOT_FOUND",
+					"message": "Petition not found",
+				},
+			})
+		case service.ErrForbidden:
+			respondForbidden(c, "You do not have access to this petition")
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "RETRIEVAL_FAILED",
+					"message": err.Error(),
+				},
+			})
+		}
+		return nil, "", false
+	}
+
+	return result.Petition, result.Role, true
+}
+
+// ListPetitions handles GET /api/petitions
+//
+// Query parameters: status, visa_type, client_name (substring), created_after,
+// created_before, updated_after, updated_before (RFC 3339 or YYYY-MM-DD),
+// sort (created_at, updated_at, client_name), order (asc, desc), limit, cursor.
+func (h *PetitionHandler) ListPetitions(c *gin.Context) {
+	req := service.ListPetitionsRequest{
+		UserID:             currentUser(c).ID,
+		ClientNameContains: strings.TrimSpace(c.Query("client_name")),
+		Cursor:             c.Query("cursor"),
+		SortDesc:           true,
+	}
+
+	if status := c.Query("status"); status != "" {
+		s := models.PetitionStatus(status)
+		req.Status = &s
+	}
+	if visaType := c.Query("visa_type"); visaType != "" {
+		v := models.VisaType(visaType)
+		req.VisaType = &v
+	}
+
+	switch sortBy := c.DefaultQuery("sort", "created_at"); sortBy {
+	case "created_at", "updated_at", "client_name":
+		req.SortBy = repository.PetitionSortField(sortBy)
+	default:
+		respondInvalidQuery(c, "sort must be one of created_at, updated_at, client_name")
 		return
 	}
 
-	var reqBody struct {
-		RefineInstructions *string `json:"refine_instructions"`
+	switch order := c.DefaultQuery("order", "desc"); order {
+	case "asc":
+		req.SortDesc = false
+	case "desc":
+		req.SortDesc = true
+	default:
+		respondInvalidQuery(c, "order must be asc or desc")
+		return
 	}
-	if err := c.ShouldBindJSON(&reqBody); err != nil && err != io.EOF {
-		// JSON is optional, ignore binding errors if body is empty
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			respondInvalidQuery(c, "limit must be a positive integer")
+			return
+		}
+		req.Limit = limit
 	}
 
-	serviceReq := service.GenerateDraftRequest{
-		PetitionID:         id,
-		RefineInstructions: reqBody.RefineInstructions,
+	dateParams := []struct {
+		name string
+		dest **time.Time
+	}{
+		{"created_after", &req.CreatedAfter},
+		{"created_before", &req.CreatedBefore},
+		{"updated_after", &req.UpdatedAfter},
+		{"updated_before", &req.UpdatedBefore},
+	}
+	for _, p := range dateParams {
+		value := c.Query(p.name)
+		if value == "" {
+			continue
+		}
+		t, err := parseDateParam(value)
+		if err != nil {
+			respondInvalidQuery(c, fmt.Sprintf("%s must be an RFC 3339 timestamp or YYYY-MM-DD date", p.name))
+			return
+		}
+		*p.dest = &t
 	}
 
-	// Create job (synchronous, fast)
-	result, err := h.draftService.GenerateDraft(c.Request.Context(), serviceReq)
+	result, err := h.petitionService.ListPetitions(c.Request.Context(), req)
 	if err != nil {
+		if err == service.ErrInvalidCursor {
+			respondInvalidQuery(c, "cursor is invalid or does not match the requested sort order")
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error": gin.H{
-				"code":    "GENERATION_FAILED",
+				"code":    "RETRIEVAL_FAILED",
 				"message": err.Error(),
 			},
 		})
 		return
 	}
 
-	// Spawn background goroutine for actual processing
-	// Use background context (not request context) to avoid cancellation
-	go func() {
-		bgCtx := context.Background()
-		if err := h.draftService.ProcessDraft(bgCtx, result.JobID); err != nil {
-			// Error is logged and stored in job.ErrorMessage
-			// No need to return to HTTP client (they'll poll status)
-			log.Printf("Generation job %s failed: %v", result.JobID, err)
-		}
-	}()
+	var nextCursor *string
+	if result.NextCursor != "" {
+		nextCursor = &result.NextCursor
+	}
 
-	// Return immediately (within 100ms)
-	c.JSON(http.StatusAccepted, gin.H{
+	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"job_id":  result.JobID,
-			"status":   "pending",
-			"message": "Generation job created. Poll /api/jobs/:id for updates.",
+			"petitions":   result.Petitions,
+			"total":       result.Total,
+			"next_cursor": nextCursor,
 		},
 	})
 }
 
-// GetJobStatus handles GET /api/jobs/:id
-func (h *PetitionHandler) GetJobStatus(c *gin.Context) {
+// parseDateParam accepts either a full RFC 3339 timestamp or a bare YYYY-MM-DD date
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// respondInvalidQuery writes the standard 400 response for a bad query parameter
+func respondInvalidQuery(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    "INVALID_QUERY",
+			"message": message,
+		},
+	})
+}
+
+// UpdatePetitionRequest represents the request body for updating a petition
+type UpdatePetitionRequest struct {
+	Status            string                 `json:"status"`
+	ClientName        string                 `json:"client_name"`
+	VisaType          string                 `json:"visa_type"`
+	PetitionerName    string                 `json:"petitioner_name"`
+	FieldOfExpertise  string                 `json:"field_of_expertise"`
+	CVFileID          *string                `json:"cv_file_id"`
+	JobOfferFileID    *string                `json:"job_offer_file_id"`
+	ScholarLink       *string                `json:"scholar_link"`
+	ParsedDocuments   map[string]interface{} `json:"parsed_documents"`
+	SelectedCriteria  []string               `json:"selected_criteria"`
+	CriteriaDetails   map[string]interface{} `json:"criteria_details"`
+	RefineInstructions *string               `json:"refine_instructions"`
+	FirmID            *string                `json:"firm_id"` // Empty string stops sharing
+	DraftingModel     *string                `json:"drafting_model"` // gemini, openai or fake; empty string uses the deployment default
+}
+
+// UpdatePetition handles PUT /api/petitions/:id
+func (h *PetitionHandler) UpdatePetition(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -378,7 +554,886 @@ func (h *PetitionHandler) GetJobStatus(c *gin.Context) {
 			"success": false,
 			"error": gin.H{
 				"code":    "INVALID_ID",
-				"message": "Invalid job ID format",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	// Get existing petition
+	petition, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+	if !role.CanEdit() {
+		respondForbidden(c, "Your role does not allow editing this petition")
+		return
+	}
+
+	var req UpdatePetitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	// Update fields if provided
+	if req.Status != "" {
+		if !models.PetitionStatus(req.Status).Settable() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_STATUS",
+					"message": "status must be draft, in_progress or completed; archive a petition by deleting it",
+				},
+			})
+			return
+		}
+		if models.PetitionStatus(req.Status) == models.StatusCompleted && !role.CanDraft() {
+			respondForbidden(c, "Only attorneys can mark a petition completed")
+			return
+		}
+		petition.Status = models.PetitionStatus(req.Status)
+	}
+	if req.FirmID != nil {
+		// Only the petition's creator decides which firm it is shared with
+		if petition.UserID != currentUser(c).ID {
+			respondForbidden(c, "Only the petition's creator can change firm sharing")
+			return
+		}
+		if *req.FirmID == "" {
+			petition.FirmID = nil
+		} else {
+			firmID, err := uuid.Parse(*req.FirmID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"error": gin.H{
+						"code":    "INVALID_FIRM_ID",
+						"message": "Invalid firm_id format",
+					},
+				})
+				return
+			}
+			firmRole, err := h.petitionService.GetFirmRole(c.Request.Context(), firmID, currentUser(c).ID)
+			if err != nil || !firmRole.CanEdit() {
+				respondForbidden(c, "You cannot share petitions with this firm")
+				return
+			}
+			petition.FirmID = &firmID
+		}
+	}
+	if req.ClientName != "" {
+		petition.ClientName = req.ClientName
+	}
+	if req.VisaType != "" {
+		if _, ok := criteria.Lookup(req.VisaType); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_VISA_TYPE",
+					"message": "Unknown visa type",
+				},
+			})
+			return
+		}
+		petition.VisaType = models.VisaType(req.VisaType)
+	}
+	if req.PetitionerName != "" {
+		petition.PetitionerName = req.PetitionerName
+	}
+	if req.FieldOfExpertise != "" {
+		petition.FieldOfExpertise = req.FieldOfExpertise
+	}
+	if req.CVFileID != nil {
+		cvID, ok := h.documentFileID(c, petition, "cv_file_id", *req.CVFileID)
+		if !ok {
+			return
+		}
+		petition.CVFileID = &cvID
+	}
+	if req.JobOfferFileID != nil {
+		jobID, ok := h.documentFileID(c, petition, "job_offer_file_id", *req.JobOfferFileID)
+		if !ok {
+			return
+		}
+		petition.JobOfferFileID = &jobID
+	}
+	if req.ScholarLink != nil {
+		petition.ScholarLink = req.ScholarLink
+	}
+	if req.ParsedDocuments != nil {
+		// Metrics left out of the request, such as an imported h-index, are kept
+		parsed := models.ParsedDocuments{}
+		if petition.ParsedDocuments != nil {
+			parsed = *petition.ParsedDocuments
+		}
+		if pubCount, ok := req.ParsedDocuments["publicationsCount"].(float64); ok {
+			parsed.PublicationsCount = int(pubCount)
+		}
+		if citCount, ok := req.ParsedDocuments["citationsCount"].(float64); ok {
+			parsed.CitationsCount = int(citCount)
+		}
+		if hIndex, ok := req.ParsedDocuments["hIndex"].(float64); ok {
+			parsed.HIndex = int(hIndex)
+		}
+		petition.ParsedDocuments = &parsed
+	}
+	if req.SelectedCriteria != nil {
+		petition.SelectedCriteria = req.SelectedCriteria
+	}
+	if req.CriteriaDetails != nil {
+		// Validate against the schema served by GET /api/criteria/:visa_type/schema
+		visa, ok := criteria.Lookup(string(petition.VisaType))
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_VISA_TYPE",
+					"message": "Set the petition's visa type before its criteria_details",
+				},
+			})
+			return
+		}
+		fieldErrors := visa.Validate(req.CriteriaDetails)
+		if len(fieldErrors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_CRITERIA_DETAILS",
+					"message": "criteria_details failed validation",
+					"fields":  fieldErrors,
+				},
+			})
+			return
+		}
+
+		criteriaDetails := make(models.CriteriaDetails)
+		for k, v := range req.CriteriaDetails {
+			if detailMap, ok := v.(map[string]interface{}); ok {
+				criteriaDetails[k] = models.CriteriaDetail(detailMap)
+			}
+		}
+		petition.CriteriaDetails = criteriaDetails
+	}
+	if req.RefineInstructions != nil {
+		petition.RefineInstructions = req.RefineInstructions
+	}
+	if req.DraftingModel != nil {
+		if *req.DraftingModel == "" {
+			petition.DraftingModel = nil
+		} else if !llm.ProviderType(*req.DraftingModel).Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_DRAFTING_MODEL",
+					"message": "drafting_model must be one of gemini, openai, fake",
+				},
+			})
+			return
+		} else {
+			petition.DraftingModel = req.DraftingModel
+		}
+	}
+
+	updateReq := service.UpdatePetitionRequest{
+		Petition: petition,
+	}
+
+	updateResult, err := h.petitionService.UpdatePetition(c.Request.Context(), updateReq)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "UPDATE_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updateResult.Petition,
+	})
+}
+
+// DeletePetition handles DELETE /api/petitions/:id
+//
+// By default the petition is archived (soft-deleted). With ?purge=true it is
+// also scheduled for permanent deletion of its files, blobs and generation
+// jobs once the retention window has elapsed; until then it can be restored.
+func (h *PetitionHandler) DeletePetition(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	purge, err := strconv.ParseBool(c.DefaultQuery("purge", "false"))
+	if err != nil {
+		respondInvalidQuery(c, "purge must be true or false")
+		return
+	}
+
+	_, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+	if purge && role != models.FirmRoleOwner {
+		respondForbidden(c, "Only the petition owner can purge a petition")
+		return
+	}
+	if !role.CanDraft() {
+		respondForbidden(c, "Only attorneys can archive petitions")
+		return
+	}
+
+	result, err := h.petitionService.ArchivePetition(c.Request.Context(), service.ArchivePetitionRequest{
+		PetitionID: id,
+		Purge:      purge,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "DELETE_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"id":          id,
+			"status":      models.StatusArchived,
+			"purge_after": result.PurgeAfter,
+		},
+	})
+}
+
+// RestorePetition handles POST /api/petitions/:id/restore
+func (h *PetitionHandler) RestorePetition(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	petition, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+	if !role.CanDraft() {
+		respondForbidden(c, "Only attorneys can restore petitions")
+		return
+	}
+	if petition.Status != models.StatusArchived {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "NOT_ARCHIVED",
+				"message": "Petition is not archived",
+			},
+		})
+		return
+	}
+
+	err = h.petitionService.RestorePetition(c.Request.Context(), service.RestorePetitionRequest{PetitionID: id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "RESTORE_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	petition, _, ok = h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    petition,
+	})
+}
+
+// GenerateDraft handles POST /api/petitions/:id/generate
+func (h *PetitionHandler) GenerateDraft(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	_, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+	if !role.CanDraft() {
+		respondForbidden(c, "Only attorneys can generate drafts")
+		return
+	}
+
+	var reqBody struct {
+		RefineInstructions *string `json:"refine_instructions"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil && err != io.EOF {
+		// JSON is optional, ignore binding errors if body is empty
+	}
+
+	serviceReq := service.GenerateDraftRequest{
+		PetitionID:         id,
+		RefineInstructions: reqBody.RefineInstructions,
+	}
+
+	// Create job (synchronous, fast)
+	result, err := h.draftService.GenerateDraft(c.Request.Context(), serviceReq)
+	var notReady *service.NotReadyError
+	if errors.As(err, &notReady) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":      "PETITION_NOT_READY",
+				"message":   "Petition is missing evidence required for drafting",
+				"readiness": notReady.Report,
+			},
+		})
+		return
+	}
+	if err == service.ErrDraftingModelUnavailable {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "DRAFTING_MODEL_UNAVAILABLE",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "GENERATION_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	// The job is queued as pending; a worker leases and processes it.
+	// Return immediately (within 100ms)
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data": gin.H{
+			"job_id":  result.JobID,
+			"status":   "pending",
+			"message": "Generation job created. Poll /api/jobs/:id for updates.",
+		},
+	})
+}
+
+// GetReadiness handles GET /api/petitions/:id/readiness
+//
+// Returns a per-criterion checklist of missing and weak evidence. Drafting
+// is refused with the same report while any issue is blocking.
+func (h *PetitionHandler) GetReadiness(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	if _, _, ok := h.loadAuthorizedPetition(c, id); !ok {
+		return
+	}
+
+	result, err := h.draftService.CheckReadiness(c.Request.Context(), service.CheckReadinessRequest{
+		PetitionID: id,
+	})
+	if err == service.ErrPetitionNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "NOT_FOUND",
+				"message": "Petition not found",
+			},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "RETRIEVAL_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result.Report,
+	})
+}
+
+// SuggestStrategy handles POST /api/petitions/:id/strategy
+//
+// Scores every criterion of the petition's visa type from its entered and
+// parsed evidence and the most similar winning appeal arguments, and
+// recommends at least three to argue. With {"apply": true} the
+// recommendation is saved as the petition's selected criteria.
+func (h *PetitionHandler) SuggestStrategy(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	_, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+
+	var reqBody struct {
+		Apply bool `json:"apply"`
+	}
+	if err := c.ShouldBindJSON(&reqBody); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if reqBody.Apply && !role.CanEdit() {
+		respondForbidden(c, "Your role does not allow editing this petition")
+		return
+	}
+
+	result, err := h.draftService.SuggestStrategy(c.Request.Context(), service.SuggestStrategyRequest{
+		PetitionID: id,
+		Apply:      reqBody.Apply,
+	})
+	if err == service.ErrPetitionNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "NOT_FOUND",
+				"message": "Petition not found",
+			},
+		})
+		return
+	}
+	if err == service.ErrStrategyNotApplicable {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "STRATEGY_NOT_APPLICABLE",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if errors.Is(err, service.ErrUnknownVisaType) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "UNKNOWN_VISA_TYPE",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "STRATEGY_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result.Report,
+	})
+}
+
+// maxScholarProfileSize bounds uploaded scholar profiles
+const maxScholarProfileSize = 10 * 1024 * 1024 // 10MB
+
+// ImportScholarProfile handles POST /api/petitions/:id/scholar
+//
+// Accepts a saved Google Scholar profile page, or a BibTeX or CSV export of
+// one, as the multipart "file" field. Its publications are merged into the
+// authorship criterion details and parsed_documents gets the publication
+// count, total citations and h-index.
+func (h *PetitionHandler) ImportScholarProfile(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	petition, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+	if !role.CanEdit() {
+		respondForbidden(c, "Your role does not allow editing this petition")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "MISSING_FILE",
+				"message": "File is required",
+			},
+		})
+		return
+	}
+	if fileHeader.Size > maxScholarProfileSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "FILE_TOO_LARGE",
+				"message": fmt.Sprintf("File size exceeds maximum of %d bytes", maxScholarProfileSize),
+			},
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "FILE_OPEN_ERROR",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxScholarProfileSize))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "FILE_OPEN_ERROR",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.petitionService.ImportScholarProfile(c.Request.Context(), service.ImportScholarProfileRequest{
+		Petition: petition,
+		Filename: fileHeader.Filename,
+		MimeType: fileHeader.Header.Get("Content-Type"),
+		Data:     data,
+	})
+	if errors.Is(err, service.ErrUnsupportedScholarProfile) || errors.Is(err, service.ErrEmptyScholarProfile) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_SCHOLAR_PROFILE",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "IMPORT_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"profile":            result.Profile,
+			"parsed_documents":   result.ParsedDocuments,
+			"authorship_updated": result.AuthorshipUpdated,
+			"publications_added": result.PublicationsAdded,
+		},
+	})
+}
+
+// ExportPetition handles GET /api/petitions/:id/export?format=docx|pdf. The
+// document is sent as an attachment. Placeholders that cite no tagged
+// exhibit are counted in the X-Unresolved-Exhibits header.
+func (h *PetitionHandler) ExportPetition(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	petition, _, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+
+	result, err := h.petitionService.ExportPetition(c.Request.Context(), service.ExportPetitionRequest{
+		Petition: petition,
+		Format:   strings.ToLower(c.DefaultQuery("format", service.ExportFormatDOCX)),
+	})
+	if err != nil {
+		respondExportError(c, err)
+		return
+	}
+
+	headers := map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": result.Filename}),
+	}
+	if result.Unresolved > 0 {
+		headers["X-Unresolved-Exhibits"] = strconv.Itoa(result.Unresolved)
+	}
+	c.DataFromReader(http.StatusOK, int64(len(result.Data)), result.MimeType, bytes.NewReader(result.Data), headers)
+}
+
+// SaveExportRequest represents the optional request body for saving an export
+type SaveExportRequest struct {
+	Format string `json:"format"` // docx (default) or pdf
+}
+
+// SaveExport handles POST /api/petitions/:id/export, storing the exported
+// document as a file of the petition. The file is listed and downloaded
+// like any upload.
+func (h *PetitionHandler) SaveExport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid petition ID format",
+			},
+		})
+		return
+	}
+
+	var req SaveExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	if req.Format == "" {
+		req.Format = service.ExportFormatDOCX
+	}
+
+	petition, role, ok := h.loadAuthorizedPetition(c, id)
+	if !ok {
+		return
+	}
+	if !role.CanEdit() {
+		respondForbidden(c, "Your role does not allow saving files to this petition")
+		return
+	}
+
+	result, err := h.petitionService.ExportPetition(c.Request.Context(), service.ExportPetitionRequest{
+		Petition: petition,
+		Format:   strings.ToLower(req.Format),
+		Save:     true,
+		UserID:   currentUser(c).ID,
+	})
+	if err != nil {
+		respondExportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data": gin.H{
+			"file":       result.File,
+			"unresolved": result.Unresolved,
+		},
+	})
+}
+
+// documentFileID parses the ID of a file to use as the petition's CV or job
+// offer and checks the caller may use it, writing the error response and
+// returning false when not
+func (h *PetitionHandler) documentFileID(c *gin.Context, petition *models.Petition, field, value string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_FILE_ID",
+				"message": "Invalid " + field + " format",
+			},
+		})
+		return uuid.Nil, false
+	}
+
+	err = h.petitionService.CheckDocumentFile(c.Request.Context(), service.CheckDocumentFileRequest{
+		Petition: petition,
+		FileID:   id,
+		UserID:   currentUser(c).ID,
+	})
+	if errors.Is(err, service.ErrDocumentFileNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_FILE_ID",
+				"message": field + ": " + err.Error(),
+			},
+		})
+		return uuid.Nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "UPDATE_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// respondExportError writes the response for a failed export
+func respondExportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnsupportedExportFormat):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_FORMAT",
+				"message": err.Error(),
+			},
+		})
+	case errors.Is(err, service.ErrNothingToExport):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "NOTHING_TO_EXPORT",
+				"message": err.Error(),
+			},
+		})
+	case errors.Is(err, service.ErrUnsupportedCharacter):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "UNSUPPORTED_CHARACTER",
+				"message": err.Error(),
+			},
+		})
+	case errors.Is(err, service.ErrUnknownVisaType):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "UNKNOWN_VISA_TYPE",
+				"message": err.Error(),
+			},
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "EXPORT_FAILED",
+				"message": err.Error(),
+			},
+		})
+	}
+}
+
+// GetJobStatus handles GET /api/jobs/:id
+func (h *PetitionHandler) GetJobStatus(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid job ID format",
 			},
 		})
 		return
@@ -410,9 +1465,187 @@ func (h *PetitionHandler) GetJobStatus(c *gin.Context) {
 		return
 	}
 
+	if _, _, ok := h.loadAuthorizedPetition(c, result.Job.PetitionID); !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result.Job,
+	})
+}
+
+
+// CancelJob handles POST /api/jobs/:id/cancel
+func (h *PetitionHandler) CancelJob(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid job ID format",
+			},
+		})
+		return
+	}
+
+	status, err := h.draftService.GetJobStatus(c.Request.Context(), service.GetJobStatusRequest{
+		JobID: id,
+	})
+	if err != nil {
+		if err == service.ErrJobNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "NOT_FOUND",
+					"message": "Generation job not found",
+				},
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "RETRIEVAL_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	_, role, ok := h.loadAuthorizedPetition(c, status.Job.PetitionID)
+	if !ok {
+		return
+	}
+	if !role.CanDraft() {
+		respondForbidden(c, "Only attorneys can cancel generation jobs")
+		return
+	}
+
+	result, err := h.draftService.CancelJob(c.Request.Context(), service.CancelJobRequest{
+		JobID: id,
+	})
+	if err != nil {
+		switch err {
+		case service.ErrJobNotFound:
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "NOT_FOUND",
+					"message": "Generation job not found",
+				},
+			})
+		case service.ErrJobNotCancellable:
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "JOB_FINISHED",
+					"message": "Generation job has already finished",
+				},
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "CANCEL_FAILED",
+					"message": err.Error(),
+				},
+			})
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    result.Job,
 	})
 }
 
+// RetryJob handles POST /api/jobs/:id/retry
+func (h *PetitionHandler) RetryJob(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INVALID_ID",
+				"message": "Invalid job ID format",
+			},
+		})
+		return
+	}
+
+	status, err := h.draftService.GetJobStatus(c.Request.Context(), service.GetJobStatusRequest{
+		JobID: id,
+	})
+	if err != nil {
+		if err == service.ErrJobNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "NOT_FOUND",
+					"message": "Generation job not found",
+				},
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "RETRIEVAL_FAILED",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+
+	_, role, ok := h.loadAuthorizedPetition(c, status.Job.PetitionID)
+	if !ok {
+		return
+	}
+	if !role.CanDraft() {
+		respondForbidden(c, "Only attorneys can retry generation jobs")
+		return
+	}
+
+	result, err := h.draftService.RetryJob(c.Request.Context(), service.RetryJobRequest{
+		JobID: id,
+	})
+	if err != nil {
+		switch err {
+		case service.ErrJobNotFound:
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "NOT_FOUND",
+					"message": "Generation job not found",
+				},
+			})
+		case service.ErrJobNotRetryable:
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "JOB_NOT_RETRYABLE",
+					"message": "Only failed or cancelled generation jobs can be retried",
+				},
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "RETRY_FAILED",
+					"message": err.Error(),
+				},
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    result.Job,
+	})
+}
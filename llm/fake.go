@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+)
+
+const fakeDefaultDimensions = 768
+
+// FakeClient is a deterministic, offline TextGenerator and Embedder for
+// tests and local development. The same input always yields the same output.
+type FakeClient struct{}
+
+// NewFakeClient creates a fake LLM client
+func NewFakeClient() *FakeClient {
+	return &FakeClient{}
+}
+
+// Generate returns placeholder paragraphs derived from the prompt
+func (f *FakeClient) Generate(ctx context.Context, req GenerateRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return "", ErrEmptyResponse
+	}
+
+	digest := fakeHash(req.SystemInstruction + "\x00" + req.Prompt)
+	subject := firstLine(req.Prompt)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "This section was produced by the offline fake model (ref %016x).\n\n", digest)
+	fmt.Fprintf(&b, "It responds to the instruction: %s\n\n", subject)
+	b.WriteString("The evidence in the record satisfies the regulatory standard by a preponderance of the evidence.")
+	return b.String(), nil
+}
+
+// GenerateStream delivers the Generate output word by word
+func (f *FakeClient) GenerateStream(ctx context.Context, req GenerateRequest, onDelta func(string)) (string, error) {
+	text, err := f.Generate(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	if onDelta != nil {
+		words := strings.SplitAfter(text, " ")
+		for _, word := range words {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			onDelta(word)
+		}
+	}
+	return text, nil
+}
+
+// Embed returns a pseudo-random vector seeded by the text
+func (f *FakeClient) Embed(ctx context.Context, req EmbedRequest) ([]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	dims := req.Dimensions
+	if dims <= 0 {
+		dims = fakeDefaultDimensions
+	}
+
+	rng := rand.New(rand.NewSource(int64(fakeHash(string(req.TaskType) + "\x00" + req.Text))))
+	vector := make([]float64, dims)
+	for i := range vector {
+		vector[i] = rng.Float64()*2 - 1
+	}
+	return vector, nil
+}
+
+// fakeHash hashes s with 64-bit FNV-1a
+func fakeHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// firstLine returns the first non-empty line of s, shortened for display
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if runes := []rune(line); len(runes) > 120 {
+			line = string(runes[:120]) + "..."
+		}
+		return line
+	}
+	return ""
+}
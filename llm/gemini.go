@@ -0,0 +1,348 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	geminiGenerationAPI       = "https://generativelanguage.googleapis.com/v1beta/models/gemini-3-pro-preview:generateContent"
+	geminiStreamGenerationAPI = "https://generativelanguage.googleapis.com/v1beta/models/gemini-3-pro-preview:streamGenerateContent?alt=sse"
+	geminiEmbeddingAPI        = "https://generativelanguage.googleapis.com/v1beta/models/gemini-embedding-001:embedContent"
+	geminiEmbeddingModel      = "models/gemini-embedding-001"
+
+	embedMaxRetries     = 3
+	embedInitialBackoff = time.Second
+)
+
+// GeminiClient calls the Gemini REST API for generation and embeddings
+type GeminiClient struct {
+	apiKey string
+}
+
+// NewGeminiClient creates a Gemini client using the given API key
+func NewGeminiClient(apiKey string) *GeminiClient {
+	if apiKey == "" {
+		log.Println("Warning: GEMINI_API_KEY not set")
+	}
+	return &GeminiClient{apiKey: apiKey}
+}
+
+// geminiResponse is one response (or one streamed chunk) of generateContent
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+		FinishReason string `json:"finishReason,omitempty"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason,omitempty"`
+	} `json:"promptFeedback,omitempty"`
+	Error struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"error,omitempty"`
+}
+
+// newGenerateRequest builds an HTTP request for a generateContent endpoint
+func (c *GeminiClient) newGenerateRequest(ctx context.Context, url string, req GenerateRequest) (*http.Request, error) {
+	if c.apiKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	prompt := req.Prompt
+	if req.SystemInstruction != "" {
+		prompt = req.SystemInstruction + "\n\n" + req.Prompt
+	}
+
+	reqBody := map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"parts": []map[string]interface{}{
+					{"text": prompt},
+				},
+			},
+		},
+		"generationConfig": map[string]interface{}{
+			"temperature": req.Temperature,
+			// No maxOutputTokens limit - let the model use its default maximum
+		},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", c.apiKey)
+	return httpReq, nil
+}
+
+// Generate calls generateContent and returns the full response text
+func (c *GeminiClient) Generate(ctx context.Context, req GenerateRequest) (string, error) {
+	httpReq, err := c.newGenerateRequest(ctx, geminiGenerationAPI, req)
+	if err != nil {
+		return "", err
+	}
+
+	client := &http.Client{Timeout: 120 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("Gemini API error: Status %d, Body: %s", resp.StatusCode, string(bodyBytes))
+		return "", fmt.Errorf("API error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var apiResp geminiResponse
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
+		log.Printf("Failed to decode response. Body: %s", string(bodyBytes))
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Check for API errors in response
+	if apiResp.Error.Message != "" {
+		return "", fmt.Errorf("API error: %s (code: %d)", apiResp.Error.Message, apiResp.Error.Code)
+	}
+
+	if apiResp.PromptFeedback.BlockReason != "" {
+		return "", fmt.Errorf("%w: %s", ErrPromptBlocked, apiResp.PromptFeedback.BlockReason)
+	}
+
+	if len(apiResp.Candidates) == 0 {
+		log.Printf("API returned no candidates. Full response: %s", string(bodyBytes))
+		return "", fmt.Errorf("%w: no candidates", ErrEmptyResponse)
+	}
+
+	var responseText strings.Builder
+	for i, candidate := range apiResp.Candidates {
+		// Log finish reason if present (e.g., SAFETY, RECITATION)
+		if candidate.FinishReason != "" && candidate.FinishReason != "STOP" {
+			log.Printf("Warning: Candidate %d finished with reason: %s", i, candidate.FinishReason)
+		}
+
+		// Check if content exists and has parts
+		if len(candidate.Content.Parts) == 0 {
+			candidateJSON, _ := json.Marshal(candidate)
+			log.Printf("Error: Candidate %d has no parts. Candidate structure: %s", i, string(candidateJSON))
+			log.Printf("Full API response (first 1000 chars): %s", string(bodyBytes[:min(1000, len(bodyBytes))]))
+			return "", fmt.Errorf("%w: candidate has no parts (finish reason: %s)", ErrEmptyResponse, candidate.FinishReason)
+		}
+
+		for j, part := range candidate.Content.Parts {
+			if part.Text == "" {
+				log.Printf("Warning: Candidate %d, part %d has empty text", i, j)
+				continue // Skip empty parts but don't fail
+			}
+			responseText.WriteString(part.Text)
+		}
+	}
+
+	result := responseText.String()
+	if result == "" {
+		return "", ErrEmptyResponse
+	}
+
+	return result, nil
+}
+
+// GenerateStream calls streamGenerateContent, passing each piece of text to
+// onDelta as it arrives, and returns the full text
+func (c *GeminiClient) GenerateStream(ctx context.Context, req GenerateRequest, onDelta func(string)) (string, error) {
+	ctx, touch, cancel := withIdleTimeout(ctx)
+	defer cancel()
+
+	httpReq, err := c.newGenerateRequest(ctx, geminiStreamGenerationAPI, req)
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	// No client timeout: a long draft streams for minutes. The caller's
+	// context bounds the call, and a stream that stalls is abandoned.
+	client := &http.Client{}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", streamError(ctx, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result strings.Builder
+	var finishReason string
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		touch()
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" {
+			continue
+		}
+
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error.Message != "" {
+			return "", fmt.Errorf("API error: %s (code: %d)", chunk.Error.Message, chunk.Error.Code)
+		}
+		if chunk.PromptFeedback.BlockReason != "" {
+			return "", fmt.Errorf("%w: %s", ErrPromptBlocked, chunk.PromptFeedback.BlockReason)
+		}
+
+		for _, candidate := range chunk.Candidates {
+			if candidate.FinishReason != "" {
+				finishReason = candidate.FinishReason
+			}
+			for _, part := range candidate.Content.Parts {
+				if part.Text == "" {
+					continue
+				}
+				result.WriteString(part.Text)
+				if onDelta != nil {
+					onDelta(part.Text)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read stream: %w", streamError(ctx, err))
+	}
+
+	if finishReason != "" && finishReason != "STOP" {
+		log.Printf("Warning: Streamed generation finished with reason: %s", finishReason)
+	}
+	if result.Len() == 0 {
+		return "", fmt.Errorf("%w (finish reason: %s)", ErrEmptyResponse, finishReason)
+	}
+
+	return result.String(), nil
+}
+
+// geminiEmbedRequest is the embedContent request body
+type geminiEmbedRequest struct {
+	Model   string `json:"model"`
+	Content struct {
+		Parts []struct {
+			Text string `json:"text"`
+		} `json:"parts"`
+	} `json:"content"`
+	TaskType             string `json:"task_type,omitempty"`
+	OutputDimensionality int    `json:"output_dimensionality,omitempty"`
+}
+
+// geminiEmbedResponse is the embedContent response body
+type geminiEmbedResponse struct {
+	Embedding struct {
+		Values []float64 `json:"values"`
+	} `json:"embedding"`
+}
+
+// Embed calls embedContent, retrying transient failures
+func (c *GeminiClient) Embed(ctx context.Context, req EmbedRequest) ([]float64, error) {
+	if c.apiKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	reqBody := geminiEmbedRequest{
+		Model:                geminiEmbeddingModel,
+		TaskType:             string(req.TaskType),
+		OutputDimensionality: req.Dimensions,
+	}
+	reqBody.Content.Parts = []struct {
+		Text string `json:"text"`
+	}{{Text: req.Text}}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	backoff := embedInitialBackoff
+	for attempt := 0; attempt < embedMaxRetries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", geminiEmbeddingAPI, bytes.NewBuffer(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("x-goog-api-key", c.apiKey)
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			if attempt == embedMaxRetries-1 {
+				return nil, fmt.Errorf("failed to send request after %d attempts: %w", embedMaxRetries, err)
+			}
+			continue
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			var apiResp geminiEmbedResponse
+			err := json.NewDecoder(resp.Body).Decode(&apiResp)
+			resp.Body.Close()
+			if err != nil {
+				if attempt == embedMaxRetries-1 {
+					return nil, fmt.Errorf("failed to decode response: %w", err)
+				}
+				continue
+			}
+			return apiResp.Embedding.Values, nil
+		}
+
+		resp.Body.Close()
+
+		// Don't retry on 400 or 401 errors
+		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("API error: %d", resp.StatusCode)
+		}
+
+		if attempt == embedMaxRetries-1 {
+			return nil, fmt.Errorf("API error after %d attempts: %d", embedMaxRetries, resp.StatusCode)
+		}
+	}
+
+	return nil, ErrEmptyResponse
+}
@@ -0,0 +1,187 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// GenerateRequest is a single-turn text generation request
+type GenerateRequest struct {
+	SystemInstruction string // Role and tone guidance for the model
+	Prompt            string
+	Temperature       float64
+}
+
+// TextGenerator produces text from a prompt
+type TextGenerator interface {
+	// Generate returns the complete generated text
+	Generate(ctx context.Context, req GenerateRequest) (string, error)
+}
+
+// StreamingTextGenerator is a TextGenerator that can also deliver text
+// incrementally as the model produces it
+type StreamingTextGenerator interface {
+	TextGenerator
+
+	// GenerateStream passes each piece of text to onDelta as it arrives and
+	// returns the complete text
+	GenerateStream(ctx context.Context, req GenerateRequest, onDelta func(string)) (string, error)
+}
+
+// EmbedTaskType tells the embedding model how the vector will be used
+type EmbedTaskType string
+
+const (
+	EmbedTaskRetrievalQuery    EmbedTaskType = "RETRIEVAL_QUERY"
+	EmbedTaskRetrievalDocument EmbedTaskType = "RETRIEVAL_DOCUMENT"
+)
+
+// EmbedRequest is a request to embed one piece of text
+type EmbedRequest struct {
+	Text       string
+	TaskType   EmbedTaskType
+	Dimensions int // Must match the dimension of the stored legal chunk vectors
+}
+
+// Embedder turns text into a vector for similarity search
+type Embedder interface {
+	// Embed returns the raw (unnormalized) embedding of the text
+	Embed(ctx context.Context, req EmbedRequest) ([]float64, error)
+}
+
+var (
+	ErrMissingAPIKey = errors.New("llm API key not set")
+	ErrPromptBlocked = errors.New("prompt blocked by provider safety filters")
+	ErrEmptyResponse = errors.New("provider returned empty content")
+	ErrStreamStalled = errors.New("provider stream stalled")
+)
+
+// streamIdleTimeout is how long a streamed response may go without sending
+// a line. Streams have no overall timeout, since a long draft streams for
+// minutes.
+const streamIdleTimeout = 120 * time.Second
+
+// withIdleTimeout returns a context that is cancelled with ErrStreamStalled
+// unless touch is called at least every streamIdleTimeout
+func withIdleTimeout(ctx context.Context) (_ context.Context, touch func(), cancel func()) {
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(streamIdleTimeout, func() { cancelCause(ErrStreamStalled) })
+	touch = func() { timer.Reset(streamIdleTimeout) }
+	cancel = func() {
+		timer.Stop()
+		cancelCause(nil)
+	}
+	return ctx, touch, cancel
+}
+
+// streamError reports a stream cut off by withIdleTimeout as
+// ErrStreamStalled rather than as a cancelled context
+func streamError(ctx context.Context, err error) error {
+	if errors.Is(context.Cause(ctx), ErrStreamStalled) {
+		return ErrStreamStalled
+	}
+	return err
+}
+
+// ProviderType represents the LLM backend
+type ProviderType string
+
+const (
+	ProviderGemini ProviderType = "gemini"
+	ProviderOpenAI ProviderType = "openai" // Any OpenAI-compatible chat completions API
+	ProviderFake   ProviderType = "fake"
+)
+
+// Valid reports whether p is a known provider
+func (p ProviderType) Valid() bool {
+	switch p {
+	case ProviderGemini, ProviderOpenAI, ProviderFake:
+		return true
+	}
+	return false
+}
+
+// Config holds configuration for the LLM backends
+type Config struct {
+	Provider     ProviderType // Default drafting model
+	GeminiAPIKey string       // For the Gemini provider and embeddings
+
+	OpenAIBaseURL string // For the OpenAI-compatible provider
+	OpenAIAPIKey  string
+	OpenAIModel   string
+}
+
+// New creates the default text generator and the embedder. Retrieval always
+// embeds with Gemini (or the fake) because the stored legal chunk vectors
+// come from the Gemini embedding model.
+func New(cfg Config) (TextGenerator, Embedder, error) {
+	generator, err := NewGenerator(cfg, cfg.Provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch g := generator.(type) {
+	case *FakeClient:
+		return g, g, nil
+	case *GeminiClient:
+		return g, g, nil
+	}
+	return generator, NewGeminiClient(cfg.GeminiAPIKey), nil
+}
+
+// NewGenerator creates the text generator for one provider
+func NewGenerator(cfg Config, provider ProviderType) (TextGenerator, error) {
+	switch provider {
+	case ProviderGemini:
+		return NewGeminiClient(cfg.GeminiAPIKey), nil
+	case ProviderOpenAI:
+		return NewOpenAIClient(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, cfg.OpenAIModel)
+	case ProviderFake:
+		return NewFakeClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", provider)
+	}
+}
+
+// NewGenerators creates a generator for every configured provider, so
+// individual petitions can choose a drafting model other than the default
+func NewGenerators(cfg Config) map[ProviderType]TextGenerator {
+	generators := map[ProviderType]TextGenerator{}
+	if cfg.GeminiAPIKey != "" || cfg.Provider == ProviderGemini {
+		generators[ProviderGemini] = NewGeminiClient(cfg.GeminiAPIKey)
+	}
+	if cfg.OpenAIBaseURL != "" {
+		if client, err := NewOpenAIClient(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, cfg.OpenAIModel); err == nil {
+			generators[ProviderOpenAI] = client
+		}
+	}
+	if cfg.Provider == ProviderFake {
+		generators[ProviderFake] = NewFakeClient()
+	}
+	return generators
+}
+
+// ConfigFromEnv reads LLM configuration from environment variables
+func ConfigFromEnv() Config {
+	provider := os.Getenv("LLM_PROVIDER")
+	if provider == "" {
+		provider = string(ProviderGemini)
+	}
+
+	return Config{
+		Provider:      ProviderType(provider),
+		GeminiAPIKey:  os.Getenv("GEMINI_API_KEY"),
+		OpenAIBaseURL: os.Getenv("OPENAI_BASE_URL"),
+		OpenAIAPIKey:  os.Getenv("OPENAI_API_KEY"),
+		OpenAIModel:   os.Getenv("OPENAI_MODEL"),
+	}
+}
+
+// NewFromEnv creates the default text generator and the embedder from
+// environment variables
+func NewFromEnv() (TextGenerator, Embedder, error) {
+	return New(ConfigFromEnv())
+}
@@ -0,0 +1,260 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIClient calls an OpenAI-compatible chat completions endpoint, such as
+// a self-hosted vLLM, Ollama or LiteLLM server
+type OpenAIClient struct {
+	baseURL string // e.g. http://localhost:8000/v1
+	apiKey  string // Optional for self-hosted servers
+	model   string
+}
+
+// NewOpenAIClient creates a client for an OpenAI-compatible API
+func NewOpenAIClient(baseURL, apiKey, model string) (*OpenAIClient, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("OpenAI-compatible base URL not set")
+	}
+	if model == "" {
+		return nil, fmt.Errorf("OpenAI-compatible model not set")
+	}
+
+	return &OpenAIClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+	}, nil
+}
+
+// openAIMessage is a chat message
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIError is the error object returned by OpenAI-compatible servers
+type openAIError struct {
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Code    interface{} `json:"code"` // String on OpenAI, sometimes numeric elsewhere
+}
+
+// openAIResponse is a chat completion response or streamed chunk
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+			Refusal string `json:"refusal,omitempty"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+// newChatRequest maps a GenerateRequest onto a chat completions request
+func (c *OpenAIClient) newChatRequest(ctx context.Context, req GenerateRequest, stream bool) (*http.Request, error) {
+	messages := make([]openAIMessage, 0, 2)
+	if req.SystemInstruction != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: req.SystemInstruction})
+	}
+	messages = append(messages, openAIMessage{Role: "user", Content: req.Prompt})
+
+	reqBody := map[string]interface{}{
+		"model":       c.model,
+		"messages":    messages,
+		"temperature": req.Temperature,
+		"stream":      stream,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	return httpReq, nil
+}
+
+// Generate sends a chat completion request and returns the reply text
+func (c *OpenAIClient) Generate(ctx context.Context, req GenerateRequest) (string, error) {
+	httpReq, err := c.newChatRequest(ctx, req, false)
+	if err != nil {
+		return "", err
+	}
+
+	client := &http.Client{Timeout: 120 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	var apiResp openAIResponse
+	decodeErr := json.Unmarshal(bodyBytes, &apiResp)
+
+	if resp.StatusCode != http.StatusOK {
+		if decodeErr == nil && apiResp.Error != nil {
+			return "", apiResp.Error.normalize(resp.StatusCode)
+		}
+		log.Printf("OpenAI-compatible API error: Status %d, Body: %s", resp.StatusCode, string(bodyBytes))
+		return "", fmt.Errorf("API error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+	if decodeErr != nil {
+		log.Printf("Failed to decode response. Body: %s", string(bodyBytes))
+		return "", fmt.Errorf("failed to decode response: %w", decodeErr)
+	}
+	if apiResp.Error != nil {
+		return "", apiResp.Error.normalize(resp.StatusCode)
+	}
+
+	if len(apiResp.Choices) == 0 {
+		return "", fmt.Errorf("%w: no choices", ErrEmptyResponse)
+	}
+
+	choice := apiResp.Choices[0]
+	if err := normalizeFinishReason(choice.FinishReason); err != nil {
+		return "", err
+	}
+	if choice.Message.Refusal != "" {
+		return "", fmt.Errorf("%w: %s", ErrPromptBlocked, choice.Message.Refusal)
+	}
+	if strings.TrimSpace(choice.Message.Content) == "" {
+		return "", fmt.Errorf("%w (finish reason: %s)", ErrEmptyResponse, choice.FinishReason)
+	}
+
+	return choice.Message.Content, nil
+}
+
+// GenerateStream streams a chat completion, passing each piece of text to
+// onDelta as it arrives, and returns the full text
+func (c *OpenAIClient) GenerateStream(ctx context.Context, req GenerateRequest, onDelta func(string)) (string, error) {
+	ctx, touch, cancel := withIdleTimeout(ctx)
+	defer cancel()
+
+	httpReq, err := c.newChatRequest(ctx, req, true)
+	if err != nil {
+		return "", err
+	}
+
+	// No client timeout: a long draft streams for minutes. The caller's
+	// context bounds the call, and a stream that stalls is abandoned.
+	client := &http.Client{}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", streamError(ctx, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		var apiResp openAIResponse
+		if json.Unmarshal(bodyBytes, &apiResp) == nil && apiResp.Error != nil {
+			return "", apiResp.Error.normalize(resp.StatusCode)
+		}
+		return "", fmt.Errorf("API error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result strings.Builder
+	var finishReason string
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		touch()
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return "", chunk.Error.normalize(http.StatusOK)
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			result.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read stream: %w", streamError(ctx, err))
+	}
+
+	if err := normalizeFinishReason(finishReason); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(result.String()) == "" {
+		return "", fmt.Errorf("%w (finish reason: %s)", ErrEmptyResponse, finishReason)
+	}
+
+	return result.String(), nil
+}
+
+// normalizeFinishReason maps chat completion finish reasons onto the errors
+// the Gemini client returns for the equivalent outcomes
+func normalizeFinishReason(reason string) error {
+	switch reason {
+	case "", "stop":
+		return nil
+	case "content_filter":
+		return fmt.Errorf("%w: content_filter", ErrPromptBlocked)
+	default:
+		// "length" and others still carry usable text, as with Gemini's MAX_TOKENS
+		log.Printf("Warning: Chat completion finished with reason: %s", reason)
+		return nil
+	}
+}
+
+// normalize converts an API error object into an error, mapping safety
+// rejections onto ErrPromptBlocked
+func (e *openAIError) normalize(status int) error {
+	code := fmt.Sprint(e.Code)
+	if code == "content_filter" || code == "content_policy_violation" || e.Type == "content_filter" {
+		return fmt.Errorf("%w: %s", ErrPromptBlocked, e.Message)
+	}
+	return fmt.Errorf("API error: %s (code: %d)", e.Message, status)
+}
@@ -0,0 +1,241 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// Typed views of CriteriaDetail for the O-1A and EB-1A criteria. Field
+// names match the facts in the criteria registry, which validates the
+// details before they are stored; criterion_facts_test.go checks that they
+// still do.
+
+// Significance holds the optional notes on why evidence matters that every
+// criterion accepts
+type Significance struct {
+	Importance string `json:"importance,omitempty"`
+	Impact     string `json:"impact,omitempty"`
+}
+
+// Award is a prize or award
+type Award struct {
+	Name        string `json:"name"`
+	Date        string `json:"date,omitempty"`
+	Description string `json:"description,omitempty"`
+	Importance  string `json:"importance,omitempty"`
+	Impact      string `json:"impact,omitempty"`
+}
+
+// AwardsFacts holds the facts of the awards criterion
+type AwardsFacts struct {
+	Awards      []Award `json:"awards"`
+	Description string  `json:"description,omitempty"`
+	Significance
+}
+
+// Association is a membership in an association in the field
+type Association struct {
+	Name                   string `json:"name"`
+	MembershipRequirements string `json:"membership_requirements,omitempty"`
+	Date                   string `json:"date,omitempty"`
+	Description            string `json:"description,omitempty"`
+}
+
+// MembershipFacts holds the facts of the membership criterion
+type MembershipFacts struct {
+	Associations []Association `json:"associations,omitempty"`
+	Description  string        `json:"description,omitempty"`
+	Significance
+}
+
+// MediaArticle is published material about the client
+type MediaArticle struct {
+	Title       string `json:"title"`
+	Publication string `json:"publication"`
+	Author      string `json:"author,omitempty"`
+	Date        string `json:"date,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// MediaCoverageFacts holds the facts of the media coverage criterion
+type MediaCoverageFacts struct {
+	Articles    []MediaArticle `json:"articles,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Significance
+}
+
+// JudgingFacts holds the facts of the judging criterion
+type JudgingFacts struct {
+	Venue          string `json:"venue"`
+	Role           string `json:"role"`
+	PapersReviewed *int   `json:"papers_reviewed,omitempty"`
+	Description    string `json:"description,omitempty"`
+	Significance
+}
+
+// Contribution is an original contribution to the field
+type Contribution struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Impact      string `json:"impact,omitempty"`
+	Evidence    string `json:"evidence,omitempty"`
+}
+
+// OriginalContributionsFacts holds the facts of the original contributions criterion
+type OriginalContributionsFacts struct {
+	Contributions []Contribution `json:"contributions,omitempty"`
+	Description   string         `json:"description,omitempty"`
+	Significance
+}
+
+// Publication is a scholarly article
+type Publication struct {
+	Title        string   `json:"title"`
+	Journal      string   `json:"journal,omitempty"`
+	Year         *int     `json:"year,omitempty"`
+	ImpactFactor *float64 `json:"impact_factor,omitempty"`
+	Citations    *int     `json:"citations,omitempty"`
+	Importance   string   `json:"importance,omitempty"`
+	Impact       string   `json:"impact,omitempty"`
+}
+
+// AuthorshipFacts holds the facts of the authorship criterion
+type AuthorshipFacts struct {
+	Publications []Publication `json:"publications"`
+	Description  string        `json:"description,omitempty"`
+	Significance
+}
+
+// Exhibition is a display of the client's work
+type Exhibition struct {
+	Name        string `json:"name"`
+	Venue       string `json:"venue,omitempty"`
+	Date        string `json:"date,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ExhibitionsFacts holds the facts of the exhibitions criterion
+type ExhibitionsFacts struct {
+	Exhibitions []Exhibition `json:"exhibitions,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Significance
+}
+
+// Role is a leading or critical role for an organization
+type Role struct {
+	Organization           string `json:"organization"`
+	Title                  string `json:"title,omitempty"`
+	StartDate              string `json:"start_date,omitempty"`
+	EndDate                string `json:"end_date,omitempty"`
+	Description            string `json:"description,omitempty"`
+	OrganizationReputation string `json:"organization_reputation,omitempty"`
+}
+
+// CriticalRoleFacts holds the facts of the critical role criterion
+type CriticalRoleFacts struct {
+	Roles       []Role `json:"roles,omitempty"`
+	Description string `json:"description,omitempty"`
+	Significance
+}
+
+// HighSalaryFacts holds the facts of the high salary criterion
+type HighSalaryFacts struct {
+	Salary           *float64 `json:"salary,omitempty"`
+	Currency         string   `json:"currency,omitempty"`
+	Period           string   `json:"period,omitempty"` // e.g. "annual", "hourly"
+	ComparisonSalary *float64 `json:"comparison_salary,omitempty"`
+	ComparisonSource string   `json:"comparison_source,omitempty"` // e.g. BLS OEWS, industry survey
+	Description      string   `json:"description,omitempty"`
+	Significance
+}
+
+// CommercialSuccess is a commercial success in the performing arts
+type CommercialSuccess struct {
+	Title       string   `json:"title"`
+	Metric      string   `json:"metric,omitempty"` // e.g. "box office receipts", "album sales"
+	Amount      *float64 `json:"amount,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// CommercialSuccessFacts holds the facts of the commercial success criterion
+type CommercialSuccessFacts struct {
+	Successes   []CommercialSuccess `json:"successes,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Significance
+}
+
+// Decode decodes the detail into v, a pointer to one of the facts structs
+func (d CriteriaDetail) Decode(v interface{}) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// MergePublications merges publications into authorship details. A
+// publication already listed under the same title gets the new citation
+// count and any venue or year it lacks, keeping the notes entered for it;
+// the others are appended. Returns the merged details and the number of
+// publications added.
+func MergePublications(detail CriteriaDetail, publications []Publication) (CriteriaDetail, int, error) {
+	merged := make(CriteriaDetail, len(detail)+1)
+	for key, value := range detail {
+		merged[key] = value
+	}
+
+	existing, _ := merged["publications"].([]interface{})
+	entries := append([]interface{}{}, existing...)
+	index := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		if object, ok := entry.(map[string]interface{}); ok {
+			if title, ok := object["title"].(string); ok {
+				index[titleKey(title)] = i
+			}
+		}
+	}
+
+	added := 0
+	for _, publication := range publications {
+		data, err := json.Marshal(publication)
+		if err != nil {
+			return nil, 0, err
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, 0, err
+		}
+
+		key := titleKey(publication.Title)
+		if i, ok := index[key]; ok {
+			if current, ok := entries[i].(map[string]interface{}); ok {
+				updated := make(map[string]interface{}, len(current)+len(entry))
+				for k, v := range current {
+					updated[k] = v
+				}
+				for k, v := range entry {
+					if _, ok := updated[k]; k == "citations" || !ok || updated[k] == "" {
+						updated[k] = v
+					}
+				}
+				entries[i] = updated
+				continue
+			}
+		}
+		index[key] = len(entries)
+		entries = append(entries, entry)
+		added++
+	}
+
+	merged["publications"] = entries
+	return merged, added, nil
+}
+
+// titleKey normalizes a title for matching: lowercase letters and digits,
+// separated by single spaces
+func titleKey(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
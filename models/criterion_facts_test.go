@@ -0,0 +1,124 @@
+package models
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"meritdraft-backend/criteria"
+)
+
+// typedFacts are the facts structs of the criteria the drafting service
+// formats from typed facts
+var typedFacts = map[string]interface{}{
+	"awards":                 AwardsFacts{},
+	"membership":             MembershipFacts{},
+	"media_coverage":         MediaCoverageFacts{},
+	"judging":                JudgingFacts{},
+	"original_contributions": OriginalContributionsFacts{},
+	"authorship":             AuthorshipFacts{},
+	"exhibitions":            ExhibitionsFacts{},
+	"critical_role":          CriticalRoleFacts{},
+	"high_salary":            HighSalaryFacts{},
+	"commercial_success":     CommercialSuccessFacts{},
+}
+
+// jsonField is a field of a facts struct as it is encoded
+type jsonField struct {
+	name      string
+	typ       reflect.Type
+	omitempty bool
+}
+
+// jsonFields returns the encoded fields of a struct by key, including
+// those of embedded structs such as Significance
+func jsonFields(t reflect.Type) map[string]jsonField {
+	fields := make(map[string]jsonField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("json")
+		if f.Anonymous && !ok {
+			for key, embedded := range jsonFields(f.Type) {
+				fields[key] = embedded
+			}
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = jsonField{name: f.Name, typ: f.Type, omitempty: options == "omitempty"}
+	}
+	return fields
+}
+
+// compareFacts reports differences between registry facts and the encoded
+// fields of a struct
+func compareFacts(t *testing.T, path string, facts []criteria.FactField, typ reflect.Type) {
+	t.Helper()
+	fields := jsonFields(typ)
+
+	for _, fact := range facts {
+		field, ok := fields[fact.Key]
+		if !ok {
+			t.Errorf("%s.%s: registry fact has no field in %s", path, fact.Key, typ.Name())
+			continue
+		}
+		delete(fields, fact.Key)
+
+		if fact.Required == field.omitempty {
+			t.Errorf("%s.%s: required is %t but %s.%s omitempty is %t", path, fact.Key, fact.Required, typ.Name(), field.name, field.omitempty)
+		}
+
+		ft := field.typ
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		var kindOK bool
+		switch fact.Type {
+		case criteria.TypeString:
+			kindOK = ft.Kind() == reflect.String
+		case criteria.TypeInteger:
+			kindOK = ft.Kind() == reflect.Int
+		case criteria.TypeNumber:
+			kindOK = ft.Kind() == reflect.Float64
+		case criteria.TypeBoolean:
+			kindOK = ft.Kind() == reflect.Bool
+		case criteria.TypeArray:
+			kindOK = ft.Kind() == reflect.Slice
+			if kindOK && len(fact.Items) > 0 {
+				compareFacts(t, path+"."+fact.Key+"[]", fact.Items, ft.Elem())
+			}
+		}
+		if !kindOK {
+			t.Errorf("%s.%s: registry type %s does not match %s.%s of type %s", path, fact.Key, fact.Type, typ.Name(), field.name, field.typ)
+		}
+	}
+
+	extra := make([]string, 0, len(fields))
+	for key := range fields {
+		extra = append(extra, key)
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		t.Errorf("%s.%s: %s.%s is not a registry fact", path, key, typ.Name(), fields[key].name)
+	}
+}
+
+func TestCriterionFactsMatchRegistry(t *testing.T) {
+	for _, visaType := range []VisaType{VisaTypeO1A, VisaTypeEB1A} {
+		visa, ok := criteria.Lookup(string(visaType))
+		if !ok {
+			t.Fatalf("%s is not registered", visaType)
+		}
+		for _, criterion := range visa.Criteria {
+			facts, ok := typedFacts[criterion.ID]
+			if !ok {
+				t.Errorf("%s %s: no typed facts struct", visaType, criterion.ID)
+				continue
+			}
+			compareFacts(t, string(visaType)+" "+criterion.ID, criterion.Facts, reflect.TypeOf(facts))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExtractionStatus represents the status of a document extraction
+type ExtractionStatus string
+
+const (
+	ExtractionPending    ExtractionStatus = "pending"
+	ExtractionInProgress ExtractionStatus = "in_progress"
+	ExtractionCompleted  ExtractionStatus = "completed"
+	ExtractionFailed     ExtractionStatus = "failed"
+)
+
+// EvidenceKind is the kind of fact an evidence candidate holds
+type EvidenceKind string
+
+const (
+	EvidencePublication EvidenceKind = "publication"
+	EvidenceAward       EvidenceKind = "award"
+	EvidenceMembership  EvidenceKind = "membership"
+	EvidenceReview      EvidenceKind = "review"
+	EvidenceRole        EvidenceKind = "role"
+	EvidenceSalary      EvidenceKind = "salary"
+)
+
+// SourceSpan locates the text a candidate was read from
+type SourceSpan struct {
+	Start int    `json:"start"` // Byte offset into DocumentExtraction.Text
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// EvidenceCandidate is a fact found in an uploaded document, awaiting
+// attorney review
+type EvidenceCandidate struct {
+	Kind      EvidenceKind           `json:"kind"`
+	Criterion string                 `json:"criterion"` // criteria_details key the candidate prefills
+	Facts     map[string]interface{} `json:"facts"`     // Shaped like the criterion's facts, or one entry of its list
+	Source    SourceSpan             `json:"source"`
+}
+
+// EvidenceCandidates represents a list of evidence candidates
+type EvidenceCandidates []EvidenceCandidate
+
+// Value implements driver.Valuer for JSONB
+func (e EvidenceCandidates) Value() (driver.Value, error) {
+	if e == nil {
+		return json.Marshal(EvidenceCandidates{})
+	}
+	return json.Marshal(e)
+}
+
+// Scan implements sql.Scanner for JSONB
+func (e *EvidenceCandidates) Scan(value interface{}) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	}
+
+	if len(bytes) == 0 {
+		*e = make(EvidenceCandidates, 0)
+		return nil
+	}
+
+	return json.Unmarshal(bytes, e)
+}
+
+// DocumentExtraction is the text and evidence candidates parsed from an
+// uploaded file. Extractions are queued on upload and processed in the
+// background.
+type DocumentExtraction struct {
+	ID           uuid.UUID          `json:"id"`
+	FileID       uuid.UUID          `json:"file_id"`
+	PetitionID   *uuid.UUID         `json:"petition_id,omitempty"`
+	Status       ExtractionStatus   `json:"status"`
+	Text         string             `json:"text,omitempty"` // Extracted plain text that source spans point into
+	Candidates   EvidenceCandidates `json:"candidates"`
+	Prefilled    []string           `json:"prefilled"` // Criteria whose empty details were filled from the candidates
+	ErrorMessage *string            `json:"error_message,omitempty"`
+	Attempts     int                `json:"attempts"`
+	LeaseExpires *time.Time         `json:"lease_expires_at,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	CompletedAt  *time.Time         `json:"completed_at,omitempty"`
+}
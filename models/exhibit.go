@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Exhibit is an uploaded file tagged as evidence of a criterion fact
+type Exhibit struct {
+	ID          uuid.UUID  `json:"id"`
+	PetitionID  uuid.UUID  `json:"petition_id"`
+	FileID      uuid.UUID  `json:"file_id"`
+	Filename    string     `json:"filename"`
+	MimeType    string     `json:"mime_type"`
+	Criterion   string     `json:"criterion"`  // Criterion ID the exhibit supports
+	FactKey     *string    `json:"fact_key"`   // Fact of the criterion's details, e.g. "awards"
+	FactIndex   *int       `json:"fact_index"` // Entry of an array fact, e.g. the second award
+	Description string     `json:"description"`
+	Label       string     `json:"label"`      // Exhibit letter, assigned in order of first reference
+	References  int        `json:"references"` // Placeholders in the generated content that cite the exhibit
+	CreatedBy   *uuid.UUID `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Ref returns the placeholder key the drafting prompts give the model for
+// the exhibit, as in "[Exhibit 3f2a9c1b]"
+func (e *Exhibit) Ref() string {
+	return e.ID.String()[:8]
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FirmRole represents a member's role within a law firm workspace
+type FirmRole string
+
+const (
+	FirmRoleOwner     FirmRole = "owner"
+	FirmRoleAttorney  FirmRole = "attorney"
+	FirmRoleParalegal FirmRole = "paralegal"
+	FirmRoleReadOnly  FirmRole = "read_only"
+)
+
+// Valid reports whether the role is one of the known firm roles
+func (r FirmRole) Valid() bool {
+	switch r {
+	case FirmRoleOwner, FirmRoleAttorney, FirmRoleParalegal, FirmRoleReadOnly:
+		return true
+	}
+	return false
+}
+
+// CanEdit reports whether the role may change petition data and upload files
+func (r FirmRole) CanEdit() bool {
+	return r == FirmRoleOwner || r == FirmRoleAttorney || r == FirmRoleParalegal
+}
+
+// CanDraft reports whether the role may generate drafts and mark petitions completed
+func (r FirmRole) CanDraft() bool {
+	return r == FirmRoleOwner || r == FirmRoleAttorney
+}
+
+// CanManageMembers reports whether the role may add, remove or re-role firm members
+func (r FirmRole) CanManageMembers() bool {
+	return r == FirmRoleOwner
+}
+
+// Firm represents a law firm workspace shared by its members
+type Firm struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Letterhead []string  `json:"letterhead"` // Address, phone and email lines printed under the name on exported petitions
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// FirmMember represents a user's membership in a firm
+type FirmMember struct {
+	FirmID    uuid.UUID `json:"firm_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Role      FirmRole  `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
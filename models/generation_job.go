@@ -16,12 +16,13 @@ const (
 	JobStatusInProgress GenerationJobStatus = "in_progress"
 	JobStatusCompleted  GenerationJobStatus = "completed"
 	JobStatusFailed     GenerationJobStatus = "failed"
+	JobStatusCancelled  GenerationJobStatus = "cancelled"
 )
 
 // GenerationStep represents a step in the generation process
 type GenerationStep struct {
 	Name        string `json:"name"`
-	Status      string `json:"status"` // "pending", "in_progress", "completed", "failed"
+	Status      string `json:"status"` // "pending", "in_progress", "completed", "failed", "skipped"
 	Description string `json:"description,omitempty"`
 }
 
@@ -70,8 +71,27 @@ type GenerationJob struct {
 	CurrentStep  *string            `json:"current_step,omitempty"`
 	Steps        GenerationSteps    `json:"steps"`
 	ErrorMessage *string            `json:"error_message,omitempty"`
+	PartialText  *string            `json:"partial_text,omitempty"` // Text of the current step's section streamed so far
+	Attempts     int                `json:"attempts"`
+	LeaseOwner   *string            `json:"lease_owner,omitempty"`      // Worker currently processing the job
+	LeaseExpires *time.Time         `json:"lease_expires_at,omitempty"` // Job is re-queued if not renewed by then
+	HeartbeatAt  *time.Time         `json:"heartbeat_at,omitempty"`
 	CreatedAt    time.Time          `json:"created_at"`
 	UpdatedAt    time.Time          `json:"updated_at"`
 	CompletedAt  *time.Time         `json:"completed_at,omitempty"`
 }
 
+
+// GenerationSection is a drafted section saved as soon as its step completes,
+// so a failed job can be retried without regenerating finished criteria
+type GenerationSection struct {
+	ID        uuid.UUID   `json:"id"`
+	JobID     uuid.UUID   `json:"job_id"`
+	StepName  string      `json:"step_name"`
+	Position  int         `json:"position"`
+	Title     string      `json:"title"`
+	Content   string      `json:"content"`
+	Citations []string    `json:"citations"`
+	ChunkIDs  []uuid.UUID `json:"chunk_ids"` // Legal chunks retrieved as context for the section
+	CreatedAt time.Time   `json:"created_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// JobEventType identifies the kind of generation job event
+type JobEventType string
+
+const (
+	JobEventStep         JobEventType = "step"          // A step changed status
+	JobEventSectionDelta JobEventType = "section_delta" // More text of a section was produced
+	JobEventStatus       JobEventType = "status"        // The job changed status
+)
+
+// JobEvent is a progress update for a generation job, published over
+// Postgres NOTIFY so every server instance can stream it to clients
+type JobEvent struct {
+	JobID       uuid.UUID           `json:"job_id"`
+	Type        JobEventType        `json:"type"`
+	Status      GenerationJobStatus `json:"status,omitempty"`
+	CurrentStep string              `json:"current_step,omitempty"`
+	Steps       GenerationSteps     `json:"steps,omitempty"`
+	StepName    string              `json:"step_name,omitempty"`
+	Text        string              `json:"text,omitempty"`
+	Reset       bool                `json:"reset,omitempty"` // Discard text received so far for StepName before appending Text
+	Error       string              `json:"error,omitempty"`
+}
+
+// Terminal reports whether the event ends the job's stream
+func (e JobEvent) Terminal() bool {
+	if e.Type != JobEventStatus {
+		return false
+	}
+	switch e.Status {
+	case JobStatusCompleted, JobStatusFailed, JobStatusCancelled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PacketStatus represents the status of a filing packet build
+type PacketStatus string
+
+const (
+	PacketPending    PacketStatus = "pending"
+	PacketInProgress PacketStatus = "in_progress"
+	PacketCompleted  PacketStatus = "completed"
+	PacketFailed     PacketStatus = "failed"
+)
+
+// PacketExhibit is where an exhibit falls in a built packet
+type PacketExhibit struct {
+	ExhibitID  uuid.UUID `json:"exhibit_id"`
+	Label      string    `json:"label"`
+	Filename   string    `json:"filename"`
+	BatesFirst string    `json:"bates_first"`    // Stamp of the exhibit's tab page
+	BatesLast  string    `json:"bates_last"`     // Stamp of the exhibit's last page
+	Pages      int       `json:"pages"`          // Pages of the file, excluding the tab page
+	Note       string    `json:"note,omitempty"` // Why a slip sheet stands in for the file
+}
+
+// PacketExhibits represents a list of packet exhibits
+type PacketExhibits []PacketExhibit
+
+// Value implements driver.Valuer for JSONB
+func (p PacketExhibits) Value() (driver.Value, error) {
+	if p == nil {
+		return json.Marshal(PacketExhibits{})
+	}
+	return json.Marshal(p)
+}
+
+// Scan implements sql.Scanner for JSONB
+func (p *PacketExhibits) Scan(value interface{}) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	}
+
+	if len(bytes) == 0 {
+		*p = make(PacketExhibits, 0)
+		return nil
+	}
+
+	return json.Unmarshal(bytes, p)
+}
+
+// Packet is a filing packet: the petition letter and every exhibit behind
+// a tab page, bundled into one Bates-stamped PDF. Packets are queued on
+// request and built in the background.
+type Packet struct {
+	ID           uuid.UUID      `json:"id"`
+	PetitionID   uuid.UUID      `json:"petition_id"`
+	Status       PacketStatus   `json:"status"`
+	BatesPrefix  string         `json:"bates_prefix"`
+	FileID       *uuid.UUID     `json:"file_id,omitempty"` // The bundled PDF, once built
+	PageCount    int            `json:"page_count"`
+	Exhibits     PacketExhibits `json:"exhibits"`
+	ErrorMessage *string        `json:"error_message,omitempty"`
+	Attempts     int            `json:"attempts"`
+	LeaseExpires *time.Time     `json:"lease_expires_at,omitempty"`
+	CreatedBy    *uuid.UUID     `json:"created_by"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	CompletedAt  *time.Time     `json:"completed_at,omitempty"`
+}
@@ -18,11 +18,22 @@ const (
 	StatusArchived    PetitionStatus = "archived"
 )
 
+// Settable reports whether a client may set the status directly. Petitions
+// are archived only by deleting them, which also schedules the purge.
+func (s PetitionStatus) Settable() bool {
+	switch s {
+	case StatusDraft, StatusInProgress, StatusCompleted:
+		return true
+	}
+	return false
+}
+
 // VisaType represents the type of visa
 type VisaType string
 
 const (
 	VisaTypeO1A   VisaType = "O-1A"
+	VisaTypeO1B   VisaType = "O-1B"
 	VisaTypeEB1A  VisaType = "EB-1A"
 	VisaTypeEB2NIW VisaType = "EB-2 NIW"
 )
@@ -31,6 +42,7 @@ const (
 type ParsedDocuments struct {
 	PublicationsCount int `json:"publicationsCount"`
 	CitationsCount    int `json:"citationsCount"`
+	HIndex            int `json:"hIndex"`
 }
 
 // Value implements driver.Valuer for JSONB
@@ -90,6 +102,7 @@ func (c *CriteriaDetails) Scan(value interface{}) error {
 type Petition struct {
 	ID                uuid.UUID       `json:"id"`
 	UserID            uuid.UUID       `json:"user_id"`
+	FirmID            *uuid.UUID      `json:"firm_id,omitempty"` // Shared with this firm's members when set
 	Status            PetitionStatus  `json:"status"`
 	
 	// Step 1: Intake
@@ -113,9 +126,12 @@ type Petition struct {
 	// Step 5/6: Generation
 	GeneratedContent  *string         `json:"generated_content"`
 	RefineInstructions *string        `json:"refine_instructions"`
+	DraftingModel     *string         `json:"drafting_model,omitempty"` // LLM provider for this petition; deployment default when nil
 	
 	CreatedAt         time.Time       `json:"created_at"`
 	UpdatedAt         time.Time       `json:"updated_at"`
 	CompletedAt       *time.Time      `json:"completed_at,omitempty"`
+	ArchivedAt        *time.Time      `json:"archived_at,omitempty"`
+	PurgeAfter        *time.Time      `json:"purge_after,omitempty"` // Scheduled hard delete, if requested
 }
 
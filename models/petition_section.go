@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PetitionSection is one section of a petition's draft. A completed draft
+// is stored as its sections in document order; the petition's generated
+// content is their rendering.
+type PetitionSection struct {
+	ID         uuid.UUID   `json:"id"`
+	PetitionID uuid.UUID   `json:"petition_id"`
+	JobID      *uuid.UUID  `json:"job_id"` // Generation job that drafted the section
+	Position   int         `json:"position"`
+	Level      int         `json:"level"`     // 0 for the title, 1 for the Roman-numeral parts, 2 for criteria and prongs
+	StepName   *string     `json:"step_name"` // Generation step that wrote the content; nil for boilerplate
+	Criterion  *string     `json:"criterion"` // Criterion or prong the section argues
+	Title      string      `json:"title"`
+	Content    string      `json:"content"`
+	Citations  []string    `json:"citations"`
+	ChunkIDs   []uuid.UUID `json:"chunk_ids"` // Legal chunks retrieved as context for the content
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// RenderSections renders a draft's sections as the plain text stored in
+// petitions.generated_content. Each title is followed by its content on the
+// next line, unless the model already opened the content with the title.
+func RenderSections(sections []*PetitionSection) string {
+	var b strings.Builder
+	for _, section := range sections {
+		if section.Content == "" {
+			if section.Title != "" {
+				b.WriteString(section.Title + "\n\n")
+			}
+			continue
+		}
+
+		opening := strings.ToLower(strings.TrimSpace(section.Content))
+		if section.Title != "" && !strings.HasPrefix(opening, strings.ToLower(section.Title)) {
+			b.WriteString(section.Title + "\n")
+		}
+		b.WriteString(section.Content + "\n\n")
+	}
+
+	rendered := strings.TrimRight(b.String(), "\n")
+	if rendered == "" {
+		return ""
+	}
+	return rendered + "\n"
+}
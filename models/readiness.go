@@ -0,0 +1,49 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// ReadinessSeverity says whether a readiness issue prevents drafting
+type ReadinessSeverity string
+
+const (
+	ReadinessBlocking ReadinessSeverity = "blocking" // Drafting cannot start until it is resolved
+	ReadinessWarning  ReadinessSeverity = "warning"  // Drafting can start, but the evidence is weak
+)
+
+// CriterionReadinessStatus summarizes the issues of one criterion
+type CriterionReadinessStatus string
+
+const (
+	CriterionReady      CriterionReadinessStatus = "ready"      // No issues
+	CriterionWeak       CriterionReadinessStatus = "weak"       // Only warnings
+	CriterionIncomplete CriterionReadinessStatus = "incomplete" // At least one blocking issue
+)
+
+// ReadinessIssue is one missing or weak piece of evidence
+type ReadinessIssue struct {
+	Code     string            `json:"code"`
+	Severity ReadinessSeverity `json:"severity"`
+	Field    string            `json:"field,omitempty"` // Path such as criteria_details.judging.papers_reviewed
+	Message  string            `json:"message"`
+}
+
+// CriterionReadiness is the checklist of one selected criterion, or of one
+// Dhanasar prong for EB-2 NIW petitions
+type CriterionReadiness struct {
+	Criterion string                   `json:"criterion"`
+	Title     string                   `json:"title"`
+	Status    CriterionReadinessStatus `json:"status"`
+	Issues    []ReadinessIssue         `json:"issues"`
+}
+
+// ReadinessReport says whether a petition has the evidence needed to
+// draft it, and where its evidence is missing or weak
+type ReadinessReport struct {
+	PetitionID uuid.UUID            `json:"petition_id"`
+	VisaType   VisaType             `json:"visa_type"`
+	Ready      bool                 `json:"ready"`  // No blocking issues
+	Issues     []ReadinessIssue     `json:"issues"` // Issues of the petition as a whole
+	Criteria   []CriterionReadiness `json:"criteria"`
+}
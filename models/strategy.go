@@ -0,0 +1,64 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// CriterionStrength rates how well the evidence supports a criterion
+type CriterionStrength string
+
+const (
+	StrengthStrong   CriterionStrength = "strong"   // Well evidenced and close to winning arguments
+	StrengthModerate CriterionStrength = "moderate" // Evidenced, but with gaps or weak precedent
+	StrengthWeak     CriterionStrength = "weak"     // Little evidence
+	StrengthNone     CriterionStrength = "none"     // No evidence found
+)
+
+// FactSource says where a supporting fact was found
+type FactSource string
+
+const (
+	FactFromDetails  FactSource = "criteria_details" // Entered in the Deep Dive step
+	FactFromCV       FactSource = "cv"               // Parsed from the uploaded CV
+	FactFromJobOffer FactSource = "job_offer"        // Parsed from the uploaded job offer
+)
+
+// SupportingFact is one fact a criterion's score rests on
+type SupportingFact struct {
+	Source FactSource `json:"source"`
+	Text   string     `json:"text"`
+}
+
+// SimilarArgument is a winning appeal argument resembling the client's evidence
+type SimilarArgument struct {
+	ChunkID        uuid.UUID `json:"chunk_id"`
+	SourceDocument string    `json:"source_document"`
+	Citation       string    `json:"citation,omitempty"`
+	Excerpt        string    `json:"excerpt"`
+	Similarity     float64   `json:"similarity"` // 1 - cosine distance
+}
+
+// CriterionScore is the strength of one criterion and why
+type CriterionScore struct {
+	Criterion   string            `json:"criterion"`
+	Title       string            `json:"title"`
+	Score       int               `json:"score"` // 0-100
+	Strength    CriterionStrength `json:"strength"`
+	Recommended bool              `json:"recommended"`
+	Selected    bool              `json:"selected"` // Already in the petition's selected criteria
+	Reasons     []string          `json:"reasons"`
+	Facts       []SupportingFact  `json:"facts"`
+	Arguments   []SimilarArgument `json:"arguments"`
+}
+
+// StrategyReport recommends the criteria a petition should be argued on,
+// ranked by the strength of the client's evidence
+type StrategyReport struct {
+	PetitionID  uuid.UUID        `json:"petition_id"`
+	VisaType    VisaType         `json:"visa_type"`
+	MinCriteria int              `json:"min_criteria"`
+	Recommended []string         `json:"recommended"` // Criterion IDs, strongest first
+	Sufficient  bool             `json:"sufficient"`  // Enough recommended criteria are at least moderate
+	Applied     bool             `json:"applied"`     // Recommended criteria were saved as the selected criteria
+	Criteria    []CriterionScore `json:"criteria"`    // Every criterion of the visa type, strongest first
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"meritdraft-backend/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DocumentExtractionRepository handles database operations for document extractions
+type DocumentExtractionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewDocumentExtractionRepository creates a new document extraction repository
+func NewDocumentExtractionRepository(db *pgxpool.Pool) *DocumentExtractionRepository {
+	return &DocumentExtractionRepository{db: db}
+}
+
+const documentExtractionColumns = `
+	id, file_id, petition_id, status, text, candidates, prefilled, error_message,
+	attempts, lease_expires_at, created_at, updated_at, completed_at`
+
+func scanDocumentExtraction(row pgx.Row) (*models.DocumentExtraction, error) {
+	extraction := &models.DocumentExtraction{}
+	err := row.Scan(
+		&extraction.ID,
+		&extraction.FileID,
+		&extraction.PetitionID,
+		&extraction.Status,
+		&extraction.Text,
+		&extraction.Candidates,
+		&extraction.Prefilled,
+		&extraction.ErrorMessage,
+		&extraction.Attempts,
+		&extraction.LeaseExpires,
+		&extraction.CreatedAt,
+		&extraction.UpdatedAt,
+		&extraction.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if extraction.Prefilled == nil {
+		extraction.Prefilled = []string{}
+	}
+	return extraction, nil
+}
+
+// Queue queues a file for extraction, resetting any earlier extraction of it
+func (r *DocumentExtractionRepository) Queue(ctx context.Context, fileID uuid.UUID, petitionID *uuid.UUID) (*models.DocumentExtraction, error) {
+	query := `
+		INSERT INTO document_extractions (file_id, petition_id, status)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (file_id) DO UPDATE SET
+			petition_id = EXCLUDED.petition_id,
+			status = EXCLUDED.status,
+			text = '',
+			candidates = '[]'::jsonb,
+			prefilled = '{}',
+			error_message = NULL,
+			attempts = 0,
+			lease_expires_at = NULL,
+			completed_at = NULL,
+			updated_at = NOW()
+		RETURNING` + documentExtractionColumns
+
+	return scanDocumentExtraction(r.db.QueryRow(ctx, query, fileID, petitionID, models.ExtractionPending))
+}
+
+// GetByFileID retrieves the extraction of a file
+func (r *DocumentExtractionRepository) GetByFileID(ctx context.Context, fileID uuid.UUID) (*models.DocumentExtraction, error) {
+	query := `SELECT` + documentExtractionColumns + ` FROM document_extractions WHERE file_id = $1`
+	return scanDocumentExtraction(r.db.QueryRow(ctx, query, fileID))
+}
+
+// Lease atomically claims the oldest pending extraction, or one whose lease
+// expired because its process stopped mid-way. Returns nil, nil when none
+// is available.
+func (r *DocumentExtractionRepository) Lease(ctx context.Context, leaseDuration time.Duration) (*models.DocumentExtraction, error) {
+	query := `
+		UPDATE document_extractions SET
+			status = $2,
+			lease_expires_at = NOW() + $1::interval,
+			attempts = attempts + 1,
+			updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM document_extractions
+			WHERE status = $3
+				OR (status = $2 AND lease_expires_at < NOW())
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING` + documentExtractionColumns
+
+	extraction, err := scanDocumentExtraction(r.db.QueryRow(ctx, query, leaseDuration, models.ExtractionInProgress, models.ExtractionPending))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return extraction, err
+}
+
+// Complete stores the extracted text and candidates of an in-progress extraction
+func (r *DocumentExtractionRepository) Complete(ctx context.Context, id uuid.UUID, text string, candidates models.EvidenceCandidates, prefilled []string) error {
+	query := `
+		UPDATE document_extractions SET
+			status = $2,
+			text = $3,
+			candidates = $4,
+			prefilled = $5,
+			lease_expires_at = NULL,
+			completed_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1 AND status = $6`
+
+	_, err := r.db.Exec(ctx, query, id, models.ExtractionCompleted, text, candidates, prefilled, models.ExtractionInProgress)
+	return err
+}
+
+// Fail marks an in-progress extraction as failed
+func (r *DocumentExtractionRepository) Fail(ctx context.Context, id uuid.UUID, errorMessage string) error {
+	query := `
+		UPDATE document_extractions SET
+			status = $2,
+			error_message = $3,
+			lease_expires_at = NULL,
+			updated_at = NOW()
+		WHERE id = $1 AND status = $4`
+
+	_, err := r.db.Exec(ctx, query, id, models.ExtractionFailed, errorMessage, models.ExtractionInProgress)
+	return err
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"meritdraft-backend/export"
+	"meritdraft-backend/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrExhibitExists is returned when a file is already an exhibit of the petition
+var ErrExhibitExists = errors.New("file is already an exhibit of this petition")
+
+// ExhibitRepository handles database operations for exhibits
+type ExhibitRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewExhibitRepository creates a new exhibit repository
+func NewExhibitRepository(db *pgxpool.Pool) *ExhibitRepository {
+	return &ExhibitRepository{db: db}
+}
+
+const exhibitColumns = `
+	e.id, e.petition_id, e.file_id, f.filename, f.mime_type, e.criterion, e.fact_key,
+	e.fact_index, e.description, e.label, e.reference_count, e.created_by, e.created_at, e.updated_at`
+
+func scanExhibit(row pgx.Row) (*models.Exhibit, error) {
+	exhibit := &models.Exhibit{}
+	err := row.Scan(
+		&exhibit.ID,
+		&exhibit.PetitionID,
+		&exhibit.FileID,
+		&exhibit.Filename,
+		&exhibit.MimeType,
+		&exhibit.Criterion,
+		&exhibit.FactKey,
+		&exhibit.FactIndex,
+		&exhibit.Description,
+		&exhibit.Label,
+		&exhibit.References,
+		&exhibit.CreatedBy,
+		&exhibit.CreatedAt,
+		&exhibit.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return exhibit, nil
+}
+
+// Create tags a file as an exhibit. Returns ErrExhibitExists when the file
+// is already an exhibit of the petition.
+func (r *ExhibitRepository) Create(ctx context.Context, exhibit *models.Exhibit) error {
+	query := `
+		INSERT INTO exhibits (
+			petition_id, file_id, criterion, fact_key, fact_index, description, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (petition_id, file_id) DO NOTHING
+		RETURNING id, label, reference_count, created_at, updated_at`
+
+	err := r.db.QueryRow(
+		ctx, query,
+		exhibit.PetitionID,
+		exhibit.FileID,
+		exhibit.Criterion,
+		exhibit.FactKey,
+		exhibit.FactIndex,
+		exhibit.Description,
+		exhibit.CreatedBy,
+	).Scan(&exhibit.ID, &exhibit.Label, &exhibit.References, &exhibit.CreatedAt, &exhibit.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrExhibitExists
+	}
+	return err
+}
+
+// GetByID retrieves an exhibit by ID
+func (r *ExhibitRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Exhibit, error) {
+	query := `SELECT` + exhibitColumns + `
+		FROM exhibits e
+		JOIN files f ON f.id = e.file_id
+		WHERE e.id = $1`
+
+	return scanExhibit(r.db.QueryRow(ctx, query, id))
+}
+
+// ListByPetitionID retrieves the exhibits of a petition in the order they were tagged
+func (r *ExhibitRepository) ListByPetitionID(ctx context.Context, petitionID uuid.UUID) ([]*models.Exhibit, error) {
+	return r.list(ctx, r.db, petitionID)
+}
+
+// queryer is satisfied by both the pool and a transaction
+type queryer interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func (r *ExhibitRepository) list(ctx context.Context, q queryer, petitionID uuid.UUID) ([]*models.Exhibit, error) {
+	query := `SELECT` + exhibitColumns + `
+		FROM exhibits e
+		JOIN files f ON f.id = e.file_id
+		WHERE e.petition_id = $1
+		ORDER BY e.created_at, e.id`
+
+	rows, err := q.Query(ctx, query, petitionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exhibits []*models.Exhibit
+	for rows.Next() {
+		exhibit, err := scanExhibit(rows)
+		if err != nil {
+			return nil, err
+		}
+		exhibits = append(exhibits, exhibit)
+	}
+
+	return exhibits, rows.Err()
+}
+
+// Delete removes an exhibit; the file itself is kept
+func (r *ExhibitRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM exhibits WHERE id = $1`, id)
+	return err
+}
+
+// Resolve letters the petition's exhibits by their first reference in the
+// generated content and rewrites its placeholders to match, under a lock
+// on the petition so concurrent resolutions and draft completions do not
+// interleave. A draft stored as sections has its sections rewritten and
+// its content rendered from them again. Returns the exhibits in tagging
+// order.
+func (r *ExhibitRepository) Resolve(ctx context.Context, petitionID uuid.UUID, criterionTitles map[string]string) ([]*models.Exhibit, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var content *string
+	err = tx.QueryRow(ctx, `SELECT generated_content FROM petitions WHERE id = $1 FOR UPDATE`, petitionID).Scan(&content)
+	if err != nil {
+		return nil, err
+	}
+
+	exhibits, err := r.list(ctx, tx, petitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := listPetitionSections(ctx, tx, petitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := ""
+	if content != nil {
+		current = *content
+	}
+	var resolved string
+	if len(sections) > 0 {
+		for _, section := range export.ResolveSectionExhibits(sections, criterionTitles, exhibits) {
+			_, err = tx.Exec(ctx, `
+				UPDATE petition_sections SET
+					content = $2,
+					updated_at = NOW()
+				WHERE id = $1`, section.ID, section.Content)
+			if err != nil {
+				return nil, err
+			}
+		}
+		resolved = models.RenderSections(sections)
+	} else {
+		resolved = export.ResolveExhibits(current, criterionTitles, exhibits)
+	}
+
+	if content != nil && resolved != current {
+		_, err = tx.Exec(ctx, `
+			UPDATE petitions SET
+				generated_content = $2,
+				updated_at = NOW()
+			WHERE id = $1`, petitionID, resolved)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, exhibit := range exhibits {
+		_, err = tx.Exec(ctx, `
+			UPDATE exhibits SET
+				label = $2,
+				reference_count = $3,
+				updated_at = NOW()
+			WHERE id = $1 AND (label <> $2 OR reference_count <> $3)`,
+			exhibit.ID, exhibit.Label, exhibit.References)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return exhibits, tx.Commit(ctx)
+}
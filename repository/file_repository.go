@@ -145,3 +145,40 @@ func (r *FileRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+// ListBlobDeletions returns storage paths whose file rows were deleted but
+// whose blobs have not been confirmed deleted, oldest first
+func (r *FileRepository) ListBlobDeletions(ctx context.Context, limit int) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT storage_path FROM blob_deletions
+		ORDER BY created_at
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// CompleteBlobDeletion removes a storage path from the queue once its blob
+// is deleted
+func (r *FileRepository) CompleteBlobDeletion(ctx context.Context, storagePath string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM blob_deletions WHERE storage_path = $1`, storagePath)
+	return err
+}
+
+// FailBlobDeletion records a failed blob delete; the path stays queued
+func (r *FileRepository) FailBlobDeletion(ctx context.Context, storagePath string, errorMessage string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE blob_deletions SET attempts = attempts + 1, last_error = $2
+		WHERE storage_path = $1`, storagePath, errorMessage)
+	return err
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+
+	"meritdraft-backend/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// FirmRepository handles database operations for firms and their members
+type FirmRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewFirmRepository creates a new firm repository
+func NewFirmRepository(db *pgxpool.Pool) *FirmRepository {
+	return &FirmRepository{db: db}
+}
+
+// Create creates a new firm and makes ownerID its owner in one transaction
+func (r *FirmRepository) Create(ctx context.Context, firm *models.Firm, ownerID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO firms (name, letterhead)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`,
+		firm.Name, firm.Letterhead,
+	).Scan(&firm.ID, &firm.CreatedAt, &firm.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO firm_members (firm_id, user_id, role)
+		VALUES ($1, $2, $3)`,
+		firm.ID, ownerID, models.FirmRoleOwner,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetByID retrieves a firm by ID
+func (r *FirmRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Firm, error) {
+	firm := &models.Firm{}
+	query := `
+		SELECT id, name, letterhead, created_at, updated_at
+		FROM firms
+		WHERE id = $1`
+
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&firm.ID,
+		&firm.Name,
+		&firm.Letterhead,
+		&firm.CreatedAt,
+		&firm.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return firm, nil
+}
+
+// Update updates a firm's name and letterhead
+func (r *FirmRepository) Update(ctx context.Context, firm *models.Firm) error {
+	query := `
+		UPDATE firms SET
+			name = $2,
+			letterhead = $3,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at`
+
+	return r.db.QueryRow(ctx, query, firm.ID, firm.Name, firm.Letterhead).Scan(&firm.UpdatedAt)
+}
+
+// ListByUserID retrieves all firms a user belongs to
+func (r *FirmRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Firm, error) {
+	query := `
+		SELECT f.id, f.name, f.letterhead, f.created_at, f.updated_at
+		FROM firms f
+		JOIN firm_members m ON m.firm_id = f.id
+		WHERE m.user_id = $1
+		ORDER BY f.name`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var firms []*models.Firm
+	for rows.Next() {
+		firm := &models.Firm{}
+		err := rows.Scan(
+			&firm.ID,
+			&firm.Name,
+			&firm.Letterhead,
+			&firm.CreatedAt,
+			&firm.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		firms = append(firms, firm)
+	}
+
+	return firms, rows.Err()
+}
+
+// GetMembership retrieves a user's membership in a firm
+func (r *FirmRepository) GetMembership(ctx context.Context, firmID, userID uuid.UUID) (*models.FirmMember, error) {
+	member := &models.FirmMember{}
+	query := `
+		SELECT m.firm_id, m.user_id, u.email, u.name, m.role, m.created_at
+		FROM firm_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.firm_id = $1 AND m.user_id = $2`
+
+	err := r.db.QueryRow(ctx, query, firmID, userID).Scan(
+		&member.FirmID,
+		&member.UserID,
+		&member.Email,
+		&member.Name,
+		&member.Role,
+		&member.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// ListMembers retrieves all members of a firm
+func (r *FirmRepository) ListMembers(ctx context.Context, firmID uuid.UUID) ([]*models.FirmMember, error) {
+	query := `
+		SELECT m.firm_id, m.user_id, u.email, u.name, m.role, m.created_at
+		FROM firm_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.firm_id = $1
+		ORDER BY m.created_at`
+
+	rows, err := r.db.Query(ctx, query, firmID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*models.FirmMember
+	for rows.Next() {
+		member := &models.FirmMember{}
+		err := rows.Scan(
+			&member.FirmID,
+			&member.UserID,
+			&member.Email,
+			&member.Name,
+			&member.Role,
+			&member.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// AddMember adds a user to a firm, or updates their role if already a member
+func (r *FirmRepository) AddMember(ctx context.Context, firmID, userID uuid.UUID, role models.FirmRole) error {
+	query := `
+		INSERT INTO firm_members (firm_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (firm_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+
+	_, err := r.db.Exec(ctx, query, firmID, userID, role)
+	return err
+}
+
+// RemoveMember removes a user from a firm
+func (r *FirmRepository) RemoveMember(ctx context.Context, firmID, userID uuid.UUID) error {
+	query := `DELETE FROM firm_members WHERE firm_id = $1 AND user_id = $2`
+	_, err := r.db.Exec(ctx, query, firmID, userID)
+	return err
+}
+
+// CountOwners returns the number of owners in a firm
+func (r *FirmRepository) CountOwners(ctx context.Context, firmID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM firm_members WHERE firm_id = $1 AND role = $2`
+	err := r.db.QueryRow(ctx, query, firmID, models.FirmRoleOwner).Scan(&count)
+	return count, err
+}
@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"meritdraft-backend/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -44,7 +46,8 @@ func (r *GenerationJobRepository) Create(ctx context.Context, job *models.Genera
 func (r *GenerationJobRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.GenerationJob, error) {
 	job := &models.GenerationJob{}
 	query := `
-		SELECT id, petition_id, status, current_step, steps, error_message,
+		SELECT id, petition_id, status, current_step, steps, error_message, partial_text,
+			attempts, lease_owner, lease_expires_at, heartbeat_at,
 			created_at, updated_at, completed_at
 		FROM generation_jobs
 		WHERE id = $1`
@@ -56,6 +59,11 @@ func (r *GenerationJobRepository) GetByID(ctx context.Context, id uuid.UUID) (*m
 		&job.CurrentStep,
 		&job.Steps,
 		&job.ErrorMessage,
+		&job.PartialText,
+		&job.Attempts,
+		&job.LeaseOwner,
+		&job.LeaseExpires,
+		&job.HeartbeatAt,
 		&job.CreatedAt,
 		&job.UpdatedAt,
 		&job.CompletedAt,
@@ -77,7 +85,8 @@ func (r *GenerationJobRepository) GetByID(ctx context.Context, id uuid.UUID) (*m
 func (r *GenerationJobRepository) GetByPetitionID(ctx context.Context, petitionID uuid.UUID) (*models.GenerationJob, error) {
 	job := &models.GenerationJob{}
 	query := `
-		SELECT id, petition_id, status, current_step, steps, error_message,
+		SELECT id, petition_id, status, current_step, steps, error_message, partial_text,
+			attempts, lease_owner, lease_expires_at, heartbeat_at,
 			created_at, updated_at, completed_at
 		FROM generation_jobs
 		WHERE petition_id = $1
@@ -91,6 +100,11 @@ func (r *GenerationJobRepository) GetByPetitionID(ctx context.Context, petitionI
 		&job.CurrentStep,
 		&job.Steps,
 		&job.ErrorMessage,
+		&job.PartialText,
+		&job.Attempts,
+		&job.LeaseOwner,
+		&job.LeaseExpires,
+		&job.HeartbeatAt,
 		&job.CreatedAt,
 		&job.UpdatedAt,
 		&job.CompletedAt,
@@ -114,9 +128,9 @@ func (r *GenerationJobRepository) UpdateStatus(ctx context.Context, id uuid.UUID
 		UPDATE generation_jobs SET
 			status = $2,
 			updated_at = NOW()
-		WHERE id = $1`
+		WHERE id = $1 AND status <> $3`
 
-	_, err := r.db.Exec(ctx, query, id, status)
+	_, err := r.db.Exec(ctx, query, id, status, models.JobStatusCancelled)
 	return err
 }
 
@@ -127,23 +141,22 @@ func (r *GenerationJobRepository) UpdateProgress(ctx context.Context, id uuid.UU
 			current_step = $2,
 			steps = $3,
 			updated_at = NOW()
-		WHERE id = $1`
+		WHERE id = $1 AND status <> $4`
 
-	_, err := r.db.Exec(ctx, query, id, currentStep, steps)
+	_, err := r.db.Exec(ctx, query, id, currentStep, steps, models.JobStatusCancelled)
 	return err
 }
 
-// Complete marks a generation job as completed
-func (r *GenerationJobRepository) Complete(ctx context.Context, id uuid.UUID) error {
-	now := time.Now()
+// UpdatePartialText records the text streamed so far for the job's current
+// step; pass nil to clear it once the section is complete
+func (r *GenerationJobRepository) UpdatePartialText(ctx context.Context, id uuid.UUID, text *string) error {
 	query := `
 		UPDATE generation_jobs SET
-			status = $2,
-			completed_at = $3,
-			updated_at = $3
-		WHERE id = $1`
+			partial_text = $2,
+			updated_at = NOW()
+		WHERE id = $1 AND status = $3`
 
-	_, err := r.db.Exec(ctx, query, id, models.JobStatusCompleted, now)
+	_, err := r.db.Exec(ctx, query, id, text, models.JobStatusInProgress)
 	return err
 }
 
@@ -153,9 +166,281 @@ func (r *GenerationJobRepository) Fail(ctx context.Context, id uuid.UUID, errorM
 		UPDATE generation_jobs SET
 			status = $2,
 			error_message = $3,
+			updated_at = NOW(),
+			lease_owner = NULL,
+			lease_expires_at = NULL
+		WHERE id = $1 AND status <> $4`
+
+	_, err := r.db.Exec(ctx, query, id, models.JobStatusFailed, errorMessage, models.JobStatusCancelled)
+	return err
+}
+
+var (
+	// ErrLeaseLost is returned when a worker no longer holds the lease on a job
+	ErrLeaseLost = errors.New("generation job lease lost")
+	// ErrJobNotActive is returned when a job is no longer pending or in progress
+	ErrJobNotActive = errors.New("generation job is not active")
+	// ErrJobNotRetryable is returned when requeueing a job that has not failed or been cancelled
+	ErrJobNotRetryable = errors.New("generation job is not failed or cancelled")
+)
+
+// Lease atomically claims the oldest runnable job for a worker. Runnable jobs
+// are pending jobs and in-progress jobs whose lease has expired (their worker
+// crashed or was shut down without releasing them). SKIP LOCKED lets many
+// workers poll concurrently without blocking each other. Returns nil, nil
+// when no job is available.
+func (r *GenerationJobRepository) Lease(ctx context.Context, workerID string, leaseDuration time.Duration) (*models.GenerationJob, error) {
+	query := `
+		UPDATE generation_jobs SET
+			status = $3,
+			lease_owner = $1,
+			lease_expires_at = NOW() + $2::interval,
+			heartbeat_at = NOW(),
+			attempts = attempts + 1,
 			updated_at = NOW()
-		WHERE id = $1`
+		WHERE id = (
+			SELECT id FROM generation_jobs
+			WHERE status = $4
+				OR (status = $3 AND lease_expires_at IS NOT NULL AND lease_expires_at < NOW())
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id`
+
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, query, workerID, leaseDuration, models.JobStatusInProgress, models.JobStatusPending).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// Heartbeat extends the lease on a job held by workerID.
+// Returns ErrLeaseLost if another worker has taken the job over, or it has
+// finished or been cancelled.
+func (r *GenerationJobRepository) Heartbeat(ctx context.Context, id uuid.UUID, workerID string, leaseDuration time.Duration) error {
+	query := `
+		UPDATE generation_jobs SET
+			lease_expires_at = NOW() + $3::interval,
+			heartbeat_at = NOW()
+		WHERE id = $1 AND lease_owner = $2 AND status = $4`
+
+	tag, err := r.db.Exec(ctx, query, id, workerID, leaseDuration, models.JobStatusInProgress)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Release returns an in-progress job held by workerID to the pending queue,
+// e.g. when the worker is shutting down mid-job. The interrupted attempt is
+// not counted against the job.
+func (r *GenerationJobRepository) Release(ctx context.Context, id uuid.UUID, workerID string) error {
+	query := `
+		UPDATE generation_jobs SET
+			status = $3,
+			lease_owner = NULL,
+			lease_expires_at = NULL,
+			attempts = GREATEST(attempts - 1, 0),
+			updated_at = NOW()
+		WHERE id = $1 AND lease_owner = $2 AND status = $4`
 
-	_, err := r.db.Exec(ctx, query, id, models.JobStatusFailed, errorMessage)
+	_, err := r.db.Exec(ctx, query, id, workerID, models.JobStatusPending, models.JobStatusInProgress)
 	return err
 }
+
+// Cancel stops a pending or in-progress job, marking its unfinished steps as
+// skipped and dropping any lease. The row is locked so cancellation cannot
+// interleave with CompleteWithSections. Returns ErrJobNotActive if the job has
+// already finished.
+func (r *GenerationJobRepository) Cancel(ctx context.Context, id uuid.UUID) (*models.GenerationJob, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var status models.GenerationJobStatus
+	var steps models.GenerationSteps
+	err = tx.QueryRow(ctx, `SELECT status, steps FROM generation_jobs WHERE id = $1 FOR UPDATE`, id).Scan(&status, &steps)
+	if err != nil {
+		return nil, err
+	}
+	if status != models.JobStatusPending && status != models.JobStatusInProgress {
+		return nil, ErrJobNotActive
+	}
+
+	for i := range steps {
+		if steps[i].Status != "completed" {
+			steps[i].Status = "skipped"
+		}
+	}
+
+	query := `
+		UPDATE generation_jobs SET
+			status = $2,
+			steps = $3,
+			lease_owner = NULL,
+			lease_expires_at = NULL,
+			completed_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1`
+
+	if _, err := tx.Exec(ctx, query, id, models.JobStatusCancelled, steps); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// CompleteWithSections stores a job's assembled sections as its petition's
+// draft, with their rendering as the generated content, and marks the job
+// completed in a single transaction. Returns ErrJobNotActive, leaving the
+// petition untouched, if the job was cancelled in the meantime.
+func (r *GenerationJobRepository) CompleteWithSections(ctx context.Context, id, petitionID uuid.UUID, sections []*models.PetitionSection) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var status models.GenerationJobStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM generation_jobs WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != models.JobStatusInProgress {
+		return ErrJobNotActive
+	}
+
+	if err := replacePetitionSections(ctx, tx, petitionID, sections); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE petitions SET
+			generated_content = $2,
+			updated_at = NOW()
+		WHERE id = $1`, petitionID, models.RenderSections(sections))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE generation_jobs SET
+			status = $2,
+			partial_text = NULL,
+			completed_at = NOW(),
+			updated_at = NOW(),
+			lease_owner = NULL,
+			lease_expires_at = NULL
+		WHERE id = $1`, id, models.JobStatusCompleted)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Requeue returns a failed or cancelled job to the pending queue with the
+// given step progress, clearing its error and attempt count. Returns
+// ErrJobNotRetryable if the job is in any other state.
+func (r *GenerationJobRepository) Requeue(ctx context.Context, id uuid.UUID, steps models.GenerationSteps) error {
+	query := `
+		UPDATE generation_jobs SET
+			status = $2,
+			current_step = NULL,
+			steps = $3,
+			error_message = NULL,
+			partial_text = NULL,
+			attempts = 0,
+			lease_owner = NULL,
+			lease_expires_at = NULL,
+			completed_at = NULL,
+			updated_at = NOW()
+		WHERE id = $1 AND status IN ($4, $5)`
+
+	tag, err := r.db.Exec(ctx, query, id, models.JobStatusPending, steps, models.JobStatusFailed, models.JobStatusCancelled)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobNotRetryable
+	}
+	return nil
+}
+
+// SaveSection stores a completed section for a job, replacing any earlier
+// version of the same step
+func (r *GenerationJobRepository) SaveSection(ctx context.Context, section *models.GenerationSection) error {
+	query := `
+		INSERT INTO generation_job_sections (job_id, step_name, position, title, content, citations, chunk_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (job_id, step_name) DO UPDATE SET
+			position = EXCLUDED.position,
+			title = EXCLUDED.title,
+			content = EXCLUDED.content,
+			citations = EXCLUDED.citations,
+			chunk_ids = EXCLUDED.chunk_ids,
+			created_at = NOW()
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(ctx, query,
+		section.JobID,
+		section.StepName,
+		section.Position,
+		section.Title,
+		section.Content,
+		section.Citations,
+		section.ChunkIDs,
+	).Scan(&section.ID, &section.CreatedAt)
+}
+
+// ListSections returns the saved sections of a job in document order
+func (r *GenerationJobRepository) ListSections(ctx context.Context, jobID uuid.UUID) ([]*models.GenerationSection, error) {
+	query := `
+		SELECT id, job_id, step_name, position, title, content, citations, chunk_ids, created_at
+		FROM generation_job_sections
+		WHERE job_id = $1
+		ORDER BY position`
+
+	rows, err := r.db.Query(ctx, query, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := make([]*models.GenerationSection, 0)
+	for rows.Next() {
+		section := &models.GenerationSection{}
+		err := rows.Scan(
+			&section.ID,
+			&section.JobID,
+			&section.StepName,
+			&section.Position,
+			&section.Title,
+			&section.Content,
+			&section.Citations,
+			&section.ChunkIDs,
+			&section.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+
+	return sections, rows.Err()
+}
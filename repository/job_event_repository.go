@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"unicode/utf8"
+
+	"meritdraft-backend/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// JobEventChannel is the Postgres NOTIFY channel carrying generation job events
+const JobEventChannel = "generation_job_events"
+
+// maxNotifyPayload keeps payloads under Postgres' 8000 byte NOTIFY limit
+const maxNotifyPayload = 7900
+
+// JobEventRepository publishes and listens for generation job events
+type JobEventRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewJobEventRepository creates a new job event repository
+func NewJobEventRepository(db *pgxpool.Pool) *JobEventRepository {
+	return &JobEventRepository{db: db}
+}
+
+// Publish sends an event to every listening server instance. Section text
+// too large for one notification is split across several delta events.
+func (r *JobEventRepository) Publish(ctx context.Context, event models.JobEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxNotifyPayload && event.Type == models.JobEventSectionDelta && len(event.Text) > 1 {
+		overhead := len(payload) - len(event.Text)
+		for _, part := range splitText(event.Text, maxNotifyPayload-overhead) {
+			chunk := event
+			chunk.Text = part
+			if err := r.Publish(ctx, chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	_, err = r.db.Exec(ctx, "SELECT pg_notify($1, $2)", JobEventChannel, string(payload))
+	return err
+}
+
+// Listen delivers events to handle until ctx is cancelled or the connection
+// fails. It holds a dedicated pool connection for the duration.
+func (r *JobEventRepository) Listen(ctx context.Context, handle func(models.JobEvent)) error {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+JobEventChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			// Leave no LISTEN behind on a connection going back to the pool
+			conn.Conn().Close(context.Background())
+			return err
+		}
+
+		var event models.JobEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			continue
+		}
+		handle(event)
+	}
+}
+
+// splitText splits s into pieces of at most size bytes (after JSON escaping,
+// approximately) without breaking UTF-8 sequences
+func splitText(s string, size int) []string {
+	// JSON escaping can grow text up to 6x for control characters; halve the
+	// budget to cover the common case of quotes and newlines
+	size = size / 2
+	if size < 1 {
+		size = 1
+	}
+
+	parts := make([]string, 0, len(s)/size+1)
+	for len(s) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			cut = size
+		}
+		parts = append(parts, s[:cut])
+		s = s[cut:]
+	}
+	return append(parts, s)
+}
@@ -34,12 +34,14 @@ func formatVector(embedding []float64) string {
 
 // SearchByCriterion performs a hybrid vector search for legal chunks
 // embedding: Query embedding vector (768 dimensions)
+// visaType: Corpus to search ("O-1", "EB-1A", "NIW")
 // criterion: Criterion tag (e.g., "awards", "judging")
 // sourceType: Source type filter ("regulation", "appeal_decision", "precedent_case")
 // limit: Maximum number of chunks to return
 func (r *LegalChunkRepository) SearchByCriterion(
 	ctx context.Context,
 	embedding []float64,
+	visaType string,
 	criterion string,
 	sourceType string,
 	limit int,
@@ -55,12 +57,54 @@ func (r *LegalChunkRepository) SearchByCriterion(
 	var args []interface{}
 	if criterion == "" {
 		criterionFilter = "criterion_tag IS NULL"
-		args = []interface{}{vectorStr, sourceType, limit}
+		args = []interface{}{vectorStr, visaType, sourceType, limit}
 	} else {
-		criterionFilter = "criterion_tag = $2"
-		args = []interface{}{vectorStr, criterion, sourceType, limit}
+		criterionFilter = "criterion_tag = $3"
+		args = []interface{}{vectorStr, visaType, criterion, sourceType, limit}
 	}
 
+	return r.search(ctx, criterionFilter, args)
+}
+
+// SearchNIW performs a hybrid vector search over the NIW corpus for one
+// Dhanasar prong
+// embedding: Query embedding vector (768 dimensions)
+// visaType: Corpus to search (the registry's corpus for EB-2 NIW, "NIW")
+// tags: Criterion tags of the prong (e.g., "niw_substantial_merit", "niw_national_importance");
+// empty searches untagged chunks such as the Dhanasar balancing test
+// sourceType: Source type filter ("regulation", "appeal_decision", "precedent_case")
+// limit: Maximum number of chunks to return
+func (r *LegalChunkRepository) SearchNIW(
+	ctx context.Context,
+	embedding []float64,
+	visaType string,
+	tags []string,
+	sourceType string,
+	limit int,
+) ([]models.LegalChunk, error) {
+	if len(embedding) != 768 {
+		return nil, fmt.Errorf("embedding must be 768 dimensions, got %d", len(embedding))
+	}
+
+	vectorStr := formatVector(embedding)
+
+	var criterionFilter string
+	var args []interface{}
+	if len(tags) == 0 {
+		criterionFilter = "criterion_tag IS NULL"
+		args = []interface{}{vectorStr, visaType, sourceType, limit}
+	} else {
+		criterionFilter = "criterion_tag = ANY($3)"
+		args = []interface{}{vectorStr, visaType, tags, sourceType, limit}
+	}
+
+	return r.search(ctx, criterionFilter, args)
+}
+
+// search runs a vector search with the given criterion filter. args holds
+// the query vector, the visa type, any filter arguments, then the source
+// type and limit.
+func (r *LegalChunkRepository) search(ctx context.Context, criterionFilter string, args []interface{}) ([]models.LegalChunk, error) {
 	query := fmt.Sprintf(`
 		SELECT 
 			id,
@@ -81,7 +125,7 @@ func (r *LegalChunkRepository) SearchByCriterion(
 		WHERE 
 			%s
 			AND source_type = $%d
-			AND visa_type = 'O-1'
+			AND visa_type = $2
 			AND (
 				source_type != 'appeal_decision' 
 				OR is_winning_argument = true
@@ -131,4 +175,3 @@ func (r *LegalChunkRepository) SearchByCriterion(
 
 	return chunks, nil
 }
-
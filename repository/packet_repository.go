@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"meritdraft-backend/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PacketRepository handles database operations for filing packets
+type PacketRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPacketRepository creates a new packet repository
+func NewPacketRepository(db *pgxpool.Pool) *PacketRepository {
+	return &PacketRepository{db: db}
+}
+
+const packetColumns = `
+	id, petition_id, status, bates_prefix, file_id, page_count, exhibits, error_message,
+	attempts, lease_expires_at, created_by, created_at, updated_at, completed_at`
+
+func scanPacket(row pgx.Row) (*models.Packet, error) {
+	packet := &models.Packet{}
+	err := row.Scan(
+		&packet.ID,
+		&packet.PetitionID,
+		&packet.Status,
+		&packet.BatesPrefix,
+		&packet.FileID,
+		&packet.PageCount,
+		&packet.Exhibits,
+		&packet.ErrorMessage,
+		&packet.Attempts,
+		&packet.LeaseExpires,
+		&packet.CreatedBy,
+		&packet.CreatedAt,
+		&packet.UpdatedAt,
+		&packet.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// Create queues a packet build
+func (r *PacketRepository) Create(ctx context.Context, petitionID uuid.UUID, batesPrefix string, createdBy *uuid.UUID) (*models.Packet, error) {
+	query := `
+		INSERT INTO packets (petition_id, status, bates_prefix, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING` + packetColumns
+
+	return scanPacket(r.db.QueryRow(ctx, query, petitionID, models.PacketPending, batesPrefix, createdBy))
+}
+
+// GetByID retrieves a packet by ID
+func (r *PacketRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Packet, error) {
+	query := `SELECT` + packetColumns + ` FROM packets WHERE id = $1`
+	return scanPacket(r.db.QueryRow(ctx, query, id))
+}
+
+// Lease atomically claims the oldest pending packet, or one whose lease
+// expired because its process stopped mid-way. Returns nil, nil when none
+// is available.
+func (r *PacketRepository) Lease(ctx context.Context, leaseDuration time.Duration) (*models.Packet, error) {
+	query := `
+		UPDATE packets SET
+			status = $2,
+			lease_expires_at = NOW() + $1::interval,
+			attempts = attempts + 1,
+			updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM packets
+			WHERE status = $3
+				OR (status = $2 AND lease_expires_at < NOW())
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING` + packetColumns
+
+	packet, err := scanPacket(r.db.QueryRow(ctx, query, leaseDuration, models.PacketInProgress, models.PacketPending))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return packet, err
+}
+
+// ErrPacketLeaseLost is returned when a build no longer holds the lease on
+// its packet, because the lease expired and another process leased it again
+var ErrPacketLeaseLost = errors.New("packet lease lost")
+
+// Heartbeat extends the lease of an in-progress packet. attempt is the
+// packet's Attempts when leased; each lease counts an attempt, so it names
+// the lease. Returns ErrPacketLeaseLost if the packet has been leased again.
+func (r *PacketRepository) Heartbeat(ctx context.Context, id uuid.UUID, attempt int, leaseDuration time.Duration) error {
+	query := `
+		UPDATE packets SET
+			lease_expires_at = NOW() + $3::interval,
+			updated_at = NOW()
+		WHERE id = $1 AND attempts = $2 AND status = $4`
+
+	return r.exec(ctx, query, id, attempt, leaseDuration, models.PacketInProgress)
+}
+
+// Complete records the bundled file of a packet leased at attempt.
+// Returns ErrPacketLeaseLost if the packet has been leased again.
+func (r *PacketRepository) Complete(ctx context.Context, id uuid.UUID, attempt int, fileID uuid.UUID, pageCount int, exhibits models.PacketExhibits) error {
+	query := `
+		UPDATE packets SET
+			status = $3,
+			file_id = $4,
+			page_count = $5,
+			exhibits = $6,
+			lease_expires_at = NULL,
+			completed_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1 AND attempts = $2 AND status = $7`
+
+	return r.exec(ctx, query, id, attempt, models.PacketCompleted, fileID, pageCount, exhibits, models.PacketInProgress)
+}
+
+// Retry returns a packet leased at attempt to the queue after a failed
+// build, keeping the error until the next build
+func (r *PacketRepository) Retry(ctx context.Context, id uuid.UUID, attempt int, errorMessage string) error {
+	query := `
+		UPDATE packets SET
+			status = $3,
+			error_message = $4,
+			lease_expires_at = NULL,
+			updated_at = NOW()
+		WHERE id = $1 AND attempts = $2 AND status = $5`
+
+	return r.exec(ctx, query, id, attempt, models.PacketPending, errorMessage, models.PacketInProgress)
+}
+
+// Fail marks a packet leased at attempt as failed
+func (r *PacketRepository) Fail(ctx context.Context, id uuid.UUID, attempt int, errorMessage string) error {
+	query := `
+		UPDATE packets SET
+			status = $3,
+			error_message = $4,
+			lease_expires_at = NULL,
+			updated_at = NOW()
+		WHERE id = $1 AND attempts = $2 AND status = $5`
+
+	return r.exec(ctx, query, id, attempt, models.PacketFailed, errorMessage, models.PacketInProgress)
+}
+
+// exec runs an update of a leased packet, returning ErrPacketLeaseLost if
+// it matched no row
+func (r *PacketRepository) exec(ctx context.Context, query string, args ...interface{}) error {
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPacketLeaseLost
+	}
+	return nil
+}
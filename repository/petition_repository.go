@@ -2,11 +2,16 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"meritdraft-backend/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -27,9 +32,9 @@ func (r *PetitionRepository) Create(ctx context.Context, petition *models.Petiti
 			user_id, status, client_name, visa_type, petitioner_name, 
 			field_of_expertise, cv_file_id, job_offer_file_id, scholar_link,
 			parsed_documents, selected_criteria, criteria_details,
-			generated_content, refine_instructions
+			generated_content, refine_instructions, firm_id, drafting_model
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 		) RETURNING id, created_at, updated_at`
 
 	err := r.db.QueryRow(
@@ -48,6 +53,8 @@ func (r *PetitionRepository) Create(ctx context.Context, petition *models.Petiti
 		petition.CriteriaDetails,
 		petition.GeneratedContent,
 		petition.RefineInstructions,
+		petition.FirmID,
+		petition.DraftingModel,
 	).Scan(&petition.ID, &petition.CreatedAt, &petition.UpdatedAt)
 
 	return err
@@ -57,17 +64,18 @@ func (r *PetitionRepository) Create(ctx context.Context, petition *models.Petiti
 func (r *PetitionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Petition, error) {
 	petition := &models.Petition{}
 	query := `
-		SELECT id, user_id, status, client_name, visa_type, petitioner_name,
+		SELECT id, user_id, firm_id, status, client_name, visa_type, petitioner_name,
 			field_of_expertise, cv_file_id, job_offer_file_id, scholar_link,
 			parsed_documents, selected_criteria, criteria_details,
-			generated_content, refine_instructions,
-			created_at, updated_at, completed_at
+			generated_content, refine_instructions, drafting_model,
+			created_at, updated_at, completed_at, archived_at, purge_after
 		FROM petitions
 		WHERE id = $1`
 
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&petition.ID,
 		&petition.UserID,
+		&petition.FirmID,
 		&petition.Status,
 		&petition.ClientName,
 		&petition.VisaType,
@@ -81,9 +89,12 @@ func (r *PetitionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 		&petition.CriteriaDetails,
 		&petition.GeneratedContent,
 		&petition.RefineInstructions,
+		&petition.DraftingModel,
 		&petition.CreatedAt,
 		&petition.UpdatedAt,
 		&petition.CompletedAt,
+		&petition.ArchivedAt,
+		&petition.PurgeAfter,
 	)
 
 	if err != nil {
@@ -93,6 +104,30 @@ func (r *PetitionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 	return petition, nil
 }
 
+// GetAccessRole returns the user's effective role on a petition. Petitions shared
+// with a firm use the user's firm role; unshared petitions grant the creator
+// the owner role. An empty role means the user has no access.
+func (r *PetitionRepository) GetAccessRole(ctx context.Context, petition *models.Petition, userID uuid.UUID) (models.FirmRole, error) {
+	if petition.FirmID == nil {
+		if petition.UserID == userID {
+			return models.FirmRoleOwner, nil
+		}
+		return "", nil
+	}
+
+	var role models.FirmRole
+	query := `SELECT role FROM firm_members WHERE firm_id = $1 AND user_id = $2`
+	err := r.db.QueryRow(ctx, query, *petition.FirmID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
 // Update updates a petition
 func (r *PetitionRepository) Update(ctx context.Context, petition *models.Petition) error {
 	query := `
@@ -110,6 +145,8 @@ func (r *PetitionRepository) Update(ctx context.Context, petition *models.Petiti
 			criteria_details = $12,
 			generated_content = $13,
 			refine_instructions = $14,
+			firm_id = $15,
+			drafting_model = $16,
 			updated_at = NOW()
 		WHERE id = $1
 		RETURNING updated_at`
@@ -130,55 +167,250 @@ func (r *PetitionRepository) Update(ctx context.Context, petition *models.Petiti
 		petition.CriteriaDetails,
 		petition.GeneratedContent,
 		petition.RefineInstructions,
+		petition.FirmID,
+		petition.DraftingModel,
 	).Scan(&petition.UpdatedAt)
 
 	return err
 }
 
-// UpdateGeneratedContent updates only the generated content
-func (r *PetitionRepository) UpdateGeneratedContent(ctx context.Context, id uuid.UUID, content string) error {
+// UpdateSelectedCriteria saves only the petition's selected criteria, so
+// fields changed since the petition was read are left as they are
+func (r *PetitionRepository) UpdateSelectedCriteria(ctx context.Context, petition *models.Petition) error {
 	query := `
 		UPDATE petitions SET
-			generated_content = $2,
+			selected_criteria = $2,
 			updated_at = NOW()
-		WHERE id = $1`
+		WHERE id = $1
+		RETURNING updated_at`
 
-	_, err := r.db.Exec(ctx, query, id, content)
-	return err
+	return r.db.QueryRow(ctx, query, petition.ID, petition.SelectedCriteria).Scan(&petition.UpdatedAt)
 }
 
-// ListByUserID retrieves all petitions for a user
-func (r *PetitionRepository) ListByUserID(ctx context.Context, userID uuid.UUID, status *models.PetitionStatus, limit, offset int) ([]*models.Petition, error) {
-	query := `
-		SELECT id, user_id, status, client_name, visa_type, petitioner_name,
-			field_of_expertise, cv_file_id, job_offer_file_id, scholar_link,
-			parsed_documents, selected_criteria, criteria_details,
-			generated_content, refine_instructions,
-			created_at, updated_at, completed_at
-		FROM petitions
-		WHERE user_id = $1`
+// PrefillCriteriaDetails fills the petition's empty criteria_details entries
+// from details, and its publication count when none is recorded. Entries
+// the user has already filled in are left untouched. The row is locked so a
+// concurrent update cannot be overwritten. Returns the criteria filled.
+func (r *PetitionRepository) PrefillCriteriaDetails(ctx context.Context, id uuid.UUID, details models.CriteriaDetails, publicationsCount int) ([]string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-	args := []interface{}{userID}
-	argIndex := 2
+	var current models.CriteriaDetails
+	var parsed *models.ParsedDocuments
+	err = tx.QueryRow(ctx, `SELECT criteria_details, parsed_documents FROM petitions WHERE id = $1 FOR UPDATE`, id).Scan(&current, &parsed)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		current = make(models.CriteriaDetails)
+	}
+
+	filled := make([]string, 0)
+	for criterion, detail := range details {
+		if len(current[criterion]) > 0 {
+			continue
+		}
+		current[criterion] = detail
+		filled = append(filled, criterion)
+	}
+	sort.Strings(filled)
+
+	if parsed == nil {
+		parsed = &models.ParsedDocuments{}
+	}
+	countFilled := parsed.PublicationsCount == 0 && publicationsCount > 0
+	if countFilled {
+		parsed.PublicationsCount = publicationsCount
+	}
+	if len(filled) == 0 && !countFilled {
+		return filled, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE petitions SET
+			criteria_details = $2,
+			parsed_documents = $3,
+			updated_at = NOW()
+		WHERE id = $1`, id, current, parsed)
+	if err != nil {
+		return nil, err
+	}
 
-	if status != nil {
-		query += fmt.Sprintf(" AND status = $%d", argIndex)
-		args = append(args, *status)
-		argIndex++
+	return filled, tx.Commit(ctx)
+}
+
+// ImportPublications merges publications into the petition's authorship
+// details when authorship is true, replaces its publication metrics, and
+// sets its scholar link when none is recorded. The row is locked so a
+// concurrent update cannot be overwritten. Returns the number of
+// publications added to the authorship details.
+func (r *PetitionRepository) ImportPublications(
+	ctx context.Context,
+	id uuid.UUID,
+	publications []models.Publication,
+	authorship bool,
+	metrics models.ParsedDocuments,
+	scholarLink *string,
+) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
 	}
+	defer tx.Rollback(ctx)
 
-	query += " ORDER BY created_at DESC"
+	var current models.CriteriaDetails
+	err = tx.QueryRow(ctx, `SELECT criteria_details FROM petitions WHERE id = $1 FOR UPDATE`, id).Scan(&current)
+	if err != nil {
+		return 0, err
+	}
+	if current == nil {
+		current = make(models.CriteriaDetails)
+	}
 
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argIndex)
-		args = append(args, limit)
-		argIndex++
-		if offset > 0 {
-			query += fmt.Sprintf(" OFFSET $%d", argIndex)
-			args = append(args, offset)
+	added := 0
+	if authorship {
+		current["authorship"], added, err = models.MergePublications(current["authorship"], publications)
+		if err != nil {
+			return 0, err
 		}
 	}
 
+	_, err = tx.Exec(ctx, `
+		UPDATE petitions SET
+			criteria_details = $2,
+			parsed_documents = $3,
+			scholar_link = COALESCE(NULLIF(scholar_link, ''), $4),
+			updated_at = NOW()
+		WHERE id = $1`, id, current, metrics, scholarLink)
+	if err != nil {
+		return 0, err
+	}
+
+	return added, tx.Commit(ctx)
+}
+
+// PetitionSortField is a column petitions can be ordered by
+type PetitionSortField string
+
+const (
+	PetitionSortCreatedAt  PetitionSortField = "created_at"
+	PetitionSortUpdatedAt  PetitionSortField = "updated_at"
+	PetitionSortClientName PetitionSortField = "client_name"
+)
+
+// sortExpression returns the SQL expression used to order by the field
+func (f PetitionSortField) sortExpression() string {
+	switch f {
+	case PetitionSortUpdatedAt:
+		return "updated_at"
+	case PetitionSortClientName:
+		return "COALESCE(client_name, '')"
+	default:
+		return "created_at"
+	}
+}
+
+// PetitionCursor identifies the last row of a page for keyset pagination.
+// Value is a time.Time for timestamp sorts and a string for client_name.
+type PetitionCursor struct {
+	Value interface{}
+	ID    uuid.UUID
+}
+
+// PetitionListOptions holds filters, ordering and paging for ListByUserID
+type PetitionListOptions struct {
+	Status             *models.PetitionStatus
+	ExcludeArchived    bool
+	VisaType           *models.VisaType
+	ClientNameContains string
+	CreatedAfter       *time.Time
+	CreatedBefore      *time.Time
+	UpdatedAfter       *time.Time
+	UpdatedBefore      *time.Time
+	SortBy             PetitionSortField
+	SortDesc           bool
+	After              *PetitionCursor // Rows strictly after this cursor in sort order
+	Limit              int
+}
+
+// buildListFilter returns the WHERE clause and args shared by list and count queries
+func buildListFilter(userID uuid.UUID, opts PetitionListOptions) (string, []interface{}) {
+	where := `
+		WHERE (
+			(firm_id IS NULL AND user_id = $1)
+			OR firm_id IN (SELECT firm_id FROM firm_members WHERE user_id = $1)
+		)`
+	args := []interface{}{userID}
+
+	addArg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if opts.Status != nil {
+		where += " AND status = " + addArg(*opts.Status)
+	}
+	if opts.ExcludeArchived {
+		where += " AND status != " + addArg(models.StatusArchived)
+	}
+	if opts.VisaType != nil {
+		where += " AND visa_type = " + addArg(*opts.VisaType)
+	}
+	if opts.ClientNameContains != "" {
+		// Escape LIKE wildcards so the input is matched literally
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(opts.ClientNameContains)
+		where += " AND client_name ILIKE " + addArg("%"+escaped+"%")
+	}
+	if opts.CreatedAfter != nil {
+		where += " AND created_at >= " + addArg(*opts.CreatedAfter)
+	}
+	if opts.CreatedBefore != nil {
+		where += " AND created_at < " + addArg(*opts.CreatedBefore)
+	}
+	if opts.UpdatedAfter != nil {
+		where += " AND updated_at >= " + addArg(*opts.UpdatedAfter)
+	}
+	if opts.UpdatedBefore != nil {
+		where += " AND updated_at < " + addArg(*opts.UpdatedBefore)
+	}
+
+	return where, args
+}
+
+// ListByUserID retrieves petitions visible to a user: their own unshared
+// petitions plus every petition shared with a firm they belong to
+func (r *PetitionRepository) ListByUserID(ctx context.Context, userID uuid.UUID, opts PetitionListOptions) ([]*models.Petition, error) {
+	where, args := buildListFilter(userID, opts)
+
+	sortExpr := opts.SortBy.sortExpression()
+	direction, comparator := "ASC", ">"
+	if opts.SortDesc {
+		direction, comparator = "DESC", "<"
+	}
+
+	if opts.After != nil {
+		args = append(args, opts.After.Value, opts.After.ID)
+		where += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", sortExpr, comparator, len(args)-1, len(args))
+	}
+
+	query := `
+		SELECT id, user_id, firm_id, status, client_name, visa_type, petitioner_name,
+			field_of_expertise, cv_file_id, job_offer_file_id, scholar_link,
+			parsed_documents, selected_criteria, criteria_details,
+			generated_content, refine_instructions, drafting_model,
+			created_at, updated_at, completed_at, archived_at, purge_after
+		FROM petitions` + where
+
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s", sortExpr, direction, direction)
+
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
@@ -191,6 +423,7 @@ func (r *PetitionRepository) ListByUserID(ctx context.Context, userID uuid.UUID,
 		err := rows.Scan(
 			&petition.ID,
 			&petition.UserID,
+			&petition.FirmID,
 			&petition.Status,
 			&petition.ClientName,
 			&petition.VisaType,
@@ -204,9 +437,12 @@ func (r *PetitionRepository) ListByUserID(ctx context.Context, userID uuid.UUID,
 			&petition.CriteriaDetails,
 			&petition.GeneratedContent,
 			&petition.RefineInstructions,
+			&petition.DraftingModel,
 			&petition.CreatedAt,
 			&petition.UpdatedAt,
 			&petition.CompletedAt,
+			&petition.ArchivedAt,
+			&petition.PurgeAfter,
 		)
 		if err != nil {
 			return nil, err
@@ -217,6 +453,16 @@ func (r *PetitionRepository) ListByUserID(ctx context.Context, userID uuid.UUID,
 	return petitions, rows.Err()
 }
 
+// CountByUserID counts petitions visible to a user matching the filters in opts.
+// Ordering, cursor and limit are ignored.
+func (r *PetitionRepository) CountByUserID(ctx context.Context, userID uuid.UUID, opts PetitionListOptions) (int, error) {
+	where, args := buildListFilter(userID, opts)
+
+	var count int
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM petitions"+where, args...).Scan(&count)
+	return count, err
+}
+
 // Delete deletes a petition
 func (r *PetitionRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM petitions WHERE id = $1`
@@ -224,3 +470,134 @@ func (r *PetitionRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+// Archive soft-deletes a petition by moving it to the archived status.
+// A non-nil purgeDelay schedules a hard purge that long from now; the
+// scheduled time is returned (nil when no purge was requested).
+func (r *PetitionRepository) Archive(ctx context.Context, id uuid.UUID, purgeDelay *time.Duration) (*time.Time, error) {
+	query := `
+		UPDATE petitions SET
+			status = $2,
+			archived_at = COALESCE(archived_at, NOW()),
+			purge_after = NOW() + $3::interval,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING purge_after`
+
+	var purgeAfter *time.Time
+	err := r.db.QueryRow(ctx, query, id, models.StatusArchived, purgeDelay).Scan(&purgeAfter)
+	return purgeAfter, err
+}
+
+// Restore moves an archived petition back to draft and cancels any scheduled purge
+func (r *PetitionRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE petitions SET
+			status = $2,
+			archived_at = NULL,
+			purge_after = NULL,
+			updated_at = NOW()
+		WHERE id = $1 AND status = $3`
+
+	_, err := r.db.Exec(ctx, query, id, models.StatusDraft, models.StatusArchived)
+	return err
+}
+
+// ListPurgeable returns IDs of archived petitions whose retention window has elapsed
+func (r *PetitionRepository) ListPurgeable(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id FROM petitions
+		WHERE status = $1 AND purge_after IS NOT NULL AND purge_after <= NOW()
+		ORDER BY purge_after
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, models.StatusArchived, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Purge permanently deletes a petition whose retention window has elapsed,
+// together with its generation jobs and file rows, in a single transaction.
+// The storage paths of the deleted files are queued in blob_deletions (see
+// FileRepository.ListBlobDeletions). Returns pgx.ErrNoRows if the petition
+// is no longer due for purging (e.g. it was restored).
+func (r *PetitionRepository) Purge(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the row and re-check the schedule so a concurrent restore wins
+	var userID uuid.UUID
+	var cvFileID, jobOfferFileID *uuid.UUID
+	err = tx.QueryRow(ctx, `
+		SELECT user_id, cv_file_id, job_offer_file_id FROM petitions
+		WHERE id = $1 AND status = $2 AND purge_after IS NOT NULL AND purge_after <= NOW()
+		FOR UPDATE`,
+		id, models.StatusArchived,
+	).Scan(&userID, &cvFileID, &jobOfferFileID)
+	if err != nil {
+		return err
+	}
+
+	// Drop the petition's references to files so the file rows can be deleted
+	_, err = tx.Exec(ctx, `UPDATE petitions SET cv_file_id = NULL, job_offer_file_id = NULL WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	// Delete files attached to the petition, plus the owner's unattached
+	// CV and job offer uploads unless another petition still references
+	// them. Files of other users or other petitions are never deleted. The
+	// blobs are queued for deletion after commit, so rows never point at
+	// missing files and failed blob deletes are retried.
+	_, err = tx.Exec(ctx, `
+		WITH deleted AS (
+			DELETE FROM files f
+			WHERE f.petition_id = $1
+				OR (
+					f.id IN ($2, $3)
+					AND f.petition_id IS NULL
+					AND f.user_id = $4
+					AND NOT EXISTS (
+						SELECT 1 FROM petitions p
+						WHERE p.id != $1 AND (p.cv_file_id = f.id OR p.job_offer_file_id = f.id)
+					)
+				)
+			RETURNING f.storage_path
+		)
+		INSERT INTO blob_deletions (storage_path)
+		SELECT storage_path FROM deleted
+		ON CONFLICT (storage_path) DO NOTHING`,
+		id, cvFileID, jobOfferFileID, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM generation_jobs WHERE petition_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM petitions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+
+	"meritdraft-backend/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PetitionSectionRepository handles database operations for the sections
+// of petition drafts
+type PetitionSectionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPetitionSectionRepository creates a new petition section repository
+func NewPetitionSectionRepository(db *pgxpool.Pool) *PetitionSectionRepository {
+	return &PetitionSectionRepository{db: db}
+}
+
+const petitionSectionColumns = `
+	id, petition_id, job_id, position, level, step_name, criterion, title, content,
+	citations, chunk_ids, created_at, updated_at`
+
+// ListByPetitionID retrieves the sections of a petition's draft in
+// document order. Petitions without a stored draft have none.
+func (r *PetitionSectionRepository) ListByPetitionID(ctx context.Context, petitionID uuid.UUID) ([]*models.PetitionSection, error) {
+	return listPetitionSections(ctx, r.db, petitionID)
+}
+
+func listPetitionSections(ctx context.Context, q queryer, petitionID uuid.UUID) ([]*models.PetitionSection, error) {
+	query := `SELECT` + petitionSectionColumns + `
+		FROM petition_sections
+		WHERE petition_id = $1
+		ORDER BY position`
+
+	rows, err := q.Query(ctx, query, petitionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := make([]*models.PetitionSection, 0)
+	for rows.Next() {
+		section := &models.PetitionSection{}
+		err := rows.Scan(
+			&section.ID,
+			&section.PetitionID,
+			&section.JobID,
+			&section.Position,
+			&section.Level,
+			&section.StepName,
+			&section.Criterion,
+			&section.Title,
+			&section.Content,
+			&section.Citations,
+			&section.ChunkIDs,
+			&section.CreatedAt,
+			&section.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+
+	return sections, rows.Err()
+}
+
+// Update saves an edited title and content of a section and renders the
+// petition's generated content from its sections again, under a lock on the
+// petition so concurrent edits, exhibit lettering and draft completions do
+// not interleave. Returns pgx.ErrNoRows if the section is not part of the
+// petition's draft.
+func (r *PetitionSectionRepository) Update(ctx context.Context, petitionID, sectionID uuid.UUID, title, content string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM petitions WHERE id = $1 FOR UPDATE`, petitionID); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE petition_sections SET
+			title = $3,
+			content = $4,
+			updated_at = NOW()
+		WHERE id = $1 AND petition_id = $2`, sectionID, petitionID, title, content)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	sections, err := listPetitionSections(ctx, tx, petitionID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE petitions SET
+			generated_content = $2,
+			updated_at = NOW()
+		WHERE id = $1`, petitionID, models.RenderSections(sections))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// replacePetitionSections stores sections as the petition's draft in place
+// of its previous one, renumbering them in the given order
+func replacePetitionSections(ctx context.Context, tx pgx.Tx, petitionID uuid.UUID, sections []*models.PetitionSection) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM petition_sections WHERE petition_id = $1`, petitionID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO petition_sections (
+			petition_id, job_id, position, level, step_name, criterion, title, content,
+			citations, chunk_ids
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at`
+
+	for i, section := range sections {
+		section.PetitionID = petitionID
+		section.Position = i
+		if section.Citations == nil {
+			section.Citations = []string{}
+		}
+		if section.ChunkIDs == nil {
+			section.ChunkIDs = []uuid.UUID{}
+		}
+
+		err := tx.QueryRow(ctx, query,
+			section.PetitionID,
+			section.JobID,
+			section.Position,
+			section.Level,
+			section.StepName,
+			section.Criterion,
+			section.Title,
+			section.Content,
+			section.Citations,
+			section.ChunkIDs,
+		).Scan(&section.ID, &section.CreatedAt, &section.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
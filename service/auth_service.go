@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"meritdraft-backend/models"
+	"meritdraft-backend/repository"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AuthService handles login and session token verification
+type AuthService struct {
+	userRepo *repository.UserRepository
+	secret   []byte
+	tokenTTL time.Duration
+}
+
+// AuthServiceOption is a functional option for AuthService
+type AuthServiceOption func(*AuthService)
+
+// AuthWithUserRepository sets the user repository
+func AuthWithUserRepository(repo *repository.UserRepository) AuthServiceOption {
+	return func(s *AuthService) {
+		s.userRepo = repo
+	}
+}
+
+// AuthWithSecret sets the HMAC secret used to sign session tokens
+func AuthWithSecret(secret []byte) AuthServiceOption {
+	return func(s *AuthService) {
+		s.secret = secret
+	}
+}
+
+// AuthWithTokenTTL sets how long issued session tokens remain valid
+func AuthWithTokenTTL(ttl time.Duration) AuthServiceOption {
+	return func(s *AuthService) {
+		s.tokenTTL = ttl
+	}
+}
+
+// NewAuthService creates a new auth service
+func NewAuthService(opts ...AuthServiceOption) *AuthService {
+	s := &AuthService{
+		tokenTTL: 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidToken       = errors.New("invalid session token")
+	ErrTokenExpired       = errors.New("session token expired")
+	ErrForbidden          = errors.New("access to this resource is forbidden")
+)
+
+// sessionClaims is the signed payload of a session token
+type sessionClaims struct {
+	UserID    uuid.UUID `json:"sub"`
+	IssuedAt  int64     `json:"iat"`
+	ExpiresAt int64     `json:"exp"`
+}
+
+// LoginRequest represents a request to log in
+type LoginRequest struct {
+	Email    string
+	Password string
+}
+
+// LoginResult represents the result of a successful login
+type LoginResult struct {
+	Token     string
+	ExpiresAt time.Time
+	User      *models.User
+}
+
+// Login verifies the user's password and issues a signed session token
+func (s *AuthService) Login(ctx context.Context, req LoginRequest) (*LoginResult, error) {
+	if s.userRepo == nil {
+		return nil, errors.New("user repository not set")
+	}
+	if len(s.secret) == 0 {
+		return nil, errors.New("session secret not set")
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(req.Email))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.tokenTTL)
+	token, err := s.signToken(sessionClaims{
+		UserID:    user.ID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      user,
+	}, nil
+}
+
+// Authenticate verifies a session token and returns the user it was issued to
+func (s *AuthService) Authenticate(ctx context.Context, token string) (*models.User, error) {
+	if s.userRepo == nil {
+		return nil, errors.New("user repository not set")
+	}
+
+	claims, err := s.verifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		// The user was deleted after the token was issued
+		return nil, ErrInvalidToken
+	}
+
+	return user, nil
+}
+
+// signToken encodes claims as base64url(payload).base64url(hmac)
+func (s *AuthService) signToken(claims sessionClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encodedPayload))
+	signature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	return encodedPayload + "." + signature, nil
+}
+
+// verifyToken checks the token signature and expiry and returns its claims
+func (s *AuthService) verifyToken(token string) (*sessionClaims, error) {
+	if len(s.secret) == 0 {
+		return nil, errors.New("session secret not set")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(parts[0]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims sessionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/models"
+)
+
+// factWriter builds a fact listing one "Label: value" line at a time
+type factWriter struct {
+	strings.Builder
+}
+
+// line writes a fact on its own line; empty values are skipped
+func (w *factWriter) line(label, value string) {
+	if value == "" {
+		return
+	}
+	if w.Len() > 0 {
+		w.WriteString("\n")
+	}
+	w.WriteString(label + ": " + value)
+}
+
+// significance writes the notes every criterion accepts
+func (w *factWriter) significance(s models.Significance) {
+	w.line("Significance", s.Importance)
+	w.line("Impact", s.Impact)
+}
+
+// formatNumber formats a number exactly as entered
+// CRITICAL: Use exact numbers to prevent LLM hallucination
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// typedCriterion returns the criterion when the visa type argues it with
+// the typed O-1A/EB-1A facts, or "" so it is formatted from the registry
+func typedCriterion(visa *criteria.VisaType, criterion string) string {
+	switch models.VisaType(visa.VisaType) {
+	case models.VisaTypeO1A, models.VisaTypeEB1A:
+		return criterion
+	}
+	return ""
+}
+
+// decodeFacts decodes details into facts, logging when stored details no
+// longer match the typed schema
+func decodeFacts(criterion string, details models.CriteriaDetail, facts interface{}) bool {
+	if err := details.Decode(facts); err != nil {
+		log.Printf("Warning: Failed to decode %s facts: %v. Using generic formatting.", criterion, err)
+		return false
+	}
+	return true
+}
+
+// formatClientFacts formats criterion details as a readable string
+func (s *DraftService) formatClientFacts(visa *criteria.VisaType, criterion string, details models.CriteriaDetail) string {
+	var w factWriter
+
+	switch typedCriterion(visa, criterion) {
+	case "awards":
+		var f models.AwardsFacts
+		if !decodeFacts(criterion, details, &f) {
+			break
+		}
+		for _, award := range f.Awards {
+			name := award.Name
+			if award.Date != "" {
+				name += fmt.Sprintf(" (Date: %s)", award.Date)
+			}
+			w.line("Award", name)
+			w.line("Description", award.Description)
+			w.line("Significance", award.Importance)
+			w.line("Impact", award.Impact)
+		}
+		w.line("Description", f.Description)
+		w.significance(f.Significance)
+		return w.String()
+	case "membership":
+		var f models.MembershipFacts
+		if !decodeFacts(criterion, details, &f) {
+			break
+		}
+		for _, association := range f.Associations {
+			w.line("Association", association.Name)
+			w.line("Membership Requirements", association.MembershipRequirements)
+			w.line("Date Joined", association.Date)
+			w.line("Description", association.Description)
+		}
+		w.line("Description", f.Description)
+		w.significance(f.Significance)
+		return w.String()
+	case "media_coverage":
+		var f models.MediaCoverageFacts
+		if !decodeFacts(criterion, details, &f) {
+			break
+		}
+		for _, article := range f.Articles {
+			w.line("Article", article.Title)
+			w.line("Publication", article.Publication)
+			w.line("Author", article.Author)
+			w.line("Date", article.Date)
+		}
+		w.line("Description", f.Description)
+		w.significance(f.Significance)
+		return w.String()
+	case "judging":
+		var f models.JudgingFacts
+		if !decodeFacts(criterion, details, &f) {
+			break
+		}
+		w.line("Venue", f.Venue)
+		w.line("Role", f.Role)
+		if f.PapersReviewed != nil {
+			w.line("Papers Reviewed", strconv.Itoa(*f.PapersReviewed))
+		}
+		w.line("Description", f.Description)
+		w.significance(f.Significance)
+		return w.String()
+	case "original_contributions":
+		var f models.OriginalContributionsFacts
+		if !decodeFacts(criterion, details, &f) {
+			break
+		}
+		for _, contribution := range f.Contributions {
+			w.line("Contribution", contribution.Title)
+			w.line("Description", contribution.Description)
+			w.line("Impact", contribution.Impact)
+			w.line("Evidence of Significance", contribution.Evidence)
+		}
+		w.line("Description", f.Description)
+		w.significance(f.Significance)
+		return w.String()
+	case "authorship":
+		var f models.AuthorshipFacts
+		if !decodeFacts(criterion, details, &f) {
+			break
+		}
+		for i, publication := range f.Publications {
+			if i > 0 {
+				w.WriteString("\n")
+			}
+			w.line(fmt.Sprintf("Publication %d", i+1), publication.Title)
+			w.line("Journal", publication.Journal)
+			if publication.Year != nil {
+				w.line("Year", strconv.Itoa(*publication.Year))
+			}
+			if publication.ImpactFactor != nil && *publication.ImpactFactor > 0 {
+				w.line("Impact Factor", fmt.Sprintf("%.2f", *publication.ImpactFactor))
+			}
+			if publication.Citations != nil {
+				w.line("Citations", strconv.Itoa(*publication.Citations))
+			}
+			w.line("Significance", publication.Importance)
+			w.line("Impact", publication.Impact)
+		}
+		w.line("Description", f.Description)
+		w.significance(f.Significance)
+		return w.String()
+	case "exhibitions":
+		var f models.ExhibitionsFacts
+		if !decodeFacts(criterion, details, &f) {
+			break
+		}
+		for _, exhibition := range f.Exhibitions {
+			w.line("Exhibition", exhibition.Name)
+			w.line("Venue", exhibition.Venue)
+			w.line("Date", exhibition.Date)
+			w.line("Description", exhibition.Description)
+		}
+		w.line("Description", f.Description)
+		w.significance(f.Significance)
+		return w.String()
+	case "critical_role":
+		var f models.CriticalRoleFacts
+		if !decodeFacts(criterion, details, &f) {
+			break
+		}
+		for _, role := range f.Roles {
+			w.line("Organization", role.Organization)
+			w.line("Title", role.Title)
+			w.line("Start Date", role.StartDate)
+			w.line("End Date", role.EndDate)
+			w.line("Description", role.Description)
+			w.line("Organization Reputation", role.OrganizationReputation)
+		}
+		w.line("Description", f.Description)
+		w.significance(f.Significance)
+		return w.String()
+	case "high_salary":
+		var f models.HighSalaryFacts
+		if !decodeFacts(criterion, details, &f) {
+			break
+		}
+		if f.Salary != nil {
+			w.line("Salary", strings.TrimSpace(formatNumber(*f.Salary)+" "+f.Currency))
+		}
+		w.line("Pay Period", f.Period)
+		if f.ComparisonSalary != nil {
+			w.line("Comparative Wage", strings.TrimSpace(formatNumber(*f.ComparisonSalary)+" "+f.Currency))
+		}
+		w.line("Comparative Wage Source", f.ComparisonSource)
+		w.line("Description", f.Description)
+		w.significance(f.Significance)
+		return w.String()
+	case "commercial_success":
+		var f models.CommercialSuccessFacts
+		if !decodeFacts(criterion, details, &f) {
+			break
+		}
+		for _, success := range f.Successes {
+			w.line("Success", success.Title)
+			if success.Amount != nil {
+				w.line(strings.TrimSpace("Amount "+success.Metric), formatNumber(*success.Amount))
+			} else {
+				w.line("Metric", success.Metric)
+			}
+			w.line("Description", success.Description)
+		}
+		w.line("Description", f.Description)
+		w.significance(f.Significance)
+		return w.String()
+	}
+
+	// Criteria without a typed schema are formatted from the registry
+	if c, ok := visa.Criterion(criterion); ok {
+		return formatFacts(c.Facts, details)
+	}
+	return formatFacts(nil, details)
+}
+
+// formatExhibitFacts lists the exhibits tagged to a criterion with the
+// placeholders the model cites them by, or returns "" when there are none
+func formatExhibitFacts(exhibits []*models.Exhibit) string {
+	if len(exhibits) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\nEXHIBITS:")
+	for _, exhibit := range exhibits {
+		b.WriteString(fmt.Sprintf("\n[Exhibit %s] %s", exhibit.Ref(), exhibit.Description))
+	}
+	return b.String()
+}
+
+// extractFactSummary extracts a fact summary from criterion details
+func (s *DraftService) extractFactSummary(visa *criteria.VisaType, criterion string, details models.CriteriaDetail) string {
+	var facts []string
+	add := func(values ...string) {
+		for _, value := range values {
+			if value != "" {
+				facts = append(facts, value)
+			}
+		}
+	}
+
+	switch typedCriterion(visa, criterion) {
+	case "awards":
+		var f models.AwardsFacts
+		if decodeFacts(criterion, details, &f) {
+			for _, award := range f.Awards {
+				add(award.Name, award.Description)
+			}
+			add(f.Description)
+			return strings.Join(facts, " ")
+		}
+	case "judging":
+		var f models.JudgingFacts
+		if decodeFacts(criterion, details, &f) {
+			add(f.Venue, f.Role)
+			if f.PapersReviewed != nil {
+				add(fmt.Sprintf("%d papers reviewed", *f.PapersReviewed))
+			}
+			add(f.Description)
+			return strings.Join(facts, " ")
+		}
+	case "authorship":
+		var f models.AuthorshipFacts
+		if decodeFacts(criterion, details, &f) {
+			for _, publication := range f.Publications {
+				add(publication.Title, publication.Journal)
+				// Use exact numbers from JSON to prevent hallucination
+				if publication.Citations != nil {
+					add(fmt.Sprintf("%d citations", *publication.Citations))
+				}
+			}
+			add(f.Description)
+			return strings.Join(facts, " ")
+		}
+	case "original_contributions":
+		var f models.OriginalContributionsFacts
+		if decodeFacts(criterion, details, &f) {
+			for _, contribution := range f.Contributions {
+				add(contribution.Title, contribution.Impact)
+			}
+			add(f.Description)
+			return strings.Join(facts, " ")
+		}
+	}
+
+	// Other criteria are summarized from every fact they hold
+	return strings.ReplaceAll(s.formatClientFacts(visa, criterion, details), "\n", " ")
+}
+
+// getMostCompellingFact extracts the most compelling fact from details
+func (s *DraftService) getMostCompellingFact(visa *criteria.VisaType, criterion string, details models.CriteriaDetail) string {
+	switch typedCriterion(visa, criterion) {
+	case "awards":
+		var f models.AwardsFacts
+		if decodeFacts(criterion, details, &f) && len(f.Awards) > 0 && f.Awards[0].Name != "" {
+			return f.Awards[0].Name
+		}
+	case "judging":
+		var f models.JudgingFacts
+		if decodeFacts(criterion, details, &f) && f.Venue != "" {
+			return fmt.Sprintf("serving as %s at %s", f.Role, f.Venue)
+		}
+	case "authorship":
+		var f models.AuthorshipFacts
+		if decodeFacts(criterion, details, &f) && len(f.Publications) > 0 && f.Publications[0].Title != "" {
+			return f.Publications[0].Title
+		}
+	case "original_contributions":
+		var f models.OriginalContributionsFacts
+		if decodeFacts(criterion, details, &f) && len(f.Contributions) > 0 && f.Contributions[0].Title != "" {
+			return f.Contributions[0].Title
+		}
+	case "critical_role":
+		var f models.CriticalRoleFacts
+		if decodeFacts(criterion, details, &f) && len(f.Roles) > 0 && f.Roles[0].Organization != "" {
+			role := f.Roles[0]
+			if role.Title != "" {
+				return fmt.Sprintf("serving as %s at %s", role.Title, role.Organization)
+			}
+			return role.Organization
+		}
+	}
+	return "their achievements in the field"
+}
+
+// formatFacts formats details in registry order. Facts outside the
+// registry are ignored; without fields every fact is listed by key.
+func formatFacts(fields []criteria.FactField, details map[string]interface{}) string {
+	if fields == nil {
+		keys := make([]string, 0, len(details))
+		for key := range details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fields = append(fields, criteria.FactField{Key: key, Label: key})
+		}
+	}
+
+	var w factWriter
+	for _, field := range fields {
+		w.line(field.Label, formatFactValue(field, details[field.Key]))
+	}
+	return w.String()
+}
+
+// formatFactValue formats one fact on a single line
+func formatFactValue(field criteria.FactField, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return formatNumber(v)
+	case []interface{}:
+		entries := make([]string, 0, len(v))
+		for _, entry := range v {
+			if object, ok := entry.(map[string]interface{}); ok && len(field.Items) > 0 {
+				entry := strings.ReplaceAll(formatFacts(field.Items, object), "\n", ", ")
+				entries = append(entries, entry)
+				continue
+			}
+			entries = append(entries, fmt.Sprintf("%v", entry))
+		}
+		return strings.Join(entries, "; ")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/documents"
+	"meritdraft-backend/models"
+	"meritdraft-backend/repository"
+	"meritdraft-backend/storage"
+
+	"github.com/google/uuid"
+)
+
+// maxExtractionSize bounds how much of a stored file is read for extraction
+const maxExtractionSize = 20 * 1024 * 1024
+
+var (
+	ErrExtractionNotFound  = errors.New("document extraction not found")
+	ErrUnsupportedDocument = errors.New("text cannot be extracted from this file type")
+)
+
+// DocumentService parses uploaded documents into evidence candidates in the
+// background and prefills the petition's empty criteria details from them
+type DocumentService struct {
+	extractionRepo *repository.DocumentExtractionRepository
+	fileRepo       *repository.FileRepository
+	petitionRepo   *repository.PetitionRepository
+	storage        storage.Storage
+	leaseDuration  time.Duration
+	maxAttempts    int
+}
+
+// DocumentServiceOption is a functional option for DocumentService
+type DocumentServiceOption func(*DocumentService)
+
+// DocumentWithExtractionRepository sets the document extraction repository
+func DocumentWithExtractionRepository(repo *repository.DocumentExtractionRepository) DocumentServiceOption {
+	return func(s *DocumentService) {
+		s.extractionRepo = repo
+	}
+}
+
+// DocumentWithFileRepository sets the file repository
+func DocumentWithFileRepository(repo *repository.FileRepository) DocumentServiceOption {
+	return func(s *DocumentService) {
+		s.fileRepo = repo
+	}
+}
+
+// DocumentWithPetitionRepository sets the petition repository
+func DocumentWithPetitionRepository(repo *repository.PetitionRepository) DocumentServiceOption {
+	return func(s *DocumentService) {
+		s.petitionRepo = repo
+	}
+}
+
+// DocumentWithStorage sets the file storage documents are read from
+func DocumentWithStorage(storage storage.Storage) DocumentServiceOption {
+	return func(s *DocumentService) {
+		s.storage = storage
+	}
+}
+
+// DocumentWithLeaseDuration sets how long an extraction may run before
+// another process picks it up again
+func DocumentWithLeaseDuration(d time.Duration) DocumentServiceOption {
+	return func(s *DocumentService) {
+		s.leaseDuration = d
+	}
+}
+
+// DocumentWithMaxAttempts sets how many times an extraction is tried before it is failed
+func DocumentWithMaxAttempts(n int) DocumentServiceOption {
+	return func(s *DocumentService) {
+		s.maxAttempts = n
+	}
+}
+
+// NewDocumentService creates a new document service
+func NewDocumentService(opts ...DocumentServiceOption) *DocumentService {
+	s := &DocumentService{
+		leaseDuration: 5 * time.Minute,
+		maxAttempts:   3,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// QueueExtractionRequest represents a request to parse an uploaded file
+type QueueExtractionRequest struct {
+	File *models.File
+}
+
+// QueueExtractionResult represents the result of queueing an extraction
+type QueueExtractionResult struct {
+	Extraction *models.DocumentExtraction
+}
+
+// QueueExtraction queues a file for background parsing, replacing any
+// earlier extraction of it. Returns ErrUnsupportedDocument for file types
+// without a text extractor.
+func (s *DocumentService) QueueExtraction(ctx context.Context, req QueueExtractionRequest) (*QueueExtractionResult, error) {
+	if s.extractionRepo == nil {
+		return nil, errors.New("document extraction repository not set")
+	}
+	if !documents.Supported(req.File.MimeType, req.File.Filename) {
+		return nil, ErrUnsupportedDocument
+	}
+
+	extraction, err := s.extractionRepo.Queue(ctx, req.File.ID, req.File.PetitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueueExtractionResult{Extraction: extraction}, nil
+}
+
+// GetExtractionRequest represents a request to get a file's extraction
+type GetExtractionRequest struct {
+	FileID uuid.UUID
+}
+
+// GetExtractionResult represents a file's extraction
+type GetExtractionResult struct {
+	Extraction *models.DocumentExtraction
+}
+
+// GetExtraction returns the extraction of a file, or ErrExtractionNotFound
+// if the file was never queued
+func (s *DocumentService) GetExtraction(ctx context.Context, req GetExtractionRequest) (*GetExtractionResult, error) {
+	if s.extractionRepo == nil {
+		return nil, errors.New("document extraction repository not set")
+	}
+
+	extraction, err := s.extractionRepo.GetByFileID(ctx, req.FileID)
+	if err != nil {
+		return nil, ErrExtractionNotFound
+	}
+
+	return &GetExtractionResult{Extraction: extraction}, nil
+}
+
+// ProcessNext leases one queued extraction and processes it. Returns false
+// when the queue is empty.
+func (s *DocumentService) ProcessNext(ctx context.Context) (bool, error) {
+	if s.extractionRepo == nil || s.fileRepo == nil || s.petitionRepo == nil {
+		return false, errors.New("document service repositories not set")
+	}
+	if s.storage == nil {
+		return false, errors.New("storage not set")
+	}
+
+	extraction, err := s.extractionRepo.Lease(ctx, s.leaseDuration)
+	if err != nil || extraction == nil {
+		return false, err
+	}
+
+	if extraction.Attempts > s.maxAttempts {
+		msg := fmt.Sprintf("extraction abandoned after %d attempts", extraction.Attempts-1)
+		return true, s.extractionRepo.Fail(ctx, extraction.ID, msg)
+	}
+
+	if err := s.process(ctx, extraction); err != nil {
+		log.Printf("Document extraction for file %s failed: %v", extraction.FileID, err)
+		if ctx.Err() != nil {
+			// Shutting down: the expired lease returns it to the queue
+			return true, nil
+		}
+		return true, s.extractionRepo.Fail(ctx, extraction.ID, err.Error())
+	}
+	return true, nil
+}
+
+// process extracts a file's text and candidates and prefills its petition
+func (s *DocumentService) process(ctx context.Context, extraction *models.DocumentExtraction) error {
+	file, err := s.fileRepo.GetByID(ctx, extraction.FileID)
+	if err != nil {
+		return fmt.Errorf("failed to load file: %w", err)
+	}
+
+	reader, err := s.storage.Download(ctx, file.StoragePath)
+	if err != nil {
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, maxExtractionSize))
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	text, err := documents.ExtractText(file.MimeType, file.Filename, data)
+	if err != nil {
+		return err
+	}
+	candidates := documents.FindEvidence(text)
+
+	prefilled := []string{}
+	if extraction.PetitionID != nil {
+		prefilled, err = s.prefill(ctx, *extraction.PetitionID, candidates)
+		if err != nil {
+			return fmt.Errorf("failed to prefill criteria details: %w", err)
+		}
+	}
+
+	return s.extractionRepo.Complete(ctx, extraction.ID, text, candidates, prefilled)
+}
+
+// prefill writes the candidates into the petition's empty criteria details,
+// keeping only criteria of its visa type whose facts validate
+func (s *DocumentService) prefill(ctx context.Context, petitionID uuid.UUID, candidates models.EvidenceCandidates) ([]string, error) {
+	petition, err := s.petitionRepo.GetByID(ctx, petitionID)
+	if err != nil {
+		return nil, err
+	}
+	// Without a registered visa type there are no criteria to fill, but
+	// the publication count is still recorded
+	details := make(models.CriteriaDetails)
+	if visa, ok := criteria.Lookup(string(petition.VisaType)); ok {
+		for criterion, detail := range documents.CriteriaDetails(candidates) {
+			if errs := visa.Validate(map[string]interface{}{criterion: detail}); len(errs) > 0 {
+				continue
+			}
+			details[criterion] = detail.(map[string]interface{})
+		}
+	}
+
+	publications := 0
+	for _, c := range candidates {
+		if c.Kind == models.EvidencePublication {
+			publications++
+		}
+	}
+
+	return s.petitionRepo.PrefillCriteriaDetails(ctx, petitionID, details, publications)
+}
+
+// RunExtractor processes queued extractions until ctx is cancelled, polling
+// every interval once the queue is empty
+func (s *DocumentService) RunExtractor(ctx context.Context, interval time.Duration) {
+	for {
+		processed, err := s.ProcessNext(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Warning: Document extraction failed: %v", err)
+		}
+		if processed && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/llm"
+	"meritdraft-backend/models"
+)
+
+// processNIWDraft drafts the three Dhanasar prongs of an EB-2 NIW petition
+// and stores the assembled document
+func (s *DraftService) processNIWDraft(
+	ctx context.Context,
+	job *models.GenerationJob,
+	petition *models.Petition,
+	visa *criteria.VisaType,
+	generator llm.TextGenerator,
+	savedByStep map[string]*models.GenerationSection,
+	exhibits map[string][]*models.Exhibit, // By criterion
+) error {
+	jobID := job.ID
+	prongs := visa.Criteria
+	sections := make([]DraftSection, 0, len(prongs))
+
+	for i, prong := range prongs {
+		if section, ok := savedByStep[prong.StepName]; ok && stepCompleted(job.Steps, prong.StepName) {
+			sections = append(sections, DraftSection{
+				StepName:  prong.StepName,
+				Criterion: prong.ID,
+				Title:     section.Title,
+				Content:   section.Content,
+				Citations: section.Citations,
+				ChunkIDs:  section.ChunkIDs,
+			})
+			continue
+		}
+
+		err := s.updateStepStatus(ctx, jobID, prong.StepName, "in_progress")
+		if err != nil {
+			s.markJobFailed(ctx, jobID, "failed to update step: "+err.Error())
+			return err
+		}
+
+		// The balancing prong can rest on the earlier prongs alone
+		details := petition.CriteriaDetails[prong.ID]
+		if details == nil {
+			details = models.CriteriaDetail{}
+		}
+
+		context, err := s.retrieveNIWContext(ctx, visa, &prong, petition.FieldOfExpertise, details)
+		if err != nil {
+			log.Printf("Warning: Failed to retrieve context for %s: %v. Continuing with empty context.", prong.ID, err)
+			context = &RetrievedContext{}
+		}
+
+		writer := s.newSectionWriter(jobID, prong.StepName)
+		content, err := s.generateNIWSection(ctx, generator, visa, &prong, details, exhibits[prong.ID], context, sections, petition, writer)
+		if err != nil {
+			s.markJobFailed(ctx, jobID, fmt.Sprintf("failed to generate section for %s: %v", prong.ID, err))
+			return fmt.Errorf("failed to generate section for %s: %w", prong.ID, err)
+		}
+
+		citations := []string{prong.Citation}
+		citations = append(citations, s.extractCitations(context, visa, "")...)
+		section := DraftSection{
+			StepName:  prong.StepName,
+			Criterion: prong.ID,
+			Title:     prong.Title,
+			Content:   content,
+			Citations: citations,
+			ChunkIDs:  context.chunkIDs(),
+		}
+		sections = append(sections, section)
+
+		err = s.saveSection(ctx, jobID, prong.StepName, i, section)
+		if err != nil {
+			s.markJobFailed(ctx, jobID, "failed to save section: "+err.Error())
+			return err
+		}
+
+		err = s.jobRepo.UpdatePartialText(ctx, jobID, nil)
+		if err != nil {
+			s.markJobFailed(ctx, jobID, "failed to clear partial text: "+err.Error())
+			return err
+		}
+
+		err = s.updateStepStatus(ctx, jobID, prong.StepName, "completed")
+		if err != nil {
+			s.markJobFailed(ctx, jobID, "failed to update step: "+err.Error())
+			return err
+		}
+	}
+
+	return s.completeDraft(ctx, job, petition, visa, func() []*models.PetitionSection {
+		return s.assembleNIWDocument(petition, visa, sections)
+	})
+}
+
+// retrieveNIWContext retrieves NIW legal context for a Dhanasar prong
+func (s *DraftService) retrieveNIWContext(
+	ctx context.Context,
+	visa *criteria.VisaType,
+	prong *criteria.Criterion,
+	fieldOfExpertise string,
+	details models.CriteriaDetail,
+) (*RetrievedContext, error) {
+	if s.legalChunkRepo == nil {
+		return nil, errors.New("legal chunk repository not set")
+	}
+
+	factSummary := "Matter of Dhanasar " + strings.ReplaceAll(formatFacts(prong.Facts, details), "\n", " ")
+	embedding, err := s.generateQueryEmbedding(ctx, prong.ID, fieldOfExpertise, factSummary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate embedding: %w", err)
+	}
+
+	context := &RetrievedContext{}
+
+	regs, err := s.legalChunkRepo.SearchNIW(ctx, embedding, visa.Corpus, prong.Tags, "regulation", 3)
+	if err != nil {
+		log.Printf("Warning: Failed to retrieve regulations: %v", err)
+	} else {
+		context.Regulations = regs
+	}
+
+	appeals, err := s.legalChunkRepo.SearchNIW(ctx, embedding, visa.Corpus, prong.Tags, "appeal_decision", 3)
+	if err != nil {
+		log.Printf("Warning: Failed to retrieve appeals: %v", err)
+	} else {
+		context.Appeals = appeals
+	}
+
+	cases, err := s.legalChunkRepo.SearchNIW(ctx, embedding, visa.Corpus, prong.Tags, "precedent_case", 2)
+	if err != nil {
+		log.Printf("Warning: Failed to retrieve cases: %v", err)
+	} else {
+		context.Cases = cases
+	}
+
+	return context, nil
+}
+
+// generateNIWSection generates one Dhanasar prong using IRAC format
+func (s *DraftService) generateNIWSection(
+	ctx context.Context,
+	generator llm.TextGenerator,
+	visa *criteria.VisaType,
+	prong *criteria.Criterion,
+	details models.CriteriaDetail,
+	exhibits []*models.Exhibit, // Tagged to the prong
+	context *RetrievedContext,
+	previous []DraftSection, // Prongs already drafted
+	petition *models.Petition,
+	w *sectionWriter, // Receives text as it streams in; may be nil
+) (string, error) {
+	if generator == nil {
+		return "", errors.New("text generator not set")
+	}
+
+	var regulationText strings.Builder
+	for _, reg := range context.Regulations {
+		regulationText.WriteString(reg.Text)
+		regulationText.WriteString("\n\n")
+	}
+
+	// Guard clause: Use fallback if no regulation context retrieved
+	if regulationText.Len() == 0 {
+		log.Printf("Warning: No regulation context found for %s. Using fallback.", prong.ID)
+		regulationText.WriteString(getHardcodedRegulation(visa, prong.ID))
+		regulationText.WriteString("\n\n")
+	}
+
+	var appealText strings.Builder
+	for _, appeal := range context.Appeals {
+		appealText.WriteString(appeal.Text)
+		appealText.WriteString("\n\n")
+	}
+
+	clientFacts := formatFacts(prong.Facts, details)
+	if clientFacts == "" {
+		clientFacts = "(No additional facts provided; rely on the prongs drafted above.)"
+	}
+	clientFacts += formatExhibitFacts(exhibits)
+
+	var previousText strings.Builder
+	for _, section := range previous {
+		previousText.WriteString(section.Title)
+		previousText.WriteString("\n")
+		previousText.WriteString(section.Content)
+		previousText.WriteString("\n\n")
+	}
+
+	prompt := fmt.Sprintf(`You are an expert EB-2 National Interest Waiver immigration attorney drafting a petition section under the Matter of Dhanasar framework.
+
+LEGAL STANDARD:
+%s
+
+PRECEDENT DECISIONS:
+%s
+
+CLIENT FACTS:
+%s
+
+PRONGS ALREADY DRAFTED:
+%s
+
+CLIENT: %s
+FIELD OF EXPERTISE: %s
+
+TASK:
+Write the "%s" section using IRAC format:
+
+1. Issue: State the requirement of this prong in plain language (1 paragraph)
+2. Rule: Cite Matter of Dhanasar %s (1 paragraph)
+3. Analysis:
+%s
+   - Argue by analogy to the precedent decisions above
+   - Preempt common denial reasons (3-4 paragraphs)
+4. Conclusion: State that the client satisfies this prong (1 paragraph)
+
+OUTPUT REQUIREMENTS:
+- Use formal legal language
+- Include proper citations: %s
+- 5-7 paragraphs total
+- No markdown formatting (plain text)
+- Write in third person about the client
+- When referencing specific evidence (letters, publications, plans, etc.), append [Exhibit __] placeholders at the end of the sentence (e.g., "as documented in the exhibits attached hereto [Exhibit __]"). When CLIENT FACTS lists EXHIBITS, use the placeholder listed for the exhibit that documents the evidence instead
+- Do NOT include a section header/title - the content will be inserted under an existing header
+- CRITICAL: Use EXACT numbers from CLIENT FACTS above. Do NOT estimate, round, or aggregate numbers.
+
+TONE CONSTRAINTS (CRITICAL):
+- Do NOT use flowery adjectives (e.g., "game-changing", "revolutionary", "esteemed", "world-renowned")
+- Use objective descriptors (e.g., "significant", "highly cited", "nationally recognized", "peer-reviewed")
+- Avoid hyperbole and marketing language
+- Maintain professional, factual tone throughout
+
+Write the section now:`,
+		regulationText.String(),
+		appealText.String(),
+		clientFacts,
+		previousText.String(),
+		petition.ClientName,
+		petition.FieldOfExpertise,
+		prong.Title,
+		prong.Citation,
+		"   - "+strings.Join(prong.PromptHints, "\n   - "),
+		prong.Citation,
+	)
+
+	systemInstruction := "You are an expert EB-2 National Interest Waiver immigration attorney. Use formal legal language. Avoid flowery adjectives. Use objective descriptors only."
+
+	var content string
+	var err error
+	backoff := initialBackoff
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, backoff); err != nil {
+				return "", err
+			}
+			backoff *= 2
+		}
+
+		content, err = s.generateStreaming(ctx, generator, llm.GenerateRequest{
+			SystemInstruction: systemInstruction,
+			Prompt:            truncatePrompt(systemInstruction, prompt),
+			Temperature:       0.2,
+		}, w)
+		if err != nil {
+			if attempt == maxRetries-1 {
+				return "", fmt.Errorf("failed to generate content after %d attempts: %w", maxRetries, err)
+			}
+			continue
+		}
+
+		if content != "" {
+			break
+		}
+	}
+
+	if content == "" {
+		return "", ErrGenerationFailed
+	}
+
+	return content, nil
+}
+
+// assembleNIWDocument lays out the Dhanasar prongs as a complete document
+func (s *DraftService) assembleNIWDocument(petition *models.Petition, visa *criteria.VisaType, sections []DraftSection) []*models.PetitionSection {
+	assembled := []*models.PetitionSection{
+		{Level: 0, Title: visa.Heading},
+		{
+			Level:   1,
+			Title:   "I. INTRODUCTION",
+			Content: fmt.Sprintf("%s, in the field of %s", petition.ClientName, petition.FieldOfExpertise),
+		},
+		{
+			Level:   1,
+			Title:   "II. LEGAL STANDARD",
+			Content: "Under Matter of Dhanasar, 26 I&N Dec. 884 (AAO 2016), USCIS may grant a national interest waiver of the job offer and labor certification requirements of INA § 203(b)(2)(A) if the petitioner demonstrates that (1) the proposed endeavor has both substantial merit and national importance; (2) the beneficiary is well positioned to advance the proposed endeavor; and (3) on balance, it would be beneficial to the United States to waive the requirements of a job offer and thus of a labor certification.",
+		},
+		{Level: 1, Title: "III. THE DHANASAR FRAMEWORK"},
+	}
+
+	for _, section := range sections {
+		assembled = append(assembled, draftedSection(2, section.Title, section))
+	}
+
+	assembled = append(assembled, &models.PetitionSection{
+		Level: 1,
+		Title: "IV. CONCLUSION",
+		Content: fmt.Sprintf("Based on the evidence presented, the client satisfies each prong of Matter of Dhanasar and merits a national interest waiver in connection with %s classification.",
+			visa.Classification),
+	})
+
+	return assembled
+}
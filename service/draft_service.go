@@ -1,24 +1,22 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"math"
-	"net/http"
-	"os"
 	"strings"
+	"sync"
 	"time"
 
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/llm"
 	"meritdraft-backend/models"
 	"meritdraft-backend/repository"
 
-	"github.com/google/generative-ai-go/genai"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -27,8 +25,17 @@ type DraftService struct {
 	petitionRepo   *repository.PetitionRepository
 	jobRepo        *repository.GenerationJobRepository
 	legalChunkRepo *repository.LegalChunkRepository
+	extractionRepo *repository.DocumentExtractionRepository
+	exhibitRepo    *repository.ExhibitRepository
 	db             *pgxpool.Pool
-	geminiClient   *genai.Client
+	generator      llm.TextGenerator                      // Default drafting model
+	generators     map[llm.ProviderType]llm.TextGenerator // Models petitions may select instead
+	embedder       llm.Embedder
+	events         *JobEventService
+
+	// Cancel functions for jobs running in this process, keyed by job ID
+	runningMu sync.Mutex
+	running   map[uuid.UUID]context.CancelFunc
 }
 
 // DraftServiceOption is a functional option for DraftService
@@ -55,6 +62,22 @@ func DraftWithLegalChunkRepository(repo *repository.LegalChunkRepository) DraftS
 	}
 }
 
+// DraftWithDocumentExtractionRepository sets the repository of parsed
+// documents whose evidence informs criteria recommendations
+func DraftWithDocumentExtractionRepository(repo *repository.DocumentExtractionRepository) DraftServiceOption {
+	return func(s *DraftService) {
+		s.extractionRepo = repo
+	}
+}
+
+// DraftWithExhibitRepository sets the repository of the exhibits the
+// drafting prompts cite and assembled drafts are lettered against
+func DraftWithExhibitRepository(repo *repository.ExhibitRepository) DraftServiceOption {
+	return func(s *DraftService) {
+		s.exhibitRepo = repo
+	}
+}
+
 // DraftWithDatabase sets the database pool
 func DraftWithDatabase(db *pgxpool.Pool) DraftServiceOption {
 	return func(s *DraftService) {
@@ -62,16 +85,39 @@ func DraftWithDatabase(db *pgxpool.Pool) DraftServiceOption {
 	}
 }
 
-// DraftWithGeminiClient sets the Gemini client
-func DraftWithGeminiClient(client *genai.Client) DraftServiceOption {
+// DraftWithTextGenerator sets the model used to draft sections
+func DraftWithTextGenerator(generator llm.TextGenerator) DraftServiceOption {
+	return func(s *DraftService) {
+		s.generator = generator
+	}
+}
+
+// DraftWithTextGenerators sets the drafting models petitions may select by provider name
+func DraftWithTextGenerators(generators map[llm.ProviderType]llm.TextGenerator) DraftServiceOption {
+	return func(s *DraftService) {
+		s.generators = generators
+	}
+}
+
+// DraftWithEmbedder sets the model used to embed retrieval queries
+func DraftWithEmbedder(embedder llm.Embedder) DraftServiceOption {
 	return func(s *DraftService) {
-		s.geminiClient = client
+		s.embedder = embedder
+	}
+}
+
+// DraftWithJobEventService sets the service used to publish job progress events
+func DraftWithJobEventService(events *JobEventService) DraftServiceOption {
+	return func(s *DraftService) {
+		s.events = events
 	}
 }
 
 // NewDraftService creates a new draft service
 func NewDraftService(opts ...DraftServiceOption) *DraftService {
-	s := &DraftService{}
+	s := &DraftService{
+		running: make(map[uuid.UUID]context.CancelFunc),
+	}
 	for _, opt := range opts {
 		opt(s)
 	}
@@ -107,11 +153,23 @@ var (
 	ErrGenerationFailed    = errors.New("failed to generate content")
 	ErrEmbeddingFailed     = errors.New("failed to generate embedding")
 	ErrJobNotFound         = errors.New("generation job not found")
+	ErrJobNotCancellable   = errors.New("generation job has already finished")
+	ErrJobCancelled        = errors.New("generation job was cancelled")
+	ErrJobNotRetryable     = errors.New("only failed or cancelled generation jobs can be retried")
+	ErrUnknownVisaType     = errors.New("petition does not have a registered visa type")
+
+	ErrDraftingModelUnavailable = errors.New("the petition's drafting model is not configured on this server")
 )
 
+// Names of the generation steps that follow the per-criterion steps
 const (
-	embeddingAPI   = "https://generativelanguage.googleapis.com/v1beta/models/gemini-embedding-001:embedContent"
-	generationAPI  = "https://generativelanguage.googleapis.com/v1beta/models/gemini-3-pro-preview:generateContent"
+	stepFinalMerits = "Final Merits Determination"
+	stepAssembling  = "Assembling Document"
+)
+
+const (
+	embeddingDims  = 768 // Dimension of the stored legal chunk vectors
+	maxPromptChars = 30000
 	maxRetries     = 3
 	initialBackoff = time.Second
 )
@@ -135,18 +193,16 @@ func (s *DraftService) GenerateDraft(
 		return nil, ErrPetitionNotFound
 	}
 
-	// 2. Validate required fields
-	if petition.ClientName == "" {
-		return nil, ErrMissingRequiredData
+	// 2. Check the petition has the evidence drafting needs
+	if report := evaluateReadiness(petition); !report.Ready {
+		return nil, &NotReadyError{Report: report}
 	}
-	if petition.FieldOfExpertise == "" {
-		return nil, ErrMissingRequiredData
-	}
-	if len(petition.SelectedCriteria) == 0 {
-		return nil, ErrMissingRequiredData
+	visa, err := lookupVisaType(petition)
+	if err != nil {
+		return nil, err
 	}
-	if len(petition.CriteriaDetails) == 0 {
-		return nil, ErrMissingRequiredData
+	if _, err := s.generatorFor(petition); err != nil {
+		return nil, err
 	}
 
 	// 3. Create generation job with initial steps
@@ -154,7 +210,7 @@ func (s *DraftService) GenerateDraft(
 		ID:         uuid.New(),
 		PetitionID: req.PetitionID,
 		Status:     models.JobStatusPending,
-		Steps:      s.initializeSteps(petition.SelectedCriteria),
+		Steps:      s.initializeSteps(petition, visa),
 	}
 
 	// Store refine instructions if provided
@@ -192,53 +248,201 @@ func (s *DraftService) GetJobStatus(
 	}, nil
 }
 
-// initializeSteps creates the initial generation steps based on selected criteria
-func (s *DraftService) initializeSteps(criteria []string) models.GenerationSteps {
+// CancelJobRequest represents a request to cancel a generation job
+type CancelJobRequest struct {
+	JobID uuid.UUID
+}
+
+// CancelJobResult represents the result of cancelling a generation job
+type CancelJobResult struct {
+	Job *models.GenerationJob
+}
+
+// CancelJob stops a pending or running generation job. Unfinished steps are
+// marked skipped and the petition's existing generated content is kept.
+// A job running in this process is interrupted immediately; one running on
+// another worker stops at that worker's next heartbeat.
+func (s *DraftService) CancelJob(
+	ctx context.Context,
+	req CancelJobRequest,
+) (*CancelJobResult, error) {
+	if s.jobRepo == nil {
+		return nil, errors.New("generation job repository not set")
+	}
+
+	job, err := s.jobRepo.Cancel(ctx, req.JobID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrJobNotActive):
+			return nil, ErrJobNotCancellable
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+
+	s.runningMu.Lock()
+	cancel, ok := s.running[req.JobID]
+	s.runningMu.Unlock()
+	if ok {
+		cancel()
+	}
+
+	s.events.Publish(ctx, models.JobEvent{
+		JobID:  job.ID,
+		Type:   models.JobEventStatus,
+		Status: job.Status,
+		Steps:  job.Steps,
+	})
+
+	return &CancelJobResult{
+		Job: job,
+	}, nil
+}
+
+// RetryJobRequest represents a request to retry a failed or cancelled job
+type RetryJobRequest struct {
+	JobID uuid.UUID
+}
+
+// RetryJobResult represents the result of retrying a generation job
+type RetryJobResult struct {
+	Job *models.GenerationJob
+}
+
+// RetryJob requeues a failed or cancelled job. Criterion sections that
+// already completed are kept; failed and pending criteria are regenerated,
+// followed by Prong 2 and document assembly.
+func (s *DraftService) RetryJob(
+	ctx context.Context,
+	req RetryJobRequest,
+) (*RetryJobResult, error) {
+	if s.jobRepo == nil {
+		return nil, errors.New("generation job repository not set")
+	}
+
+	job, err := s.jobRepo.GetByID(ctx, req.JobID)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+
+	saved, err := s.jobRepo.ListSections(ctx, req.JobID)
+	if err != nil {
+		return nil, err
+	}
+	savedByStep := make(map[string]*models.GenerationSection, len(saved))
+	for _, section := range saved {
+		savedByStep[section.StepName] = section
+	}
+
+	err = s.jobRepo.Requeue(ctx, req.JobID, resumableSteps(job.Steps, savedByStep))
+	if errors.Is(err, repository.ErrJobNotRetryable) {
+		return nil, ErrJobNotRetryable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job, err = s.jobRepo.GetByID(ctx, req.JobID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, models.JobEvent{
+		JobID:  job.ID,
+		Type:   models.JobEventStatus,
+		Status: job.Status,
+		Steps:  job.Steps,
+	})
+
+	return &RetryJobResult{
+		Job: job,
+	}, nil
+}
+
+// trackRunning registers the cancel function of a job being processed
+// in this process and returns a function that unregisters it
+func (s *DraftService) trackRunning(jobID uuid.UUID, cancel context.CancelFunc) func() {
+	s.runningMu.Lock()
+	s.running[jobID] = cancel
+	s.runningMu.Unlock()
+
+	return func() {
+		s.runningMu.Lock()
+		delete(s.running, jobID)
+		s.runningMu.Unlock()
+	}
+}
+
+// initializeSteps creates the initial generation steps based on selected
+// criteria, or on the Dhanasar prongs for an NIW petition
+func (s *DraftService) initializeSteps(petition *models.Petition, visa *criteria.VisaType) models.GenerationSteps {
 	steps := make(models.GenerationSteps, 0)
 
+	if petition.VisaType == models.VisaTypeEB2NIW {
+		for _, prong := range visa.Criteria {
+			steps = append(steps, models.GenerationStep{
+				Name:   prong.StepName,
+				Status: "pending",
+			})
+		}
+		steps = append(steps, models.GenerationStep{
+			Name:   stepAssembling,
+			Status: "pending",
+		})
+		return steps
+	}
+
 	// Add step for each criterion
-	for _, criterion := range criteria {
+	for _, criterion := range petition.SelectedCriteria {
 		steps = append(steps, models.GenerationStep{
-			Name:   getCriterionStepName(criterion),
+			Name:   getCriterionStepName(visa, criterion),
 			Status: "pending",
 		})
 	}
 
 	// Add final steps
 	steps = append(steps, models.GenerationStep{
-		Name:   "Final Merits Determination",
+		Name:   stepFinalMerits,
 		Status: "pending",
 	})
 	steps = append(steps, models.GenerationStep{
-		Name:   "Assembling Document",
+		Name:   stepAssembling,
 		Status: "pending",
 	})
 
 	return steps
 }
 
+// criterionExhibits loads the petition's exhibits by criterion. Drafting
+// goes ahead without them when they cannot be loaded.
+func (s *DraftService) criterionExhibits(ctx context.Context, petitionID uuid.UUID) map[string][]*models.Exhibit {
+	byCriterion := make(map[string][]*models.Exhibit)
+	if s.exhibitRepo == nil {
+		return byCriterion
+	}
+
+	exhibits, err := s.exhibitRepo.ListByPetitionID(ctx, petitionID)
+	if err != nil {
+		log.Printf("Warning: Failed to load exhibits of petition %s: %v. Drafting with blank placeholders.", petitionID, err)
+		return byCriterion
+	}
+	for _, exhibit := range exhibits {
+		byCriterion[exhibit.Criterion] = append(byCriterion[exhibit.Criterion], exhibit)
+	}
+	return byCriterion
+}
+
 // getCriterionStepName returns a human-readable step name for a criterion
-func getCriterionStepName(criterion string) string {
-	titles := map[string]string{
-		"awards":                 "Drafting Awards Criterion",
-		"membership":             "Drafting Membership Criterion",
-		"media_coverage":         "Drafting Media Coverage Criterion",
-		"judging":                "Drafting Judging Criterion",
-		"original_contributions": "Drafting Original Contributions Criterion",
-		"authorship":             "Drafting Authorship Criterion",
-		"exhibitions":            "Drafting Exhibitions Criterion",
-		"critical_role":          "Drafting Critical Role Criterion",
-		"high_salary":            "Drafting High Salary Criterion",
-		"commercial_success":     "Drafting Commercial Success Criterion",
-	}
-	if title, ok := titles[criterion]; ok {
-		return title
+func getCriterionStepName(visa *criteria.VisaType, criterion string) string {
+	if c, ok := visa.Criterion(criterion); ok {
+		return c.StepName
 	}
 	return "Drafting " + criterion + " Criterion"
 }
 
-// ProcessDraft performs the actual generation work in the background
-// This method runs in a goroutine and can take 45-90 seconds
+// ProcessDraft performs the actual generation work for a leased job
+// This method runs on a queue worker and can take 45-90 seconds
 func (s *DraftService) ProcessDraft(
 	ctx context.Context,
 	jobID uuid.UUID,
@@ -250,11 +454,19 @@ func (s *DraftService) ProcessDraft(
 		return errors.New("petition repository not set")
 	}
 
+	// Allow CancelJob to interrupt in-flight API calls
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer s.trackRunning(jobID, cancel)()
+
 	// 1. Load job and petition
 	job, err := s.jobRepo.GetByID(ctx, jobID)
 	if err != nil {
 		return fmt.Errorf("failed to load generation job: %w", err)
 	}
+	if job.Status == models.JobStatusCancelled {
+		return nil
+	}
 
 	petition, err := s.petitionRepo.GetByID(ctx, job.PetitionID)
 	if err != nil {
@@ -262,17 +474,69 @@ func (s *DraftService) ProcessDraft(
 		return err
 	}
 
+	visa, err := lookupVisaType(petition)
+	if err != nil {
+		s.markJobFailed(ctx, jobID, err.Error())
+		return err
+	}
+	generator, err := s.generatorFor(petition)
+	if err != nil {
+		s.markJobFailed(ctx, jobID, err.Error())
+		return err
+	}
+
 	// 2. Update job status to in_progress
 	err = s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusInProgress)
 	if err != nil {
 		return fmt.Errorf("failed to update job status: %w", err)
 	}
+	s.events.Publish(ctx, models.JobEvent{
+		JobID:  jobID,
+		Type:   models.JobEventStatus,
+		Status: models.JobStatusInProgress,
+	})
+
+	// Sections saved by an earlier attempt or before a retry are reused
+	saved, err := s.jobRepo.ListSections(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to load saved sections: %w", err)
+	}
+	savedByStep := make(map[string]*models.GenerationSection, len(saved))
+	for _, section := range saved {
+		savedByStep[section.StepName] = section
+	}
+
+	// Every step without a saved section runs again, including any left
+	// in_progress by an interrupted attempt
+	job.Steps = resumableSteps(job.Steps, savedByStep)
+	err = s.jobRepo.UpdateProgress(ctx, jobID, "", job.Steps)
+	if err != nil {
+		return fmt.Errorf("failed to reset job steps: %w", err)
+	}
+
+	exhibits := s.criterionExhibits(ctx, petition.ID)
+
+	if petition.VisaType == models.VisaTypeEB2NIW {
+		return s.processNIWDraft(ctx, job, petition, visa, generator, savedByStep, exhibits)
+	}
 
 	// 3. Process each criterion (Prong 1)
 	sections := make([]DraftSection, 0)
 
-	for _, criterion := range petition.SelectedCriteria {
-		stepName := getCriterionStepName(criterion)
+	for i, criterion := range petition.SelectedCriteria {
+		stepName := getCriterionStepName(visa, criterion)
+
+		if section, ok := savedByStep[stepName]; ok && stepCompleted(job.Steps, stepName) {
+			sections = append(sections, DraftSection{
+				StepName:  stepName,
+				Criterion: criterion,
+				Title:     section.Title,
+				Content:   section.Content,
+				Citations: section.Citations,
+				ChunkIDs:  section.ChunkIDs,
+			})
+			continue
+		}
 
 		// Update step to in_progress
 		err = s.updateStepStatus(ctx, jobID, stepName, "in_progress")
@@ -288,24 +552,43 @@ func (s *DraftService) ProcessDraft(
 			return fmt.Errorf("missing details for criterion: %s", criterion)
 		}
 
-		context, err := s.retrieveContext(ctx, criterion, petition.FieldOfExpertise, details)
+		context, err := s.retrieveContext(ctx, visa, criterion, petition.FieldOfExpertise, details)
 		if err != nil {
 			log.Printf("Warning: Failed to retrieve context for %s: %v. Continuing with empty context.", criterion, err)
 			context = &RetrievedContext{}
 		}
 
-		content, err := s.generateProng1Section(ctx, criterion, details, context, petition.ClientName, petition.FieldOfExpertise)
+		writer := s.newSectionWriter(jobID, stepName)
+		content, err := s.generateProng1Section(ctx, generator, visa, criterion, details, exhibits[criterion], context, petition.ClientName, petition.FieldOfExpertise, writer)
 		if err != nil {
 			s.markJobFailed(ctx, jobID, fmt.Sprintf("failed to generate section for %s: %v", criterion, err))
 			return fmt.Errorf("failed to generate section for %s: %w", criterion, err)
 		}
 
-		citations := s.extractCitations(context, criterion)
-		sections = append(sections, DraftSection{
-			Title:     getCriterionTitle(criterion),
+		section := DraftSection{
+			StepName:  stepName,
+			Criterion: criterion,
+			Title:     getCriterionTitle(visa, criterion),
 			Content:   content,
-			Citations: citations,
-		})
+			Citations: s.extractCitations(context, visa, criterion),
+			ChunkIDs:  context.chunkIDs(),
+		}
+		sections = append(sections, section)
+
+		// Persist the section before marking the step completed so a retry
+		// never sees a completed step without its content
+		err = s.saveSection(ctx, jobID, stepName, i, section)
+		if err != nil {
+			s.markJobFailed(ctx, jobID, "failed to save section: "+err.Error())
+			return err
+		}
+
+		// The saved section supersedes the streamed partial text
+		err = s.jobRepo.UpdatePartialText(ctx, jobID, nil)
+		if err != nil {
+			s.markJobFailed(ctx, jobID, "failed to clear partial text: "+err.Error())
+			return err
+		}
 
 		// Update step to completed
 		err = s.updateStepStatus(ctx, jobID, stepName, "completed")
@@ -316,66 +599,157 @@ func (s *DraftService) ProcessDraft(
 	}
 
 	// 4. Generate Final Merits (Prong 2)
-	err = s.updateStepStatus(ctx, jobID, "Final Merits Determination", "in_progress")
+	err = s.updateStepStatus(ctx, jobID, stepFinalMerits, "in_progress")
 	if err != nil {
 		s.markJobFailed(ctx, jobID, "failed to update step: "+err.Error())
 		return err
 	}
 
-	finalMeritsContent, err := s.generateProng2(ctx, sections, petition)
+	finalMeritsContent, err := s.generateProng2(ctx, generator, visa, sections, petition)
 	if err != nil {
 		s.markJobFailed(ctx, jobID, fmt.Sprintf("failed to generate final merits: %v", err))
 		return fmt.Errorf("failed to generate final merits: %w", err)
 	}
 
 	finalMerits := DraftSection{
-		Title:   "Final Merits Determination",
-		Content: finalMeritsContent,
+		StepName: stepFinalMerits,
+		Title:    "Final Merits Determination",
+		Content:  finalMeritsContent,
 	}
 	sections = append(sections, finalMerits)
 
-	err = s.updateStepStatus(ctx, jobID, "Final Merits Determination", "completed")
+	s.events.Publish(ctx, models.JobEvent{
+		JobID:    jobID,
+		Type:     models.JobEventSectionDelta,
+		StepName: stepFinalMerits,
+		Text:     finalMeritsContent,
+	})
+
+	err = s.saveSection(ctx, jobID, stepFinalMerits, len(petition.SelectedCriteria), finalMerits)
+	if err != nil {
+		s.markJobFailed(ctx, jobID, "failed to save section: "+err.Error())
+		return err
+	}
+
+	err = s.updateStepStatus(ctx, jobID, stepFinalMerits, "completed")
 	if err != nil {
 		s.markJobFailed(ctx, jobID, "failed to update step: "+err.Error())
 		return err
 	}
 
-	// 5. Assemble document
-	err = s.updateStepStatus(ctx, jobID, "Assembling Document", "in_progress")
+	// 5. Assemble document and store the result
+	return s.completeDraft(ctx, job, petition, visa, func() []*models.PetitionSection {
+		return s.assembleDocument(petition, visa, sections)
+	})
+}
+
+// completeDraft runs the assembly step and stores the assembled sections
+// as the petition's draft, with its exhibits lettered
+func (s *DraftService) completeDraft(ctx context.Context, job *models.GenerationJob, petition *models.Petition, visa *criteria.VisaType, assemble func() []*models.PetitionSection) error {
+	jobID := job.ID
+
+	err := s.updateStepStatus(ctx, jobID, stepAssembling, "in_progress")
 	if err != nil {
 		s.markJobFailed(ctx, jobID, "failed to update step: "+err.Error())
 		return err
 	}
 
-	assembledContent := s.assembleDocument(petition, sections)
+	assembled := assemble()
+	for _, section := range assembled {
+		section.JobID = &jobID
+	}
 
-	err = s.updateStepStatus(ctx, jobID, "Assembling Document", "completed")
+	err = s.updateStepStatus(ctx, jobID, stepAssembling, "completed")
 	if err != nil {
 		s.markJobFailed(ctx, jobID, "failed to update step: "+err.Error())
 		return err
 	}
 
-	// 6. Store result
-	err = s.petitionRepo.UpdateGeneratedContent(ctx, job.PetitionID, assembledContent)
+	// Store result and mark job as completed. Both happen in one
+	// transaction so a job cancelled at the last moment never overwrites
+	// the petition's previous draft.
+	err = s.jobRepo.CompleteWithSections(ctx, jobID, job.PetitionID, assembled)
+	if errors.Is(err, repository.ErrJobNotActive) {
+		return ErrJobCancelled
+	}
 	if err != nil {
 		s.markJobFailed(ctx, jobID, "failed to store generated content: "+err.Error())
 		return err
 	}
 
-	// 7. Mark job as completed
-	err = s.jobRepo.Complete(ctx, jobID)
-	if err != nil {
-		return fmt.Errorf("failed to complete job: %w", err)
+	// Letter the exhibits before announcing the draft; the draft stays
+	// usable with blank placeholders if this fails
+	if s.exhibitRepo != nil {
+		if _, err := s.exhibitRepo.Resolve(ctx, petition.ID, exhibitSections(visa)); err != nil {
+			log.Printf("Warning: Failed to letter exhibits of petition %s: %v", petition.ID, err)
+		}
 	}
 
+	s.events.Publish(ctx, models.JobEvent{
+		JobID:  jobID,
+		Type:   models.JobEventStatus,
+		Status: models.JobStatusCompleted,
+	})
+
 	return nil
 }
 
 // DraftSection represents a section of the generated document
 type DraftSection struct {
+	StepName  string // Generation step that wrote the section
+	Criterion string // Criterion or prong the section argues; empty for Prong 2
 	Title     string
 	Content   string
 	Citations []string
+	ChunkIDs  []uuid.UUID // Legal chunks retrieved as context for the section
+}
+
+// saveSection persists a completed section of a job
+func (s *DraftService) saveSection(ctx context.Context, jobID uuid.UUID, stepName string, position int, section DraftSection) error {
+	citations := section.Citations
+	if citations == nil {
+		citations = []string{}
+	}
+	chunkIDs := section.ChunkIDs
+	if chunkIDs == nil {
+		chunkIDs = []uuid.UUID{}
+	}
+
+	return s.jobRepo.SaveSection(ctx, &models.GenerationSection{
+		JobID:     jobID,
+		StepName:  stepName,
+		Position:  position,
+		Title:     section.Title,
+		Content:   section.Content,
+		Citations: citations,
+		ChunkIDs:  chunkIDs,
+	})
+}
+
+// resumableSteps returns steps with only those criterion steps that have a
+// saved section left completed. Prong 2, the NIW balancing prong and
+// assembly always run again since they depend on the sections before them.
+func resumableSteps(steps models.GenerationSteps, saved map[string]*models.GenerationSection) models.GenerationSteps {
+	result := make(models.GenerationSteps, len(steps))
+	for i, step := range steps {
+		result[i] = step
+		_, hasSection := saved[step.Name]
+		isCriterion := step.Name != stepFinalMerits && step.Name != stepAssembling && !criteria.IsSynthesisStep(step.Name)
+		if !(isCriterion && step.Status == "completed" && hasSection) {
+			result[i].Status = "pending"
+		}
+	}
+	return result
+}
+
+// stepCompleted reports whether the named step is marked completed
+func stepCompleted(steps models.GenerationSteps, name string) bool {
+	for _, step := range steps {
+		if step.Name == name {
+			return step.Status == "completed"
+		}
+	}
+	return false
 }
 
 // updateStepStatus updates the status of a specific step in the generation job
@@ -401,35 +775,76 @@ func (s *DraftService) updateStepStatus(ctx context.Context, jobID uuid.UUID, st
 		}
 	}
 
-	return s.jobRepo.UpdateProgress(ctx, jobID, currentStep, steps)
+	err = s.jobRepo.UpdateProgress(ctx, jobID, currentStep, steps)
+	if err != nil {
+		return err
+	}
+
+	s.events.Publish(ctx, models.JobEvent{
+		JobID:       jobID,
+		Type:        models.JobEventStep,
+		CurrentStep: currentStep,
+		Steps:       steps,
+		StepName:    stepName,
+	})
+	return nil
 }
 
 // markJobFailed marks a job as failed with an error message
 func (s *DraftService) markJobFailed(ctx context.Context, jobID uuid.UUID, errorMessage string) {
+	// A cancelled context means the worker is shutting down or lost its lease;
+	// the job is re-queued rather than failed
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Flag the step that was running so a retry reruns it
+	if job, err := s.jobRepo.GetByID(ctx, jobID); err == nil {
+		for i := range job.Steps {
+			if job.Steps[i].Status == "in_progress" {
+				job.Steps[i].Status = "failed"
+			}
+		}
+		var currentStep string
+		if job.CurrentStep != nil {
+			currentStep = *job.CurrentStep
+		}
+		_ = s.jobRepo.UpdateProgress(ctx, jobID, currentStep, job.Steps)
+	}
+
 	err := s.jobRepo.Fail(ctx, jobID, errorMessage)
 	if err != nil {
 		// Log error but don't return - we're already in error handling
 		// In production, use proper logging
 		_ = err
+		return
+	}
+
+	s.events.Publish(ctx, models.JobEvent{
+		JobID:  jobID,
+		Type:   models.JobEventStatus,
+		Status: models.JobStatusFailed,
+		Error:  errorMessage,
+	})
+}
+
+// sleepContext waits for d, returning early with ctx's error if it is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
 }
 
 // getCriterionTitle returns the human-readable title for a criterion
-func getCriterionTitle(criterion string) string {
-	titles := map[string]string{
-		"awards":                 "Criterion 1: Receipt of Nationally or Internationally Recognized Prizes or Awards",
-		"membership":             "Criterion 2: Membership in Associations",
-		"media_coverage":         "Criterion 3: Published Material About the Person",
-		"judging":                "Criterion 4: Participation as a Judge",
-		"original_contributions": "Criterion 5: Original Scientific Contributions",
-		"authorship":             "Criterion 6: Scholarly Articles",
-		"exhibitions":            "Criterion 7: Display of Work",
-		"critical_role":          "Criterion 8: Critical or Essential Capacity",
-		"high_salary":            "Criterion 9: High Salary",
-		"commercial_success":     "Criterion 10: Commercial Success",
-	}
-	if title, ok := titles[criterion]; ok {
-		return title
+func getCriterionTitle(visa *criteria.VisaType, criterion string) string {
+	if c, ok := visa.Criterion(criterion); ok {
+		return c.Title
 	}
 	return "Criterion: " + criterion
 }
@@ -441,32 +856,15 @@ type RetrievedContext struct {
 	Cases       []models.LegalChunk // Precedent cases (1-2 chunks)
 }
 
-// EmbeddingRequest represents an embedding API request
-type EmbeddingRequest struct {
-	Model                string       `json:"model"`
-	Content              ContentInput `json:"content"`
-	TaskType             string       `json:"task_type,omitempty"`
-	OutputDimensionality int          `json:"output_dimensionality,omitempty"`
-}
-
-// ContentInput represents content for embedding
-type ContentInput struct {
-	Parts []PartInput `json:"parts"`
-}
-
-// PartInput represents a part of content
-type PartInput struct {
-	Text string `json:"text"`
-}
-
-// EmbeddingResponse represents an embedding API response
-type EmbeddingResponse struct {
-	Embedding EmbeddingData `json:"embedding"`
-}
-
-// EmbeddingData contains the embedding values
-type EmbeddingData struct {
-	Values []float64 `json:"values"`
+// chunkIDs returns the IDs of every retrieved chunk
+func (c *RetrievedContext) chunkIDs() []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(c.Regulations)+len(c.Appeals)+len(c.Cases))
+	for _, chunks := range [][]models.LegalChunk{c.Regulations, c.Appeals, c.Cases} {
+		for _, chunk := range chunks {
+			ids = append(ids, chunk.ID)
+		}
+	}
+	return ids
 }
 
 // sanitizeFieldOfExpertise extracts a canonical field name from user input
@@ -507,168 +905,41 @@ func (s *DraftService) generateQueryEmbedding(
 	// Build query text
 	queryText := fmt.Sprintf("[CRITERION: %s] [FIELD: %s] %s", criterion, sanitizedField, factSummary)
 
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY not set")
+	if s.embedder == nil {
+		return nil, errors.New("embedder not set")
 	}
 
-	reqBody := EmbeddingRequest{
-		Model: "models/gemini-embedding-001",
-		Content: ContentInput{
-			Parts: []PartInput{{Text: queryText}},
-		},
-		TaskType:             "RETRIEVAL_QUERY",
-		OutputDimensionality: 768,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
+	embedding, err := s.embedder.Embed(ctx, llm.EmbedRequest{
+		Text:       queryText,
+		TaskType:   llm.EmbedTaskRetrievalQuery,
+		Dimensions: embeddingDims,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrEmbeddingFailed, err)
 	}
-
-	var embedding []float64
-	backoff := initialBackoff
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if attempt > 0 {
-			time.Sleep(backoff)
-			backoff *= 2
-		}
-
-		req, err := http.NewRequestWithContext(ctx, "POST", embeddingAPI, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("x-goog-api-key", apiKey)
-
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			if attempt == maxRetries-1 {
-				return nil, fmt.Errorf("failed to send request after %d attempts: %w", maxRetries, err)
-			}
-			continue
-		}
-
-		if resp.StatusCode == http.StatusOK {
-			var apiResp EmbeddingResponse
-			if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-				resp.Body.Close()
-				if attempt == maxRetries-1 {
-					return nil, fmt.Errorf("failed to decode response: %w", err)
-				}
-				continue
-			}
-			resp.Body.Close()
-
-			embedding = apiResp.Embedding.Values
-			// Normalize embedding
-			norm := 0.0
-			for _, v := range embedding {
-				norm += v * v
-			}
-			norm = math.Sqrt(norm)
-			if norm > 0 {
-				for i := range embedding {
-					embedding[i] /= norm
-				}
-			}
-
-			return embedding, nil
-		}
-
-		resp.Body.Close()
-
-		// Don't retry on 400 or 401 errors
-		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
-			return nil, fmt.Errorf("API error: %d", resp.StatusCode)
-		}
-
-		if attempt == maxRetries-1 {
-			return nil, fmt.Errorf("API error after %d attempts: %d", maxRetries, resp.StatusCode)
-		}
+	if len(embedding) != embeddingDims {
+		return nil, fmt.Errorf("%w: got %d dimensions, want %d", ErrEmbeddingFailed, len(embedding), embeddingDims)
 	}
 
-	return nil, ErrEmbeddingFailed
-}
-
-// extractFactSummary extracts a fact summary from criterion details
-func (s *DraftService) extractFactSummary(criterion string, details models.CriteriaDetail) string {
-	var facts []string
-
-	switch criterion {
-	case "awards":
-		if awards, ok := details["awards"].([]interface{}); ok {
-			for _, award := range awards {
-				if a, ok := award.(map[string]interface{}); ok {
-					if name, ok := a["name"].(string); ok {
-						facts = append(facts, name)
-					}
-					if desc, ok := a["description"].(string); ok && desc != "" {
-						facts = append(facts, desc)
-					}
-				}
-			}
-		}
-	case "judging":
-		if venue, ok := details["venue"].(string); ok {
-			facts = append(facts, venue)
-		}
-		if role, ok := details["role"].(string); ok {
-			facts = append(facts, role)
-		}
-		if count, ok := details["papers_reviewed"].(float64); ok {
-			facts = append(facts, fmt.Sprintf("%.0f papers reviewed", count))
-		}
-	case "authorship":
-		if publications, ok := details["publications"].([]interface{}); ok {
-			for _, pub := range publications {
-				if p, ok := pub.(map[string]interface{}); ok {
-					if title, ok := p["title"].(string); ok {
-						facts = append(facts, title)
-					}
-					if journal, ok := p["journal"].(string); ok {
-						facts = append(facts, journal)
-					}
-					// Extract citations - handle both int and float64 types
-					// Use exact numbers from JSON to prevent hallucination
-					if citationsFloat, ok := p["citations"].(float64); ok {
-						facts = append(facts, fmt.Sprintf("%.0f citations", citationsFloat))
-					} else if citationsInt, ok := p["citations"].(int); ok {
-						facts = append(facts, fmt.Sprintf("%d citations", citationsInt))
-					} else if citationsInt64, ok := p["citations"].(int64); ok {
-						facts = append(facts, fmt.Sprintf("%d citations", citationsInt64))
-					}
-				}
-			}
-		}
-	case "original_contributions":
-		if contributions, ok := details["contributions"].([]interface{}); ok {
-			for _, contrib := range contributions {
-				if c, ok := contrib.(map[string]interface{}); ok {
-					if title, ok := c["title"].(string); ok {
-						facts = append(facts, title)
-					}
-					if impact, ok := c["impact"].(string); ok {
-						facts = append(facts, impact)
-					}
-				}
-			}
-		}
-	default:
-		// Generic extraction for other criteria
-		if desc, ok := details["description"].(string); ok {
-			facts = append(facts, desc)
+	// Normalize embedding
+	norm := 0.0
+	for _, v := range embedding {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	if norm > 0 {
+		for i := range embedding {
+			embedding[i] /= norm
 		}
 	}
 
-	return strings.Join(facts, " ")
+	return embedding, nil
 }
 
 // retrieveContext retrieves legal context for a criterion
 func (s *DraftService) retrieveContext(
 	ctx context.Context,
+	visa *criteria.VisaType,
 	criterion string,
 	fieldOfExpertise string,
 	details models.CriteriaDetail,
@@ -677,7 +948,7 @@ func (s *DraftService) retrieveContext(
 		return nil, errors.New("legal chunk repository not set")
 	}
 
-	factSummary := s.extractFactSummary(criterion, details)
+	factSummary := s.extractFactSummary(visa, criterion, details)
 
 	// Generate query embedding
 	embedding, err := s.generateQueryEmbedding(ctx, criterion, fieldOfExpertise, factSummary)
@@ -686,9 +957,10 @@ func (s *DraftService) retrieveContext(
 	}
 
 	context := &RetrievedContext{}
+	corpus := visa.Corpus
 
 	// Retrieve regulations
-	regs, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, criterion, "regulation", 3)
+	regs, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, corpus, criterion, "regulation", 3)
 	if err != nil {
 		log.Printf("Warning: Failed to retrieve regulations: %v", err)
 	} else {
@@ -696,7 +968,7 @@ func (s *DraftService) retrieveContext(
 	}
 
 	// Retrieve appeals
-	appeals, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, criterion, "appeal_decision", 3)
+	appeals, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, corpus, criterion, "appeal_decision", 3)
 	if err != nil {
 		log.Printf("Warning: Failed to retrieve appeals: %v", err)
 	} else {
@@ -704,7 +976,7 @@ func (s *DraftService) retrieveContext(
 	}
 
 	// Retrieve cases
-	cases, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, criterion, "precedent_case", 2)
+	cases, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, corpus, criterion, "precedent_case", 2)
 	if err != nil {
 		log.Printf("Warning: Failed to retrieve cases: %v", err)
 	} else {
@@ -716,188 +988,37 @@ func (s *DraftService) retrieveContext(
 
 // getCriterionCitation returns the regulatory citation for a criterion
 // Format matches IMPLEMENTATION_SPEC.md Appendix B: (8 C.F.R. § 214.2(o)(3)(iii)(X))
-// All 10 O-1A criteria are mapped A-J as specified
-func getCriterionCitation(criterion string) string {
-	citations := map[string]string{
-		"awards":                 "(8 C.F.R. § 214.2(o)(3)(iii)(A))",
-		"membership":             "(8 C.F.R. § 214.2(o)(3)(iii)(B))",
-		"media_coverage":         "(8 C.F.R. § 214.2(o)(3)(iii)(C))",
-		"judging":                "(8 C.F.R. § 214.2(o)(3)(iii)(D))",
-		"original_contributions": "(8 C.F.R. § 214.2(o)(3)(iii)(E))",
-		"authorship":             "(8 C.F.R. § 214.2(o)(3)(iii)(F))",
-		"exhibitions":            "(8 C.F.R. § 214.2(o)(3)(iii)(G))",
-		"critical_role":          "(8 C.F.R. § 214.2(o)(3)(iii)(H))",
-		"high_salary":            "(8 C.F.R. § 214.2(o)(3)(iii)(I))",
-		"commercial_success":     "(8 C.F.R. § 214.2(o)(3)(iii)(J))",
-	}
-	if citation, ok := citations[criterion]; ok {
-		return citation
+func getCriterionCitation(visa *criteria.VisaType, criterion string) string {
+	if c, ok := visa.Criterion(criterion); ok {
+		return c.Citation
 	}
 	return ""
 }
 
 // getHardcodedRegulation returns fallback regulation text for a criterion
 // This prevents LLM hallucination when retrieval fails or returns empty results
-func getHardcodedRegulation(criterion string) string {
-	regulations := map[string]string{
-		"awards":                 `Documentation of the alien's receipt of lesser nationally or internationally recognized prizes or awards for excellence in the field of endeavor (8 C.F.R. § 214.2(o)(3)(iii)(A)).`,
-		"membership":             `Documentation of the alien's membership in associations in the field for which classification is sought, which require outstanding achievements of their members, as judged by recognized national or international experts in their disciplines or fields (8 C.F.R. § 214.2(o)(3)(iii)(B)).`,
-		"media_coverage":         `Published material about the alien in professional or major trade publications or other major media, relating to the alien's work in the field for which classification is sought. Such evidence shall include the title, date, and author of the material, and any necessary translation (8 C.F.R. § 214.2(o)(3)(iii)(C)).`,
-		"judging":                `Evidence of the alien's participation, either individually or on a panel, as a judge of the work of others in the same or an allied field of specification for which classification is sought (8 C.F.R. § 214.2(o)(3)(iii)(D)).`,
-		"original_contributions": `Evidence of the alien's original scientific, scholarly, artistic, athletic, or business-related contributions of major significance in the field (8 C.F.R. § 214.2(o)(3)(iii)(E)).`,
-		"authorship":             `Evidence of the alien's authorship of scholarly articles in the field, in professional or major trade publications or other major media (8 C.F.R. § 214.2(o)(3)(iii)(F)).`,
-		"exhibitions":            `Evidence of the display of the alien's work in the field at artistic exhibitions or showcases (8 C.F.R. § 214.2(o)(3)(iii)(G)).`,
-		"critical_role":          `Evidence that the alien has performed in a leading or critical role for organizations or establishments that have a distinguished reputation (8 C.F.R. § 214.2(o)(3)(iii)(H)).`,
-		"high_salary":            `Evidence that the alien has commanded a high salary or other significantly high remuneration for services, in relation to others in the field (8 C.F.R. § 214.2(o)(3)(iii)(I)).`,
-		"commercial_success":     `Evidence of commercial successes in the performing arts, as shown by box office receipts or record, cassette, compact disk, or video sales (8 C.F.R. § 214.2(o)(3)(iii)(J)).`,
-	}
-	if regulation, ok := regulations[criterion]; ok {
-		return regulation
-	}
-	return "Evidence that the alien meets the regulatory criteria for extraordinary ability (8 C.F.R. § 214.2(o)(3)(iii))."
-}
-
-// formatClientFacts formats criterion details as a readable string
-func (s *DraftService) formatClientFacts(criterion string, details models.CriteriaDetail) string {
-	var builder strings.Builder
-
-	switch criterion {
-	case "awards":
-		if awards, ok := details["awards"].([]interface{}); ok {
-			for i, award := range awards {
-				if i > 0 {
-					builder.WriteString("\n")
-				}
-				if a, ok := award.(map[string]interface{}); ok {
-					if name, ok := a["name"].(string); ok {
-						builder.WriteString(fmt.Sprintf("Award: %s", name))
-					}
-					if date, ok := a["date"].(string); ok {
-						builder.WriteString(fmt.Sprintf(" (Date: %s)", date))
-					}
-					if desc, ok := a["description"].(string); ok && desc != "" {
-						builder.WriteString(fmt.Sprintf("\nDescription: %s", desc))
-					}
-					// Add impact hints if available
-					if importance, ok := a["importance"].(string); ok && importance != "" {
-						builder.WriteString(fmt.Sprintf("\nSignificance: %s", importance))
-					}
-					if impact, ok := a["impact"].(string); ok && impact != "" {
-						builder.WriteString(fmt.Sprintf("\nImpact: %s", impact))
-					}
-				}
-			}
-		}
-	case "judging":
-		if venue, ok := details["venue"].(string); ok {
-			builder.WriteString(fmt.Sprintf("Venue: %s\n", venue))
-		}
-		if role, ok := details["role"].(string); ok {
-			builder.WriteString(fmt.Sprintf("Role: %s\n", role))
-		}
-		if count, ok := details["papers_reviewed"].(float64); ok {
-			builder.WriteString(fmt.Sprintf("Papers Reviewed: %.0f", count))
-		}
-		// Add impact hints if available
-		if importance, ok := details["importance"].(string); ok && importance != "" {
-			builder.WriteString(fmt.Sprintf("\nSignificance: %s", importance))
-		}
-		if impact, ok := details["impact"].(string); ok && impact != "" {
-			builder.WriteString(fmt.Sprintf("\nImpact: %s", impact))
-		}
-	case "authorship":
-		if publications, ok := details["publications"].([]interface{}); ok {
-			for i, pub := range publications {
-				if i > 0 {
-					builder.WriteString("\n\n")
-				}
-				builder.WriteString(fmt.Sprintf("Publication %d:", i+1))
-				if p, ok := pub.(map[string]interface{}); ok {
-					if title, ok := p["title"].(string); ok {
-						builder.WriteString(fmt.Sprintf("\nTitle: %s", title))
-					}
-					if journal, ok := p["journal"].(string); ok {
-						builder.WriteString(fmt.Sprintf("\nJournal: %s", journal))
-					}
-					// Add journal impact factor if available
-					if impactFactor, ok := p["impact_factor"].(float64); ok && impactFactor > 0 {
-						builder.WriteString(fmt.Sprintf("\nImpact Factor: %.2f", impactFactor))
-					}
-					// Extract citations - handle both int and float64 types
-					// CRITICAL: Use exact numbers to prevent LLM hallucination
-					if citationsFloat, ok := p["citations"].(float64); ok {
-						builder.WriteString(fmt.Sprintf("\nCitations: %.0f", citationsFloat))
-					} else if citationsInt, ok := p["citations"].(int); ok {
-						builder.WriteString(fmt.Sprintf("\nCitations: %d", citationsInt))
-					} else if citationsInt64, ok := p["citations"].(int64); ok {
-						builder.WriteString(fmt.Sprintf("\nCitations: %d", citationsInt64))
-					}
-					// Add impact hints if available
-					if importance, ok := p["importance"].(string); ok && importance != "" {
-						builder.WriteString(fmt.Sprintf("\nSignificance: %s", importance))
-					}
-					if impact, ok := p["impact"].(string); ok && impact != "" {
-						builder.WriteString(fmt.Sprintf("\nImpact: %s", impact))
-					}
-				}
-			}
-		}
-	default:
-		if desc, ok := details["description"].(string); ok {
-			builder.WriteString(desc)
-		} else {
-			builder.WriteString(fmt.Sprintf("%v", details))
-		}
-		// Add impact hints if available for generic criteria
-		if importance, ok := details["importance"].(string); ok && importance != "" {
-			builder.WriteString(fmt.Sprintf("\nSignificance: %s", importance))
-		}
-		if impact, ok := details["impact"].(string); ok && impact != "" {
-			builder.WriteString(fmt.Sprintf("\nImpact: %s", impact))
-		}
+func getHardcodedRegulation(visa *criteria.VisaType, criterion string) string {
+	if c, ok := visa.Criterion(criterion); ok && c.Regulation != "" {
+		return c.Regulation
 	}
-
-	return builder.String()
-}
-
-// getMostCompellingFact extracts the most compelling fact from details
-func (s *DraftService) getMostCompellingFact(criterion string, details models.CriteriaDetail) string {
-	switch criterion {
-	case "awards":
-		if awards, ok := details["awards"].([]interface{}); ok && len(awards) > 0 {
-			if a, ok := awards[0].(map[string]interface{}); ok {
-				if name, ok := a["name"].(string); ok {
-					return name
-				}
-			}
-		}
-	case "judging":
-		if venue, ok := details["venue"].(string); ok {
-			return fmt.Sprintf("serving as %s at %s", details["role"], venue)
-		}
-	case "authorship":
-		if publications, ok := details["publications"].([]interface{}); ok && len(publications) > 0 {
-			if p, ok := publications[0].(map[string]interface{}); ok {
-				if title, ok := p["title"].(string); ok {
-					return title
-				}
-			}
-		}
-	}
-	return "their achievements in the field"
+	return visa.FallbackRegulation
 }
 
 // generateProng1Section generates a Prong 1 section using IRAC format
 func (s *DraftService) generateProng1Section(
 	ctx context.Context,
+	generator llm.TextGenerator,
+	visa *criteria.VisaType,
 	criterion string,
 	details models.CriteriaDetail,
+	exhibits []*models.Exhibit, // Tagged to the criterion
 	context *RetrievedContext,
 	clientName string,
 	fieldOfExpertise string,
+	w *sectionWriter, // Receives text as it streams in; may be nil
 ) (string, error) {
-	if s.geminiClient == nil {
-		return "", errors.New("gemini client not set")
+	if generator == nil {
+		return "", errors.New("text generator not set")
 	}
 
 	// Build prompt
@@ -911,7 +1032,7 @@ func (s *DraftService) generateProng1Section(
 	// This prevents LLM hallucination when retrieval fails
 	if regulationText.Len() == 0 {
 		log.Printf("Warning: No regulation context found for %s. Using fallback.", criterion)
-		regulationText.WriteString(getHardcodedRegulation(criterion))
+		regulationText.WriteString(getHardcodedRegulation(visa, criterion))
 		regulationText.WriteString("\n\n")
 	}
 
@@ -921,12 +1042,21 @@ func (s *DraftService) generateProng1Section(
 		appealText.WriteString("\n\n")
 	}
 
-	clientFacts := s.formatClientFacts(criterion, details)
-	specificFact := s.getMostCompellingFact(criterion, details)
-	criterionTitle := getCriterionTitle(criterion)
-	citation := getCriterionCitation(criterion)
+	clientFacts := s.formatClientFacts(visa, criterion, details) + formatExhibitFacts(exhibits)
+	specificFact := s.getMostCompellingFact(visa, criterion, details)
+	criterionTitle := getCriterionTitle(visa, criterion)
+	citation := getCriterionCitation(visa, criterion)
+	classification := visa.Classification
 
-	prompt := fmt.Sprintf(`You are an expert O-1A immigration attorney drafting a support letter section.
+	// Criterion-specific arguments to make in the Analysis
+	var hints strings.Builder
+	if c, ok := visa.Criterion(criterion); ok {
+		for _, hint := range c.PromptHints {
+			hints.WriteString("\n   - " + hint)
+		}
+	}
+
+	prompt := fmt.Sprintf(`You are an expert %s immigration attorney drafting a support letter section.
 
 LEGAL STANDARD:
 %s
@@ -947,7 +1077,7 @@ Write the "%s" section using IRAC format:
 3. Analysis: 
    - Present the client's specific achievement: %s
    - Argue by analogy to the precedent case(s) above
-   - Preempt common denial reasons (e.g., "This is not a student award but a professional recognition")
+   - Preempt common denial reasons (e.g., "%s")%s
    - Link to field of expertise (3-4 paragraphs)
 4. Conclusion: State that the client satisfies this criterion (1 paragraph)
 
@@ -957,7 +1087,7 @@ OUTPUT REQUIREMENTS:
 - 5-7 paragraphs total
 - No markdown formatting (plain text)
 - Write in third person about the client
-- When referencing specific evidence (awards, publications, etc.), append [Exhibit __] placeholders at the end of the sentence (e.g., "as documented in the exhibits attached hereto [Exhibit A]")
+- When referencing specific evidence (awards, publications, etc.), append [Exhibit __] placeholders at the end of the sentence (e.g., "as documented in the exhibits attached hereto [Exhibit __]"). When CLIENT FACTS lists EXHIBITS, use the placeholder listed for the exhibit that documents the evidence instead
 - Do NOT include a section header/title - the content will be inserted under an existing header
 - CRITICAL: Use EXACT numbers from CLIENT FACTS above. Do NOT estimate, round, or aggregate numbers. If CLIENT FACTS shows "Citations: 89", use "89 citations" exactly, not "350 citations" or any other number.
 
@@ -968,6 +1098,7 @@ TONE CONSTRAINTS (CRITICAL):
 - Maintain professional, factual tone throughout
 
 Write the section now:`,
+		classification,
 		regulationText.String(),
 		appealText.String(),
 		clientFacts,
@@ -975,30 +1106,30 @@ Write the section now:`,
 		criterionTitle,
 		citation,
 		specificFact,
+		visa.DenialExample,
+		hints.String(),
 		citation,
 	)
 
 	// Generate content with retry using HTTP API
-	systemInstruction := "You are an expert O-1A immigration attorney. Use formal legal language. Avoid flowery adjectives. Use objective descriptors only."
-	fullPrompt := systemInstruction + "\n\n" + prompt
+	systemInstruction := fmt.Sprintf("You are an expert %s immigration attorney. Use formal legal language. Avoid flowery adjectives. Use objective descriptors only.", classification)
 
 	var content string
 	var err error
 	backoff := initialBackoff
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
-			time.Sleep(backoff)
+			if err := sleepContext(ctx, backoff); err != nil {
+				return "", err
+			}
 			backoff *= 2
 		}
 
-		// Truncate prompt if too long to avoid context limits
-		truncatedPrompt := fullPrompt
-		// Gemini models have context limits - if prompt is very long, truncate
-		if len(fullPrompt) > 30000 { // Rough estimate: truncate if over ~30k chars
-			truncatedPrompt = fullPrompt[:30000] + "\n\n[Content truncated due to length...]"
-			log.Printf("Warning: Prompt too long (%d chars), truncating to 30000 chars", len(fullPrompt))
-		}
-		content, err = s.callGenerationAPI(ctx, truncatedPrompt, 0.2)
+		content, err = s.generateStreaming(ctx, generator, llm.GenerateRequest{
+			SystemInstruction: systemInstruction,
+			Prompt:            truncatePrompt(systemInstruction, prompt),
+			Temperature:       0.2,
+		}, w)
 		if err != nil {
 			if attempt == maxRetries-1 {
 				return "", fmt.Errorf("failed to generate content after %d attempts: %w", maxRetries, err)
@@ -1025,11 +1156,13 @@ Write the section now:`,
 // generateProng2 generates the Final Merits Determination section
 func (s *DraftService) generateProng2(
 	ctx context.Context,
+	generator llm.TextGenerator,
+	visa *criteria.VisaType,
 	sections []DraftSection,
 	petition *models.Petition,
 ) (string, error) {
-	if s.geminiClient == nil {
-		return "", errors.New("gemini client not set")
+	if generator == nil {
+		return "", errors.New("text generator not set")
 	}
 	if s.legalChunkRepo == nil {
 		return "", errors.New("legal chunk repository not set")
@@ -1047,7 +1180,11 @@ func (s *DraftService) generateProng2(
 	var chawatheText strings.Builder
 
 	// Search for Kazarian chunks
-	kazarianChunks, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, "", "regulation", 5)
+	if visa.FinalMerits == nil {
+		return "", fmt.Errorf("%s petitions have no final merits determination", visa.VisaType)
+	}
+	corpus := visa.Corpus
+	kazarianChunks, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, corpus, "", "regulation", 5)
 	if err == nil {
 		for _, chunk := range kazarianChunks {
 			if chunk.LegalStandard != nil && strings.Contains(*chunk.LegalStandard, "Kazarian") {
@@ -1058,7 +1195,7 @@ func (s *DraftService) generateProng2(
 	}
 
 	// Search for Chawathe chunks
-	chawatheChunks, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, "", "appeal_decision", 5)
+	chawatheChunks, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, corpus, "", "appeal_decision", 5)
 	if err == nil {
 		for _, chunk := range chawatheChunks {
 			if chunk.AppealCitation != nil && strings.Contains(*chunk.AppealCitation, "Chawathe") {
@@ -1074,11 +1211,14 @@ func (s *DraftService) generateProng2(
 		if i > 0 {
 			criteriaSummary.WriteString(", ")
 		}
-		criteriaSummary.WriteString(getCriterionTitle(criterion))
+		criteriaSummary.WriteString(getCriterionTitle(visa, criterion))
 	}
 
+	classification := visa.Classification
+	framing := visa.FinalMerits
+
 	// Build prompt
-	prompt := fmt.Sprintf(`You are an expert O-1A immigration attorney drafting the Final Merits Determination section.
+	prompt := fmt.Sprintf(`You are an expert %s immigration attorney drafting the Final Merits Determination section.
 
 LEGAL STANDARD (Kazarian):
 %s
@@ -1094,15 +1234,15 @@ TASK:
 Write the "Final Merits Determination" section that:
 
 1. Opens by stating the "Preponderance of the Evidence" standard (Matter of Chawathe) to frame the legal standard immediately
-2. States the legal standard (Kazarian two-part test)
+2. States the legal standard: %s
 3. Summarizes the evidence presented (do not repeat verbatim)
-4. Argues that the totality of evidence demonstrates the client has risen to the very top of the field
-5. Links the criteria together (e.g., "The client's awards (Criterion 1) are supported by their peer recognition as a Senior Area Chair (Criterion 3), which together with their highly cited publications (Criterion 2) demonstrate sustained impact")
+4. Argues that the totality of evidence demonstrates %s
+5. Links the criteria together (e.g., "%s")
 6. Concludes by reinforcing the preponderance of evidence standard
 
 OUTPUT REQUIREMENTS:
 - Use formal legal language
-- Include proper citations: (8 C.F.R. § 214.2(o)(3)(iii)) and (Matter of Chawathe, 25 I&N Dec. 369 (AAO 2010))
+- Include proper citations: %s
 - 6-8 paragraphs
 - No markdown formatting
 - Write in third person
@@ -1116,30 +1256,34 @@ TONE CONSTRAINTS (CRITICAL):
 - Maintain professional, factual tone throughout
 
 Write the section now:`,
+		classification,
 		kazarianText.String(),
 		chawatheText.String(),
 		criteriaSummary.String(),
+		framing.Standard,
+		framing.Acclaim,
+		framing.LinkExample,
+		framing.Citations,
 	)
 
 	// Generate content with retry using HTTP API
-	systemInstruction := "You are an expert O-1A immigration attorney. Use formal legal language. Avoid flowery adjectives. Use objective descriptors only."
-	fullPrompt := systemInstruction + "\n\n" + prompt
+	systemInstruction := fmt.Sprintf("You are an expert %s immigration attorney. Use formal legal language. Avoid flowery adjectives. Use objective descriptors only.", classification)
 
 	var content string
 	backoff := initialBackoff
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
-			time.Sleep(backoff)
+			if err := sleepContext(ctx, backoff); err != nil {
+				return "", err
+			}
 			backoff *= 2
 		}
 
-		// Truncate prompt if too long to avoid context limits
-		truncatedPrompt := fullPrompt
-		if len(fullPrompt) > 30000 {
-			truncatedPrompt = fullPrompt[:30000] + "\n\n[Content truncated due to length...]"
-			log.Printf("Warning: Prompt too long (%d chars), truncating to 30000 chars", len(fullPrompt))
-		}
-		content, err = s.callGenerationAPI(ctx, truncatedPrompt, 0.3)
+		content, err = generator.Generate(ctx, llm.GenerateRequest{
+			SystemInstruction: systemInstruction,
+			Prompt:            truncatePrompt(systemInstruction, prompt),
+			Temperature:       0.3,
+		})
 		if err != nil {
 			if attempt == maxRetries-1 {
 				return "", fmt.Errorf("failed to generate final merits after %d attempts: %w", maxRetries, err)
@@ -1164,11 +1308,11 @@ Write the section now:`,
 }
 
 // extractCitations extracts citations from retrieved context
-func (s *DraftService) extractCitations(context *RetrievedContext, criterion string) []string {
+func (s *DraftService) extractCitations(context *RetrievedContext, visa *criteria.VisaType, criterion string) []string {
 	citations := make([]string, 0)
 
 	// Add regulatory citation
-	citation := getCriterionCitation(criterion)
+	citation := getCriterionCitation(visa, criterion)
 	if citation != "" {
 		citations = append(citations, citation)
 	}
@@ -1190,166 +1334,66 @@ func (s *DraftService) extractCitations(context *RetrievedContext, criterion str
 	return citations
 }
 
-// callGenerationAPI calls the Gemini generation API directly via HTTP
-func (s *DraftService) callGenerationAPI(ctx context.Context, prompt string, temperature float64) (string, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("GEMINI_API_KEY not set")
-	}
-
-	reqBody := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]interface{}{
-					{"text": prompt},
-				},
-			},
-		},
-		"generationConfig": map[string]interface{}{
-			"temperature": temperature,
-			// No maxOutputTokens limit - let the model use its default maximum
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", generationAPI, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-goog-api-key", apiKey)
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("Gemini API error: Status %d, Body: %s", resp.StatusCode, string(bodyBytes))
-		return "", fmt.Errorf("API error: %d - %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var apiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-			FinishReason string `json:"finishReason,omitempty"`
-		} `json:"candidates"`
-		PromptFeedback struct {
-			BlockReason string `json:"blockReason,omitempty"`
-		} `json:"promptFeedback,omitempty"`
-		Error struct {
-			Code    int    `json:"code,omitempty"`
-			Message string `json:"message,omitempty"`
-		} `json:"error,omitempty"`
-	}
-
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
-		log.Printf("Failed to decode response. Body: %s", string(bodyBytes))
-		return "", fmt.Errorf("failed to decode response: %w", err)
+// lookupVisaType returns the registered visa type of a petition. Petitions
+// created without a visa type, or with one no longer registered, are
+// rejected rather than drafted under another visa type's criteria.
+func lookupVisaType(petition *models.Petition) (*criteria.VisaType, error) {
+	visa, ok := criteria.Lookup(string(petition.VisaType))
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownVisaType, petition.VisaType)
 	}
+	return visa, nil
+}
 
-	// Check for API errors in response
-	if apiResp.Error.Message != "" {
-		return "", fmt.Errorf("API error: %s (code: %d)", apiResp.Error.Message, apiResp.Error.Code)
-	}
-
-	if apiResp.PromptFeedback.BlockReason != "" {
-		return "", fmt.Errorf("API blocked prompt: %s", apiResp.PromptFeedback.BlockReason)
-	}
-
-	if len(apiResp.Candidates) == 0 {
-		log.Printf("API returned no candidates. Full response: %s", string(bodyBytes))
-		return "", fmt.Errorf("API returned no candidates")
-	}
-
-	var responseText strings.Builder
-	for i, candidate := range apiResp.Candidates {
-		// Log finish reason if present (e.g., SAFETY, RECITATION)
-		if candidate.FinishReason != "" && candidate.FinishReason != "STOP" {
-			log.Printf("Warning: Candidate %d finished with reason: %s", i, candidate.FinishReason)
-		}
-
-		// Check if content exists and has parts
-		if len(candidate.Content.Parts) == 0 {
-			// Log the actual candidate structure for debugging
-			candidateJSON, _ := json.Marshal(candidate)
-			log.Printf("Error: Candidate %d has no parts. Candidate structure: %s", i, string(candidateJSON))
-			log.Printf("Full API response (first 1000 chars): %s", string(bodyBytes[:min(1000, len(bodyBytes))]))
-			return "", fmt.Errorf("API candidate has no parts (finish reason: %s)", candidate.FinishReason)
-		}
-
-		for j, part := range candidate.Content.Parts {
-			if part.Text == "" {
-				log.Printf("Warning: Candidate %d, part %d has empty text", i, j)
-				continue // Skip empty parts but don't fail
-			}
-			responseText.WriteString(part.Text)
-		}
+// generatorFor returns the drafting model selected by the petition, or the
+// deployment default when it has none
+func (s *DraftService) generatorFor(petition *models.Petition) (llm.TextGenerator, error) {
+	if petition.DraftingModel == nil || *petition.DraftingModel == "" {
+		return s.generator, nil
 	}
 
-	result := responseText.String()
-	if result == "" {
-		return "", fmt.Errorf("API returned empty content")
+	generator, ok := s.generators[llm.ProviderType(*petition.DraftingModel)]
+	if !ok {
+		return nil, ErrDraftingModelUnavailable
 	}
-
-	return result, nil
+	return generator, nil
 }
 
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
+// truncatePrompt shortens prompt so that, together with the system
+// instruction, it stays within model context limits
+func truncatePrompt(systemInstruction, prompt string) string {
+	limit := maxPromptChars - len(systemInstruction) - 2
+	if len(prompt) <= limit {
+		return prompt
 	}
-	return b
+	log.Printf("Warning: Prompt too long (%d chars), truncating to %d chars", len(systemInstruction)+2+len(prompt), maxPromptChars)
+	return prompt[:limit] + "\n\n[Content truncated due to length...]"
 }
 
-// assembleDocument combines all sections into a complete document
-func (s *DraftService) assembleDocument(petition *models.Petition, sections []DraftSection) string {
-	var builder strings.Builder
-
-	builder.WriteString("PETITION FOR O-1A VISA\n\n")
-	builder.WriteString("I. INTRODUCTION\n")
-	builder.WriteString(fmt.Sprintf("%s, in the field of %s\n\n",
-		petition.ClientName, petition.FieldOfExpertise))
-
-	builder.WriteString("II. QUALIFICATIONS SUMMARY\n")
-	builder.WriteString(fmt.Sprintf("The client has satisfied the following criteria: %s\n\n",
-		strings.Join(petition.SelectedCriteria, ", ")))
+// assembleDocument lays out the drafted sections as a complete document,
+// between the introduction and the conclusion
+func (s *DraftService) assembleDocument(petition *models.Petition, visa *criteria.VisaType, sections []DraftSection) []*models.PetitionSection {
+	assembled := []*models.PetitionSection{
+		{Level: 0, Title: visa.Heading},
+		{
+			Level:   1,
+			Title:   "I. INTRODUCTION",
+			Content: fmt.Sprintf("%s, in the field of %s", petition.ClientName, petition.FieldOfExpertise),
+		},
+		{
+			Level:   1,
+			Title:   "II. QUALIFICATIONS SUMMARY",
+			Content: fmt.Sprintf("The client has satisfied the following criteria: %s", strings.Join(petition.SelectedCriteria, ", ")),
+		},
+		{Level: 1, Title: "III. REGULATORY CRITERIA"},
+	}
 
-	builder.WriteString("III. REGULATORY CRITERIA\n\n")
 	for _, section := range sections {
 		if section.Title != "Final Merits Determination" {
-			// Check if content already starts with a header (common patterns)
-			content := section.Content
-			contentLower := strings.ToLower(strings.TrimSpace(content))
-
-			// Remove duplicate header if content already starts with the section title
-			titleLower := strings.ToLower(section.Title)
-			if strings.HasPrefix(contentLower, titleLower) {
-				// Content already has header, skip adding it
-				builder.WriteString(content + "\n\n")
-			} else {
-				// Add header if not present
-				builder.WriteString(section.Title + "\n")
-				builder.WriteString(content + "\n\n")
-			}
+			assembled = append(assembled, draftedSection(2, section.Title, section))
 		}
 	}
 
-	builder.WriteString("IV. FINAL MERITS DETERMINATION\n")
 	for _, section := range sections {
 		if section.Title == "Final Merits Determination" {
 			// Check if content already starts with "Final Merits Determination" header
@@ -1370,12 +1414,39 @@ func (s *DraftService) assembleDocument(petition *models.Petition, sections []Dr
 					}
 				}
 			}
-			builder.WriteString(content + "\n\n")
+			section.Content = content
+			assembled = append(assembled, draftedSection(1, "IV. FINAL MERITS DETERMINATION", section))
 		}
 	}
 
-	builder.WriteString("V. CONCLUSION\n")
-	builder.WriteString("Based on the evidence presented, the client satisfies the requirements for O-1A classification.\n")
+	assembled = append(assembled, &models.PetitionSection{
+		Level: 1,
+		Title: "V. CONCLUSION",
+		Content: fmt.Sprintf("Based on the evidence presented, the client satisfies the requirements for %s classification.",
+			visa.Classification),
+	})
+
+	return assembled
+}
 
-	return builder.String()
+// draftedSection places a drafted section under title at level. Content
+// the model opened with the title itself is kept as is; rendering then
+// leaves out the title.
+func draftedSection(level int, title string, section DraftSection) *models.PetitionSection {
+	assembled := &models.PetitionSection{
+		Level:     level,
+		Title:     title,
+		Content:   section.Content,
+		Citations: section.Citations,
+		ChunkIDs:  section.ChunkIDs,
+	}
+	if section.StepName != "" {
+		stepName := section.StepName
+		assembled.StepName = &stepName
+	}
+	if section.Criterion != "" {
+		criterion := section.Criterion
+		assembled.Criterion = &criterion
+	}
+	return assembled
 }
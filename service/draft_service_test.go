@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/llm"
+	"meritdraft-backend/models"
+	"meritdraft-backend/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testDatabase connects to the database named by TEST_DATABASE_URL, whose
+// schema must have been created with cmd/create-petition-schema. Tests that
+// need it are skipped when it is not set.
+func testDatabase(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	connString := os.Getenv("TEST_DATABASE_URL")
+	if connString == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), connString)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// testUser creates a user whose petitions are deleted with it when the test ends
+func testUser(t *testing.T, pool *pgxpool.Pool) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+
+	var id uuid.UUID
+	err := pool.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash, name)
+		VALUES ($1, 'unused', 'Draft Test')
+		RETURNING id`, "draft-test-"+uuid.NewString()+"@example.com").Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+			t.Errorf("failed to delete user: %v", err)
+		}
+	})
+	return id
+}
+
+func TestProcessDraftWithFakeModel(t *testing.T) {
+	pool := testDatabase(t)
+	ctx := context.Background()
+
+	petitionRepo := repository.NewPetitionRepository(pool)
+	jobRepo := repository.NewGenerationJobRepository(pool)
+	sectionRepo := repository.NewPetitionSectionRepository(pool)
+
+	petition := &models.Petition{
+		UserID:           testUser(t, pool),
+		Status:           models.StatusDraft,
+		ClientName:       "Jane Doe",
+		VisaType:         models.VisaTypeO1A,
+		FieldOfExpertise: "Computational biology",
+		SelectedCriteria: []string{"awards"},
+		CriteriaDetails: models.CriteriaDetails{
+			"awards": {
+				"awards": []interface{}{
+					map[string]interface{}{"name": "Young Investigator Award", "date": "2023"},
+				},
+				"description": "Recognized for protein folding research",
+			},
+		},
+	}
+	if err := petitionRepo.Create(ctx, petition); err != nil {
+		t.Fatalf("failed to create petition: %v", err)
+	}
+
+	// Without a legal chunk repository the sections are drafted without
+	// retrieved authority, as when retrieval fails
+	fake := llm.NewFakeClient()
+	drafts := NewDraftService(
+		DraftWithPetitionRepository(petitionRepo),
+		DraftWithGenerationJobRepository(jobRepo),
+		DraftWithTextGenerator(fake),
+		DraftWithEmbedder(fake),
+	)
+
+	queued, err := drafts.GenerateDraft(ctx, GenerateDraftRequest{PetitionID: petition.ID})
+	if err != nil {
+		t.Fatalf("GenerateDraft: %v", err)
+	}
+	if err := drafts.ProcessDraft(ctx, queued.JobID); err != nil {
+		t.Fatalf("ProcessDraft: %v", err)
+	}
+
+	job, err := jobRepo.GetByID(ctx, queued.JobID)
+	if err != nil {
+		t.Fatalf("failed to load job: %v", err)
+	}
+	if job.Status != models.JobStatusCompleted {
+		t.Errorf("job status = %s, want %s", job.Status, models.JobStatusCompleted)
+	}
+	for _, step := range job.Steps {
+		if step.Status != "completed" {
+			t.Errorf("step %q status = %s, want completed", step.Name, step.Status)
+		}
+	}
+
+	sections, err := sectionRepo.ListByPetitionID(ctx, petition.ID)
+	if err != nil {
+		t.Fatalf("failed to load sections: %v", err)
+	}
+	visa, _ := criteria.Lookup(string(models.VisaTypeO1A))
+	if len(sections) == 0 || sections[0].Level != 0 || sections[0].Title != visa.Heading {
+		t.Fatalf("draft does not open with the petition title: %+v", sections)
+	}
+
+	var awards *models.PetitionSection
+	for _, section := range sections {
+		if section.Criterion != nil && *section.Criterion == "awards" {
+			awards = section
+		}
+	}
+	if awards == nil {
+		t.Fatal("draft has no section for the awards criterion")
+	}
+	if awards.Level != 2 || awards.JobID == nil || *awards.JobID != queued.JobID {
+		t.Errorf("awards section = level %d, job %v; want level 2 of job %s", awards.Level, awards.JobID, queued.JobID)
+	}
+	if !strings.Contains(awards.Content, "offline fake model") {
+		t.Errorf("awards section was not drafted by the fake model: %q", awards.Content)
+	}
+
+	stored, err := petitionRepo.GetByID(ctx, petition.ID)
+	if err != nil {
+		t.Fatalf("failed to reload petition: %v", err)
+	}
+	if stored.GeneratedContent == nil || *stored.GeneratedContent != models.RenderSections(sections) {
+		t.Error("generated_content is not the rendering of the draft's sections")
+	}
+}
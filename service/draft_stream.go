@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"meritdraft-backend/llm"
+	"meritdraft-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// Partial section text is written to the job record at most this often
+const partialTextFlushInterval = time.Second
+
+// generateStreaming streams a generation into w, falling back to a unary
+// call if the stream fails part way or the generator cannot stream
+func (s *DraftService) generateStreaming(ctx context.Context, generator llm.TextGenerator, req llm.GenerateRequest, w *sectionWriter) (string, error) {
+	streamer, ok := generator.(llm.StreamingTextGenerator)
+	if w == nil || !ok {
+		content, err := generator.Generate(ctx, req)
+		if err == nil && w != nil {
+			w.Reset()
+			w.Write(ctx, content)
+			w.Flush(ctx)
+		}
+		return content, err
+	}
+
+	w.Reset()
+	content, err := streamer.GenerateStream(ctx, req, func(delta string) {
+		w.Write(ctx, delta)
+	})
+	if err == nil {
+		w.Flush(ctx)
+		return content, nil
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	log.Printf("Warning: Streaming generation failed, falling back to unary call: %v", err)
+	content, err = generator.Generate(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	w.Reset()
+	w.Write(ctx, content)
+	w.Flush(ctx)
+	return content, nil
+}
+
+// sectionWriter publishes text of the section being drafted as it streams in,
+// both to SSE subscribers and, throttled, to the job's partial_text
+type sectionWriter struct {
+	s        *DraftService
+	jobID    uuid.UUID
+	stepName string
+
+	mu        sync.Mutex
+	text      strings.Builder
+	reset     bool
+	lastFlush time.Time
+}
+
+// newSectionWriter creates a writer for one step of a job
+func (s *DraftService) newSectionWriter(jobID uuid.UUID, stepName string) *sectionWriter {
+	return &sectionWriter{
+		s:        s,
+		jobID:    jobID,
+		stepName: stepName,
+	}
+}
+
+// Reset discards text written so far, e.g. before a retry of the same section
+func (w *sectionWriter) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.text.Len() == 0 {
+		return
+	}
+	w.text.Reset()
+	w.reset = true
+}
+
+// Write appends delta to the section
+func (w *sectionWriter) Write(ctx context.Context, delta string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.text.WriteString(delta)
+	w.s.events.Publish(ctx, models.JobEvent{
+		JobID:    w.jobID,
+		Type:     models.JobEventSectionDelta,
+		StepName: w.stepName,
+		Text:     delta,
+		Reset:    w.reset,
+	})
+	w.reset = false
+
+	if time.Since(w.lastFlush) >= partialTextFlushInterval {
+		w.flushLocked(ctx)
+	}
+}
+
+// Flush writes the text so far to the job record
+func (w *sectionWriter) Flush(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushLocked(ctx)
+}
+
+func (w *sectionWriter) flushLocked(ctx context.Context) {
+	w.lastFlush = time.Now()
+	if w.s.jobRepo == nil {
+		return
+	}
+
+	text := w.text.String()
+	if err := w.s.jobRepo.UpdatePartialText(ctx, w.jobID, &text); err != nil && ctx.Err() == nil {
+		log.Printf("Warning: Failed to store partial text for job %s: %v", w.jobID, err)
+	}
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/export"
+	"meritdraft-backend/models"
+	"meritdraft-backend/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrExhibitNotFound     = errors.New("exhibit not found")
+	ErrExhibitFileNotFound = errors.New("file not found on this petition")
+	ErrExhibitExists       = repository.ErrExhibitExists
+	ErrInvalidExhibitFact  = errors.New("invalid exhibit fact")
+)
+
+// Fields whose value names an entry of an array fact, in order of preference
+var exhibitNameFields = []string{"name", "title", "venue", "organization", "publication"}
+
+// ExhibitService tags uploaded files as exhibits of criterion facts and
+// letters them by their first reference in the generated petition
+type ExhibitService struct {
+	exhibitRepo *repository.ExhibitRepository
+	fileRepo    *repository.FileRepository
+}
+
+// ExhibitServiceOption is a functional option for ExhibitService
+type ExhibitServiceOption func(*ExhibitService)
+
+// ExhibitWithRepository sets the exhibit repository
+func ExhibitWithRepository(repo *repository.ExhibitRepository) ExhibitServiceOption {
+	return func(s *ExhibitService) {
+		s.exhibitRepo = repo
+	}
+}
+
+// ExhibitWithFileRepository sets the file repository
+func ExhibitWithFileRepository(repo *repository.FileRepository) ExhibitServiceOption {
+	return func(s *ExhibitService) {
+		s.fileRepo = repo
+	}
+}
+
+// NewExhibitService creates a new exhibit service
+func NewExhibitService(opts ...ExhibitServiceOption) *ExhibitService {
+	s := &ExhibitService{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateExhibitRequest represents a request to tag a file as an exhibit
+type CreateExhibitRequest struct {
+	Petition    *models.Petition
+	FileID      uuid.UUID
+	Criterion   string
+	FactKey     *string // Optional fact of the criterion, e.g. "awards"
+	FactIndex   *int    // Optional entry of an array fact
+	Description string  // Defaults to the name of the fact entry, else the filename
+	ActorID     uuid.UUID
+}
+
+// CreateExhibitResult represents the result of tagging a file as an exhibit
+type CreateExhibitResult struct {
+	Exhibit *models.Exhibit
+}
+
+// CreateExhibit tags a file of the petition as an exhibit and reletters
+// the petition's exhibits
+func (s *ExhibitService) CreateExhibit(ctx context.Context, req CreateExhibitRequest) (*CreateExhibitResult, error) {
+	if s.exhibitRepo == nil {
+		return nil, errors.New("exhibit repository not set")
+	}
+	if s.fileRepo == nil {
+		return nil, errors.New("file repository not set")
+	}
+
+	petition := req.Petition
+	file, err := s.fileRepo.GetByID(ctx, req.FileID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (file.PetitionID == nil || *file.PetitionID != petition.ID)) {
+		return nil, ErrExhibitFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := exhibitFact(petition, req.Criterion, req.FactKey, req.FactIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	description := strings.TrimSpace(req.Description)
+	if description == "" {
+		description = name
+	}
+	if description == "" {
+		description = file.Filename
+	}
+
+	actorID := req.ActorID
+	exhibit := &models.Exhibit{
+		PetitionID:  petition.ID,
+		FileID:      file.ID,
+		Filename:    file.Filename,
+		MimeType:    file.MimeType,
+		Criterion:   req.Criterion,
+		FactKey:     req.FactKey,
+		FactIndex:   req.FactIndex,
+		Description: description,
+		CreatedBy:   &actorID,
+	}
+	if err := s.exhibitRepo.Create(ctx, exhibit); err != nil {
+		return nil, err
+	}
+
+	exhibits, err := s.resolve(ctx, petition)
+	if err != nil {
+		return nil, err
+	}
+	for _, resolved := range exhibits {
+		if resolved.ID == exhibit.ID {
+			exhibit = resolved
+		}
+	}
+
+	return &CreateExhibitResult{Exhibit: exhibit}, nil
+}
+
+// DeleteExhibitRequest represents a request to untag an exhibit
+type DeleteExhibitRequest struct {
+	Petition  *models.Petition
+	ExhibitID uuid.UUID
+}
+
+// DeleteExhibitResult represents the result of untagging an exhibit
+type DeleteExhibitResult struct{}
+
+// DeleteExhibit untags an exhibit, keeping its file, and reletters the
+// remaining exhibits. Placeholders that cited it are matched again.
+func (s *ExhibitService) DeleteExhibit(ctx context.Context, req DeleteExhibitRequest) (*DeleteExhibitResult, error) {
+	if s.exhibitRepo == nil {
+		return nil, errors.New("exhibit repository not set")
+	}
+
+	exhibit, err := s.exhibitRepo.GetByID(ctx, req.ExhibitID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && exhibit.PetitionID != req.Petition.ID) {
+		return nil, ErrExhibitNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.exhibitRepo.Delete(ctx, exhibit.ID); err != nil {
+		return nil, err
+	}
+	if _, err := s.resolve(ctx, req.Petition); err != nil {
+		return nil, err
+	}
+
+	return &DeleteExhibitResult{}, nil
+}
+
+// ListExhibitsRequest represents a request for a petition's exhibit list
+type ListExhibitsRequest struct {
+	Petition *models.Petition
+}
+
+// ListExhibitsResult represents a petition's exhibit list
+type ListExhibitsResult struct {
+	Exhibits   []*models.Exhibit // In letter order
+	Unresolved int               // Placeholders in the generated content that cite no exhibit
+}
+
+// ListExhibits returns the petition's exhibits in letter order
+func (s *ExhibitService) ListExhibits(ctx context.Context, req ListExhibitsRequest) (*ListExhibitsResult, error) {
+	if s.exhibitRepo == nil {
+		return nil, errors.New("exhibit repository not set")
+	}
+
+	exhibits, err := s.exhibitRepo.ListByPetitionID(ctx, req.Petition.ID)
+	if err != nil {
+		return nil, err
+	}
+	sortExhibits(exhibits)
+
+	result := &ListExhibitsResult{Exhibits: exhibits}
+	if req.Petition.GeneratedContent != nil {
+		result.Unresolved = export.CountUnresolvedExhibits(*req.Petition.GeneratedContent)
+	}
+	return result, nil
+}
+
+// resolve reletters the petition's exhibits against its generated content
+func (s *ExhibitService) resolve(ctx context.Context, petition *models.Petition) ([]*models.Exhibit, error) {
+	return s.exhibitRepo.Resolve(ctx, petition.ID, petitionExhibitSections(petition))
+}
+
+// petitionExhibitSections maps the criterion headings of the petition's
+// visa type to criterion IDs. A petition without a registered visa type has
+// no criterion sections, so only placeholders naming an exhibit resolve.
+func petitionExhibitSections(petition *models.Petition) map[string]string {
+	visa, ok := criteria.Lookup(string(petition.VisaType))
+	if !ok {
+		return map[string]string{}
+	}
+	return exhibitSections(visa)
+}
+
+// exhibitSections maps the criterion headings of an assembled petition to
+// criterion IDs
+func exhibitSections(visa *criteria.VisaType) map[string]string {
+	sections := make(map[string]string, len(visa.Criteria))
+	for _, criterion := range visa.Criteria {
+		sections[criterion.Title] = criterion.ID
+	}
+	return sections
+}
+
+// sortExhibits orders exhibits by letter: A-Z, then AA, AB, ...
+func sortExhibits(exhibits []*models.Exhibit) {
+	sort.SliceStable(exhibits, func(i, j int) bool {
+		a, b := exhibits[i].Label, exhibits[j].Label
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+}
+
+// exhibitFact checks that the criterion, fact and entry an exhibit is
+// linked to exist, and returns the name of the entry when it has one
+func exhibitFact(petition *models.Petition, criterion string, factKey *string, factIndex *int) (string, error) {
+	visa, ok := criteria.Lookup(string(petition.VisaType))
+	if !ok {
+		return "", fmt.Errorf("%w: the petition has no registered visa type", ErrInvalidExhibitFact)
+	}
+	c, ok := visa.Criterion(criterion)
+	if !ok {
+		return "", fmt.Errorf("%w: %s has no criterion %q", ErrInvalidExhibitFact, petition.VisaType, criterion)
+	}
+	if factKey == nil {
+		if factIndex != nil {
+			return "", fmt.Errorf("%w: fact_index requires fact_key", ErrInvalidExhibitFact)
+		}
+		return "", nil
+	}
+
+	var field *criteria.FactField
+	for i := range c.Facts {
+		if c.Facts[i].Key == *factKey {
+			field = &c.Facts[i]
+		}
+	}
+	if field == nil {
+		return "", fmt.Errorf("%w: criterion %q has no fact %q", ErrInvalidExhibitFact, criterion, *factKey)
+	}
+
+	value := petition.CriteriaDetails[criterion][*factKey]
+	if factIndex == nil {
+		if s, ok := value.(string); ok {
+			return strings.TrimSpace(s), nil
+		}
+		return "", nil
+	}
+
+	entries, _ := value.([]interface{})
+	if field.Type != criteria.TypeArray || *factIndex < 0 || *factIndex >= len(entries) {
+		return "", fmt.Errorf("%w: %s.%s has no entry %d", ErrInvalidExhibitFact, criterion, *factKey, *factIndex)
+	}
+	switch entry := entries[*factIndex].(type) {
+	case string:
+		return strings.TrimSpace(entry), nil
+	case map[string]interface{}:
+		for _, key := range exhibitNameFields {
+			if name, ok := entry[key].(string); ok && strings.TrimSpace(name) != "" {
+				return strings.TrimSpace(name), nil
+			}
+		}
+	}
+	return "", nil
+}
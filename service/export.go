@@ -0,0 +1,198 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"meritdraft-backend/export"
+	"meritdraft-backend/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Export formats
+const (
+	ExportFormatDOCX = "docx"
+	ExportFormatPDF  = "pdf"
+)
+
+var (
+	ErrNothingToExport         = errors.New("petition has no generated draft to export")
+	ErrUnsupportedExportFormat = errors.New("unsupported export format")
+
+	// ErrUnsupportedCharacter is returned for PDF exports of text with a
+	// character none of the PDF fonts can print
+	ErrUnsupportedCharacter = export.ErrUnsupportedCharacter
+)
+
+// ExportPetitionRequest represents a request to export a petition's generated content
+type ExportPetitionRequest struct {
+	Petition *models.Petition
+	Format   string
+	Save     bool      // Also store the export as a file linked to the petition
+	UserID   uuid.UUID // Owner of the saved file
+}
+
+// ExportPetitionResult represents an exported petition
+type ExportPetitionResult struct {
+	Filename   string
+	MimeType   string
+	Data       []byte
+	File       *models.File // Saved file; nil unless Save was set
+	Unresolved int          // Placeholders that cite no exhibit, printed as "[Exhibit __]"
+}
+
+// ExportPetition renders the sections of the petition's draft as a filing
+// document on the firm's letterhead, and optionally saves it to storage
+func (s *PetitionService) ExportPetition(ctx context.Context, req ExportPetitionRequest) (*ExportPetitionResult, error) {
+	if s.sectionRepo == nil {
+		return nil, errors.New("petition section repository not set")
+	}
+
+	petition := req.Petition
+	sections, err := s.sectionRepo.ListByPetitionID(ctx, petition.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load draft sections: %w", err)
+	}
+	if len(sections) == 0 {
+		return nil, ErrNothingToExport
+	}
+
+	doc, err := s.exportDocument(ctx, petition, sections)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ExportPetitionResult{
+		Unresolved: export.CountUnresolvedExhibits(models.RenderSections(sections)),
+	}
+	var buf bytes.Buffer
+	switch req.Format {
+	case ExportFormatDOCX:
+		if err := export.WriteDOCX(&buf, doc); err != nil {
+			return nil, fmt.Errorf("failed to write docx: %w", err)
+		}
+		result.MimeType = export.MimeTypeDOCX
+	case ExportFormatPDF:
+		if err := export.WritePDF(&buf, doc); err != nil {
+			return nil, fmt.Errorf("failed to write pdf: %w", err)
+		}
+		result.MimeType = export.MimeTypePDF
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedExportFormat, req.Format)
+	}
+	result.Data = buf.Bytes()
+	result.Filename = exportFilename(petition, "Petition", req.Format)
+
+	if req.Save {
+		file, err := s.saveExport(ctx, petition, req.UserID, result)
+		if err != nil {
+			return nil, err
+		}
+		result.File = file
+	}
+
+	return result, nil
+}
+
+// exportDocument lays out the draft's sections with the firm's letterhead
+func (s *PetitionService) exportDocument(ctx context.Context, petition *models.Petition, sections []*models.PetitionSection) (*export.Document, error) {
+	doc := export.FromSections(sections)
+	if doc.Title == "" {
+		visa, err := lookupVisaType(petition)
+		if err != nil {
+			return nil, err
+		}
+		doc.Title = visa.Heading
+	}
+
+	letterhead, err := s.letterhead(ctx, petition)
+	if err != nil {
+		return nil, err
+	}
+	doc.Letterhead = letterhead
+
+	// The exhibit list is the lettered exhibits, cited or not, so it matches
+	// the tabs of a filing packet
+	if s.exhibitRepo != nil {
+		exhibits, err := s.exhibitRepo.ListByPetitionID(ctx, petition.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load exhibits: %w", err)
+		}
+		sortExhibits(exhibits)
+		for _, exhibit := range exhibits {
+			if exhibit.Label != "" {
+				doc.Exhibits = append(doc.Exhibits, export.Exhibit{Label: exhibit.Label, Description: exhibit.Description})
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+// letterhead returns the letterhead of the petition's firm, empty for
+// petitions without a firm
+func (s *PetitionService) letterhead(ctx context.Context, petition *models.Petition) (export.Letterhead, error) {
+	if petition.FirmID == nil || s.firmRepo == nil {
+		return export.Letterhead{}, nil
+	}
+
+	firm, err := s.firmRepo.GetByID(ctx, *petition.FirmID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// Firm was deleted; export without letterhead
+		return export.Letterhead{}, nil
+	case err != nil:
+		return export.Letterhead{}, fmt.Errorf("failed to load firm: %w", err)
+	}
+	return export.Letterhead{Name: firm.Name, Lines: firm.Letterhead}, nil
+}
+
+// saveExport uploads an export and records it as a file of the petition
+func (s *PetitionService) saveExport(ctx context.Context, petition *models.Petition, userID uuid.UUID, result *ExportPetitionResult) (*models.File, error) {
+	if s.storage == nil {
+		return nil, errors.New("storage not set")
+	}
+	if s.fileRepo == nil {
+		return nil, errors.New("file repository not set")
+	}
+
+	storagePath, err := s.storage.Upload(ctx, uuid.New(), result.Filename, bytes.NewReader(result.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload export: %w", err)
+	}
+
+	petitionID := petition.ID
+	file := &models.File{
+		UserID:      userID,
+		PetitionID:  &petitionID,
+		Filename:    result.Filename,
+		MimeType:    result.MimeType,
+		Size:        int64(len(result.Data)),
+		StoragePath: storagePath,
+	}
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		s.storage.Delete(ctx, storagePath)
+		return nil, fmt.Errorf("failed to save export record: %w", err)
+	}
+
+	return file, nil
+}
+
+// exportFilename names an export after the client, visa type and kind of
+// document, e.g. "Jane Doe - O-1A Petition.docx"
+func exportFilename(petition *models.Petition, document, format string) string {
+	name := strings.TrimSpace(fmt.Sprintf("%s - %s %s", petition.ClientName, petition.VisaType, document))
+	name = strings.TrimPrefix(name, "- ")
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	return name + "." + format
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"meritdraft-backend/models"
+	"meritdraft-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// FirmService handles business logic for law firm workspaces
+type FirmService struct {
+	firmRepo *repository.FirmRepository
+	userRepo *repository.UserRepository
+}
+
+// FirmServiceOption is a functional option for FirmService
+type FirmServiceOption func(*FirmService)
+
+// FirmWithFirmRepository sets the firm repository
+func FirmWithFirmRepository(repo *repository.FirmRepository) FirmServiceOption {
+	return func(s *FirmService) {
+		s.firmRepo = repo
+	}
+}
+
+// FirmWithUserRepository sets the user repository
+func FirmWithUserRepository(repo *repository.UserRepository) FirmServiceOption {
+	return func(s *FirmService) {
+		s.userRepo = repo
+	}
+}
+
+// NewFirmService creates a new firm service
+func NewFirmService(opts ...FirmServiceOption) *FirmService {
+	s := &FirmService{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+var (
+	ErrFirmNotFound     = errors.New("firm not found")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrInvalidRole      = errors.New("invalid firm role")
+	ErrLastFirmOwner    = errors.New("firm must keep at least one owner")
+	ErrFirmNameRequired = errors.New("firm name is required")
+)
+
+// CreateFirmRequest represents a request to create a firm
+type CreateFirmRequest struct {
+	Name       string
+	Letterhead []string // Optional lines printed under the name on exported petitions
+	OwnerID    uuid.UUID
+}
+
+// CreateFirmResult represents the result of creating a firm
+type CreateFirmResult struct {
+	Firm *models.Firm
+}
+
+// CreateFirm creates a firm with the caller as its owner
+func (s *FirmService) CreateFirm(ctx context.Context, req CreateFirmRequest) (*CreateFirmResult, error) {
+	if s.firmRepo == nil {
+		return nil, errors.New("firm repository not set")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrFirmNameRequired
+	}
+
+	firm := &models.Firm{Name: name, Letterhead: letterheadLines(req.Letterhead)}
+	if err := s.firmRepo.Create(ctx, firm, req.OwnerID); err != nil {
+		return nil, err
+	}
+
+	return &CreateFirmResult{Firm: firm}, nil
+}
+
+// UpdateFirmRequest represents a request to change a firm's name or letterhead
+type UpdateFirmRequest struct {
+	FirmID     uuid.UUID
+	ActorID    uuid.UUID // Caller, must be an owner
+	Name       *string   // Unchanged when nil
+	Letterhead []string  // Unchanged when nil
+}
+
+// UpdateFirmResult represents the result of updating a firm
+type UpdateFirmResult struct {
+	Firm *models.Firm
+}
+
+// UpdateFirm changes a firm's name or letterhead
+func (s *FirmService) UpdateFirm(ctx context.Context, req UpdateFirmRequest) (*UpdateFirmResult, error) {
+	if s.firmRepo == nil {
+		return nil, errors.New("firm repository not set")
+	}
+
+	if err := s.requireOwner(ctx, req.FirmID, req.ActorID); err != nil {
+		return nil, err
+	}
+
+	firm, err := s.firmRepo.GetByID(ctx, req.FirmID)
+	if err != nil {
+		return nil, ErrFirmNotFound
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, ErrFirmNameRequired
+		}
+		firm.Name = name
+	}
+	if req.Letterhead != nil {
+		firm.Letterhead = letterheadLines(req.Letterhead)
+	}
+
+	if err := s.firmRepo.Update(ctx, firm); err != nil {
+		return nil, err
+	}
+
+	return &UpdateFirmResult{Firm: firm}, nil
+}
+
+// letterheadLines trims letterhead lines and drops empty ones
+func letterheadLines(lines []string) []string {
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// ListFirmsRequest represents a request to list a user's firms
+type ListFirmsRequest struct {
+	UserID uuid.UUID
+}
+
+// ListFirmsResult represents the result of listing firms
+type ListFirmsResult struct {
+	Firms []*models.Firm
+}
+
+// ListFirms lists the firms a user belongs to
+func (s *FirmService) ListFirms(ctx context.Context, req ListFirmsRequest) (*ListFirmsResult, error) {
+	if s.firmRepo == nil {
+		return nil, errors.New("firm repository not set")
+	}
+
+	firms, err := s.firmRepo.ListByUserID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListFirmsResult{Firms: firms}, nil
+}
+
+// GetFirmRequest represents a request to get a firm and its members
+type GetFirmRequest struct {
+	FirmID uuid.UUID
+	UserID uuid.UUID // Caller, must be a member
+}
+
+// GetFirmResult represents a firm with its members and the caller's role
+type GetFirmResult struct {
+	Firm    *models.Firm
+	Members []*models.FirmMember
+	Role    models.FirmRole
+}
+
+// GetFirm retrieves a firm and its members if the caller belongs to it
+func (s *FirmService) GetFirm(ctx context.Context, req GetFirmRequest) (*GetFirmResult, error) {
+	if s.firmRepo == nil {
+		return nil, errors.New("firm repository not set")
+	}
+
+	firm, err := s.firmRepo.GetByID(ctx, req.FirmID)
+	if err != nil {
+		return nil, ErrFirmNotFound
+	}
+
+	membership, err := s.firmRepo.GetMembership(ctx, req.FirmID, req.UserID)
+	if err != nil {
+		return nil, ErrForbidden
+	}
+
+	members, err := s.firmRepo.ListMembers(ctx, req.FirmID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetFirmResult{
+		Firm:    firm,
+		Members: members,
+		Role:    membership.Role,
+	}, nil
+}
+
+// AddMemberRequest represents a request to add a user to a firm by email
+type AddMemberRequest struct {
+	FirmID      uuid.UUID
+	ActorID     uuid.UUID // Caller, must be an owner
+	MemberEmail string
+	Role        models.FirmRole
+}
+
+// AddMemberResult represents the result of adding a member
+type AddMemberResult struct {
+	Member *models.FirmMember
+}
+
+// AddMember adds a user to a firm, or changes their role if already a member
+func (s *FirmService) AddMember(ctx context.Context, req AddMemberRequest) (*AddMemberResult, error) {
+	if s.firmRepo == nil {
+		return nil, errors.New("firm repository not set")
+	}
+	if s.userRepo == nil {
+		return nil, errors.New("user repository not set")
+	}
+	if !req.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	if err := s.requireOwner(ctx, req.FirmID, req.ActorID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(req.MemberEmail))
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.guardLastOwner(ctx, req.FirmID, user.ID, req.Role); err != nil {
+		return nil, err
+	}
+
+	if err := s.firmRepo.AddMember(ctx, req.FirmID, user.ID, req.Role); err != nil {
+		return nil, err
+	}
+
+	member, err := s.firmRepo.GetMembership(ctx, req.FirmID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AddMemberResult{Member: member}, nil
+}
+
+// UpdateMemberRoleRequest represents a request to change a member's role
+type UpdateMemberRoleRequest struct {
+	FirmID   uuid.UUID
+	ActorID  uuid.UUID // Caller, must be an owner
+	MemberID uuid.UUID
+	Role     models.FirmRole
+}
+
+// UpdateMemberRoleResult represents the result of changing a member's role
+type UpdateMemberRoleResult struct {
+	Member *models.FirmMember
+}
+
+// UpdateMemberRole changes an existing member's role
+func (s *FirmService) UpdateMemberRole(ctx context.Context, req UpdateMemberRoleRequest) (*UpdateMemberRoleResult, error) {
+	if s.firmRepo == nil {
+		return nil, errors.New("firm repository not set")
+	}
+	if !req.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	if err := s.requireOwner(ctx, req.FirmID, req.ActorID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.firmRepo.GetMembership(ctx, req.FirmID, req.MemberID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.guardLastOwner(ctx, req.FirmID, req.MemberID, req.Role); err != nil {
+		return nil, err
+	}
+
+	if err := s.firmRepo.AddMember(ctx, req.FirmID, req.MemberID, req.Role); err != nil {
+		return nil, err
+	}
+
+	member, err := s.firmRepo.GetMembership(ctx, req.FirmID, req.MemberID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UpdateMemberRoleResult{Member: member}, nil
+}
+
+// RemoveMemberRequest represents a request to remove a member from a firm
+type RemoveMemberRequest struct {
+	FirmID   uuid.UUID
+	ActorID  uuid.UUID // Caller, must be an owner unless removing themselves
+	MemberID uuid.UUID
+}
+
+// RemoveMember removes a member from a firm. Members may always leave a firm themselves.
+func (s *FirmService) RemoveMember(ctx context.Context, req RemoveMemberRequest) error {
+	if s.firmRepo == nil {
+		return errors.New("firm repository not set")
+	}
+
+	if req.ActorID != req.MemberID {
+		if err := s.requireOwner(ctx, req.FirmID, req.ActorID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.firmRepo.GetMembership(ctx, req.FirmID, req.MemberID); err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.guardLastOwner(ctx, req.FirmID, req.MemberID, ""); err != nil {
+		return err
+	}
+
+	return s.firmRepo.RemoveMember(ctx, req.FirmID, req.MemberID)
+}
+
+// requireOwner returns ErrForbidden unless the user may manage the firm's members
+func (s *FirmService) requireOwner(ctx context.Context, firmID, userID uuid.UUID) error {
+	if _, err := s.firmRepo.GetByID(ctx, firmID); err != nil {
+		return ErrFirmNotFound
+	}
+
+	membership, err := s.firmRepo.GetMembership(ctx, firmID, userID)
+	if err != nil || !membership.Role.CanManageMembers() {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// guardLastOwner prevents demoting or removing the only remaining owner.
+// newRole is empty when the member is being removed.
+func (s *FirmService) guardLastOwner(ctx context.Context, firmID, memberID uuid.UUID, newRole models.FirmRole) error {
+	if newRole == models.FirmRoleOwner {
+		return nil
+	}
+
+	current, err := s.firmRepo.GetMembership(ctx, firmID, memberID)
+	if err != nil || current.Role != models.FirmRoleOwner {
+		// Not currently an owner, nothing to protect
+		return nil
+	}
+
+	owners, err := s.firmRepo.CountOwners(ctx, firmID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastFirmOwner
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"meritdraft-backend/models"
+	"meritdraft-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind by
+// before further events are dropped for it
+const subscriberBuffer = 256
+
+// JobEventService fans generation job events out to in-process subscribers.
+// Events travel through Postgres LISTEN/NOTIFY, so a client connected to one
+// server instance sees progress from a worker running anywhere.
+type JobEventService struct {
+	eventRepo *repository.JobEventRepository
+
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan models.JobEvent]struct{}
+}
+
+// JobEventServiceOption is a functional option for JobEventService
+type JobEventServiceOption func(*JobEventService)
+
+// JobEventWithRepository sets the job event repository
+func JobEventWithRepository(repo *repository.JobEventRepository) JobEventServiceOption {
+	return func(s *JobEventService) {
+		s.eventRepo = repo
+	}
+}
+
+// NewJobEventService creates a new job event service
+func NewJobEventService(opts ...JobEventServiceOption) *JobEventService {
+	s := &JobEventService{
+		subscribers: make(map[uuid.UUID]map[chan models.JobEvent]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Publish broadcasts an event to all server instances. Failures are logged
+// rather than returned since progress events are best effort.
+func (s *JobEventService) Publish(ctx context.Context, event models.JobEvent) {
+	if s == nil || s.eventRepo == nil {
+		return
+	}
+	if err := s.eventRepo.Publish(ctx, event); err != nil && ctx.Err() == nil {
+		log.Printf("Warning: Failed to publish %s event for job %s: %v", event.Type, event.JobID, err)
+	}
+}
+
+// Subscribe returns a channel receiving the events of one job and a function
+// that ends the subscription
+func (s *JobEventService) Subscribe(jobID uuid.UUID) (<-chan models.JobEvent, func()) {
+	ch := make(chan models.JobEvent, subscriberBuffer)
+
+	s.mu.Lock()
+	if s.subscribers[jobID] == nil {
+		s.subscribers[jobID] = make(map[chan models.JobEvent]struct{})
+	}
+	s.subscribers[jobID][ch] = struct{}{}
+	s.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subscribers[jobID], ch)
+			if len(s.subscribers[jobID]) == 0 {
+				delete(s.subscribers, jobID)
+			}
+			s.mu.Unlock()
+		})
+	}
+}
+
+// Run listens for events until ctx is cancelled, reconnecting after failures
+func (s *JobEventService) Run(ctx context.Context) error {
+	if s.eventRepo == nil {
+		return errors.New("job event repository not set")
+	}
+
+	backoff := initialBackoff
+	for {
+		err := s.eventRepo.Listen(ctx, s.dispatch)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		log.Printf("Warning: Job event listener stopped: %v. Reconnecting in %s", err, backoff)
+		if err := sleepContext(ctx, backoff); err != nil {
+			return nil
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// dispatch delivers an event to the job's subscribers without blocking the listener
+func (s *JobEventService) dispatch(event models.JobEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subscribers[event.JobID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Warning: Dropping %s event for job %s, subscriber is too slow", event.Type, event.JobID)
+		}
+	}
+}
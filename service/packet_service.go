@@ -0,0 +1,479 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/export"
+	"meritdraft-backend/models"
+	"meritdraft-backend/repository"
+	"meritdraft-backend/storage"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxPacketFileSize bounds the exhibit files bundled into a packet; larger
+// files get a slip sheet instead
+const maxPacketFileSize = 50 * 1024 * 1024
+
+var (
+	ErrPacketNotFound     = errors.New("packet not found")
+	ErrInvalidBatesPrefix = errors.New("bates prefix must be at most 20 letters, digits, spaces, dots, dashes or underscores")
+
+	batesPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9 ._-]{0,20}$`)
+)
+
+// PacketService bundles a petition's letter and exhibits into one
+// Bates-stamped PDF in the background and saves it as a file of the petition
+type PacketService struct {
+	packetRepo      *repository.PacketRepository
+	petitionRepo    *repository.PetitionRepository
+	exhibitRepo     *repository.ExhibitRepository
+	sectionRepo     *repository.PetitionSectionRepository
+	fileRepo        *repository.FileRepository
+	petitionService *PetitionService
+	storage         storage.Storage
+	leaseDuration   time.Duration
+	maxAttempts     int
+}
+
+// PacketServiceOption is a functional option for PacketService
+type PacketServiceOption func(*PacketService)
+
+// PacketWithRepository sets the packet repository
+func PacketWithRepository(repo *repository.PacketRepository) PacketServiceOption {
+	return func(s *PacketService) {
+		s.packetRepo = repo
+	}
+}
+
+// PacketWithPetitionRepository sets the petition repository
+func PacketWithPetitionRepository(repo *repository.PetitionRepository) PacketServiceOption {
+	return func(s *PacketService) {
+		s.petitionRepo = repo
+	}
+}
+
+// PacketWithExhibitRepository sets the exhibit repository
+func PacketWithExhibitRepository(repo *repository.ExhibitRepository) PacketServiceOption {
+	return func(s *PacketService) {
+		s.exhibitRepo = repo
+	}
+}
+
+// PacketWithPetitionSectionRepository sets the repository of the draft
+// sections a packet's letter is exported from
+func PacketWithPetitionSectionRepository(repo *repository.PetitionSectionRepository) PacketServiceOption {
+	return func(s *PacketService) {
+		s.sectionRepo = repo
+	}
+}
+
+// PacketWithFileRepository sets the file repository exhibit files are looked up in
+func PacketWithFileRepository(repo *repository.FileRepository) PacketServiceOption {
+	return func(s *PacketService) {
+		s.fileRepo = repo
+	}
+}
+
+// PacketWithPetitionService sets the petition service that exports the
+// letter and saves the bundled PDF
+func PacketWithPetitionService(petitionService *PetitionService) PacketServiceOption {
+	return func(s *PacketService) {
+		s.petitionService = petitionService
+	}
+}
+
+// PacketWithStorage sets the file storage exhibit files are read from
+func PacketWithStorage(storage storage.Storage) PacketServiceOption {
+	return func(s *PacketService) {
+		s.storage = storage
+	}
+}
+
+// PacketWithLeaseDuration sets how long a build may run before another
+// process picks it up again
+func PacketWithLeaseDuration(d time.Duration) PacketServiceOption {
+	return func(s *PacketService) {
+		s.leaseDuration = d
+	}
+}
+
+// PacketWithMaxAttempts sets how many times a build is tried before it is failed
+func PacketWithMaxAttempts(n int) PacketServiceOption {
+	return func(s *PacketService) {
+		s.maxAttempts = n
+	}
+}
+
+// NewPacketService creates a new packet service
+func NewPacketService(opts ...PacketServiceOption) *PacketService {
+	s := &PacketService{
+		leaseDuration: 10 * time.Minute,
+		maxAttempts:   3,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// QueuePacketRequest represents a request to build a filing packet
+type QueuePacketRequest struct {
+	Petition    *models.Petition
+	BatesPrefix *string // Defaults to the client's surname
+	ActorID     uuid.UUID
+}
+
+// QueuePacketResult represents the result of queueing a packet build
+type QueuePacketResult struct {
+	Packet *models.Packet
+}
+
+// QueuePacket queues a packet build. Returns ErrNothingToExport until a
+// draft has been generated, and ErrUnknownVisaType for petitions without a
+// registered visa type.
+func (s *PacketService) QueuePacket(ctx context.Context, req QueuePacketRequest) (*QueuePacketResult, error) {
+	if s.packetRepo == nil {
+		return nil, errors.New("packet repository not set")
+	}
+	if s.sectionRepo == nil {
+		return nil, errors.New("petition section repository not set")
+	}
+
+	petition := req.Petition
+	sections, err := s.sectionRepo.ListByPetitionID(ctx, petition.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sections) == 0 {
+		return nil, ErrNothingToExport
+	}
+	if _, err := lookupVisaType(petition); err != nil {
+		return nil, err
+	}
+
+	prefix := defaultBatesPrefix(petition.ClientName)
+	if req.BatesPrefix != nil {
+		prefix = *req.BatesPrefix
+		if !batesPrefixPattern.MatchString(prefix) {
+			return nil, ErrInvalidBatesPrefix
+		}
+	}
+
+	actorID := req.ActorID
+	packet, err := s.packetRepo.Create(ctx, petition.ID, prefix, &actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueuePacketResult{Packet: packet}, nil
+}
+
+// GetPacketRequest represents a request to get a packet of a petition
+type GetPacketRequest struct {
+	Petition *models.Petition
+	PacketID uuid.UUID
+}
+
+// GetPacketResult represents a packet
+type GetPacketResult struct {
+	Packet *models.Packet
+}
+
+// GetPacket returns a packet of the petition, or ErrPacketNotFound
+func (s *PacketService) GetPacket(ctx context.Context, req GetPacketRequest) (*GetPacketResult, error) {
+	if s.packetRepo == nil {
+		return nil, errors.New("packet repository not set")
+	}
+
+	packet, err := s.packetRepo.GetByID(ctx, req.PacketID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && packet.PetitionID != req.Petition.ID) {
+		return nil, ErrPacketNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetPacketResult{Packet: packet}, nil
+}
+
+// ProcessNext leases one queued packet and builds it, renewing the lease
+// while the build runs. A failed build is queued again until it has been
+// tried maxAttempts times. Returns false when the queue is empty.
+func (s *PacketService) ProcessNext(ctx context.Context) (bool, error) {
+	if s.packetRepo == nil || s.petitionRepo == nil || s.exhibitRepo == nil || s.fileRepo == nil {
+		return false, errors.New("packet service repositories not set")
+	}
+	if s.petitionService == nil {
+		return false, errors.New("petition service not set")
+	}
+	if s.storage == nil {
+		return false, errors.New("storage not set")
+	}
+
+	packet, err := s.packetRepo.Lease(ctx, s.leaseDuration)
+	if err != nil || packet == nil {
+		return false, err
+	}
+
+	if packet.Attempts > s.maxAttempts {
+		msg := fmt.Sprintf("packet build abandoned after %d attempts", packet.Attempts-1)
+		return true, s.packetRepo.Fail(ctx, packet.ID, packet.Attempts, msg)
+	}
+
+	buildCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// Keep the lease alive; stop the build if another process has taken it over
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(s.leaseDuration / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-buildCtx.Done():
+				return
+			case <-ticker.C:
+				err := s.packetRepo.Heartbeat(buildCtx, packet.ID, packet.Attempts, s.leaseDuration)
+				if errors.Is(err, repository.ErrPacketLeaseLost) {
+					cancel(err)
+					return
+				}
+				if err != nil && buildCtx.Err() == nil {
+					log.Printf("Warning: Heartbeat for packet %s failed: %v", packet.ID, err)
+				}
+			}
+		}
+	}()
+
+	err = s.build(buildCtx, packet)
+	cancel(nil)
+	<-heartbeatDone
+	if err == nil {
+		return true, nil
+	}
+
+	log.Printf("Packet build for petition %s failed: %v", packet.PetitionID, err)
+	if ctx.Err() != nil {
+		// Shutting down: the expired lease returns it to the queue
+		return true, nil
+	}
+	if errors.Is(err, repository.ErrPacketLeaseLost) || errors.Is(context.Cause(buildCtx), repository.ErrPacketLeaseLost) {
+		log.Printf("Packet %s was leased again by another process", packet.ID)
+		return true, nil
+	}
+	if packet.Attempts < s.maxAttempts {
+		return true, s.packetRepo.Retry(ctx, packet.ID, packet.Attempts, err.Error())
+	}
+	return true, s.packetRepo.Fail(ctx, packet.ID, packet.Attempts, err.Error())
+}
+
+// build exports the letter with freshly lettered exhibits, bundles it with
+// the exhibit files and saves the result
+func (s *PacketService) build(ctx context.Context, packet *models.Packet) error {
+	petition, err := s.petitionRepo.GetByID(ctx, packet.PetitionID)
+	if err != nil {
+		return fmt.Errorf("failed to load petition: %w", err)
+	}
+	visa, err := lookupVisaType(petition)
+	if err != nil {
+		return err
+	}
+
+	// Lettering may rewrite the placeholders, so reload the petition after it
+	exhibits, err := s.exhibitRepo.Resolve(ctx, petition.ID, exhibitSections(visa))
+	if err != nil {
+		return fmt.Errorf("failed to letter exhibits: %w", err)
+	}
+	sortExhibits(exhibits)
+	if petition, err = s.petitionRepo.GetByID(ctx, packet.PetitionID); err != nil {
+		return fmt.Errorf("failed to load petition: %w", err)
+	}
+
+	letter, err := s.petitionService.ExportPetition(ctx, ExportPetitionRequest{
+		Petition: petition,
+		Format:   ExportFormatPDF,
+	})
+	if err != nil {
+		return err
+	}
+
+	cover, err := s.coverLetter(ctx, petition, visa)
+	if err != nil {
+		return err
+	}
+	bundle := &export.Packet{
+		Title:       visa.Heading,
+		Author:      cover.Letterhead.Name,
+		Cover:       cover,
+		Letter:      letter.Data,
+		BatesPrefix: packet.BatesPrefix,
+	}
+	for _, exhibit := range exhibits {
+		bundled, err := s.exhibitFile(ctx, exhibit)
+		if err != nil {
+			return err
+		}
+		bundle.Exhibits = append(bundle.Exhibits, bundled)
+	}
+
+	var buf bytes.Buffer
+	index, err := export.WritePacket(&buf, bundle)
+	if err != nil {
+		return fmt.Errorf("failed to write packet: %w", err)
+	}
+
+	ownerID := petition.UserID
+	if packet.CreatedBy != nil {
+		ownerID = *packet.CreatedBy
+	}
+	file, err := s.petitionService.saveExport(ctx, petition, ownerID, &ExportPetitionResult{
+		Filename: exportFilename(petition, "Filing Packet", ExportFormatPDF),
+		MimeType: export.MimeTypePDF,
+		Data:     buf.Bytes(),
+	})
+	if err != nil {
+		return err
+	}
+
+	placed := make(models.PacketExhibits, len(index.Exhibits))
+	for i, entry := range index.Exhibits {
+		placed[i] = models.PacketExhibit{
+			ExhibitID:  exhibits[i].ID,
+			Label:      entry.Label,
+			Filename:   exhibits[i].Filename,
+			BatesFirst: entry.Bates.First,
+			BatesLast:  entry.Bates.Last,
+			Pages:      entry.Pages,
+			Note:       entry.Note,
+		}
+	}
+
+	if err := s.packetRepo.Complete(ctx, packet.ID, packet.Attempts, file.ID, index.Pages, placed); err != nil {
+		s.deleteFile(file)
+		return err
+	}
+	return nil
+}
+
+// deleteFile removes a saved packet that could not be recorded. It runs
+// even when the build was cancelled.
+func (s *PacketService) deleteFile(file *models.File) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.fileRepo.Delete(ctx, file.ID); err != nil {
+		log.Printf("Warning: Failed to delete packet file %s: %v", file.ID, err)
+		return
+	}
+	if err := s.storage.Delete(ctx, file.StoragePath); err != nil {
+		log.Printf("Warning: Failed to delete packet blob %s: %v", file.StoragePath, err)
+	}
+}
+
+// coverLetter addresses the packet to USCIS on the firm's letterhead,
+// signed with the firm's name
+func (s *PacketService) coverLetter(ctx context.Context, petition *models.Petition, visa *criteria.VisaType) (*export.CoverLetter, error) {
+	letterhead, err := s.petitionService.letterhead(ctx, petition)
+	if err != nil {
+		return nil, err
+	}
+
+	classification := visa.Classification
+	return &export.CoverLetter{
+		Letterhead: letterhead,
+		Date:       time.Now(),
+		Recipient:  []string{"U.S. Citizenship and Immigration Services"},
+		Subject:    fmt.Sprintf("%s petition on behalf of %s", classification, petition.ClientName),
+		Body: []string{
+			fmt.Sprintf("Please find enclosed the petition for %s classification of %s, with the evidence supporting it. The enclosures are listed below with their Bates numbers.", classification, petition.ClientName),
+		},
+		Signature: letterhead.Name,
+	}, nil
+}
+
+// exhibitFile reads an exhibit's file from storage. Files too large to
+// bundle are marked omitted rather than read.
+func (s *PacketService) exhibitFile(ctx context.Context, exhibit *models.Exhibit) (export.PacketExhibit, error) {
+	bundled := export.PacketExhibit{
+		Label:       exhibit.Label,
+		Description: exhibit.Description,
+		Filename:    exhibit.Filename,
+		MimeType:    exhibit.MimeType,
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, exhibit.FileID)
+	if err != nil {
+		return bundled, fmt.Errorf("failed to load file of exhibit %s: %w", exhibit.Label, err)
+	}
+	if file.Size > maxPacketFileSize {
+		bundled.Omitted = fmt.Sprintf("file exceeds %d MB", maxPacketFileSize/(1024*1024))
+		return bundled, nil
+	}
+
+	reader, err := s.storage.Download(ctx, file.StoragePath)
+	if err != nil {
+		return bundled, fmt.Errorf("failed to download file of exhibit %s: %w", exhibit.Label, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, maxPacketFileSize+1))
+	if err != nil {
+		return bundled, fmt.Errorf("failed to read file of exhibit %s: %w", exhibit.Label, err)
+	}
+	if len(data) > maxPacketFileSize {
+		bundled.Omitted = fmt.Sprintf("file exceeds %d MB", maxPacketFileSize/(1024*1024))
+		return bundled, nil
+	}
+
+	bundled.Data = data
+	return bundled, nil
+}
+
+// RunBuilder builds queued packets until ctx is cancelled, polling every
+// interval once the queue is empty
+func (s *PacketService) RunBuilder(ctx context.Context, interval time.Duration) {
+	for {
+		processed, err := s.ProcessNext(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Warning: Packet build failed: %v", err)
+		}
+		if processed && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// defaultBatesPrefix derives a Bates prefix from the client's surname, e.g.
+// "DOE" for "Jane Doe"
+func defaultBatesPrefix(clientName string) string {
+	fields := strings.Fields(clientName)
+	if len(fields) == 0 {
+		return ""
+	}
+	prefix := strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return -1
+	}, strings.ToUpper(fields[len(fields)-1]))
+	if len(prefix) > 10 {
+		prefix = prefix[:10]
+	}
+	return prefix
+}
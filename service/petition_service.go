@@ -2,18 +2,31 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"log"
+	"strings"
+	"time"
 
 	"meritdraft-backend/models"
 	"meritdraft-backend/repository"
+	"meritdraft-backend/storage"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 // PetitionService handles business logic for petitions
 type PetitionService struct {
 	petitionRepo *repository.PetitionRepository
 	jobRepo      *repository.GenerationJobRepository
+	firmRepo     *repository.FirmRepository
+	fileRepo     *repository.FileRepository
+	exhibitRepo  *repository.ExhibitRepository
+	sectionRepo  *repository.PetitionSectionRepository
+	storage      storage.Storage
+	retention    time.Duration // Delay between a purge request and the hard delete
 }
 
 // PetitionServiceOption is a functional option for PetitionService
@@ -33,9 +46,53 @@ func WithGenerationJobRepository(repo *repository.GenerationJobRepository) Petit
 	}
 }
 
+// WithFirmRepository sets the firm repository
+func WithFirmRepository(repo *repository.FirmRepository) PetitionServiceOption {
+	return func(s *PetitionService) {
+		s.firmRepo = repo
+	}
+}
+
+// WithFileRepository sets the file repository used to record saved exports
+func WithFileRepository(repo *repository.FileRepository) PetitionServiceOption {
+	return func(s *PetitionService) {
+		s.fileRepo = repo
+	}
+}
+
+// WithExhibitRepository sets the exhibit repository used to describe exported exhibits
+func WithExhibitRepository(repo *repository.ExhibitRepository) PetitionServiceOption {
+	return func(s *PetitionService) {
+		s.exhibitRepo = repo
+	}
+}
+
+// WithPetitionSectionRepository sets the repository of the sections drafts are stored as
+func WithPetitionSectionRepository(repo *repository.PetitionSectionRepository) PetitionServiceOption {
+	return func(s *PetitionService) {
+		s.sectionRepo = repo
+	}
+}
+
+// WithStorage sets the file storage used to save exports and remove blobs of purged petitions
+func WithStorage(storage storage.Storage) PetitionServiceOption {
+	return func(s *PetitionService) {
+		s.storage = storage
+	}
+}
+
+// WithPurgeRetention sets how long a petition stays recoverable after a purge request
+func WithPurgeRetention(retention time.Duration) PetitionServiceOption {
+	return func(s *PetitionService) {
+		s.retention = retention
+	}
+}
+
 // NewPetitionService creates a new petition service
 func NewPetitionService(opts ...PetitionServiceOption) *PetitionService {
-	s := &PetitionService{}
+	s := &PetitionService{
+		retention: 30 * 24 * time.Hour,
+	}
 	for _, opt := range opts {
 		opt(s)
 	}
@@ -45,6 +102,7 @@ func NewPetitionService(opts ...PetitionServiceOption) *PetitionService {
 // CreatePetitionRequest represents a request to create a petition
 type CreatePetitionRequest struct {
 	UserID uuid.UUID
+	FirmID *uuid.UUID // Optional, shares the petition with the firm's members
 	Status models.PetitionStatus
 }
 
@@ -59,8 +117,22 @@ func (s *PetitionService) CreatePetition(ctx context.Context, req CreatePetition
 		return nil, errors.New("petition repository not set")
 	}
 
+	if req.FirmID != nil {
+		role, err := s.GetFirmRole(ctx, *req.FirmID, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !role.CanEdit() {
+			return nil, ErrForbidden
+		}
+		if req.Status == models.StatusCompleted && !role.CanDraft() {
+			return nil, ErrForbidden
+		}
+	}
+
 	petition := &models.Petition{
 		UserID:           req.UserID,
+		FirmID:           req.FirmID,
 		Status:           req.Status,
 		SelectedCriteria: []string{},
 		CriteriaDetails:  make(models.CriteriaDetails),
@@ -102,6 +174,108 @@ func (s *PetitionService) GetPetition(ctx context.Context, req GetPetitionReques
 	return &GetPetitionResult{Petition: petition}, nil
 }
 
+// AuthorizePetitionRequest represents a request to load a petition on behalf of a user
+type AuthorizePetitionRequest struct {
+	PetitionID uuid.UUID
+	UserID     uuid.UUID
+}
+
+// AuthorizePetitionResult represents a petition together with the caller's role on it
+type AuthorizePetitionResult struct {
+	Petition *models.Petition
+	Role     models.FirmRole
+}
+
+// AuthorizePetition retrieves a petition and resolves the user's role on it.
+// Returns ErrPetitionNotFound or ErrForbidden when the user has no access.
+func (s *PetitionService) AuthorizePetition(ctx context.Context, req AuthorizePetitionRequest) (*AuthorizePetitionResult, error) {
+	if s.petitionRepo == nil {
+		return nil, errors.New("petition repository not set")
+	}
+
+	petition, err := s.petitionRepo.GetByID(ctx, req.PetitionID)
+	if err != nil {
+		return nil, ErrPetitionNotFound
+	}
+
+	role, err := s.petitionRepo.GetAccessRole(ctx, petition, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrForbidden
+	}
+
+	return &AuthorizePetitionResult{Petition: petition, Role: role}, nil
+}
+
+// GetFirmRole returns the user's role in a firm, or ErrForbidden if they are not a member
+func (s *PetitionService) GetFirmRole(ctx context.Context, firmID, userID uuid.UUID) (models.FirmRole, error) {
+	if s.firmRepo == nil {
+		return "", errors.New("firm repository not set")
+	}
+
+	member, err := s.firmRepo.GetMembership(ctx, firmID, userID)
+	if err != nil {
+		return "", ErrForbidden
+	}
+
+	return member.Role, nil
+}
+
+// ErrDocumentFileNotFound is returned for a CV or job offer file the caller
+// cannot use on the petition
+var ErrDocumentFileNotFound = errors.New("file not found on this petition or among your uploads")
+
+// CheckDocumentFileRequest represents a file to be used as a petition's CV
+// or job offer
+type CheckDocumentFileRequest struct {
+	Petition *models.Petition
+	FileID   uuid.UUID
+	UserID   uuid.UUID
+}
+
+// CheckDocumentFile checks that the caller may use a file as the petition's
+// CV or job offer: the file is attached to the petition, is the caller's
+// own unattached upload, or is attached to another petition the caller can
+// edit. Returns ErrDocumentFileNotFound otherwise.
+func (s *PetitionService) CheckDocumentFile(ctx context.Context, req CheckDocumentFileRequest) error {
+	if s.petitionRepo == nil {
+		return errors.New("petition repository not set")
+	}
+	if s.fileRepo == nil {
+		return errors.New("file repository not set")
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, req.FileID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrDocumentFileNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case file.PetitionID == nil:
+		if file.UserID != req.UserID {
+			return ErrDocumentFileNotFound
+		}
+	case *file.PetitionID != req.Petition.ID:
+		other, err := s.petitionRepo.GetByID(ctx, *file.PetitionID)
+		if err != nil {
+			return ErrDocumentFileNotFound
+		}
+		role, err := s.petitionRepo.GetAccessRole(ctx, other, req.UserID)
+		if err != nil {
+			return err
+		}
+		if !role.CanEdit() {
+			return ErrDocumentFileNotFound
+		}
+	}
+	return nil
+}
+
 // UpdatePetitionRequest represents a request to update a petition
 type UpdatePetitionRequest struct {
 	Petition *models.Petition
@@ -126,52 +300,371 @@ func (s *PetitionService) UpdatePetition(ctx context.Context, req UpdatePetition
 	return &UpdatePetitionResult{Petition: req.Petition}, nil
 }
 
-// UpdateGeneratedContentRequest represents a request to update generated content
-type UpdateGeneratedContentRequest struct {
+// ErrSectionNotFound is returned for a section that is not part of the petition's draft
+var ErrSectionNotFound = errors.New("section not found")
+
+// ErrInvalidSectionTitle is returned for a section title that spans lines
+var ErrInvalidSectionTitle = errors.New("section title must be a single line")
+
+// ListSectionsRequest represents a request for the sections of a petition's draft
+type ListSectionsRequest struct {
 	PetitionID uuid.UUID
-	Content    string
 }
 
-// UpdateGeneratedContentResult represents the result of updating generated content
-type UpdateGeneratedContentResult struct{}
+// ListSectionsResult represents the sections of a petition's draft
+type ListSectionsResult struct {
+	Sections []*models.PetitionSection // In document order
+}
 
-// UpdateGeneratedContent updates the generated content for a petition
-func (s *PetitionService) UpdateGeneratedContent(ctx context.Context, req UpdateGeneratedContentRequest) (*UpdateGeneratedContentResult, error) {
-	if s.petitionRepo == nil {
-		return nil, errors.New("petition repository not set")
+// ListSections returns the sections the petition's generated content is
+// rendered from. Drafts generated before sections were stored have none.
+func (s *PetitionService) ListSections(ctx context.Context, req ListSectionsRequest) (*ListSectionsResult, error) {
+	if s.sectionRepo == nil {
+		return nil, errors.New("petition section repository not set")
 	}
 
-	err := s.petitionRepo.UpdateGeneratedContent(ctx, req.PetitionID, req.Content)
+	sections, err := s.sectionRepo.ListByPetitionID(ctx, req.PetitionID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &UpdateGeneratedContentResult{}, nil
+	return &ListSectionsResult{Sections: sections}, nil
 }
 
 // ListPetitionsRequest represents a request to list petitions
 type ListPetitionsRequest struct {
-	UserID uuid.UUID
-	Status *models.PetitionStatus
-	Limit  int
-	Offset int
+	UserID             uuid.UUID
+	Status             *models.PetitionStatus
+	VisaType           *models.VisaType
+	ClientNameContains string
+	CreatedAfter       *time.Time
+	CreatedBefore      *time.Time
+	UpdatedAfter       *time.Time
+	UpdatedBefore      *time.Time
+	SortBy             repository.PetitionSortField
+	SortDesc           bool
+	Cursor             string // Opaque cursor from a previous ListPetitionsResult
+	Limit              int
 }
 
 // ListPetitionsResult represents the result of listing petitions
 type ListPetitionsResult struct {
-	Petitions []*models.Petition
+	Petitions  []*models.Petition
+	Total      int    // Matching petitions across all pages
+	NextCursor string // Empty when there are no more pages
+}
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// listCursor is the decoded form of the opaque pagination cursor
+type listCursor struct {
+	SortBy repository.PetitionSortField `json:"s"`
+	Desc   bool                         `json:"d"`
+	Value  string                       `json:"v"`
+	ID     uuid.UUID                    `json:"id"`
 }
 
-// ListPetitions lists petitions for a user
+// ListPetitions lists petitions visible to a user with keyset pagination
 func (s *PetitionService) ListPetitions(ctx context.Context, req ListPetitionsRequest) (*ListPetitionsResult, error) {
 	if s.petitionRepo == nil {
 		return nil, errors.New("petition repository not set")
 	}
 
-	petitions, err := s.petitionRepo.ListByUserID(ctx, req.UserID, req.Status, req.Limit, req.Offset)
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	sortBy := req.SortBy
+	if sortBy == "" {
+		sortBy = repository.PetitionSortCreatedAt
+	}
+
+	opts := repository.PetitionListOptions{
+		Status:             req.Status,
+		ExcludeArchived:    req.Status == nil, // Archived petitions only appear when asked for
+		VisaType:           req.VisaType,
+		ClientNameContains: req.ClientNameContains,
+		CreatedAfter:       req.CreatedAfter,
+		CreatedBefore:      req.CreatedBefore,
+		UpdatedAfter:       req.UpdatedAfter,
+		UpdatedBefore:      req.UpdatedBefore,
+		SortBy:             sortBy,
+		SortDesc:           req.SortDesc,
+		Limit:              limit + 1, // Fetch one extra row to detect a next page
+	}
+
+	if req.Cursor != "" {
+		after, err := decodeListCursor(req.Cursor, sortBy, req.SortDesc)
+		if err != nil {
+			return nil, err
+		}
+		opts.After = after
+	}
+
+	petitions, err := s.petitionRepo.ListByUserID(ctx, req.UserID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.petitionRepo.CountByUserID(ctx, req.UserID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ListPetitionsResult{
+		Petitions: petitions,
+		Total:     total,
+	}
+	if len(petitions) > limit {
+		result.Petitions = petitions[:limit]
+		result.NextCursor = encodeListCursor(result.Petitions[limit-1], sortBy, req.SortDesc)
+	}
+	if result.Petitions == nil {
+		result.Petitions = []*models.Petition{}
+	}
+
+	return result, nil
+}
+
+// encodeListCursor builds the opaque cursor pointing just past the given petition
+func encodeListCursor(last *models.Petition, sortBy repository.PetitionSortField, desc bool) string {
+	c := listCursor{SortBy: sortBy, Desc: desc, ID: last.ID}
+	switch sortBy {
+	case repository.PetitionSortUpdatedAt:
+		c.Value = last.UpdatedAt.Format(time.RFC3339Nano)
+	case repository.PetitionSortClientName:
+		c.Value = last.ClientName
+	default:
+		c.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListCursor parses a cursor and checks it was issued for the same ordering
+func decodeListCursor(cursor string, sortBy repository.PetitionSortField, desc bool) (*repository.PetitionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.SortBy != sortBy || c.Desc != desc {
+		return nil, ErrInvalidCursor
+	}
+
+	after := &repository.PetitionCursor{ID: c.ID}
+	if sortBy == repository.PetitionSortClientName {
+		after.Value = c.Value
+	} else {
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		after.Value = t
+	}
+
+	return after, nil
+}
+
+// ArchivePetitionRequest represents a request to archive (soft-delete) a petition
+type ArchivePetitionRequest struct {
+	PetitionID uuid.UUID
+	Purge      bool // Also schedule a hard purge after the retention window
+}
+
+// ArchivePetitionResult represents the result of archiving a petition
+type ArchivePetitionResult struct {
+	PurgeAfter *time.Time
+}
+
+// ArchivePetition moves a petition to StatusArchived and optionally schedules
+// a hard purge once the retention window has elapsed
+func (s *PetitionService) ArchivePetition(ctx context.Context, req ArchivePetitionRequest) (*ArchivePetitionResult, error) {
+	if s.petitionRepo == nil {
+		return nil, errors.New("petition repository not set")
+	}
+
+	var purgeDelay *time.Duration
+	if req.Purge {
+		purgeDelay = &s.retention
+	}
+
+	purgeAfter, err := s.petitionRepo.Archive(ctx, req.PetitionID, purgeDelay)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ListPetitionsResult{Petitions: petitions}, nil
+	return &ArchivePetitionResult{PurgeAfter: purgeAfter}, nil
+}
+
+// RestorePetitionRequest represents a request to restore an archived petition
+type RestorePetitionRequest struct {
+	PetitionID uuid.UUID
+}
+
+// RestorePetition returns an archived petition to draft and cancels any pending purge
+func (s *PetitionService) RestorePetition(ctx context.Context, req RestorePetitionRequest) error {
+	if s.petitionRepo == nil {
+		return errors.New("petition repository not set")
+	}
+
+	return s.petitionRepo.Restore(ctx, req.PetitionID)
+}
+
+// PurgeExpired hard-deletes archived petitions whose retention window has
+// elapsed, then removes their file blobs from storage. Blobs whose delete
+// fails stay queued and are retried on the next sweep. Returns the number of
+// petitions purged.
+func (s *PetitionService) PurgeExpired(ctx context.Context) (int, error) {
+	if s.petitionRepo == nil {
+		return 0, errors.New("petition repository not set")
+	}
+	if s.fileRepo == nil {
+		return 0, errors.New("file repository not set")
+	}
+	if s.storage == nil {
+		return 0, errors.New("storage not set")
+	}
+
+	ids, err := s.petitionRepo.ListPurgeable(ctx, 50)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := s.petitionRepo.Purge(ctx, id); err != nil {
+			// Restored in the meantime, or a transient failure to retry next sweep
+			log.Printf("Warning: Failed to purge petition %s: %v", id, err)
+			continue
+		}
+		purged++
+	}
+
+	// Blobs are removed after commit so rows never point at missing files
+	paths, err := s.fileRepo.ListBlobDeletions(ctx, 500)
+	if err != nil {
+		return purged, err
+	}
+	for _, path := range paths {
+		if err := s.storage.Delete(ctx, path); err != nil {
+			log.Printf("Warning: Failed to delete blob %s of a purged file: %v", path, err)
+			if err := s.fileRepo.FailBlobDeletion(ctx, path, err.Error()); err != nil {
+				log.Printf("Warning: Failed to record blob deletion failure for %s: %v", path, err)
+			}
+			continue
+		}
+		if err := s.fileRepo.CompleteBlobDeletion(ctx, path); err != nil {
+			log.Printf("Warning: Failed to dequeue deleted blob %s: %v", path, err)
+		}
+	}
+
+	return purged, nil
+}
+
+// RunPurger calls PurgeExpired every interval until ctx is cancelled
+func (s *PetitionService) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeExpired(ctx)
+			if err != nil {
+				log.Printf("Warning: Petition purge sweep failed: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d archived petitions", purged)
+			}
+		}
+	}
+}
+
+// UpdateSectionRequest represents an edit of one section of a petition's draft
+type UpdateSectionRequest struct {
+	Petition  *models.Petition
+	SectionID uuid.UUID
+	Title     *string // Unchanged when nil
+	Content   *string // Unchanged when nil
+}
+
+// UpdateSectionResult represents an edited section
+type UpdateSectionResult struct {
+	Section *models.PetitionSection
+}
+
+// UpdateSection edits a section of the petition's draft. The generated
+// content is rendered from the sections again and the exhibits are
+// re-lettered against it. Returns ErrSectionNotFound if the section is not
+// part of the draft, and ErrInvalidSectionTitle if the title spans lines.
+func (s *PetitionService) UpdateSection(ctx context.Context, req UpdateSectionRequest) (*UpdateSectionResult, error) {
+	if s.sectionRepo == nil {
+		return nil, errors.New("petition section repository not set")
+	}
+	if req.Title != nil && strings.ContainsAny(*req.Title, "\r\n") {
+		return nil, ErrInvalidSectionTitle
+	}
+
+	section, err := s.findSection(ctx, req.Petition.ID, req.SectionID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Title != nil {
+		section.Title = *req.Title
+	}
+	if req.Content != nil {
+		section.Content = *req.Content
+	}
+
+	err = s.sectionRepo.Update(ctx, req.Petition.ID, section.ID, section.Title, section.Content)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSectionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Edited text may cite exhibits differently; the edit stands with blank
+	// placeholders if lettering fails
+	if s.exhibitRepo != nil {
+		if _, err := s.exhibitRepo.Resolve(ctx, req.Petition.ID, petitionExhibitSections(req.Petition)); err != nil {
+			log.Printf("Warning: Failed to letter exhibits of petition %s: %v", req.Petition.ID, err)
+		}
+	}
+
+	section, err = s.findSection(ctx, req.Petition.ID, req.SectionID)
+	if err != nil {
+		return nil, err
+	}
+	return &UpdateSectionResult{Section: section}, nil
+}
+
+// findSection returns a section of the petition's draft, or ErrSectionNotFound
+func (s *PetitionService) findSection(ctx context.Context, petitionID, sectionID uuid.UUID) (*models.PetitionSection, error) {
+	sections, err := s.sectionRepo.ListByPetitionID(ctx, petitionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, section := range sections {
+		if section.ID == sectionID {
+			return section, nil
+		}
+	}
+	return nil, ErrSectionNotFound
 }
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// CheckReadinessRequest represents a request to check whether a petition can be drafted
+type CheckReadinessRequest struct {
+	PetitionID uuid.UUID
+}
+
+// CheckReadinessResult represents the result of a readiness check
+type CheckReadinessResult struct {
+	Report *models.ReadinessReport
+}
+
+// NotReadyError is returned by GenerateDraft when the petition has blocking
+// readiness issues. It wraps ErrMissingRequiredData.
+type NotReadyError struct {
+	Report *models.ReadinessReport
+}
+
+func (e *NotReadyError) Error() string {
+	return ErrMissingRequiredData.Error()
+}
+
+func (e *NotReadyError) Unwrap() error {
+	return ErrMissingRequiredData
+}
+
+// CheckReadiness reports the evidence a petition is missing before drafting,
+// and where the evidence it has is weak
+func (s *DraftService) CheckReadiness(
+	ctx context.Context,
+	req CheckReadinessRequest,
+) (*CheckReadinessResult, error) {
+	if s.petitionRepo == nil {
+		return nil, errors.New("petition repository not set")
+	}
+
+	petition, err := s.petitionRepo.GetByID(ctx, req.PetitionID)
+	if err != nil {
+		return nil, ErrPetitionNotFound
+	}
+
+	return &CheckReadinessResult{
+		Report: evaluateReadiness(petition),
+	}, nil
+}
+
+// evaluateReadiness checks a petition against its visa type's criteria
+func evaluateReadiness(petition *models.Petition) *models.ReadinessReport {
+	report := &models.ReadinessReport{
+		PetitionID: petition.ID,
+		VisaType:   petition.VisaType,
+		Issues:     []models.ReadinessIssue{},
+		Criteria:   []models.CriterionReadiness{},
+	}
+
+	if petition.ClientName == "" {
+		report.Issues = append(report.Issues, blockingIssue("MISSING_CLIENT_NAME", "client_name", "Client name is required"))
+	}
+	if petition.FieldOfExpertise == "" {
+		report.Issues = append(report.Issues, blockingIssue("MISSING_FIELD_OF_EXPERTISE", "field_of_expertise", "Field of expertise is required"))
+	}
+
+	visa, ok := criteria.Lookup(string(petition.VisaType))
+	if !ok {
+		// The criteria to check depend on the visa type
+		report.Issues = append(report.Issues, blockingIssue("UNKNOWN_VISA_TYPE", "visa_type", "Select the visa type the petition is filed for"))
+	} else if petition.VisaType == models.VisaTypeEB2NIW {
+		// NIW petitions are argued on every Dhanasar prong rather than selected criteria
+		for i := range visa.Criteria {
+			report.Criteria = append(report.Criteria, criterionReadiness(&visa.Criteria[i], petition.CriteriaDetails))
+		}
+	} else {
+		selected, standalone := 0, false
+		for _, id := range petition.SelectedCriteria {
+			criterion, ok := visa.Criterion(id)
+			if !ok {
+				report.Criteria = append(report.Criteria, models.CriterionReadiness{
+					Criterion: id,
+					Title:     id,
+					Status:    models.CriterionIncomplete,
+					Issues: []models.ReadinessIssue{
+						blockingIssue("UNKNOWN_CRITERION", "selected_criteria", fmt.Sprintf("%s is not a criterion of %s", id, visa.VisaType)),
+					},
+				})
+				continue
+			}
+			selected++
+			standalone = standalone || criterion.Standalone
+			report.Criteria = append(report.Criteria, criterionReadiness(criterion, petition.CriteriaDetails))
+		}
+
+		if len(petition.SelectedCriteria) == 0 {
+			report.Issues = append(report.Issues, blockingIssue("NO_CRITERIA_SELECTED", "selected_criteria", "Select the criteria the petition is argued on"))
+		} else if selected < visa.MinCriteria && !standalone {
+			report.Issues = append(report.Issues, models.ReadinessIssue{
+				Code:     "TOO_FEW_CRITERIA",
+				Severity: models.ReadinessWarning,
+				Field:    "selected_criteria",
+				Message:  fmt.Sprintf("%s petitions must meet at least %d criteria; %d selected", visa.VisaType, visa.MinCriteria, selected),
+			})
+		}
+	}
+
+	report.Ready = !hasBlockingIssue(report.Issues)
+	for _, criterion := range report.Criteria {
+		if criterion.Status == models.CriterionIncomplete {
+			report.Ready = false
+		}
+	}
+
+	return report
+}
+
+// criterionReadiness checks the details entered for one criterion
+func criterionReadiness(criterion *criteria.Criterion, details models.CriteriaDetails) models.CriterionReadiness {
+	readiness := models.CriterionReadiness{
+		Criterion: criterion.ID,
+		Title:     criterion.Title,
+		Issues:    []models.ReadinessIssue{},
+	}
+
+	detail := details[criterion.ID]
+	if len(detail) == 0 {
+		readiness.Issues = append(readiness.Issues, blockingIssue("MISSING_CRITERION_DETAILS", "criteria_details."+criterion.ID, "No evidence has been entered for this criterion"))
+	} else {
+		for _, gap := range criterion.Gaps(detail) {
+			if gap.Required {
+				readiness.Issues = append(readiness.Issues, blockingIssue("MISSING_FACT", gap.Field, gap.Label+" is required"))
+				continue
+			}
+			readiness.Issues = append(readiness.Issues, models.ReadinessIssue{
+				Code:     "WEAK_EVIDENCE",
+				Severity: models.ReadinessWarning,
+				Field:    gap.Field,
+				Message:  gap.Advice,
+			})
+		}
+	}
+
+	switch {
+	case hasBlockingIssue(readiness.Issues):
+		readiness.Status = models.CriterionIncomplete
+	case len(readiness.Issues) > 0:
+		readiness.Status = models.CriterionWeak
+	default:
+		readiness.Status = models.CriterionReady
+	}
+	return readiness
+}
+
+func blockingIssue(code, field, message string) models.ReadinessIssue {
+	return models.ReadinessIssue{
+		Code:     code,
+		Severity: models.ReadinessBlocking,
+		Field:    field,
+		Message:  message,
+	}
+}
+
+func hasBlockingIssue(issues []models.ReadinessIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == models.ReadinessBlocking {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/documents"
+	"meritdraft-backend/models"
+)
+
+// authorshipCriterion is the criterion scholar profiles are imported into
+const authorshipCriterion = "authorship"
+
+var (
+	ErrUnsupportedScholarProfile = errors.New("scholar profile must be a saved profile page (.html), or a BibTeX (.bib) or CSV export")
+	ErrEmptyScholarProfile       = errors.New("no publications were found in the scholar profile")
+)
+
+// ImportScholarProfileRequest represents an uploaded scholar profile
+type ImportScholarProfileRequest struct {
+	Petition *models.Petition
+	Filename string
+	MimeType string
+	Data     []byte
+}
+
+// ImportScholarProfileResult represents the result of importing a scholar profile
+type ImportScholarProfileResult struct {
+	Profile           *documents.ScholarProfile
+	ParsedDocuments   models.ParsedDocuments
+	AuthorshipUpdated bool // False when the visa type has no authorship criterion
+	PublicationsAdded int  // Publications not already in the authorship details
+}
+
+// ImportScholarProfile parses a saved Google Scholar profile page, or a
+// BibTeX or CSV export, merges its publications into the petition's
+// authorship details and records its publication count, total citations
+// and h-index
+func (s *PetitionService) ImportScholarProfile(ctx context.Context, req ImportScholarProfileRequest) (*ImportScholarProfileResult, error) {
+	if s.petitionRepo == nil {
+		return nil, errors.New("petition repository not set")
+	}
+
+	profile, err := documents.ParseScholarProfile(req.MimeType, req.Filename, req.Data)
+	switch {
+	case errors.Is(err, documents.ErrNoPublications):
+		return nil, ErrEmptyScholarProfile
+	case err != nil:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedScholarProfile, err)
+	}
+
+	metrics := models.ParsedDocuments{
+		PublicationsCount: len(profile.Publications),
+		CitationsCount:    profile.TotalCitations,
+		HIndex:            profile.HIndex,
+	}
+	// Publications fill the authorship criterion of visa types that have one
+	authorship := false
+	if visa, ok := criteria.Lookup(string(req.Petition.VisaType)); ok {
+		_, authorship = visa.Criterion(authorshipCriterion)
+	}
+
+	var scholarLink *string
+	if profile.URL != "" {
+		scholarLink = &profile.URL
+	}
+
+	added, err := s.petitionRepo.ImportPublications(ctx, req.Petition.ID, profile.Publications, authorship, metrics, scholarLink)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImportScholarProfileResult{
+		Profile:           profile,
+		ParsedDocuments:   metrics,
+		AuthorshipUpdated: authorship,
+		PublicationsAdded: added,
+	}, nil
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"meritdraft-backend/criteria"
+	"meritdraft-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrStrategyNotApplicable is returned for visa types argued on every
+// criterion rather than a selection of them
+var ErrStrategyNotApplicable = errors.New("this visa type is argued on every criterion; there is nothing to select")
+
+// Scoring weights. A criterion earns up to 60 points for its evidence and
+// up to 40 for how closely it resembles arguments that won on appeal.
+const (
+	strategyBasePoints     = 20 // Any evidence at all
+	strategyEntryPoints    = 4  // Per award, publication, role, ... up to strategyMaxEntries
+	strategyMaxEntries     = 5
+	strategyCoveragePoints = 20 // Scaled by the fraction of facts entered
+	strategyRequiredGap    = 10 // Deducted when required facts are missing
+	strategyArgumentPoints = 40 // Scaled by the similarity of the closest winning argument
+
+	// Similarities at or below the floor earn nothing; at or above the ceiling, every point
+	strategySimilarityFloor   = 0.5
+	strategySimilarityCeiling = 0.9
+
+	strategyStrongScore   = 70
+	strategyModerateScore = 45
+
+	strategyMinRecommended = 3 // Recommended even when the visa type requires fewer
+	strategyArguments      = 3 // Winning arguments retrieved per criterion
+	strategyMaxFacts       = 10
+	strategyExcerptChars   = 400
+)
+
+// SuggestStrategyRequest represents a request to recommend criteria
+type SuggestStrategyRequest struct {
+	PetitionID uuid.UUID
+	Apply      bool // Save the recommended criteria as the petition's selected criteria
+}
+
+// SuggestStrategyResult represents the recommended criteria
+type SuggestStrategyResult struct {
+	Report *models.StrategyReport
+}
+
+// SuggestStrategy scores each criterion of the petition's visa type from the
+// facts entered for it, the evidence parsed from the uploaded CV and job
+// offer, and the winning appeal arguments most similar to that evidence,
+// then recommends the strongest criteria to argue
+func (s *DraftService) SuggestStrategy(
+	ctx context.Context,
+	req SuggestStrategyRequest,
+) (*SuggestStrategyResult, error) {
+	if s.petitionRepo == nil {
+		return nil, errors.New("petition repository not set")
+	}
+
+	petition, err := s.petitionRepo.GetByID(ctx, req.PetitionID)
+	if err != nil {
+		return nil, ErrPetitionNotFound
+	}
+	if petition.VisaType == models.VisaTypeEB2NIW {
+		return nil, ErrStrategyNotApplicable
+	}
+
+	visa, err := lookupVisaType(petition)
+	if err != nil {
+		return nil, err
+	}
+	evidence := s.documentEvidence(ctx, petition)
+
+	selected := make(map[string]bool, len(petition.SelectedCriteria))
+	for _, id := range petition.SelectedCriteria {
+		selected[id] = true
+	}
+
+	report := &models.StrategyReport{
+		PetitionID:  petition.ID,
+		VisaType:    petition.VisaType,
+		MinCriteria: visa.MinCriteria,
+		Recommended: []string{},
+		Criteria:    make([]models.CriterionScore, 0, len(visa.Criteria)),
+	}
+	for i := range visa.Criteria {
+		criterion := &visa.Criteria[i]
+		score := s.scoreCriterion(ctx, petition, visa, criterion, evidence[criterion.ID])
+		score.Selected = selected[criterion.ID]
+		report.Criteria = append(report.Criteria, score)
+	}
+
+	// Strongest first; ties keep registry order
+	sort.SliceStable(report.Criteria, func(i, j int) bool {
+		return report.Criteria[i].Score > report.Criteria[j].Score
+	})
+	recommend(report, visa)
+
+	if req.Apply {
+		petition.SelectedCriteria = report.Recommended
+		if err := s.petitionRepo.UpdateSelectedCriteria(ctx, petition); err != nil {
+			return nil, fmt.Errorf("failed to save selected criteria: %w", err)
+		}
+		report.Applied = true
+	}
+
+	return &SuggestStrategyResult{Report: report}, nil
+}
+
+// documentEvidence returns the supporting facts parsed from the petition's
+// CV and job offer, keyed by criterion. Documents that have not been parsed
+// yet contribute nothing.
+func (s *DraftService) documentEvidence(ctx context.Context, petition *models.Petition) map[string][]models.SupportingFact {
+	evidence := make(map[string][]models.SupportingFact)
+	if s.extractionRepo == nil {
+		return evidence
+	}
+
+	documents := []struct {
+		fileID *uuid.UUID
+		source models.FactSource
+	}{
+		{petition.CVFileID, models.FactFromCV},
+		{petition.JobOfferFileID, models.FactFromJobOffer},
+	}
+	for _, document := range documents {
+		if document.fileID == nil {
+			continue
+		}
+		extraction, err := s.extractionRepo.GetByFileID(ctx, *document.fileID)
+		if err != nil || extraction.Status != models.ExtractionCompleted {
+			continue
+		}
+		for _, candidate := range extraction.Candidates {
+			text := strings.TrimSpace(candidate.Source.Text)
+			if candidate.Criterion == "" || text == "" {
+				continue
+			}
+			evidence[candidate.Criterion] = append(evidence[candidate.Criterion], models.SupportingFact{
+				Source: document.source,
+				Text:   text,
+			})
+		}
+	}
+	return evidence
+}
+
+// scoreCriterion scores one criterion from its entered details, the facts
+// parsed from documents, and the closest winning appeal arguments
+func (s *DraftService) scoreCriterion(
+	ctx context.Context,
+	petition *models.Petition,
+	visa *criteria.VisaType,
+	criterion *criteria.Criterion,
+	parsed []models.SupportingFact,
+) models.CriterionScore {
+	score := models.CriterionScore{
+		Criterion: criterion.ID,
+		Title:     criterion.Title,
+		Strength:  models.StrengthNone,
+		Reasons:   []string{},
+		Facts:     []models.SupportingFact{},
+		Arguments: []models.SimilarArgument{},
+	}
+
+	detail := petition.CriteriaDetails[criterion.ID]
+	entered := 0
+	if criterion.Coverage(detail) > 0 {
+		entered = countEntries(detail)
+		for _, line := range strings.Split(s.formatClientFacts(visa, criterion.ID, detail), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				score.Facts = append(score.Facts, models.SupportingFact{Source: models.FactFromDetails, Text: line})
+			}
+		}
+	}
+	score.Facts = append(score.Facts, parsed...)
+	if len(score.Facts) > strategyMaxFacts {
+		score.Facts = score.Facts[:strategyMaxFacts]
+	}
+
+	entries := entered
+	if len(parsed) > entries {
+		entries = len(parsed)
+	}
+	if entries == 0 {
+		score.Reasons = append(score.Reasons, "No evidence has been entered or found in the uploaded documents")
+		return score
+	}
+
+	points := float64(strategyBasePoints + strategyEntryPoints*min(entries, strategyMaxEntries))
+	switch {
+	case entered == 0:
+		score.Reasons = append(score.Reasons, fmt.Sprintf("%d item(s) found in the uploaded documents but not yet entered", len(parsed)))
+	default:
+		score.Reasons = append(score.Reasons, fmt.Sprintf("%d item(s) entered", entered))
+		if len(parsed) > 0 {
+			score.Reasons = append(score.Reasons, fmt.Sprintf("%d item(s) found in the uploaded documents", len(parsed)))
+		}
+		points += strategyCoveragePoints * criterion.Coverage(detail)
+		required := false
+		for _, gap := range criterion.Gaps(detail) {
+			if gap.Required {
+				required = true
+				score.Reasons = append(score.Reasons, gap.Label+" is required")
+			} else {
+				score.Reasons = append(score.Reasons, gap.Advice)
+			}
+		}
+		if required {
+			points -= strategyRequiredGap
+		}
+	}
+
+	if entered == 0 {
+		detail = nil // Embed the parsed facts instead
+	}
+	score.Arguments = s.similarArguments(ctx, petition, visa, criterion.ID, detail, parsed)
+	if len(score.Arguments) == 0 {
+		score.Reasons = append(score.Reasons, "No comparable winning arguments were found in the appeal decisions")
+	} else {
+		closest := score.Arguments[0]
+		points += strategyArgumentPoints * similarityWeight(closest.Similarity)
+		score.Reasons = append(score.Reasons, fmt.Sprintf("Closest winning argument: %s (similarity %.2f)", closest.SourceDocument, closest.Similarity))
+	}
+
+	score.Score = int(math.Round(math.Max(0, math.Min(100, points))))
+	switch {
+	case score.Score >= strategyStrongScore:
+		score.Strength = models.StrengthStrong
+	case score.Score >= strategyModerateScore:
+		score.Strength = models.StrengthModerate
+	default:
+		score.Strength = models.StrengthWeak
+	}
+	return score
+}
+
+// similarArguments returns the winning appeal arguments closest to the
+// criterion's evidence, closest first. Retrieval failures are logged and
+// leave the criterion scored on its evidence alone.
+func (s *DraftService) similarArguments(
+	ctx context.Context,
+	petition *models.Petition,
+	visa *criteria.VisaType,
+	criterion string,
+	detail models.CriteriaDetail,
+	parsed []models.SupportingFact,
+) []models.SimilarArgument {
+	arguments := []models.SimilarArgument{}
+	if s.legalChunkRepo == nil {
+		return arguments
+	}
+
+	summary := ""
+	if len(detail) > 0 {
+		summary = s.extractFactSummary(visa, criterion, detail)
+	} else {
+		texts := make([]string, 0, len(parsed))
+		for _, fact := range parsed {
+			texts = append(texts, fact.Text)
+		}
+		summary = strings.Join(texts, " ")
+	}
+
+	embedding, err := s.generateQueryEmbedding(ctx, criterion, petition.FieldOfExpertise, summary)
+	if err != nil {
+		log.Printf("Warning: Failed to embed %s evidence: %v", criterion, err)
+		return arguments
+	}
+
+	// appeal_decision searches return winning arguments only
+	chunks, err := s.legalChunkRepo.SearchByCriterion(ctx, embedding, visa.Corpus, criterion, "appeal_decision", strategyArguments)
+	if err != nil {
+		log.Printf("Warning: Failed to retrieve appeals for %s: %v", criterion, err)
+		return arguments
+	}
+
+	for _, chunk := range chunks {
+		argument := models.SimilarArgument{
+			ChunkID:        chunk.ID,
+			SourceDocument: chunk.SourceDocument,
+			Excerpt:        excerpt(chunk.Text, strategyExcerptChars),
+			Similarity:     math.Round((1-chunk.Distance)*100) / 100,
+		}
+		if chunk.AppealCitation != nil {
+			argument.Citation = *chunk.AppealCitation
+		}
+		arguments = append(arguments, argument)
+	}
+	return arguments
+}
+
+// recommend marks the criteria to argue: every strong or moderate one, then
+// the next strongest until the minimum is reached
+func recommend(report *models.StrategyReport, visa *criteria.VisaType) {
+	minimum := max(strategyMinRecommended, visa.MinCriteria)
+
+	supported, standalone := 0, false
+	for i := range report.Criteria {
+		score := &report.Criteria[i]
+		if score.Strength != models.StrengthStrong && score.Strength != models.StrengthModerate {
+			continue
+		}
+		score.Recommended = true
+		supported++
+		if c, ok := visa.Criterion(score.Criterion); ok && c.Standalone && score.Strength == models.StrengthStrong {
+			standalone = true
+		}
+	}
+
+	// Criteria are sorted strongest first, so padding takes the next strongest
+	for i := range report.Criteria {
+		score := &report.Criteria[i]
+		if !score.Recommended && len(report.Recommended) < minimum {
+			score.Recommended = true
+			score.Reasons = append(score.Reasons, fmt.Sprintf("Recommended to reach %d criteria; gather more evidence before drafting", minimum))
+		}
+		if score.Recommended {
+			report.Recommended = append(report.Recommended, score.Criterion)
+		}
+	}
+
+	report.Sufficient = supported >= visa.MinCriteria || standalone
+}
+
+// countEntries counts the awards, publications, roles, ... in details. A
+// criterion without list facts counts as one entry.
+func countEntries(detail models.CriteriaDetail) int {
+	entries := 0
+	for _, value := range detail {
+		if list, ok := value.([]interface{}); ok {
+			entries += len(list)
+		}
+	}
+	if entries == 0 {
+		return 1
+	}
+	return entries
+}
+
+// similarityWeight scales a similarity between the floor and ceiling to 0-1
+func similarityWeight(similarity float64) float64 {
+	weight := (similarity - strategySimilarityFloor) / (strategySimilarityCeiling - strategySimilarityFloor)
+	return math.Max(0, math.Min(1, weight))
+}
+
+// excerpt shortens text to at most limit bytes, cutting at a word boundary
+func excerpt(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= limit {
+		return text
+	}
+	cut := strings.LastIndex(text[:limit], " ")
+	if cut <= 0 {
+		cut = limit
+	}
+	return text[:cut] + "..."
+}
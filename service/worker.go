@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"meritdraft-backend/models"
+	"meritdraft-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// Worker leases generation jobs from Postgres and runs them through
+// DraftService.ProcessDraft. Jobs survive restarts: a job whose worker stops
+// heartbeating is picked up again once its lease expires.
+type Worker struct {
+	draftService      *DraftService
+	jobRepo           *repository.GenerationJobRepository
+	id                string
+	concurrency       int
+	pollInterval      time.Duration
+	leaseDuration     time.Duration
+	heartbeatInterval time.Duration
+	maxAttempts       int
+}
+
+// WorkerOption is a functional option for Worker
+type WorkerOption func(*Worker)
+
+// WorkerWithDraftService sets the draft service that processes jobs
+func WorkerWithDraftService(draftService *DraftService) WorkerOption {
+	return func(w *Worker) {
+		w.draftService = draftService
+	}
+}
+
+// WorkerWithGenerationJobRepository sets the generation job repository
+func WorkerWithGenerationJobRepository(repo *repository.GenerationJobRepository) WorkerOption {
+	return func(w *Worker) {
+		w.jobRepo = repo
+	}
+}
+
+// WorkerWithID sets the lease owner name recorded on jobs
+func WorkerWithID(id string) WorkerOption {
+	return func(w *Worker) {
+		w.id = id
+	}
+}
+
+// WorkerWithConcurrency sets how many jobs are processed in parallel
+func WorkerWithConcurrency(n int) WorkerOption {
+	return func(w *Worker) {
+		w.concurrency = n
+	}
+}
+
+// WorkerWithPollInterval sets how long an idle worker waits before polling again
+func WorkerWithPollInterval(d time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.pollInterval = d
+	}
+}
+
+// WorkerWithLeaseDuration sets how long a lease lasts without a heartbeat.
+// Heartbeats are sent at a third of this interval.
+func WorkerWithLeaseDuration(d time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.leaseDuration = d
+		w.heartbeatInterval = d / 3
+	}
+}
+
+// WorkerWithMaxAttempts sets how many times a job may be leased before it is failed
+func WorkerWithMaxAttempts(n int) WorkerOption {
+	return func(w *Worker) {
+		w.maxAttempts = n
+	}
+}
+
+// NewWorker creates a new generation job worker
+func NewWorker(opts ...WorkerOption) *Worker {
+	hostname, _ := os.Hostname()
+	w := &Worker{
+		id:                fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+		concurrency:       2,
+		pollInterval:      2 * time.Second,
+		leaseDuration:     2 * time.Minute,
+		heartbeatInterval: 40 * time.Second,
+		maxAttempts:       3,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Run processes jobs until ctx is cancelled, then waits for in-flight jobs to
+// stop and returns them to the queue
+func (w *Worker) Run(ctx context.Context) error {
+	if w.draftService == nil {
+		return errors.New("draft service not set")
+	}
+	if w.jobRepo == nil {
+		return errors.New("generation job repository not set")
+	}
+	if w.concurrency < 1 {
+		return errors.New("worker concurrency must be at least 1")
+	}
+
+	log.Printf("Worker %s started (concurrency %d)", w.id, w.concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	log.Printf("Worker %s stopped", w.id)
+	return nil
+}
+
+// loop repeatedly leases and processes one job at a time
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := w.jobRepo.Lease(ctx, w.id, w.leaseDuration)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Warning: Worker %s failed to lease job: %v", w.id, err)
+			}
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.pollInterval):
+			}
+			continue
+		}
+
+		w.process(ctx, job)
+	}
+}
+
+// process runs a leased job while heartbeating its lease
+func (w *Worker) process(ctx context.Context, job *models.GenerationJob) {
+	if job.Attempts > w.maxAttempts {
+		msg := fmt.Sprintf("job abandoned after %d attempts", job.Attempts-1)
+		w.draftService.markJobFailed(ctx, job.ID, msg)
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Keep the lease alive; cancel the job if another worker has taken it over
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(w.heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				err := w.jobRepo.Heartbeat(jobCtx, job.ID, w.id, w.leaseDuration)
+				if errors.Is(err, repository.ErrLeaseLost) {
+					log.Printf("Worker %s no longer holds job %s (cancelled or taken over)", w.id, job.ID)
+					cancel()
+					return
+				}
+				if err != nil && jobCtx.Err() == nil {
+					log.Printf("Warning: Heartbeat for job %s failed: %v", job.ID, err)
+				}
+			}
+		}
+	}()
+
+	err := w.draftService.ProcessDraft(jobCtx, job.ID)
+	cancel()
+	<-heartbeatDone
+
+	if errors.Is(err, ErrJobCancelled) || errors.Is(err, context.Canceled) {
+		log.Printf("Generation job %s stopped", job.ID)
+	} else if err != nil {
+		log.Printf("Generation job %s failed: %v", job.ID, err)
+	}
+
+	// Shutting down mid-job: hand the job back so another worker resumes it
+	// immediately instead of waiting for the lease to expire
+	if ctx.Err() != nil {
+		releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer releaseCancel()
+		if err := w.jobRepo.Release(releaseCtx, job.ID, w.id); err != nil {
+			log.Printf("Warning: Failed to release job %s: %v", job.ID, err)
+		}
+	}
+}